@@ -0,0 +1,101 @@
+// Package admin defines the staff roles of the admin API, what each may do,
+// and how admin API keys are issued and stored.
+package admin
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// Role is the role an admin API key acts with.
+type Role string
+
+const (
+	// RoleViewer looks up customers, wallets and transactions.
+	RoleViewer Role = "viewer"
+	// RoleSupport also freezes and unfreezes wallets, reviews transactions
+	// held by the risk rules, changes customers' KYC tiers and resets
+	// their transaction PINs.
+	RoleSupport Role = "support"
+	// RoleOperator also suspends, reinstates and force-disables wallets,
+	// requests and reviews balance adjustments, reviews transactions held
+	// by the risk rules, changes customers' KYC tiers and resets their
+	// transaction PINs.
+	RoleOperator Role = "operator"
+	// RoleAuditor looks up customers, wallets and transactions, reads the
+	// audit log, exports compliance reports, verifies transaction hash
+	// chains and reads reconciliation reports, but changes nothing.
+	RoleAuditor Role = "auditor"
+)
+
+// Roles lists every role in a stable order.
+var Roles = []Role{RoleViewer, RoleSupport, RoleOperator, RoleAuditor}
+
+// Permission is one kind of admin action.
+type Permission string
+
+const (
+	PermCustomersRead  Permission = "customers:read"
+	PermWalletsRead    Permission = "wallets:read"
+	PermWalletsFreeze  Permission = "wallets:freeze"
+	PermWalletsSuspend Permission = "wallets:suspend"
+	PermWalletsDisable Permission = "wallets:disable"
+	PermAuditRead      Permission = "audit:read"
+	// PermAdjustmentsWrite covers both requesting and reviewing adjustments;
+	// the service keeps one admin from doing both for the same adjustment.
+	PermAdjustmentsWrite Permission = "adjustments:write"
+	// PermRiskReview covers releasing and rejecting transactions held by
+	// the risk rules.
+	PermRiskReview Permission = "risk:review"
+	// PermComplianceRead covers exporting compliance reports.
+	PermComplianceRead Permission = "compliance:read"
+	// PermKYCManage covers upgrading and downgrading customers' KYC tiers.
+	PermKYCManage Permission = "kyc:manage"
+	// PermPINReset covers resetting customers' transaction PINs.
+	PermPINReset Permission = "pin:reset"
+	// PermLedgerRead covers verifying transaction hash chains and reading
+	// balance reconciliation reports.
+	PermLedgerRead Permission = "ledger:read"
+)
+
+var permissions = map[Role][]Permission{
+	RoleViewer:   {PermCustomersRead, PermWalletsRead},
+	RoleSupport:  {PermCustomersRead, PermWalletsRead, PermWalletsFreeze, PermRiskReview, PermKYCManage, PermPINReset},
+	RoleOperator: {PermCustomersRead, PermWalletsRead, PermWalletsFreeze, PermWalletsSuspend, PermWalletsDisable, PermAdjustmentsWrite, PermRiskReview, PermKYCManage, PermPINReset},
+	RoleAuditor:  {PermCustomersRead, PermWalletsRead, PermAuditRead, PermComplianceRead, PermLedgerRead},
+}
+
+// Valid reports whether r is one of Roles.
+func (r Role) Valid() bool {
+	_, ok := permissions[r]
+	return ok
+}
+
+// Can reports whether r grants p.
+func (r Role) Can(p Permission) bool {
+	for _, granted := range permissions[r] {
+		if granted == p {
+			return true
+		}
+	}
+	return false
+}
+
+// keyPrefix makes admin keys recognisable, for instance by secret scanners.
+const keyPrefix = "wak_"
+
+// GenerateKey returns a new random admin API key. Only its hash is stored.
+func GenerateKey() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return keyPrefix + hex.EncodeToString(b), nil
+}
+
+// HashKey is the stored form of an admin API key.
+func HashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,71 @@
+package auth
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+)
+
+// KeySize is the size of the key TOTP secrets are encrypted with, an
+// AES-256 key.
+const KeySize = 32
+
+var errCiphertext = errors.New("malformed or tampered ciphertext")
+
+// Cipher encrypts TOTP secrets at rest with AES-GCM.
+type Cipher struct {
+	aead cipher.AEAD
+}
+
+// ParseKey decodes a base64 encryption key, as kept in the configuration.
+func ParseKey(encoded string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("key is not base64: %w", err)
+	}
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("key is %d bytes, want %d", len(key), KeySize)
+	}
+	return key, nil
+}
+
+func NewCipher(key []byte) (*Cipher, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &Cipher{aead: aead}, nil
+}
+
+// Seal encrypts plaintext under a random nonce, returning both as base64.
+// additional, such as the owner's ID, must be passed to Open too, so a
+// ciphertext cannot be moved to another row.
+func (c *Cipher) Seal(plaintext, additional []byte) (string, error) {
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := c.aead.Seal(nonce, nonce, plaintext, additional)
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Open decrypts what Seal returned.
+func (c *Cipher) Open(encoded string, additional []byte) ([]byte, error) {
+	sealed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(sealed) < c.aead.NonceSize() {
+		return nil, errCiphertext
+	}
+	nonce, ciphertext := sealed[:c.aead.NonceSize()], sealed[c.aead.NonceSize():]
+	plaintext, err := c.aead.Open(nil, nonce, ciphertext, additional)
+	if err != nil {
+		return nil, errCiphertext
+	}
+	return plaintext, nil
+}
@@ -0,0 +1,53 @@
+package auth
+
+import (
+	"bytes"
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+func TestCipher(t *testing.T) {
+	key, err := ParseKey(base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{7}, KeySize)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := NewCipher(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sealed, err := c.Seal(rfcSecret, []byte("customer-1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(sealed, EncodeSecret(rfcSecret)) || strings.Contains(sealed, base64.StdEncoding.EncodeToString(rfcSecret)) {
+		t.Error("sealed secret contains the plaintext")
+	}
+	opened, err := c.Open(sealed, []byte("customer-1"))
+	if err != nil || !bytes.Equal(opened, rfcSecret) {
+		t.Fatalf("Open = (%q, %v), want the secret", opened, err)
+	}
+	if again, _ := c.Seal(rfcSecret, []byte("customer-1")); again == sealed {
+		t.Error("sealing twice gave the same ciphertext, want a random nonce")
+	}
+
+	if _, err := c.Open(sealed, []byte("customer-2")); err == nil {
+		t.Error("Open accepted the ciphertext of another customer")
+	}
+	tampered := []byte(sealed)
+	tampered[len(tampered)/2] ^= 1
+	for _, bad := range []string{string(tampered), "", "not base64!"} {
+		if _, err := c.Open(bad, []byte("customer-1")); err == nil {
+			t.Errorf("Open(%q) accepted a malformed ciphertext", bad)
+		}
+	}
+}
+
+func TestParseKey(t *testing.T) {
+	for _, bad := range []string{"", "short", base64.StdEncoding.EncodeToString(make([]byte, 16))} {
+		if _, err := ParseKey(bad); err == nil {
+			t.Errorf("ParseKey(%q) accepted a key that is not %d bytes", bad, KeySize)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+// RecoveryCodeCount is how many recovery codes an enrollment gets.
+const RecoveryCodeCount = 10
+
+// recoveryAlphabet leaves out characters easily confused with others.
+const recoveryAlphabet = "abcdefghjkmnpqrstuvwxyz23456789"
+
+// NewRecoveryCodes returns n random recovery codes such as "k7dm-x2qf".
+// Each stands in for a one-time password once. Only their HashRecoveryCode
+// is stored.
+func NewRecoveryCodes(n int) ([]string, error) {
+	codes := make([]string, n)
+	b := make([]byte, 8)
+	for i := range codes {
+		if _, err := rand.Read(b); err != nil {
+			return nil, err
+		}
+		var code strings.Builder
+		for j, c := range b {
+			if j == 4 {
+				code.WriteByte('-')
+			}
+			// The bias of the modulo is negligible for this alphabet
+			code.WriteByte(recoveryAlphabet[int(c)%len(recoveryAlphabet)])
+		}
+		codes[i] = code.String()
+	}
+	return codes, nil
+}
+
+// HashRecoveryCode is the stored form of a recovery code. Case, spaces and
+// dashes do not matter, so codes can be typed as read.
+func HashRecoveryCode(code string) string {
+	normalized := strings.Map(func(r rune) rune {
+		if r == '-' || r == ' ' {
+			return -1
+		}
+		return r
+	}, strings.ToLower(code))
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,85 @@
+package auth
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfcSecret is the SHA-1 seed of the RFC 6238 test vectors.
+var rfcSecret = []byte("12345678901234567890")
+
+func TestCodeMatchesRFC6238(t *testing.T) {
+	// The RFC vectors have 8 digits; 6-digit codes are their last 6
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+	for _, tt := range tests {
+		if got := Code(rfcSecret, Counter(time.Unix(tt.unix, 0))); got != tt.want {
+			t.Errorf("Code at %d = %s, want %s", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	at := time.Unix(1111111111, 0)
+	counter := Counter(at)
+
+	for _, step := range []int64{-1, 0, 1} {
+		got, ok := Validate(rfcSecret, Code(rfcSecret, counter+step), at)
+		if !ok || got != counter+step {
+			t.Errorf("code %+d periods away = (%d, %v), want (%d, true)", step, got, ok, counter+step)
+		}
+	}
+	for _, code := range []string{Code(rfcSecret, counter-2), Code(rfcSecret, counter+2), "", "05047", "0504710"} {
+		if _, ok := Validate(rfcSecret, code, at); ok {
+			t.Errorf("Validate accepted %q", code)
+		}
+	}
+}
+
+func TestProvisioningURI(t *testing.T) {
+	uri, err := url.Parse(ProvisioningURI("Mini Wallet", "customer-1", rfcSecret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if uri.Scheme != "otpauth" || uri.Host != "totp" || uri.Path != "/Mini Wallet:customer-1" {
+		t.Errorf("URI = %s, want otpauth://totp/Mini Wallet:customer-1", uri)
+	}
+	query := uri.Query()
+	if query.Get("secret") != "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ" || query.Get("issuer") != "Mini Wallet" || query.Get("digits") != "6" || query.Get("period") != "30" {
+		t.Errorf("query = %v, want the base32 secret, issuer, 6 digits and 30s period", query)
+	}
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	codes, err := NewRecoveryCodes(RecoveryCodeCount)
+	if err != nil {
+		t.Fatal(err)
+	}
+	seen := make(map[string]bool)
+	for _, code := range codes {
+		if len(code) != 9 || code[4] != '-' {
+			t.Errorf("code %q, want xxxx-xxxx", code)
+		}
+		if seen[code] {
+			t.Errorf("code %q issued twice", code)
+		}
+		seen[code] = true
+	}
+	if HashRecoveryCode(codes[0]) != HashRecoveryCode(" "+strings.ToUpper(strings.ReplaceAll(codes[0], "-", ""))) {
+		t.Error("hash depends on case, spaces or dashes")
+	}
+	if HashRecoveryCode(codes[0]) == HashRecoveryCode(codes[1]) {
+		t.Error("two codes have the same hash")
+	}
+}
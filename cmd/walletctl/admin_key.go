@@ -0,0 +1,105 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"mini-wallet/admin"
+	"mini-wallet/models"
+
+	"github.com/urfave/cli/v2"
+)
+
+// ctlActor names walletctl in the admin audit log.
+const ctlActor = "walletctl"
+
+func (w *ctl) adminKeyCommand() *cli.Command {
+	roles := make([]string, len(admin.Roles))
+	for i, role := range admin.Roles {
+		roles[i] = string(role)
+	}
+	return &cli.Command{
+		Name:  "admin-key",
+		Usage: "manage admin API keys",
+		Subcommands: []*cli.Command{
+			{
+				Name:  "create",
+				Usage: "issue an admin API key, printed only once",
+				Flags: []cli.Flag{
+					&cli.StringFlag{Name: "name", Usage: "who or what the key is for", Required: true},
+					&cli.StringFlag{Name: "role", Usage: strings.Join(roles, ", "), Required: true},
+				},
+				Action: w.createAdminKey,
+			},
+			{
+				Name:   "list",
+				Usage:  "list admin API keys",
+				Action: w.listAdminKeys,
+			},
+			{
+				Name:      "revoke",
+				Usage:     "revoke an admin API key",
+				ArgsUsage: "<key_id>",
+				Flags: []cli.Flag{
+					&cli.StringFlag{Name: "reason", Usage: "why the key is revoked, recorded in the audit log"},
+				},
+				Action: w.revokeAdminKey,
+			},
+		},
+	}
+}
+
+func (w *ctl) createAdminKey(c *cli.Context) error {
+	role := admin.Role(c.String("role"))
+	if !role.Valid() {
+		return cli.Exit(fmt.Sprintf("unknown role %q", role), 2)
+	}
+
+	adminKey, key, err := w.admins.CreateKey(c.String("name"), role, ctlActor)
+	if err != nil {
+		return err
+	}
+
+	if w.out.json {
+		return w.out.encode(map[string]any{"admin_key": adminKey, "key": key})
+	}
+	return w.out.message("%s %s", adminKey.ID, key)
+}
+
+func (w *ctl) listAdminKeys(c *cli.Context) error {
+	keys, err := w.adminKeyRepo.ListKeys()
+	if err != nil {
+		return err
+	}
+
+	rows := make([][]string, 0, len(keys))
+	for _, key := range keys {
+		revoked := ""
+		if key.RevokedAt != nil {
+			revoked = formatTime(*key.RevokedAt)
+		}
+		rows = append(rows, []string{key.ID, key.Name, key.Role, formatTime(key.CreatedAt), revoked})
+	}
+	if keys == nil {
+		keys = []models.AdminKey{}
+	}
+	return w.out.table(keys, []string{"ID", "NAME", "ROLE", "CREATED_AT", "REVOKED_AT"}, rows)
+}
+
+func (w *ctl) revokeAdminKey(c *cli.Context) error {
+	if c.NArg() != 1 {
+		return cli.Exit("expected exactly one key_id argument", 2)
+	}
+	id := c.Args().First()
+
+	err := w.admins.RevokeKey(id, ctlActor, c.String("reason"))
+	if errors.Is(err, sql.ErrNoRows) {
+		return cli.Exit(fmt.Sprintf("admin key %s not found or already revoked", id), 1)
+	}
+	if err != nil {
+		return err
+	}
+	return w.out.message("admin key %s revoked", id)
+}
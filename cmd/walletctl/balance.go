@@ -0,0 +1,91 @@
+package main
+
+import (
+	"strconv"
+
+	"mini-wallet/models"
+
+	"github.com/urfave/cli/v2"
+)
+
+func (w *ctl) balanceCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "balance",
+		Usage: "check stored balances against the transaction log",
+		Subcommands: []*cli.Command{
+			{
+				Name:      "recompute",
+				Usage:     "derive the balance from transactions and compare it with the stored one",
+				ArgsUsage: "<customer_xid>",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{Name: "repair", Usage: "overwrite the stored balance with the derived one when they differ"},
+					&cli.BoolFlag{Name: "all", Usage: "recompute every wallet instead of a single customer's"},
+				},
+				Action: w.recomputeBalance,
+			},
+		},
+	}
+}
+
+type balanceResult struct {
+	WalletID string `json:"wallet_id"`
+	OwnedBy  string `json:"owned_by"`
+	Stored   int64  `json:"stored_balance"`
+	Derived  int64  `json:"derived_balance"`
+	Repaired bool   `json:"repaired"`
+}
+
+func (w *ctl) recomputeBalance(c *cli.Context) error {
+	var wallets []models.Wallet
+	if c.Bool("all") {
+		all, err := w.walletRepo.ListWallets()
+		if err != nil {
+			return err
+		}
+		wallets = all
+	} else {
+		customerXID, err := customerArg(c)
+		if err != nil {
+			return err
+		}
+		wallet, err := w.walletOf(customerXID)
+		if err != nil {
+			return err
+		}
+		wallets = []models.Wallet{*wallet}
+	}
+
+	results := make([]balanceResult, 0, len(wallets))
+	rows := make([][]string, 0, len(wallets))
+	for _, wallet := range wallets {
+		transactions, err := w.transactionRepo.GetTransactionsByWalletID(wallet.ID)
+		if err != nil {
+			return err
+		}
+		result := balanceResult{
+			WalletID: wallet.ID,
+			OwnedBy:  wallet.OwnedBy,
+			Stored:   wallet.Balance,
+			Derived:  models.Balance(transactions),
+		}
+		if c.Bool("repair") && result.Stored != result.Derived {
+			derived, err := w.wallets.RecomputeBalance(c.Context, wallet.ID, wallet.OwnedBy)
+			if err != nil {
+				return err
+			}
+			result.Derived = derived
+			result.Repaired = true
+		}
+
+		results = append(results, result)
+		rows = append(rows, []string{
+			result.WalletID,
+			result.OwnedBy,
+			strconv.FormatInt(result.Stored, 10),
+			strconv.FormatInt(result.Derived, 10),
+			strconv.FormatInt(result.Derived-result.Stored, 10),
+			strconv.FormatBool(result.Repaired),
+		})
+	}
+	return w.out.table(results, []string{"WALLET_ID", "OWNED_BY", "STORED", "DERIVED", "DRIFT", "REPAIRED"}, rows)
+}
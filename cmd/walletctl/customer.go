@@ -0,0 +1,89 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"mini-wallet/models"
+
+	"github.com/urfave/cli/v2"
+)
+
+func (w *ctl) customerCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "customer",
+		Usage: "inspect customers",
+		Subcommands: []*cli.Command{
+			{
+				Name:      "show",
+				Usage:     "show a customer's token state and wallet",
+				ArgsUsage: "<customer_xid>",
+				Action:    w.showCustomer,
+			},
+		},
+	}
+}
+
+func (w *ctl) showCustomer(c *cli.Context) error {
+	customerXID, err := customerArg(c)
+	if err != nil {
+		return err
+	}
+
+	customerToken, err := w.customerTokenRepo.GetCustomerToken(customerXID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return cli.Exit(fmt.Sprintf("customer %s not found", customerXID), 1)
+	}
+	if err != nil {
+		return err
+	}
+	wallet, err := w.walletRepo.GetWalletByCustomerXID(customerXID)
+	if err != nil {
+		return err
+	}
+
+	tokenState := "active"
+	if customerToken.RevokedAt != nil {
+		tokenState = "revoked at " + formatTime(*customerToken.RevokedAt)
+	}
+	fields := [][2]string{
+		{"customer_xid", customerToken.CustomerXID},
+		{"token", tokenState},
+		{"token_created_at", formatTime(customerToken.CreatedAt)},
+	}
+	if wallet != nil {
+		fields = append(fields, walletFields(wallet)...)
+	} else {
+		fields = append(fields, [2]string{"wallet", "none"})
+	}
+
+	return w.out.record(struct {
+		Customer *models.CustomerToken `json:"customer"`
+		Wallet   *models.Wallet        `json:"wallet"`
+	}{customerToken, wallet}, fields)
+}
+
+func walletFields(wallet *models.Wallet) [][2]string {
+	return [][2]string{
+		{"wallet_id", wallet.ID},
+		{"owned_by", wallet.OwnedBy},
+		{"status", wallet.Status},
+		{"enabled_at", formatTime(wallet.EnabledAt)},
+		{"disabled_at", formatTime(wallet.DisabledAt)},
+		{"balance", strconv.FormatInt(wallet.Balance, 10)},
+	}
+}
+
+// walletOf returns the customer's wallet or an exit error when there is none.
+func (w *ctl) walletOf(customerXID string) (*models.Wallet, error) {
+	wallet, err := w.walletRepo.GetWalletByCustomerXID(customerXID)
+	if err != nil {
+		return nil, err
+	}
+	if wallet == nil {
+		return nil, cli.Exit(fmt.Sprintf("customer %s has no wallet", customerXID), 1)
+	}
+	return wallet, nil
+}
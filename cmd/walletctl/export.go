@@ -0,0 +1,117 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"os"
+	"strconv"
+
+	"mini-wallet/models"
+
+	"github.com/urfave/cli/v2"
+)
+
+func (w *ctl) exportCommand() *cli.Command {
+	flags := []cli.Flag{
+		&cli.StringFlag{Name: "format", Value: "csv", Usage: "output format, csv or json"},
+		&cli.StringFlag{Name: "output", Aliases: []string{"o"}, Value: "-", Usage: "output file, - for stdout"},
+	}
+	return &cli.Command{
+		Name:  "export",
+		Usage: "export data for analysis or migration",
+		Subcommands: []*cli.Command{
+			{
+				Name:   "wallets",
+				Usage:  "export every wallet",
+				Flags:  flags,
+				Action: w.exportWallets,
+			},
+			{
+				Name:  "transactions",
+				Usage: "export transactions of every wallet, or of one customer",
+				Flags: append([]cli.Flag{
+					&cli.StringFlag{Name: "customer", Usage: "only export this customer_xid"},
+				}, flags...),
+				Action: w.exportTransactions,
+			},
+		},
+	}
+}
+
+func (w *ctl) exportWallets(c *cli.Context) error {
+	wallets, err := w.walletRepo.ListWallets()
+	if err != nil {
+		return err
+	}
+
+	header := []string{"id", "owned_by", "status", "enabled_at", "disabled_at", "balance"}
+	rows := make([][]string, 0, len(wallets))
+	for _, wallet := range wallets {
+		rows = append(rows, []string{wallet.ID, wallet.OwnedBy, wallet.Status, formatTime(wallet.EnabledAt), formatTime(wallet.DisabledAt), strconv.FormatInt(wallet.Balance, 10)})
+	}
+	return export(c, wallets, header, rows)
+}
+
+func (w *ctl) exportTransactions(c *cli.Context) error {
+	var wallets []models.Wallet
+	if customerXID := c.String("customer"); customerXID != "" {
+		wallet, err := w.walletOf(customerXID)
+		if err != nil {
+			return err
+		}
+		wallets = []models.Wallet{*wallet}
+	} else {
+		all, err := w.walletRepo.ListWallets()
+		if err != nil {
+			return err
+		}
+		wallets = all
+	}
+
+	var transactions []models.Transaction
+	for _, wallet := range wallets {
+		walletTransactions, err := w.sortedTransactions(wallet.ID)
+		if err != nil {
+			return err
+		}
+		transactions = append(transactions, walletTransactions...)
+	}
+
+	header := []string{"id", "wallet_id", "type", "status", "amount", "reference_id", "transacted_at"}
+	rows := make([][]string, 0, len(transactions))
+	for _, t := range transactions {
+		rows = append(rows, []string{t.ID, t.WalletID, t.Type, t.Status, strconv.FormatInt(t.Amount, 10), t.ReferenceID, formatTime(t.TransactedAt)})
+	}
+	return export(c, transactions, header, rows)
+}
+
+func export(c *cli.Context, v any, header []string, rows [][]string) error {
+	var out io.Writer = c.App.Writer
+	if path := c.String("output"); path != "-" {
+		f, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		out = f
+	}
+
+	switch c.String("format") {
+	case "json":
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	case "csv":
+		cw := csv.NewWriter(out)
+		if err := cw.Write(header); err != nil {
+			return err
+		}
+		if err := cw.WriteAll(rows); err != nil {
+			return err
+		}
+		return cw.Error()
+	default:
+		return cli.Exit("unsupported format "+c.String("format")+", use csv or json", 2)
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"strconv"
+
+	"mini-wallet/ledger"
+	"mini-wallet/models"
+
+	"github.com/urfave/cli/v2"
+)
+
+func (w *ctl) ledgerCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "ledger",
+		Usage: "check the integrity of the transaction log",
+		Subcommands: []*cli.Command{
+			{
+				Name:  "verify",
+				Usage: "verify the hash chain of every wallet, or of one customer",
+				Flags: []cli.Flag{
+					&cli.StringFlag{Name: "customer", Usage: "only verify this customer_xid"},
+				},
+				Action: w.verifyLedger,
+			},
+		},
+	}
+}
+
+func (w *ctl) verifyLedger(c *cli.Context) error {
+	var wallets []models.Wallet
+	if customerXID := c.String("customer"); customerXID != "" {
+		wallet, err := w.walletOf(customerXID)
+		if err != nil {
+			return err
+		}
+		wallets = []models.Wallet{*wallet}
+	} else {
+		all, err := w.walletRepo.ListWallets()
+		if err != nil {
+			return err
+		}
+		wallets = all
+	}
+
+	reports := make([]*ledger.Report, 0, len(wallets))
+	rows := make([][]string, 0, len(wallets))
+	broken := 0
+	for _, wallet := range wallets {
+		report, err := ledger.Verify(w.transactionRepo, wallet.ID)
+		if err != nil {
+			return err
+		}
+		reports = append(reports, report)
+
+		status := "ok"
+		if report.Break != nil {
+			broken++
+			status = "broken at " + strconv.FormatInt(report.Break.Sequence, 10) + " (" + report.Break.TransactionID + "): " + report.Break.Reason
+		}
+		rows = append(rows, []string{wallet.ID, strconv.FormatInt(report.Verified, 10), strconv.FormatInt(report.Unsealed, 10), report.HeadHash, status})
+	}
+
+	if err := w.out.table(reports, []string{"WALLET_ID", "VERIFIED", "UNSEALED", "HEAD_HASH", "STATUS"}, rows); err != nil {
+		return err
+	}
+	if broken > 0 {
+		return cli.Exit(strconv.Itoa(broken)+" wallet chains are broken", 1)
+	}
+	return nil
+}
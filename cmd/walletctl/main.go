@@ -0,0 +1,119 @@
+// Command walletctl gives operators direct access to customers, wallets and
+// transactions through the same repositories and wallet service the API uses.
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+
+	"mini-wallet/config"
+	"mini-wallet/repositories"
+	"mini-wallet/service"
+	"mini-wallet/webhooks"
+
+	"github.com/joho/godotenv"
+	_ "github.com/lib/pq"
+	"github.com/urfave/cli/v2"
+)
+
+// ctl holds the dependencies shared by every subcommand.
+type ctl struct {
+	db                 *sql.DB
+	walletRepo         repositories.WalletRepository
+	transactionRepo    repositories.TransactionRepository
+	snapshotRepo       repositories.SnapshotRepository
+	customerTokenRepo  repositories.CustomerTokenRepository
+	reconciliationRepo repositories.ReconciliationRepository
+	adminKeyRepo       repositories.AdminKeyRepository
+	wallets            *service.WalletService
+	admins             *service.AdminService
+	out                *printer
+}
+
+func main() {
+	// A missing .env is fine, DATABASE_URL may come from the environment.
+	if err := godotenv.Load(); err != nil && !errors.Is(err, os.ErrNotExist) {
+		log.Fatal("Error loading .env file: ", err)
+	}
+
+	w := &ctl{}
+	app := &cli.App{
+		Name:  "walletctl",
+		Usage: "operate mini-wallet customers, wallets and transactions",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:     "database-url",
+				Usage:    "PostgreSQL connection string",
+				EnvVars:  []string{"DATABASE_URL"},
+				Required: true,
+			},
+			&cli.BoolFlag{
+				Name:  "json",
+				Usage: "print results as JSON",
+			},
+		},
+		Before: w.open,
+		After:  w.close,
+		Commands: []*cli.Command{
+			w.customerCommand(),
+			w.walletCommand(),
+			w.transactionsCommand(),
+			w.tokenCommand(),
+			w.balanceCommand(),
+			w.reconcileCommand(),
+			w.exportCommand(),
+			w.statementCommand(),
+			w.snapshotsCommand(),
+			w.ledgerCommand(),
+			w.adminKeyCommand(),
+		},
+	}
+
+	if err := app.Run(os.Args); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func (w *ctl) open(c *cli.Context) error {
+	db, err := sql.Open("postgres", c.String("database-url"))
+	if err != nil {
+		return fmt.Errorf("connecting to the database: %w", err)
+	}
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return fmt.Errorf("pinging the database: %w", err)
+	}
+
+	w.db = db
+	w.walletRepo = repositories.NewWalletRepository(db)
+	w.transactionRepo = repositories.NewTransactionRepository(db)
+	w.snapshotRepo = repositories.NewSnapshotRepository(db)
+	w.customerTokenRepo = repositories.NewCustomerTokenRepository(db)
+	w.reconciliationRepo = repositories.NewReconciliationRepository(db)
+	w.adminKeyRepo = repositories.NewAdminKeyRepository(db)
+	// Without Redis, balance changes from here do not take the API's wallet
+	// lock; events still reach webhook subscribers.
+	dispatcher := webhooks.NewDispatcher(repositories.NewWebhookRepository(db))
+	w.wallets = service.NewWalletService(w.walletRepo, w.transactionRepo, w.snapshotRepo, w.customerTokenRepo, nil, dispatcher, nil, nil, repositories.NewKYCRepository(db), repositories.NewPINRepository(db), nil, config.Default().Wallet)
+	w.admins = service.NewAdminService(w.wallets, w.walletRepo, w.transactionRepo, w.customerTokenRepo, w.adminKeyRepo, repositories.NewAuditRepository(db), repositories.NewAdjustmentRepository(db, w.transactionRepo), repositories.NewComplianceRepository(db), w.reconciliationRepo)
+	w.out = &printer{w: c.App.Writer, json: c.Bool("json")}
+	return nil
+}
+
+func (w *ctl) close(c *cli.Context) error {
+	if w.db == nil {
+		return nil
+	}
+	return w.db.Close()
+}
+
+// customerArg returns the single customer_xid argument of a command.
+func customerArg(c *cli.Context) (string, error) {
+	if c.NArg() != 1 {
+		return "", cli.Exit("expected exactly one customer_xid argument", 2)
+	}
+	return c.Args().First(), nil
+}
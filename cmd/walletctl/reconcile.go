@@ -0,0 +1,116 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"strconv"
+
+	"mini-wallet/jobs"
+	"mini-wallet/models"
+
+	"github.com/urfave/cli/v2"
+)
+
+func (w *ctl) reconcileCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "reconcile",
+		Usage: "run balance reconciliation and inspect drift reports",
+		Subcommands: []*cli.Command{
+			{
+				Name:  "run",
+				Usage: "reconcile every wallet now and record discrepancies",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{Name: "auto-correct", Usage: "overwrite drifted balances with the derived one"},
+					&cli.DurationFlag{Name: "settle-grace", Value: jobs.DefaultSettleGrace, Usage: "skip wallets with transactions younger than this"},
+				},
+				Action: w.runReconciliation,
+			},
+			{
+				Name:  "report",
+				Usage: "show a reconciliation run and its discrepancies",
+				Flags: []cli.Flag{
+					&cli.StringFlag{Name: "run", Usage: "run id, defaults to the latest run"},
+				},
+				Action: w.reconciliationReport,
+			},
+		},
+	}
+}
+
+func (w *ctl) runReconciliation(c *cli.Context) error {
+	// Without Redis the correction cannot take the API's wallet lock, so
+	// auto-correct from here is best run while the API is drained.
+	reconciler := jobs.NewReconciler(w.walletRepo, w.transactionRepo, w.reconciliationRepo, w.wallets, jobs.ReconcilerOptions{
+		AutoCorrect: c.Bool("auto-correct"),
+		SettleGrace: c.Duration("settle-grace"),
+	})
+	run, err := reconciler.Run(c.Context)
+	if err != nil {
+		return err
+	}
+	return w.printReport(run)
+}
+
+func (w *ctl) reconciliationReport(c *cli.Context) error {
+	var run *models.ReconciliationRun
+	var err error
+	if runID := c.String("run"); runID != "" {
+		run, err = w.reconciliationRepo.GetRun(runID)
+	} else {
+		run, err = w.reconciliationRepo.GetLatestRun()
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return cli.Exit("reconciliation run not found", 1)
+	}
+	if err != nil {
+		return err
+	}
+	return w.printReport(run)
+}
+
+func (w *ctl) printReport(run *models.ReconciliationRun) error {
+	discrepancies, err := w.reconciliationRepo.GetDiscrepanciesByRunID(run.ID)
+	if err != nil {
+		return err
+	}
+
+	if w.out.json {
+		return w.out.encode(struct {
+			Run           *models.ReconciliationRun   `json:"run"`
+			Discrepancies []models.BalanceDiscrepancy `json:"discrepancies"`
+		}{run, discrepancies})
+	}
+
+	finishedAt := "-"
+	if run.FinishedAt != nil {
+		finishedAt = formatTime(*run.FinishedAt)
+	}
+	if err := w.out.record(run, [][2]string{
+		{"run_id", run.ID},
+		{"started_at", formatTime(run.StartedAt)},
+		{"finished_at", finishedAt},
+		{"wallets_checked", strconv.Itoa(run.WalletsChecked)},
+		{"discrepancies", strconv.Itoa(run.Discrepancies)},
+		{"auto_correct", strconv.FormatBool(run.AutoCorrect)},
+	}); err != nil {
+		return err
+	}
+	if len(discrepancies) == 0 {
+		return nil
+	}
+
+	rows := make([][]string, 0, len(discrepancies))
+	for _, d := range discrepancies {
+		rows = append(rows, []string{
+			d.WalletID,
+			strconv.FormatInt(d.StoredBalance, 10),
+			strconv.FormatInt(d.DerivedBalance, 10),
+			strconv.FormatInt(d.Drift, 10),
+			strconv.FormatBool(d.Corrected),
+		})
+	}
+	if err := w.out.message(""); err != nil {
+		return err
+	}
+	return w.out.table(discrepancies, []string{"WALLET_ID", "STORED", "DERIVED", "DRIFT", "CORRECTED"}, rows)
+}
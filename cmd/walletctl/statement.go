@@ -0,0 +1,60 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"mini-wallet/service"
+	"mini-wallet/statements"
+
+	"github.com/urfave/cli/v2"
+)
+
+func (w *ctl) statementCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "statement",
+		Usage:     "write a customer's statement for a period",
+		ArgsUsage: "<customer_xid>",
+		Flags: []cli.Flag{
+			&cli.StringFlag{Name: "from", Usage: "first day (YYYY-MM-DD) or RFC 3339 start, defaults to the start of this month"},
+			&cli.StringFlag{Name: "to", Usage: "last day (YYYY-MM-DD) or RFC 3339 end, defaults to now"},
+			&cli.StringFlag{Name: "format", Value: "csv", Usage: "output format, " + strings.Join(statements.Formats, ", ")},
+			&cli.StringFlag{Name: "output", Aliases: []string{"o"}, Value: "-", Usage: "output file, - for stdout"},
+		},
+		Action: w.statement,
+	}
+}
+
+func (w *ctl) statement(c *cli.Context) error {
+	customerXID, err := customerArg(c)
+	if err != nil {
+		return err
+	}
+	period, err := statements.ParsePeriod(c.String("from"), c.String("to"), time.Now())
+	if err != nil {
+		return cli.Exit(err.Error(), 2)
+	}
+
+	var out io.Writer = c.App.Writer
+	if path := c.String("output"); path != "-" {
+		f, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		out = f
+	}
+	enc, err := statements.NewEncoder(c.String("format"), out)
+	if err != nil {
+		return cli.Exit("unsupported format "+c.String("format")+", use "+strings.Join(statements.Formats, ", "), 2)
+	}
+
+	err = w.wallets.Statement(customerXID, period, enc)
+	if errors.Is(err, service.ErrWalletNotFound) {
+		return cli.Exit("customer "+customerXID+" has no wallet", 1)
+	}
+	return err
+}
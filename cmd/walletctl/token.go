@@ -0,0 +1,69 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"mini-wallet/repositories"
+
+	"github.com/urfave/cli/v2"
+)
+
+func (w *ctl) tokenCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "token",
+		Usage: "manage customer API tokens",
+		Subcommands: []*cli.Command{
+			{
+				Name:      "issue",
+				Usage:     "issue a new token, replacing the current or revoked one",
+				ArgsUsage: "<customer_xid>",
+				Action:    w.issueToken,
+			},
+			{
+				Name:      "revoke",
+				Usage:     "revoke the customer's token",
+				ArgsUsage: "<customer_xid>",
+				Action:    w.revokeToken,
+			},
+		},
+	}
+}
+
+func (w *ctl) issueToken(c *cli.Context) error {
+	customerXID, err := customerArg(c)
+	if err != nil {
+		return err
+	}
+
+	token := repositories.GenerateToken(customerXID)
+	err = w.customerTokenRepo.RotateToken(customerXID, token)
+	if errors.Is(err, sql.ErrNoRows) {
+		return cli.Exit(fmt.Sprintf("customer %s not found, use POST /api/v1/init to onboard", customerXID), 1)
+	}
+	if err != nil {
+		return err
+	}
+
+	if w.out.json {
+		return w.out.encode(map[string]string{"customer_xid": customerXID, "token": token})
+	}
+	return w.out.message("%s", token)
+}
+
+func (w *ctl) revokeToken(c *cli.Context) error {
+	customerXID, err := customerArg(c)
+	if err != nil {
+		return err
+	}
+
+	err = w.customerTokenRepo.RevokeToken(customerXID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return cli.Exit(fmt.Sprintf("customer %s not found or token already revoked", customerXID), 1)
+	}
+	if err != nil {
+		return err
+	}
+	return w.out.message("token of customer %s revoked", customerXID)
+}
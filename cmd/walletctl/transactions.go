@@ -0,0 +1,57 @@
+package main
+
+import (
+	"sort"
+	"strconv"
+
+	"mini-wallet/models"
+
+	"github.com/urfave/cli/v2"
+)
+
+func (w *ctl) transactionsCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "transactions",
+		Usage: "inspect transactions",
+		Subcommands: []*cli.Command{
+			{
+				Name:      "list",
+				Usage:     "list a customer's transactions, oldest first",
+				ArgsUsage: "<customer_xid>",
+				Action:    w.listTransactions,
+			},
+		},
+	}
+}
+
+func (w *ctl) listTransactions(c *cli.Context) error {
+	customerXID, err := customerArg(c)
+	if err != nil {
+		return err
+	}
+	wallet, err := w.walletOf(customerXID)
+	if err != nil {
+		return err
+	}
+	transactions, err := w.sortedTransactions(wallet.ID)
+	if err != nil {
+		return err
+	}
+
+	rows := make([][]string, 0, len(transactions))
+	for _, t := range transactions {
+		rows = append(rows, []string{t.ID, t.Type, t.Status, strconv.FormatInt(t.Amount, 10), t.ReferenceID, formatTime(t.TransactedAt)})
+	}
+	return w.out.table(transactions, []string{"ID", "TYPE", "STATUS", "AMOUNT", "REFERENCE_ID", "TRANSACTED_AT"}, rows)
+}
+
+func (w *ctl) sortedTransactions(walletID string) ([]models.Transaction, error) {
+	transactions, err := w.transactionRepo.GetTransactionsByWalletID(walletID)
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(transactions, func(i, j int) bool {
+		return transactions[i].TransactedAt.Before(transactions[j].TransactedAt)
+	})
+	return transactions, nil
+}
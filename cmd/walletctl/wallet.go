@@ -0,0 +1,90 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"mini-wallet/service"
+
+	"github.com/urfave/cli/v2"
+)
+
+func (w *ctl) walletCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "wallet",
+		Usage: "inspect and change wallets",
+		Subcommands: []*cli.Command{
+			{
+				Name:      "show",
+				Usage:     "show a wallet by its id",
+				ArgsUsage: "<wallet_id>",
+				Action:    w.showWallet,
+			},
+			{
+				Name:      "enable",
+				Usage:     "enable a customer's wallet",
+				ArgsUsage: "<customer_xid>",
+				Action:    w.enableWallet,
+			},
+			{
+				Name:      "disable",
+				Usage:     "disable a customer's wallet",
+				ArgsUsage: "<customer_xid>",
+				Action:    w.disableWallet,
+			},
+		},
+	}
+}
+
+func (w *ctl) showWallet(c *cli.Context) error {
+	if c.NArg() != 1 {
+		return cli.Exit("expected exactly one wallet_id argument", 2)
+	}
+	wallet, err := w.walletRepo.GetWalletByID(c.Args().First())
+	if errors.Is(err, sql.ErrNoRows) {
+		return cli.Exit(fmt.Sprintf("wallet %s not found", c.Args().First()), 1)
+	}
+	if err != nil {
+		return err
+	}
+	return w.out.record(wallet, walletFields(wallet))
+}
+
+func (w *ctl) enableWallet(c *cli.Context) error {
+	customerXID, err := customerArg(c)
+	if err != nil {
+		return err
+	}
+	wallet, err := w.walletOf(customerXID)
+	if err != nil {
+		return err
+	}
+
+	if _, err := w.wallets.Enable(customerXID); err != nil {
+		if errors.Is(err, service.ErrWalletAlreadyEnabled) {
+			return cli.Exit("wallet is already enabled", 1)
+		}
+		return err
+	}
+	return w.out.message("wallet %s enabled", wallet.ID)
+}
+
+func (w *ctl) disableWallet(c *cli.Context) error {
+	customerXID, err := customerArg(c)
+	if err != nil {
+		return err
+	}
+	wallet, err := w.walletOf(customerXID)
+	if err != nil {
+		return err
+	}
+
+	if _, err := w.wallets.Disable(customerXID, service.Authorization{}); err != nil {
+		if errors.Is(err, service.ErrWalletAlreadyDisabled) {
+			return cli.Exit("wallet is already disabled", 1)
+		}
+		return err
+	}
+	return w.out.message("wallet %s disabled", wallet.ID)
+}
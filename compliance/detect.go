@@ -0,0 +1,114 @@
+// Package compliance finds the transactions a wallet operator has to report
+// to the regulator: single large ones, and series just below the reporting
+// threshold that look like a large amount split to avoid it.
+package compliance
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"mini-wallet/models"
+)
+
+// Rules are the reporting thresholds.
+type Rules struct {
+	// Threshold is the amount from which a single transaction is reported.
+	Threshold int64
+	// StructuringFloor is the amount from which a transaction below
+	// Threshold counts towards structuring.
+	StructuringFloor int64
+	// StructuringWindow and StructuringCount define structuring: at least
+	// StructuringCount such transactions of one wallet within the window.
+	StructuringWindow time.Duration
+	StructuringCount  int
+}
+
+// Detect returns the reports due for transactions, which must hold every
+// deposit and withdrawal of at least StructuringFloor in the scanned
+// period, ordered by wallet and then time. Only reports with a transaction
+// after since are returned, so transactions before since may be passed to
+// complete a structuring window that straddles it.
+func Detect(transactions []models.Transaction, since time.Time, rules Rules) []models.ComplianceReport {
+	var reports []models.ComplianceReport
+	for start := 0; start < len(transactions); {
+		end := start
+		for end < len(transactions) && transactions[end].WalletID == transactions[start].WalletID {
+			end++
+		}
+		reports = append(reports, detectWallet(transactions[start:end], since, rules)...)
+		start = end
+	}
+	return reports
+}
+
+func detectWallet(transactions []models.Transaction, since time.Time, rules Rules) []models.ComplianceReport {
+	var reports []models.ComplianceReport
+	var near []models.Transaction
+	for _, t := range transactions {
+		switch {
+		case t.Amount >= rules.Threshold:
+			if t.TransactedAt.After(since) {
+				reports = append(reports, report(models.ReportLargeTransaction, []models.Transaction{t}))
+			}
+		case t.Amount >= rules.StructuringFloor:
+			near = append(near, t)
+		}
+	}
+
+	// Each series starts at the oldest transaction not yet reported and
+	// takes every transaction within the window after it
+	for first := 0; first < len(near); {
+		last := first
+		for last+1 < len(near) && near[last+1].TransactedAt.Sub(near[first].TransactedAt) <= rules.StructuringWindow {
+			last++
+		}
+		if last-first+1 >= rules.StructuringCount && near[last].TransactedAt.After(since) {
+			reports = append(reports, report(models.ReportStructuring, near[first:last+1]))
+			first = last + 1
+			continue
+		}
+		first++
+	}
+	return reports
+}
+
+func report(kind string, transactions []models.Transaction) models.ComplianceReport {
+	r := models.ComplianceReport{
+		Kind:              kind,
+		WalletID:          transactions[0].WalletID,
+		FirstTransactedAt: transactions[0].TransactedAt,
+		LastTransactedAt:  transactions[len(transactions)-1].TransactedAt,
+	}
+	for _, t := range transactions {
+		r.TransactionIDs = append(r.TransactionIDs, t.ID)
+		r.TotalAmount += t.Amount
+	}
+	return r
+}
+
+// WriteCSV writes one row per report, with its transaction IDs separated
+// by spaces.
+func WriteCSV(w io.Writer, reports []models.ComplianceReport) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"id", "kind", "wallet_id", "customer_xid", "transaction_count", "total_amount",
+		"first_transacted_at", "last_transacted_at", "transaction_ids", "created_at"})
+	for _, r := range reports {
+		cw.Write([]string{
+			r.ID,
+			r.Kind,
+			r.WalletID,
+			r.CustomerXID,
+			strconv.Itoa(len(r.TransactionIDs)),
+			strconv.FormatInt(r.TotalAmount, 10),
+			r.FirstTransactedAt.UTC().Format(time.RFC3339),
+			r.LastTransactedAt.UTC().Format(time.RFC3339),
+			strings.Join(r.TransactionIDs, " "),
+			r.CreatedAt.UTC().Format(time.RFC3339),
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,83 @@
+package compliance
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"mini-wallet/models"
+)
+
+var (
+	start = time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	rules = Rules{Threshold: 1000, StructuringFloor: 900, StructuringWindow: 24 * time.Hour, StructuringCount: 3}
+)
+
+func transaction(id, walletID string, amount int64, at time.Duration) models.Transaction {
+	return models.Transaction{ID: id, WalletID: walletID, Type: "deposit", Amount: amount, TransactedAt: start.Add(at)}
+}
+
+func TestDetect(t *testing.T) {
+	transactions := []models.Transaction{
+		transaction("a1", "wallet-a", 950, 0),
+		transaction("a2", "wallet-a", 1500, time.Hour),
+		transaction("a3", "wallet-a", 990, 2*time.Hour),
+		transaction("a4", "wallet-a", 100, 3*time.Hour),
+		transaction("a5", "wallet-a", 900, 4*time.Hour),
+		// Too late for the series above, and alone in its own window
+		transaction("a6", "wallet-a", 950, 30*time.Hour),
+		// Two near-threshold transactions are not a series
+		transaction("b1", "wallet-b", 950, 0),
+		transaction("b2", "wallet-b", 950, time.Hour),
+	}
+
+	reports := Detect(transactions, start.Add(-time.Hour), rules)
+	if len(reports) != 2 {
+		t.Fatalf("got %d reports, want the large transaction and one series: %+v", len(reports), reports)
+	}
+	large, series := reports[0], reports[1]
+	if large.Kind != models.ReportLargeTransaction || strings.Join(large.TransactionIDs, ",") != "a2" || large.TotalAmount != 1500 {
+		t.Errorf("large report = %+v, want a2", large)
+	}
+	if series.Kind != models.ReportStructuring || strings.Join(series.TransactionIDs, ",") != "a1,a3,a5" || series.TotalAmount != 2840 {
+		t.Errorf("structuring report = %+v, want a1, a3 and a5", series)
+	}
+	if !series.FirstTransactedAt.Equal(start) || !series.LastTransactedAt.Equal(start.Add(4*time.Hour)) {
+		t.Errorf("series spans %v to %v, want a1 to a5", series.FirstTransactedAt, series.LastTransactedAt)
+	}
+}
+
+func TestDetectSkipsReportsBeforeSince(t *testing.T) {
+	transactions := []models.Transaction{
+		transaction("a1", "wallet-a", 5000, 0),
+		transaction("a2", "wallet-a", 950, time.Hour),
+		transaction("a3", "wallet-a", 950, 2*time.Hour),
+		transaction("a4", "wallet-a", 950, 3*time.Hour),
+	}
+
+	if reports := Detect(transactions, start.Add(3*time.Hour), rules); len(reports) != 0 {
+		t.Errorf("got %+v, want nothing after since", reports)
+	}
+	// A series straddling since is reported with its earlier transactions
+	reports := Detect(transactions, start.Add(150*time.Minute), rules)
+	if len(reports) != 1 || strings.Join(reports[0].TransactionIDs, ",") != "a2,a3,a4" {
+		t.Errorf("got %+v, want the series a2 to a4", reports)
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	var buf bytes.Buffer
+	err := WriteCSV(&buf, []models.ComplianceReport{{
+		ID: "r1", Kind: models.ReportStructuring, WalletID: "wallet-a", CustomerXID: "customer-a",
+		TransactionIDs: []string{"a1", "a3"}, TotalAmount: 1940, FirstTransactedAt: start, LastTransactedAt: start, CreatedAt: start,
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	want := "r1,structuring,wallet-a,customer-a,2,1940,2024-03-01T09:00:00Z,2024-03-01T09:00:00Z,a1 a3,2024-03-01T09:00:00Z"
+	if len(lines) != 2 || lines[1] != want {
+		t.Errorf("csv = %q, want a header and %q", lines, want)
+	}
+}
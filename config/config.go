@@ -0,0 +1,527 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"mini-wallet/auth"
+	"mini-wallet/ratelimit"
+
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultFile is read when it exists and no other file is requested.
+const DefaultFile = "config.yaml"
+
+type Config struct {
+	Server          ServerConfig          `yaml:"server"`
+	Database        DatabaseConfig        `yaml:"database"`
+	Redis           RedisConfig           `yaml:"redis"`
+	Wallet          WalletConfig          `yaml:"wallet"`
+	Jobs            JobsConfig            `yaml:"jobs"`
+	Webhooks        WebhooksConfig        `yaml:"webhooks"`
+	Events          EventsConfig          `yaml:"events"`
+	Risk            RiskConfig            `yaml:"risk"`
+	Compliance      ComplianceConfig      `yaml:"compliance"`
+	RateLimit       RateLimitConfig       `yaml:"rate_limit"`
+	StandingOrders  StandingOrdersConfig  `yaml:"standing_orders"`
+	PaymentRequests PaymentRequestsConfig `yaml:"payment_requests"`
+}
+
+type ServerConfig struct {
+	Addr            string        `yaml:"addr"`
+	ReadTimeout     time.Duration `yaml:"read_timeout"`
+	WriteTimeout    time.Duration `yaml:"write_timeout"`
+	IdleTimeout     time.Duration `yaml:"idle_timeout"`
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+	MaxBodyBytes    int64         `yaml:"max_body_bytes"`
+	// GRPCAddr is where the gRPC API listens; it is not served while empty.
+	GRPCAddr string `yaml:"grpc_addr"`
+	// TrustedProxies lists, comma separated, the IPs and CIDRs of the
+	// proxies whose X-Forwarded-For header names the client. Without any,
+	// the client is the address the connection comes from.
+	TrustedProxies string `yaml:"trusted_proxies"`
+}
+
+// Proxies returns the trusted proxies one by one.
+func (c ServerConfig) Proxies() []string {
+	var proxies []string
+	for _, proxy := range strings.Split(c.TrustedProxies, ",") {
+		if proxy = strings.TrimSpace(proxy); proxy != "" {
+			proxies = append(proxies, proxy)
+		}
+	}
+	return proxies
+}
+
+type DatabaseConfig struct {
+	URL             string        `yaml:"url"`
+	MaxOpenConns    int           `yaml:"max_open_conns"`
+	MaxIdleConns    int           `yaml:"max_idle_conns"`
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime"`
+	ConnMaxIdleTime time.Duration `yaml:"conn_max_idle_time"`
+}
+
+type RedisConfig struct {
+	URL          string        `yaml:"url"`
+	PoolSize     int           `yaml:"pool_size"`
+	DialTimeout  time.Duration `yaml:"dial_timeout"`
+	ReadTimeout  time.Duration `yaml:"read_timeout"`
+	WriteTimeout time.Duration `yaml:"write_timeout"`
+}
+
+type WalletConfig struct {
+	// LockTTL bounds how long a balance update may hold the wallet lock.
+	LockTTL         time.Duration `yaml:"lock_ttl"`
+	BalanceCacheTTL time.Duration `yaml:"balance_cache_ttl"`
+	// MaxSettleDelay is the upper bound of the random delay applied before
+	// a deposit or withdrawal is reflected in the stored balance.
+	MaxSettleDelay time.Duration `yaml:"max_settle_delay"`
+	SettleWorkers  int           `yaml:"settle_workers"`
+	// ClosureRetention is how long the personal data of a closed wallet's
+	// customer is kept before it is anonymized.
+	ClosureRetention time.Duration `yaml:"closure_retention"`
+	// KYC gates what a wallet may do by its customer's KYC tier.
+	KYC KYCConfig `yaml:"kyc"`
+	// PIN protects withdrawals with the customer's transaction PIN.
+	PIN PINConfig `yaml:"pin"`
+	// TOTP lets customers enroll an authenticator app as a second factor.
+	TOTP TOTPConfig `yaml:"totp"`
+}
+
+type KYCConfig struct {
+	// EnableTier and WithdrawTier are the lowest KYC tiers allowed to
+	// enable a wallet and to withdraw from it.
+	EnableTier   int `yaml:"enable_tier"`
+	WithdrawTier int `yaml:"withdraw_tier"`
+	// BasicMaxBalance and VerifiedMaxBalance cap the balance deposits may
+	// bring a wallet to at tiers 0 and 1, 0 disables the cap. Tier 2 has
+	// no cap.
+	BasicMaxBalance    int64 `yaml:"basic_max_balance"`
+	VerifiedMaxBalance int64 `yaml:"verified_max_balance"`
+}
+
+type PINConfig struct {
+	// Required refuses withdrawals from customers who have not set a PIN.
+	// Otherwise only customers with a PIN must present it.
+	Required bool `yaml:"required"`
+	// MaxAttempts wrong PINs in a row lock the PIN for LockoutBase, twice
+	// as long with each further wrong PIN, up to LockoutMax.
+	MaxAttempts int           `yaml:"max_attempts"`
+	LockoutBase time.Duration `yaml:"lockout_base"`
+	LockoutMax  time.Duration `yaml:"lockout_max"`
+	// StepUpTTL is how long a step-up token stands in for the PIN.
+	StepUpTTL time.Duration `yaml:"step_up_ttl"`
+}
+
+type TOTPConfig struct {
+	// EncryptionKey is the base64 AES-256 key TOTP secrets are encrypted
+	// with at rest. Customers cannot enroll while it is empty.
+	EncryptionKey string `yaml:"encryption_key"`
+	// Issuer names the wallet in authenticator apps.
+	Issuer string `yaml:"issuer"`
+	// LargeWithdrawal is the amount from which enrolled customers must
+	// confirm a withdrawal with a one-time code, 0 for none.
+	LargeWithdrawal int64 `yaml:"large_withdrawal"`
+	// MaxAttempts wrong codes in a row lock the enrollment for
+	// LockoutBase, twice as long with each further wrong code, up to
+	// LockoutMax.
+	MaxAttempts int           `yaml:"max_attempts"`
+	LockoutBase time.Duration `yaml:"lockout_base"`
+	LockoutMax  time.Duration `yaml:"lockout_max"`
+}
+
+type JobsConfig struct {
+	ReconciliationEnabled  bool          `yaml:"reconciliation_enabled"`
+	ReconciliationInterval time.Duration `yaml:"reconciliation_interval"`
+	// ReconciliationAutoCorrect overwrites drifted balances with the derived one.
+	ReconciliationAutoCorrect bool `yaml:"reconciliation_auto_correct"`
+	// SnapshotsEnabled records every wallet's balance at each UTC midnight.
+	SnapshotsEnabled bool `yaml:"snapshots_enabled"`
+	// AnonymizationEnabled anonymizes closed wallets whose retention ended.
+	AnonymizationEnabled bool `yaml:"anonymization_enabled"`
+	// ComplianceEnabled scans new transactions hourly for compliance reports.
+	ComplianceEnabled bool `yaml:"compliance_enabled"`
+}
+
+type WebhooksConfig struct {
+	Workers      int           `yaml:"workers"`
+	PollInterval time.Duration `yaml:"poll_interval"`
+	BatchSize    int           `yaml:"batch_size"`
+	Timeout      time.Duration `yaml:"timeout"`
+	// MaxAttempts is the number of attempts before a delivery is dead-lettered.
+	MaxAttempts int           `yaml:"max_attempts"`
+	BackoffBase time.Duration `yaml:"backoff_base"`
+	BackoffMax  time.Duration `yaml:"backoff_max"`
+}
+
+// Event bus implementations.
+const (
+	BusRedis  = "redis"
+	BusMemory = "memory"
+)
+
+type EventsConfig struct {
+	// Bus fans live events out to stream subscribers: "redis" across
+	// instances, or "memory" within a single instance.
+	Bus string `yaml:"bus"`
+	// Heartbeat is the interval of keep-alive comments on idle streams.
+	Heartbeat time.Duration `yaml:"heartbeat"`
+}
+
+type RiskConfig struct {
+	// RulesFile is the YAML file of risk rules checked before deposits and
+	// withdrawals are posted; every transaction is allowed while it is empty.
+	RulesFile string `yaml:"rules_file"`
+}
+
+type ComplianceConfig struct {
+	// ReportThreshold is the amount from which a deposit or withdrawal is
+	// reported on its own.
+	ReportThreshold int64 `yaml:"report_threshold"`
+	// StructuringFloor is the amount from which a transaction below the
+	// threshold counts towards structuring: StructuringCount of them
+	// within StructuringWindow are reported together.
+	StructuringFloor  int64         `yaml:"structuring_floor"`
+	StructuringWindow time.Duration `yaml:"structuring_window"`
+	StructuringCount  int           `yaml:"structuring_count"`
+}
+
+// Rate limit stores.
+const (
+	RateLimitRedis  = "redis"
+	RateLimitMemory = "memory"
+)
+
+type RateLimitConfig struct {
+	// Enabled limits the requests to the customer and staff APIs.
+	Enabled bool `yaml:"enabled"`
+	// Store counts requests in "redis" across instances, falling back to
+	// memory while Redis is unreachable, or in "memory" per instance.
+	Store string `yaml:"store"`
+	// AuthFailures is the budget of requests answered 401 per client IP,
+	// after which the IP is refused until it regains room.
+	AuthFailures ratelimit.Limit `yaml:"auth_failures"`
+	// Reads and Writes are the budgets of GET requests and of the others
+	// per token, or per client IP for requests without one.
+	Reads  ratelimit.Limit `yaml:"reads"`
+	Writes ratelimit.Limit `yaml:"writes"`
+	// Routes gives single routes a budget of their own instead, keyed by
+	// method and path as registered, such as "POST /wallet/deposits" or
+	// "GET /admin/v1/audit". Customer API paths leave out /api/v1 and
+	// /api/v2, which share the budget.
+	Routes map[string]ratelimit.Limit `yaml:"routes"`
+}
+
+type StandingOrdersConfig struct {
+	// Enabled runs the scheduler executing due standing orders. Orders can
+	// be managed either way.
+	Enabled      bool          `yaml:"enabled"`
+	PollInterval time.Duration `yaml:"poll_interval"`
+	BatchSize    int           `yaml:"batch_size"`
+	// Lease is how long a scheduler holds the orders it claimed; the
+	// claims of a scheduler that crashed are picked up again after it.
+	Lease time.Duration `yaml:"lease"`
+	// RetryAttempts is how many times a run finding too little balance is
+	// attempted, RetryDelay apart, before it is given up.
+	RetryAttempts int           `yaml:"retry_attempts"`
+	RetryDelay    time.Duration `yaml:"retry_delay"`
+}
+
+type PaymentRequestsConfig struct {
+	// DefaultExpiry is how long a request stays payable when it does not
+	// say; MaxExpiry is the longest it may ask for.
+	DefaultExpiry time.Duration `yaml:"default_expiry"`
+	MaxExpiry     time.Duration `yaml:"max_expiry"`
+}
+
+// Default returns the configuration used when nothing overrides it.
+func Default() *Config {
+	return &Config{
+		Server: ServerConfig{
+			Addr:            ":8080",
+			ReadTimeout:     10 * time.Second,
+			WriteTimeout:    15 * time.Second,
+			IdleTimeout:     60 * time.Second,
+			ShutdownTimeout: 10 * time.Second,
+			MaxBodyBytes:    1 << 20,
+			GRPCAddr:        ":9090",
+		},
+		Database: DatabaseConfig{
+			MaxOpenConns:    25,
+			MaxIdleConns:    5,
+			ConnMaxLifetime: 30 * time.Minute,
+			ConnMaxIdleTime: 5 * time.Minute,
+		},
+		Redis: RedisConfig{
+			PoolSize:     10,
+			DialTimeout:  5 * time.Second,
+			ReadTimeout:  3 * time.Second,
+			WriteTimeout: 3 * time.Second,
+		},
+		Wallet: WalletConfig{
+			LockTTL:         15 * time.Second,
+			BalanceCacheTTL: 15 * time.Second,
+			MaxSettleDelay:  5 * time.Second,
+			SettleWorkers:   16,
+			// Five years
+			ClosureRetention: 5 * 365 * 24 * time.Hour,
+			KYC: KYCConfig{
+				WithdrawTier:       1,
+				BasicMaxBalance:    2_000_000,
+				VerifiedMaxBalance: 20_000_000,
+			},
+			PIN: PINConfig{
+				Required:    true,
+				MaxAttempts: 5,
+				LockoutBase: time.Minute,
+				LockoutMax:  time.Hour,
+				StepUpTTL:   5 * time.Minute,
+			},
+			TOTP: TOTPConfig{
+				Issuer:          "Mini Wallet",
+				LargeWithdrawal: 1_000_000,
+				MaxAttempts:     5,
+				LockoutBase:     time.Minute,
+				LockoutMax:      time.Hour,
+			},
+		},
+		Jobs: JobsConfig{
+			ReconciliationEnabled:  true,
+			ReconciliationInterval: time.Hour,
+			SnapshotsEnabled:       true,
+			AnonymizationEnabled:   true,
+			ComplianceEnabled:      true,
+		},
+		Webhooks: WebhooksConfig{
+			Workers:      2,
+			PollInterval: 2 * time.Second,
+			BatchSize:    20,
+			Timeout:      10 * time.Second,
+			MaxAttempts:  8,
+			BackoffBase:  10 * time.Second,
+			BackoffMax:   time.Hour,
+		},
+		Events: EventsConfig{
+			Bus:       BusRedis,
+			Heartbeat: 15 * time.Second,
+		},
+		Compliance: ComplianceConfig{
+			ReportThreshold:   500_000_000,
+			StructuringFloor:  450_000_000,
+			StructuringWindow: 24 * time.Hour,
+			StructuringCount:  3,
+		},
+		RateLimit: RateLimitConfig{
+			Enabled:      true,
+			Store:        RateLimitRedis,
+			AuthFailures: ratelimit.Limit{Requests: 10, Window: 5 * time.Minute},
+			Reads:        ratelimit.Limit{Requests: 120, Window: time.Minute},
+			Writes:       ratelimit.Limit{Requests: 30, Window: time.Minute},
+			Routes: map[string]ratelimit.Limit{
+				"POST /init": {Requests: 10, Window: time.Minute},
+			},
+		},
+		StandingOrders: StandingOrdersConfig{
+			Enabled:       true,
+			PollInterval:  30 * time.Second,
+			BatchSize:     50,
+			Lease:         time.Minute,
+			RetryAttempts: 3,
+			RetryDelay:    6 * time.Hour,
+		},
+		PaymentRequests: PaymentRequestsConfig{
+			DefaultExpiry: 72 * time.Hour,
+			MaxExpiry:     30 * 24 * time.Hour,
+		},
+	}
+}
+
+// Load builds the configuration from defaults, an optional YAML file,
+// environment variables and command line flags, each overriding the previous.
+// The file is taken from the -config flag or CONFIG_FILE, falling back to
+// DefaultFile when it exists.
+func Load(name string, args []string) (*Config, error) {
+	// A missing .env is fine, the environment may already be populated.
+	if err := godotenv.Load(); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("loading .env: %w", err)
+	}
+
+	cfg := Default()
+	fields := cfg.fields()
+
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	configFile := fs.String("config", "", "path to a YAML configuration file")
+	values := make(map[string]*flagValue, len(fields))
+	for _, f := range fields {
+		v := &flagValue{boolean: f.isBool()}
+		values[f.flag] = v
+		fs.Var(v, f.flag, fmt.Sprintf("%s (env %s)", f.usage, f.env))
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	path, required := *configFile, true
+	if path == "" {
+		path = os.Getenv("CONFIG_FILE")
+	}
+	if path == "" {
+		path, required = DefaultFile, false
+	}
+	if err := cfg.loadFile(path, required); err != nil {
+		return nil, err
+	}
+
+	for _, f := range fields {
+		if v, ok := os.LookupEnv(f.env); ok && v != "" {
+			if err := f.set(v); err != nil {
+				return nil, fmt.Errorf("%s: %w", f.env, err)
+			}
+		}
+	}
+
+	var flagErr error
+	fs.Visit(func(fl *flag.Flag) {
+		v, ok := values[fl.Name]
+		if !ok || flagErr != nil {
+			return
+		}
+		for _, f := range fields {
+			if f.flag == fl.Name {
+				if err := f.set(v.raw); err != nil {
+					flagErr = fmt.Errorf("-%s: %w", fl.Name, err)
+				}
+			}
+		}
+	})
+	if flagErr != nil {
+		return nil, flagErr
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+func (c *Config) loadFile(path string, required bool) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !required && errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("reading config file: %w", err)
+	}
+	if err := yaml.Unmarshal(data, c); err != nil {
+		return fmt.Errorf("parsing config file %s: %w", path, err)
+	}
+	return nil
+}
+
+// Validate reports every invalid setting at once.
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(c.Server.Addr != "", "server.addr must be set")
+	check(c.Server.ReadTimeout > 0, "server.read_timeout must be positive")
+	check(c.Server.WriteTimeout > 0, "server.write_timeout must be positive")
+	check(c.Server.IdleTimeout > 0, "server.idle_timeout must be positive")
+	check(c.Server.ShutdownTimeout > 0, "server.shutdown_timeout must be positive")
+	check(c.Server.MaxBodyBytes > 0, "server.max_body_bytes must be positive")
+	for _, proxy := range c.Server.Proxies() {
+		_, _, cidrErr := net.ParseCIDR(proxy)
+		check(net.ParseIP(proxy) != nil || cidrErr == nil, "server.trusted_proxies has an invalid IP or CIDR %q", proxy)
+	}
+
+	check(c.Database.URL != "", "database.url must be set (DATABASE_URL)")
+	check(c.Database.MaxOpenConns > 0, "database.max_open_conns must be positive")
+	check(c.Database.MaxIdleConns >= 0, "database.max_idle_conns must not be negative")
+	check(c.Database.MaxIdleConns <= c.Database.MaxOpenConns, "database.max_idle_conns must not exceed database.max_open_conns")
+	check(c.Database.ConnMaxLifetime >= 0, "database.conn_max_lifetime must not be negative")
+	check(c.Database.ConnMaxIdleTime >= 0, "database.conn_max_idle_time must not be negative")
+
+	check(c.Redis.URL != "", "redis.url must be set (REDIS_URL)")
+	check(c.Redis.PoolSize > 0, "redis.pool_size must be positive")
+	check(c.Redis.DialTimeout > 0, "redis.dial_timeout must be positive")
+	check(c.Redis.ReadTimeout > 0, "redis.read_timeout must be positive")
+	check(c.Redis.WriteTimeout > 0, "redis.write_timeout must be positive")
+
+	check(c.Wallet.LockTTL > 0, "wallet.lock_ttl must be positive")
+	check(c.Wallet.BalanceCacheTTL > 0, "wallet.balance_cache_ttl must be positive")
+	check(c.Wallet.MaxSettleDelay >= 0, "wallet.max_settle_delay must not be negative")
+	check(c.Wallet.SettleWorkers > 0, "wallet.settle_workers must be positive")
+	check(c.Wallet.ClosureRetention >= 0, "wallet.closure_retention must not be negative")
+	check(c.Wallet.KYC.EnableTier >= 0 && c.Wallet.KYC.EnableTier <= 2, "wallet.kyc.enable_tier must be between 0 and 2")
+	check(c.Wallet.KYC.WithdrawTier >= 0 && c.Wallet.KYC.WithdrawTier <= 2, "wallet.kyc.withdraw_tier must be between 0 and 2")
+	check(c.Wallet.KYC.BasicMaxBalance >= 0, "wallet.kyc.basic_max_balance must not be negative")
+	check(c.Wallet.KYC.VerifiedMaxBalance >= 0, "wallet.kyc.verified_max_balance must not be negative")
+	check(c.Wallet.PIN.MaxAttempts > 0, "wallet.pin.max_attempts must be positive")
+	check(c.Wallet.PIN.LockoutBase > 0, "wallet.pin.lockout_base must be positive")
+	check(c.Wallet.PIN.LockoutMax >= c.Wallet.PIN.LockoutBase, "wallet.pin.lockout_max must not be below wallet.pin.lockout_base")
+	check(c.Wallet.PIN.StepUpTTL > 0, "wallet.pin.step_up_ttl must be positive")
+	if c.Wallet.TOTP.EncryptionKey != "" {
+		_, err := auth.ParseKey(c.Wallet.TOTP.EncryptionKey)
+		check(err == nil, "wallet.totp.encryption_key is invalid: %v", err)
+	}
+	check(c.Wallet.TOTP.Issuer != "", "wallet.totp.issuer must be set")
+	check(c.Wallet.TOTP.LargeWithdrawal >= 0, "wallet.totp.large_withdrawal must not be negative")
+	check(c.Wallet.TOTP.MaxAttempts > 0, "wallet.totp.max_attempts must be positive")
+	check(c.Wallet.TOTP.LockoutBase > 0, "wallet.totp.lockout_base must be positive")
+	check(c.Wallet.TOTP.LockoutMax >= c.Wallet.TOTP.LockoutBase, "wallet.totp.lockout_max must not be below wallet.totp.lockout_base")
+
+	check(c.Jobs.ReconciliationInterval > 0, "jobs.reconciliation_interval must be positive")
+
+	check(c.Webhooks.Workers >= 0, "webhooks.workers must not be negative")
+	check(c.Webhooks.PollInterval > 0, "webhooks.poll_interval must be positive")
+	check(c.Webhooks.BatchSize > 0, "webhooks.batch_size must be positive")
+	check(c.Webhooks.Timeout > 0, "webhooks.timeout must be positive")
+	check(c.Webhooks.MaxAttempts > 0, "webhooks.max_attempts must be positive")
+	check(c.Webhooks.BackoffBase > 0, "webhooks.backoff_base must be positive")
+	check(c.Webhooks.BackoffMax >= c.Webhooks.BackoffBase, "webhooks.backoff_max must not be below webhooks.backoff_base")
+
+	check(c.Events.Bus == BusRedis || c.Events.Bus == BusMemory, "events.bus must be redis or memory")
+	check(c.Events.Heartbeat > 0, "events.heartbeat must be positive")
+
+	check(c.Compliance.ReportThreshold > 0, "compliance.report_threshold must be positive")
+	check(c.Compliance.StructuringFloor > 0 && c.Compliance.StructuringFloor < c.Compliance.ReportThreshold,
+		"compliance.structuring_floor must be positive and below compliance.report_threshold")
+	check(c.Compliance.StructuringWindow > 0, "compliance.structuring_window must be positive")
+	check(c.Compliance.StructuringCount >= 2, "compliance.structuring_count must be at least 2")
+
+	check(c.RateLimit.Store == RateLimitRedis || c.RateLimit.Store == RateLimitMemory, "rate_limit.store must be redis or memory")
+	checkLimit := func(name string, limit ratelimit.Limit) {
+		check(limit.Requests > 0, "%s.requests must be positive", name)
+		check(limit.Window > 0, "%s.window must be positive", name)
+	}
+	checkLimit("rate_limit.auth_failures", c.RateLimit.AuthFailures)
+	checkLimit("rate_limit.reads", c.RateLimit.Reads)
+	checkLimit("rate_limit.writes", c.RateLimit.Writes)
+	for route, limit := range c.RateLimit.Routes {
+		method, path, ok := strings.Cut(route, " ")
+		check(ok && method == strings.ToUpper(method) && strings.HasPrefix(path, "/"), "rate_limit.routes key %q must be a method and a path", route)
+		checkLimit(fmt.Sprintf("rate_limit.routes[%q]", route), limit)
+	}
+
+	check(c.StandingOrders.PollInterval > 0, "standing_orders.poll_interval must be positive")
+	check(c.StandingOrders.BatchSize > 0, "standing_orders.batch_size must be positive")
+	check(c.StandingOrders.Lease > 0, "standing_orders.lease must be positive")
+	check(c.StandingOrders.RetryAttempts > 0, "standing_orders.retry_attempts must be positive")
+	check(c.StandingOrders.RetryDelay > 0, "standing_orders.retry_delay must be positive")
+
+	check(c.PaymentRequests.DefaultExpiry > 0, "payment_requests.default_expiry must be positive")
+	check(c.PaymentRequests.MaxExpiry >= c.PaymentRequests.DefaultExpiry, "payment_requests.max_expiry must not be less than default_expiry")
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,219 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"mini-wallet/ratelimit"
+)
+
+// validConfig returns the defaults with the settings that have none.
+func validConfig() *Config {
+	cfg := Default()
+	cfg.Database.URL = "postgres://localhost/wallet"
+	cfg.Redis.URL = "redis://localhost:6379"
+	return cfg
+}
+
+func TestDefaultIsValidOnceURLsAreSet(t *testing.T) {
+	if err := validConfig().Validate(); err != nil {
+		t.Fatalf("Validate = %v, want the defaults valid", err)
+	}
+	err := Default().Validate()
+	if err == nil || !strings.Contains(err.Error(), "database.url must be set") || !strings.Contains(err.Error(), "redis.url must be set") {
+		t.Errorf("Validate of the bare defaults = %v, want both URLs reported", err)
+	}
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	tests := []struct {
+		name     string
+		file     string
+		env      map[string]string
+		args     []string
+		wantAddr string
+		wantTTL  time.Duration
+	}{
+		{"defaults", "", nil, nil, ":8080", 15 * time.Second},
+		{"file over defaults", "server:\n  addr: \":7000\"\nwallet:\n  lock_ttl: 20s\n", nil, nil, ":7000", 20 * time.Second},
+		{"env over file", "server:\n  addr: \":7000\"\nwallet:\n  lock_ttl: 20s\n", map[string]string{"SERVER_ADDR": ":7100"}, nil, ":7100", 20 * time.Second},
+		{"empty env is ignored", "server:\n  addr: \":7000\"\n", map[string]string{"SERVER_ADDR": ""}, nil, ":7000", 15 * time.Second},
+		{"flag over env", "server:\n  addr: \":7000\"\n", map[string]string{"SERVER_ADDR": ":7100", "WALLET_LOCK_TTL": "25s"}, []string{"-addr", ":7200"}, ":7200", 25 * time.Second},
+		{"flag over file", "wallet:\n  lock_ttl: 20s\n", nil, []string{"-lock-ttl", "30s"}, ":8080", 30 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("CONFIG_FILE", "")
+			t.Setenv("DATABASE_URL", "postgres://localhost/wallet")
+			t.Setenv("REDIS_URL", "redis://localhost:6379")
+			t.Setenv("SERVER_ADDR", "")
+			t.Setenv("WALLET_LOCK_TTL", "")
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			args := tt.args
+			if tt.file != "" {
+				path := filepath.Join(t.TempDir(), "config.yaml")
+				if err := os.WriteFile(path, []byte(tt.file), 0o600); err != nil {
+					t.Fatal(err)
+				}
+				args = append([]string{"-config", path}, args...)
+			}
+
+			cfg, err := Load("wallet", args)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cfg.Server.Addr != tt.wantAddr || cfg.Wallet.LockTTL != tt.wantTTL {
+				t.Errorf("addr, lock_ttl = %q, %v, want %q, %v", cfg.Server.Addr, cfg.Wallet.LockTTL, tt.wantAddr, tt.wantTTL)
+			}
+		})
+	}
+}
+
+func TestLoadFromConfigFileEnv(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "wallet.yaml")
+	if err := os.WriteFile(path, []byte("server:\n  addr: \":7300\"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("CONFIG_FILE", path)
+	t.Setenv("SERVER_ADDR", "")
+	t.Setenv("DATABASE_URL", "postgres://localhost/wallet")
+	t.Setenv("REDIS_URL", "redis://localhost:6379")
+
+	cfg, err := Load("wallet", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Server.Addr != ":7300" {
+		t.Errorf("addr = %q, want the one from CONFIG_FILE", cfg.Server.Addr)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		args    []string
+		wantErr string
+	}{
+		{"missing requested file", nil, []string{"-config", "does-not-exist.yaml"}, "reading config file"},
+		{"malformed env duration", map[string]string{"WALLET_LOCK_TTL": "soon"}, nil, `WALLET_LOCK_TTL: invalid duration "soon"`},
+		{"malformed env integer", map[string]string{"DB_MAX_OPEN_CONNS": "many"}, nil, `DB_MAX_OPEN_CONNS: invalid integer "many"`},
+		{"malformed env boolean", map[string]string{"JOBS_SNAPSHOTS_ENABLED": "sometimes"}, nil, `JOBS_SNAPSHOTS_ENABLED: invalid boolean "sometimes"`},
+		{"malformed flag", nil, []string{"-settle-workers", "x"}, `-settle-workers: invalid integer "x"`},
+		{"unknown flag", nil, []string{"-no-such-flag"}, "flag provided but not defined"},
+		{"invalid result", map[string]string{"SERVER_MAX_BODY_BYTES": "0"}, nil, "server.max_body_bytes must be positive"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("CONFIG_FILE", "")
+			t.Setenv("DATABASE_URL", "postgres://localhost/wallet")
+			t.Setenv("REDIS_URL", "redis://localhost:6379")
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			if _, err := Load("wallet", tt.args); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Load error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		wantErr string
+		change  func(c *Config)
+	}{
+		{"server.addr must be set", func(c *Config) { c.Server.Addr = "" }},
+		{"server.read_timeout must be positive", func(c *Config) { c.Server.ReadTimeout = 0 }},
+		{"server.write_timeout must be positive", func(c *Config) { c.Server.WriteTimeout = 0 }},
+		{"server.idle_timeout must be positive", func(c *Config) { c.Server.IdleTimeout = 0 }},
+		{"server.shutdown_timeout must be positive", func(c *Config) { c.Server.ShutdownTimeout = 0 }},
+		{"server.max_body_bytes must be positive", func(c *Config) { c.Server.MaxBodyBytes = 0 }},
+		{`server.trusted_proxies has an invalid IP or CIDR "10.0.0/8"`, func(c *Config) { c.Server.TrustedProxies = "127.0.0.1, 10.0.0/8" }},
+
+		{"database.url must be set", func(c *Config) { c.Database.URL = "" }},
+		{"database.max_open_conns must be positive", func(c *Config) { c.Database.MaxOpenConns = 0; c.Database.MaxIdleConns = 0 }},
+		{"database.max_idle_conns must not be negative", func(c *Config) { c.Database.MaxIdleConns = -1 }},
+		{"database.max_idle_conns must not exceed database.max_open_conns", func(c *Config) { c.Database.MaxIdleConns = 30 }},
+		{"database.conn_max_lifetime must not be negative", func(c *Config) { c.Database.ConnMaxLifetime = -time.Second }},
+		{"database.conn_max_idle_time must not be negative", func(c *Config) { c.Database.ConnMaxIdleTime = -time.Second }},
+
+		{"redis.url must be set", func(c *Config) { c.Redis.URL = "" }},
+		{"redis.pool_size must be positive", func(c *Config) { c.Redis.PoolSize = 0 }},
+		{"redis.dial_timeout must be positive", func(c *Config) { c.Redis.DialTimeout = 0 }},
+		{"redis.read_timeout must be positive", func(c *Config) { c.Redis.ReadTimeout = 0 }},
+		{"redis.write_timeout must be positive", func(c *Config) { c.Redis.WriteTimeout = 0 }},
+
+		{"wallet.lock_ttl must be positive", func(c *Config) { c.Wallet.LockTTL = 0 }},
+		{"wallet.balance_cache_ttl must be positive", func(c *Config) { c.Wallet.BalanceCacheTTL = 0 }},
+		{"wallet.max_settle_delay must not be negative", func(c *Config) { c.Wallet.MaxSettleDelay = -time.Second }},
+		{"wallet.settle_workers must be positive", func(c *Config) { c.Wallet.SettleWorkers = 0 }},
+		{"wallet.closure_retention must not be negative", func(c *Config) { c.Wallet.ClosureRetention = -time.Hour }},
+		{"wallet.kyc.enable_tier must be between 0 and 2", func(c *Config) { c.Wallet.KYC.EnableTier = 3 }},
+		{"wallet.kyc.withdraw_tier must be between 0 and 2", func(c *Config) { c.Wallet.KYC.WithdrawTier = -1 }},
+		{"wallet.kyc.basic_max_balance must not be negative", func(c *Config) { c.Wallet.KYC.BasicMaxBalance = -1 }},
+		{"wallet.kyc.verified_max_balance must not be negative", func(c *Config) { c.Wallet.KYC.VerifiedMaxBalance = -1 }},
+		{"wallet.pin.max_attempts must be positive", func(c *Config) { c.Wallet.PIN.MaxAttempts = 0 }},
+		{"wallet.pin.lockout_base must be positive", func(c *Config) { c.Wallet.PIN.LockoutBase = 0 }},
+		{"wallet.pin.lockout_max must not be below wallet.pin.lockout_base", func(c *Config) { c.Wallet.PIN.LockoutMax = time.Second }},
+		{"wallet.pin.step_up_ttl must be positive", func(c *Config) { c.Wallet.PIN.StepUpTTL = 0 }},
+		{"wallet.totp.encryption_key is invalid", func(c *Config) { c.Wallet.TOTP.EncryptionKey = "not a key" }},
+		{"wallet.totp.issuer must be set", func(c *Config) { c.Wallet.TOTP.Issuer = "" }},
+		{"wallet.totp.large_withdrawal must not be negative", func(c *Config) { c.Wallet.TOTP.LargeWithdrawal = -1 }},
+		{"wallet.totp.max_attempts must be positive", func(c *Config) { c.Wallet.TOTP.MaxAttempts = 0 }},
+		{"wallet.totp.lockout_base must be positive", func(c *Config) { c.Wallet.TOTP.LockoutBase = 0 }},
+		{"wallet.totp.lockout_max must not be below wallet.totp.lockout_base", func(c *Config) { c.Wallet.TOTP.LockoutMax = time.Second }},
+
+		{"jobs.reconciliation_interval must be positive", func(c *Config) { c.Jobs.ReconciliationInterval = 0 }},
+
+		{"webhooks.workers must not be negative", func(c *Config) { c.Webhooks.Workers = -1 }},
+		{"webhooks.poll_interval must be positive", func(c *Config) { c.Webhooks.PollInterval = 0 }},
+		{"webhooks.batch_size must be positive", func(c *Config) { c.Webhooks.BatchSize = 0 }},
+		{"webhooks.timeout must be positive", func(c *Config) { c.Webhooks.Timeout = 0 }},
+		{"webhooks.max_attempts must be positive", func(c *Config) { c.Webhooks.MaxAttempts = 0 }},
+		{"webhooks.backoff_base must be positive", func(c *Config) { c.Webhooks.BackoffBase = 0 }},
+		{"webhooks.backoff_max must not be below webhooks.backoff_base", func(c *Config) { c.Webhooks.BackoffMax = time.Second }},
+
+		{"events.bus must be redis or memory", func(c *Config) { c.Events.Bus = "kafka" }},
+		{"events.heartbeat must be positive", func(c *Config) { c.Events.Heartbeat = 0 }},
+
+		{"compliance.report_threshold must be positive", func(c *Config) { c.Compliance.ReportThreshold = 0 }},
+		{"compliance.structuring_floor must be positive and below compliance.report_threshold", func(c *Config) { c.Compliance.StructuringFloor = c.Compliance.ReportThreshold }},
+		{"compliance.structuring_window must be positive", func(c *Config) { c.Compliance.StructuringWindow = 0 }},
+		{"compliance.structuring_count must be at least 2", func(c *Config) { c.Compliance.StructuringCount = 1 }},
+
+		{"rate_limit.store must be redis or memory", func(c *Config) { c.RateLimit.Store = "disk" }},
+		{"rate_limit.auth_failures.requests must be positive", func(c *Config) { c.RateLimit.AuthFailures.Requests = 0 }},
+		{"rate_limit.reads.window must be positive", func(c *Config) { c.RateLimit.Reads.Window = 0 }},
+		{"rate_limit.writes.requests must be positive", func(c *Config) { c.RateLimit.Writes.Requests = 0 }},
+		{`rate_limit.routes key "wallet" must be a method and a path`, func(c *Config) {
+			c.RateLimit.Routes = map[string]ratelimit.Limit{"wallet": {Requests: 1, Window: time.Minute}}
+		}},
+		{`rate_limit.routes["GET /wallet"].window must be positive`, func(c *Config) {
+			c.RateLimit.Routes = map[string]ratelimit.Limit{"GET /wallet": {Requests: 1}}
+		}},
+
+		{"standing_orders.poll_interval must be positive", func(c *Config) { c.StandingOrders.PollInterval = 0 }},
+		{"standing_orders.batch_size must be positive", func(c *Config) { c.StandingOrders.BatchSize = 0 }},
+		{"standing_orders.lease must be positive", func(c *Config) { c.StandingOrders.Lease = 0 }},
+		{"standing_orders.retry_attempts must be positive", func(c *Config) { c.StandingOrders.RetryAttempts = 0 }},
+		{"standing_orders.retry_delay must be positive", func(c *Config) { c.StandingOrders.RetryDelay = 0 }},
+
+		{"payment_requests.default_expiry must be positive", func(c *Config) { c.PaymentRequests.DefaultExpiry = 0 }},
+		{"payment_requests.max_expiry must not be less than default_expiry", func(c *Config) { c.PaymentRequests.MaxExpiry = time.Hour }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.wantErr, func(t *testing.T) {
+			cfg := validConfig()
+			tt.change(cfg)
+			if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Validate = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,161 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// field binds one setting to its environment variable and flag so both
+// sources are declared in a single place.
+type field struct {
+	flag  string
+	env   string
+	usage string
+	ptr   any
+}
+
+func (f field) set(value string) error {
+	switch p := f.ptr.(type) {
+	case *string:
+		*p = value
+	case *bool:
+		v, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", value)
+		}
+		*p = v
+	case *int:
+		v, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", value)
+		}
+		*p = v
+	case *int64:
+		v, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", value)
+		}
+		*p = v
+	case *time.Duration:
+		v, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q", value)
+		}
+		*p = v
+	default:
+		return fmt.Errorf("unsupported setting type %T", f.ptr)
+	}
+	return nil
+}
+
+func (f field) isBool() bool {
+	_, ok := f.ptr.(*bool)
+	return ok
+}
+
+// flagValue records the raw flag argument so it can be applied after the
+// file and environment, keeping flags at the highest precedence.
+type flagValue struct {
+	raw     string
+	boolean bool
+}
+
+func (v *flagValue) String() string { return v.raw }
+
+func (v *flagValue) Set(s string) error {
+	v.raw = s
+	return nil
+}
+
+func (v *flagValue) IsBoolFlag() bool { return v.boolean }
+
+func (c *Config) fields() []field {
+	return []field{
+		{"addr", "SERVER_ADDR", "HTTP listen address", &c.Server.Addr},
+		{"read-timeout", "SERVER_READ_TIMEOUT", "HTTP read timeout", &c.Server.ReadTimeout},
+		{"write-timeout", "SERVER_WRITE_TIMEOUT", "HTTP write timeout", &c.Server.WriteTimeout},
+		{"idle-timeout", "SERVER_IDLE_TIMEOUT", "HTTP keep-alive idle timeout", &c.Server.IdleTimeout},
+		{"shutdown-timeout", "SERVER_SHUTDOWN_TIMEOUT", "graceful shutdown timeout", &c.Server.ShutdownTimeout},
+		{"max-body-bytes", "SERVER_MAX_BODY_BYTES", "maximum request body size", &c.Server.MaxBodyBytes},
+		{"grpc-addr", "SERVER_GRPC_ADDR", "gRPC listen address, empty to disable", &c.Server.GRPCAddr},
+		{"trusted-proxies", "SERVER_TRUSTED_PROXIES", "comma-separated IPs and CIDRs of proxies trusted to set X-Forwarded-For", &c.Server.TrustedProxies},
+
+		{"database-url", "DATABASE_URL", "PostgreSQL connection string", &c.Database.URL},
+		{"db-max-open-conns", "DB_MAX_OPEN_CONNS", "maximum open database connections", &c.Database.MaxOpenConns},
+		{"db-max-idle-conns", "DB_MAX_IDLE_CONNS", "maximum idle database connections", &c.Database.MaxIdleConns},
+		{"db-conn-max-lifetime", "DB_CONN_MAX_LIFETIME", "maximum lifetime of a database connection", &c.Database.ConnMaxLifetime},
+		{"db-conn-max-idle-time", "DB_CONN_MAX_IDLE_TIME", "maximum idle time of a database connection", &c.Database.ConnMaxIdleTime},
+
+		{"redis-url", "REDIS_URL", "Redis connection URL", &c.Redis.URL},
+		{"redis-pool-size", "REDIS_POOL_SIZE", "Redis connection pool size", &c.Redis.PoolSize},
+		{"redis-dial-timeout", "REDIS_DIAL_TIMEOUT", "Redis dial timeout", &c.Redis.DialTimeout},
+		{"redis-read-timeout", "REDIS_READ_TIMEOUT", "Redis read timeout", &c.Redis.ReadTimeout},
+		{"redis-write-timeout", "REDIS_WRITE_TIMEOUT", "Redis write timeout", &c.Redis.WriteTimeout},
+
+		{"lock-ttl", "WALLET_LOCK_TTL", "TTL of the balance update lock", &c.Wallet.LockTTL},
+		{"balance-cache-ttl", "WALLET_BALANCE_CACHE_TTL", "TTL of the cached balance", &c.Wallet.BalanceCacheTTL},
+		{"max-settle-delay", "WALLET_MAX_SETTLE_DELAY", "upper bound of the balance settlement delay", &c.Wallet.MaxSettleDelay},
+		{"settle-workers", "WALLET_SETTLE_WORKERS", "concurrent balance settlements", &c.Wallet.SettleWorkers},
+		{"closure-retention", "WALLET_CLOSURE_RETENTION", "how long a closed wallet's personal data is kept", &c.Wallet.ClosureRetention},
+		{"kyc-enable-tier", "WALLET_KYC_ENABLE_TIER", "lowest KYC tier allowed to enable a wallet", &c.Wallet.KYC.EnableTier},
+		{"kyc-withdraw-tier", "WALLET_KYC_WITHDRAW_TIER", "lowest KYC tier allowed to withdraw", &c.Wallet.KYC.WithdrawTier},
+		{"kyc-basic-max-balance", "WALLET_KYC_BASIC_MAX_BALANCE", "maximum balance at KYC tier 0, 0 for no limit", &c.Wallet.KYC.BasicMaxBalance},
+		{"kyc-verified-max-balance", "WALLET_KYC_VERIFIED_MAX_BALANCE", "maximum balance at KYC tier 1, 0 for no limit", &c.Wallet.KYC.VerifiedMaxBalance},
+		{"pin-required", "WALLET_PIN_REQUIRED", "refuse withdrawals from customers without a transaction PIN", &c.Wallet.PIN.Required},
+		{"pin-max-attempts", "WALLET_PIN_MAX_ATTEMPTS", "wrong PINs in a row before the PIN is locked", &c.Wallet.PIN.MaxAttempts},
+		{"pin-lockout-base", "WALLET_PIN_LOCKOUT_BASE", "first lockout of a PIN, doubling with each further wrong PIN", &c.Wallet.PIN.LockoutBase},
+		{"pin-lockout-max", "WALLET_PIN_LOCKOUT_MAX", "maximum lockout of a PIN", &c.Wallet.PIN.LockoutMax},
+		{"step-up-ttl", "WALLET_PIN_STEP_UP_TTL", "how long a step-up token stands in for the PIN", &c.Wallet.PIN.StepUpTTL},
+		{"totp-encryption-key", "WALLET_TOTP_ENCRYPTION_KEY", "base64 AES-256 key encrypting TOTP secrets, empty disables TOTP", &c.Wallet.TOTP.EncryptionKey},
+		{"totp-issuer", "WALLET_TOTP_ISSUER", "wallet name shown in authenticator apps", &c.Wallet.TOTP.Issuer},
+		{"totp-large-withdrawal", "WALLET_TOTP_LARGE_WITHDRAWAL", "amount from which withdrawals need a one-time code, 0 for none", &c.Wallet.TOTP.LargeWithdrawal},
+		{"totp-max-attempts", "WALLET_TOTP_MAX_ATTEMPTS", "wrong one-time codes in a row before the codes are locked", &c.Wallet.TOTP.MaxAttempts},
+		{"totp-lockout-base", "WALLET_TOTP_LOCKOUT_BASE", "first lockout of one-time codes, doubling with each further wrong code", &c.Wallet.TOTP.LockoutBase},
+		{"totp-lockout-max", "WALLET_TOTP_LOCKOUT_MAX", "maximum lockout of one-time codes", &c.Wallet.TOTP.LockoutMax},
+
+		{"reconciliation-enabled", "JOBS_RECONCILIATION_ENABLED", "run the balance reconciliation job", &c.Jobs.ReconciliationEnabled},
+		{"reconciliation-interval", "JOBS_RECONCILIATION_INTERVAL", "interval between reconciliation runs", &c.Jobs.ReconciliationInterval},
+		{"reconciliation-auto-correct", "JOBS_RECONCILIATION_AUTO_CORRECT", "correct drifted balances during reconciliation", &c.Jobs.ReconciliationAutoCorrect},
+		{"snapshots-enabled", "JOBS_SNAPSHOTS_ENABLED", "take daily balance snapshots", &c.Jobs.SnapshotsEnabled},
+		{"anonymization-enabled", "JOBS_ANONYMIZATION_ENABLED", "anonymize closed wallets after their retention", &c.Jobs.AnonymizationEnabled},
+		{"compliance-enabled", "JOBS_COMPLIANCE_ENABLED", "scan transactions for compliance reports", &c.Jobs.ComplianceEnabled},
+
+		{"webhook-workers", "WEBHOOKS_WORKERS", "concurrent webhook delivery workers, 0 disables delivery", &c.Webhooks.Workers},
+		{"webhook-poll-interval", "WEBHOOKS_POLL_INTERVAL", "interval between webhook queue polls", &c.Webhooks.PollInterval},
+		{"webhook-batch-size", "WEBHOOKS_BATCH_SIZE", "deliveries claimed per poll", &c.Webhooks.BatchSize},
+		{"webhook-timeout", "WEBHOOKS_TIMEOUT", "timeout of one webhook request", &c.Webhooks.Timeout},
+		{"webhook-max-attempts", "WEBHOOKS_MAX_ATTEMPTS", "attempts before a delivery is dead-lettered", &c.Webhooks.MaxAttempts},
+		{"webhook-backoff-base", "WEBHOOKS_BACKOFF_BASE", "delay after the first failed attempt", &c.Webhooks.BackoffBase},
+		{"webhook-backoff-max", "WEBHOOKS_BACKOFF_MAX", "maximum delay between attempts", &c.Webhooks.BackoffMax},
+
+		{"event-bus", "EVENTS_BUS", "live event fan-out: redis or memory", &c.Events.Bus},
+		{"event-heartbeat", "EVENTS_HEARTBEAT", "interval of keep-alive comments on event streams", &c.Events.Heartbeat},
+
+		{"risk-rules-file", "RISK_RULES_FILE", "YAML file of risk rules, empty to allow every transaction", &c.Risk.RulesFile},
+
+		{"report-threshold", "COMPLIANCE_REPORT_THRESHOLD", "amount from which a transaction is reported", &c.Compliance.ReportThreshold},
+		{"structuring-floor", "COMPLIANCE_STRUCTURING_FLOOR", "amount from which a transaction counts towards structuring", &c.Compliance.StructuringFloor},
+		{"structuring-window", "COMPLIANCE_STRUCTURING_WINDOW", "window of a structuring series", &c.Compliance.StructuringWindow},
+		{"structuring-count", "COMPLIANCE_STRUCTURING_COUNT", "transactions that make a structuring series", &c.Compliance.StructuringCount},
+
+		{"rate-limit-enabled", "RATE_LIMIT_ENABLED", "limit the requests per token and client IP", &c.RateLimit.Enabled},
+		{"rate-limit-store", "RATE_LIMIT_STORE", "where requests are counted: redis or memory", &c.RateLimit.Store},
+		{"rate-limit-auth-failures", "RATE_LIMIT_AUTH_FAILURES", "requests answered 401 allowed per client IP and window", &c.RateLimit.AuthFailures.Requests},
+		{"rate-limit-auth-failures-window", "RATE_LIMIT_AUTH_FAILURES_WINDOW", "window of the auth failure budget", &c.RateLimit.AuthFailures.Window},
+		{"rate-limit-reads", "RATE_LIMIT_READS", "GET requests allowed per token and window", &c.RateLimit.Reads.Requests},
+		{"rate-limit-reads-window", "RATE_LIMIT_READS_WINDOW", "window of the read budget", &c.RateLimit.Reads.Window},
+		{"rate-limit-writes", "RATE_LIMIT_WRITES", "other requests allowed per token and window", &c.RateLimit.Writes.Requests},
+		{"rate-limit-writes-window", "RATE_LIMIT_WRITES_WINDOW", "window of the write budget", &c.RateLimit.Writes.Window},
+
+		{"standing-orders-enabled", "STANDING_ORDERS_ENABLED", "run the standing order scheduler", &c.StandingOrders.Enabled},
+		{"standing-orders-poll-interval", "STANDING_ORDERS_POLL_INTERVAL", "interval between checks for due standing orders", &c.StandingOrders.PollInterval},
+		{"standing-orders-batch-size", "STANDING_ORDERS_BATCH_SIZE", "standing orders claimed per check", &c.StandingOrders.BatchSize},
+		{"standing-orders-lease", "STANDING_ORDERS_LEASE", "how long a scheduler holds the orders it claimed", &c.StandingOrders.Lease},
+		{"standing-orders-retry-attempts", "STANDING_ORDERS_RETRY_ATTEMPTS", "attempts of a run short of balance before it is given up", &c.StandingOrders.RetryAttempts},
+		{"standing-orders-retry-delay", "STANDING_ORDERS_RETRY_DELAY", "delay between attempts of a run short of balance", &c.StandingOrders.RetryDelay},
+
+		{"payment-request-default-expiry", "PAYMENT_REQUESTS_DEFAULT_EXPIRY", "how long a payment request stays payable unless it says", &c.PaymentRequests.DefaultExpiry},
+		{"payment-request-max-expiry", "PAYMENT_REQUESTS_MAX_EXPIRY", "longest a payment request may stay payable", &c.PaymentRequests.MaxExpiry},
+	}
+}
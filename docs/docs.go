@@ -0,0 +1,11 @@
+// Package docs embeds the OpenAPI specification of the HTTP API. Keep
+// openapi.yaml in sync with the routes in package server; its test fails
+// when a registered route is missing.
+package docs
+
+import (
+	_ "embed"
+)
+
+//go:embed openapi.yaml
+var OpenAPI []byte
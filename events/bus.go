@@ -0,0 +1,132 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sync"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// subscriberBuffer is how many events a subscriber may fall behind before
+// further events are dropped for it.
+const subscriberBuffer = 32
+
+// Bus fans events out to the live subscribers of a customer.
+type Bus interface {
+	Publisher
+	// Subscribe streams the customer's events until ctx is done, then
+	// closes the channel.
+	Subscribe(ctx context.Context, customerXID string) (<-chan Event, error)
+}
+
+// LocalBus delivers events within this process only, for deployments
+// running a single instance.
+type LocalBus struct {
+	mu          sync.Mutex
+	subscribers map[string]map[chan Event]struct{}
+}
+
+func NewLocalBus() *LocalBus {
+	return &LocalBus{subscribers: make(map[string]map[chan Event]struct{})}
+}
+
+func (b *LocalBus) Publish(_ context.Context, event Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for ch := range b.subscribers[event.CustomerXID] {
+		deliver(ch, event)
+	}
+}
+
+func (b *LocalBus) Subscribe(ctx context.Context, customerXID string) (<-chan Event, error) {
+	ch := make(chan Event, subscriberBuffer)
+
+	b.mu.Lock()
+	if b.subscribers[customerXID] == nil {
+		b.subscribers[customerXID] = make(map[chan Event]struct{})
+	}
+	b.subscribers[customerXID][ch] = struct{}{}
+	b.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		b.mu.Lock()
+		delete(b.subscribers[customerXID], ch)
+		if len(b.subscribers[customerXID]) == 0 {
+			delete(b.subscribers, customerXID)
+		}
+		b.mu.Unlock()
+		close(ch)
+	}()
+	return ch, nil
+}
+
+// RedisBus delivers events through Redis pub/sub so subscribers connected
+// to any instance receive them.
+type RedisBus struct {
+	client *redis.Client
+}
+
+func NewRedisBus(client *redis.Client) *RedisBus {
+	return &RedisBus{client: client}
+}
+
+func channelName(customerXID string) string {
+	return "wallet:events:" + customerXID
+}
+
+func (b *RedisBus) Publish(ctx context.Context, event Event) {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("Failed to encode %s event %s: %v", event.Type, event.ID, err)
+		return
+	}
+	if err := b.client.Publish(ctx, channelName(event.CustomerXID), payload).Err(); err != nil {
+		log.Printf("Failed to publish %s event %s: %v", event.Type, event.ID, err)
+	}
+}
+
+func (b *RedisBus) Subscribe(ctx context.Context, customerXID string) (<-chan Event, error) {
+	pubsub := b.client.Subscribe(ctx, channelName(customerXID))
+	// Wait for the confirmation so no event published after Subscribe
+	// returns is missed
+	if _, err := pubsub.Receive(ctx); err != nil {
+		pubsub.Close()
+		return nil, err
+	}
+
+	ch := make(chan Event, subscriberBuffer)
+	go func() {
+		defer close(ch)
+		defer pubsub.Close()
+		messages := pubsub.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg, ok := <-messages:
+				if !ok {
+					return
+				}
+				var event Event
+				if err := json.Unmarshal([]byte(msg.Payload), &event); err != nil {
+					log.Printf("Failed to decode event from %s: %v", msg.Channel, err)
+					continue
+				}
+				deliver(ch, event)
+			}
+		}
+	}()
+	return ch, nil
+}
+
+// deliver hands an event to a subscriber without waiting on a slow reader.
+func deliver(ch chan Event, event Event) {
+	select {
+	case ch <- event:
+	default:
+		log.Printf("Dropped %s event %s for a slow subscriber", event.Type, event.ID)
+	}
+}
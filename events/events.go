@@ -0,0 +1,94 @@
+// Package events defines the wallet activity other parts of the system
+// react to, such as webhooks and live event streams.
+package events
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	WalletEnabled       = "wallet.enabled"
+	WalletDisabled      = "wallet.disabled"
+	WalletFrozen        = "wallet.frozen"
+	WalletUnfrozen      = "wallet.unfrozen"
+	WalletSuspended     = "wallet.suspended"
+	WalletReinstated    = "wallet.reinstated"
+	WalletClosed        = "wallet.closed"
+	DepositSucceeded    = "deposit.succeeded"
+	WithdrawalSucceeded = "withdrawal.succeeded"
+	AdjustmentPosted    = "adjustment.posted"
+	BalanceUpdated      = "balance.updated"
+	// TransferSent and TransferReceived tell the payer and the payee of a
+	// transfer between wallets, by standing order or payment request.
+	TransferSent     = "transfer.sent"
+	TransferReceived = "transfer.received"
+	// StandingOrderRetrying tells that a run of a standing order found too
+	// little balance and will be attempted again.
+	StandingOrderRetrying = "standing_order.retrying"
+	// StandingOrderFailed tells that a run of a standing order was given up.
+	StandingOrderFailed = "standing_order.failed"
+	// PaymentRequestReceived tells a customer that another asks them for
+	// money.
+	PaymentRequestReceived = "payment_request.received"
+	// PaymentRequestPaid and PaymentRequestDeclined tell the requester how
+	// their request was answered.
+	PaymentRequestPaid     = "payment_request.paid"
+	PaymentRequestDeclined = "payment_request.declined"
+)
+
+// Types lists every event type in a stable order.
+var Types = []string{WalletEnabled, WalletDisabled, WalletFrozen, WalletUnfrozen, WalletSuspended, WalletReinstated, WalletClosed, DepositSucceeded, WithdrawalSucceeded, AdjustmentPosted, BalanceUpdated, TransferSent, TransferReceived, StandingOrderRetrying, StandingOrderFailed, PaymentRequestReceived, PaymentRequestPaid, PaymentRequestDeclined}
+
+// FromTransaction reports whether events of the type describe a recorded
+// transaction. Such events reuse the transaction's ID.
+func FromTransaction(eventType string) bool {
+	switch eventType {
+	case DepositSucceeded, WithdrawalSucceeded, AdjustmentPosted, TransferSent, TransferReceived:
+		return true
+	}
+	return false
+}
+
+type Event struct {
+	ID          string    `json:"id"`
+	Type        string    `json:"type"`
+	CustomerXID string    `json:"customer_xid"`
+	WalletID    string    `json:"wallet_id"`
+	OccurredAt  time.Time `json:"occurred_at"`
+	Data        any       `json:"data"`
+}
+
+// New creates an event of the given type for a customer's wallet.
+func New(eventType, customerXID, walletID string, data any) Event {
+	return Event{
+		ID:          uuid.New().String(),
+		Type:        eventType,
+		CustomerXID: customerXID,
+		WalletID:    walletID,
+		OccurredAt:  time.Now().UTC(),
+		Data:        data,
+	}
+}
+
+// Publisher receives events. Implementations must not block the caller for
+// long, since events are published on the request path.
+type Publisher interface {
+	Publish(ctx context.Context, event Event)
+}
+
+// Nop discards every event.
+type Nop struct{}
+
+func (Nop) Publish(context.Context, Event) {}
+
+// Multi publishes every event to each of its publishers in order.
+type Multi []Publisher
+
+func (m Multi) Publish(ctx context.Context, event Event) {
+	for _, p := range m {
+		p.Publish(ctx, event)
+	}
+}
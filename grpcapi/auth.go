@@ -0,0 +1,96 @@
+package grpcapi
+
+import (
+	"context"
+	"strings"
+
+	"mini-wallet/service"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// publicMethods need no token.
+var publicMethods = map[string]bool{
+	"/wallet.v1.WalletService/Init": true,
+}
+
+type customerKey struct{}
+
+// customerXID returns the customer authenticated by the interceptors.
+func customerXID(ctx context.Context) string {
+	customerXID, _ := ctx.Value(customerKey{}).(string)
+	return customerXID
+}
+
+// authenticate resolves the "authorization: Token <token>" metadata the
+// same way the REST API reads its header.
+func authenticate(ctx context.Context, wallets *service.WalletService) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get("authorization")
+	if len(values) == 0 || values[0] == "" {
+		return nil, status.Error(codes.Unauthenticated, "authorization token is required")
+	}
+	token, ok := strings.CutPrefix(values[0], "Token ")
+	if !ok || token == "" {
+		return nil, statusError(service.ErrInvalidToken)
+	}
+	customerXID, err := wallets.Authenticate(token)
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return context.WithValue(ctx, customerKey{}, customerXID), nil
+}
+
+// authorization reads the transaction PIN or step-up token authorizing a
+// withdrawal from the x-transaction-pin and x-step-up-token metadata, and
+// the one-time code from x-totp-code.
+func authorization(ctx context.Context) service.Authorization {
+	md, _ := metadata.FromIncomingContext(ctx)
+	var auth service.Authorization
+	if values := md.Get("x-transaction-pin"); len(values) > 0 {
+		auth.PIN = values[0]
+	}
+	if values := md.Get("x-step-up-token"); len(values) > 0 {
+		auth.StepUpToken = values[0]
+	}
+	if values := md.Get("x-totp-code"); len(values) > 0 {
+		auth.TOTPCode = values[0]
+	}
+	return auth
+}
+
+func unaryAuth(wallets *service.WalletService) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if publicMethods[info.FullMethod] {
+			return handler(ctx, req)
+		}
+		ctx, err := authenticate(ctx, wallets)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+func streamAuth(wallets *service.WalletService) grpc.StreamServerInterceptor {
+	return func(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := authenticate(stream.Context(), wallets)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &authenticatedStream{ServerStream: stream, ctx: ctx})
+	}
+}
+
+// authenticatedStream carries the authenticated customer in its context.
+type authenticatedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *authenticatedStream) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,33 @@
+package grpcapi
+
+import (
+	"errors"
+	"log"
+
+	"mini-wallet/service"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var statusCodes = map[service.Kind]codes.Code{
+	service.KindInvalid:            codes.InvalidArgument,
+	service.KindUnauthenticated:    codes.Unauthenticated,
+	service.KindNotFound:           codes.NotFound,
+	service.KindConflict:           codes.AlreadyExists,
+	service.KindFailedPrecondition: codes.FailedPrecondition,
+	service.KindPermissionDenied:   codes.PermissionDenied,
+}
+
+// statusError maps a wallet service error onto a gRPC status. Unexpected
+// errors are logged and reported without their details.
+func statusError(err error) error {
+	var domainErr *service.Error
+	if errors.As(err, &domainErr) {
+		if code, ok := statusCodes[domainErr.Kind]; ok {
+			return status.Error(code, domainErr.Message)
+		}
+	}
+	log.Println("gRPC request failed:", err)
+	return status.Error(codes.Internal, "internal error")
+}
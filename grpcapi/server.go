@@ -0,0 +1,145 @@
+// Package grpcapi serves the wallet over gRPC for internal services, on top
+// of the same service layer as the REST API.
+package grpcapi
+
+import (
+	"context"
+	"time"
+
+	"mini-wallet/models"
+	walletv1 "mini-wallet/proto/wallet/v1"
+	"mini-wallet/service"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+type walletServer struct {
+	walletv1.UnimplementedWalletServiceServer
+	wallets *service.WalletService
+}
+
+// NewServer returns a gRPC server exposing the wallet service, with token
+// authentication on every method but Init.
+func NewServer(wallets *service.WalletService) *grpc.Server {
+	server := grpc.NewServer(
+		grpc.UnaryInterceptor(unaryAuth(wallets)),
+		grpc.StreamInterceptor(streamAuth(wallets)),
+	)
+	walletv1.RegisterWalletServiceServer(server, &walletServer{wallets: wallets})
+	return server
+}
+
+func (s *walletServer) Init(ctx context.Context, req *walletv1.InitRequest) (*walletv1.InitResponse, error) {
+	if req.GetCustomerXid() == "" {
+		return nil, status.Error(codes.InvalidArgument, "customer_xid is required")
+	}
+	token, err := s.wallets.Init(req.GetCustomerXid())
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return &walletv1.InitResponse{Token: token}, nil
+}
+
+func (s *walletServer) Enable(ctx context.Context, _ *walletv1.EnableRequest) (*walletv1.EnableResponse, error) {
+	wallet, err := s.wallets.Enable(customerXID(ctx))
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return &walletv1.EnableResponse{Wallet: toWallet(wallet)}, nil
+}
+
+func (s *walletServer) Disable(ctx context.Context, _ *walletv1.DisableRequest) (*walletv1.DisableResponse, error) {
+	wallet, err := s.wallets.Disable(customerXID(ctx), authorization(ctx))
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return &walletv1.DisableResponse{Wallet: toWallet(wallet)}, nil
+}
+
+func (s *walletServer) GetBalance(ctx context.Context, _ *walletv1.GetBalanceRequest) (*walletv1.GetBalanceResponse, error) {
+	wallet, err := s.wallets.Balance(customerXID(ctx))
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return &walletv1.GetBalanceResponse{Wallet: toWallet(wallet)}, nil
+}
+
+func (s *walletServer) ListTransactions(_ *walletv1.ListTransactionsRequest, stream walletv1.WalletService_ListTransactionsServer) error {
+	transactions, err := s.wallets.History(customerXID(stream.Context()))
+	if err != nil {
+		return statusError(err)
+	}
+	for i := range transactions {
+		if err := stream.Send(toTransaction(&transactions[i])); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *walletServer) Deposit(ctx context.Context, req *walletv1.DepositRequest) (*walletv1.DepositResponse, error) {
+	if err := validateTransaction(req.GetAmount(), req.GetReferenceId()); err != nil {
+		return nil, err
+	}
+	transaction, err := s.wallets.Deposit(customerXID(ctx), req.GetAmount(), req.GetReferenceId())
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return &walletv1.DepositResponse{Transaction: toTransaction(transaction)}, nil
+}
+
+func (s *walletServer) Withdraw(ctx context.Context, req *walletv1.WithdrawRequest) (*walletv1.WithdrawResponse, error) {
+	if err := validateTransaction(req.GetAmount(), req.GetReferenceId()); err != nil {
+		return nil, err
+	}
+	transaction, err := s.wallets.Withdraw(customerXID(ctx), req.GetAmount(), req.GetReferenceId(), authorization(ctx))
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return &walletv1.WithdrawResponse{Transaction: toTransaction(transaction)}, nil
+}
+
+func validateTransaction(amount int64, referenceID string) error {
+	if amount <= 0 {
+		return status.Error(codes.InvalidArgument, "amount must be greater than 0")
+	}
+	if _, err := uuid.Parse(referenceID); err != nil {
+		return status.Error(codes.InvalidArgument, "reference_id must be a valid UUID")
+	}
+	return nil
+}
+
+func toWallet(wallet *models.Wallet) *walletv1.Wallet {
+	return &walletv1.Wallet{
+		Id:         wallet.ID,
+		OwnedBy:    wallet.OwnedBy,
+		Status:     wallet.Status,
+		EnabledAt:  timestamp(wallet.EnabledAt),
+		DisabledAt: timestamp(wallet.DisabledAt),
+		Balance:    wallet.Balance,
+	}
+}
+
+func toTransaction(transaction *models.Transaction) *walletv1.Transaction {
+	return &walletv1.Transaction{
+		Id:           transaction.ID,
+		WalletId:     transaction.WalletID,
+		Type:         transaction.Type,
+		Status:       transaction.Status,
+		Amount:       transaction.Amount,
+		ReferenceId:  transaction.ReferenceID,
+		TransactedAt: timestamp(transaction.TransactedAt),
+	}
+}
+
+// timestamp leaves unset times out of the message.
+func timestamp(t time.Time) *timestamppb.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+	return timestamppb.New(t)
+}
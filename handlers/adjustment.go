@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"mini-wallet/models"
+	"mini-wallet/response"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// RequestAdjustment asks for a manual credit or debit of a wallet, which
+// another admin has to approve.
+func (h *AdminHandler) RequestAdjustment(c *gin.Context) {
+	var req adjustmentRequest
+	if fields := bindRequest(c, &req); fields != nil {
+		h.fail(c, response.Validation("invalid wallet_id, amount or reason", fields))
+		return
+	}
+	amount, err := strconv.ParseInt(string(req.Amount), 10, 64)
+	if err != nil {
+		h.fail(c, response.Validation("invalid amount", map[string][]string{"amount": {fieldMessages["integer"]}}))
+		return
+	}
+
+	adjustment, err := h.admins.RequestAdjustment(adminActor(c), string(req.WalletID), amount, string(req.Reason))
+	if err != nil {
+		h.fail(c, adminFailure(err, "Failed to request adjustment"))
+		return
+	}
+
+	response.Success(c, http.StatusCreated, gin.H{
+		"adjustment": adjustment,
+	})
+}
+
+// ListAdjustments lists adjustments, newest first, optionally filtered by
+// wallet_id and status.
+func (h *AdminHandler) ListAdjustments(c *gin.Context) {
+	limit, failure := queryLimit(c)
+	if failure != nil {
+		h.fail(c, failure)
+		return
+	}
+	walletID := c.Query("wallet_id")
+	if _, err := uuid.Parse(walletID); walletID != "" && err != nil {
+		h.fail(c, response.Validation("wallet_id must be a UUID", map[string][]string{"wallet_id": {fieldMessages["uuid"]}}))
+		return
+	}
+	status := c.Query("status")
+	switch status {
+	case "", models.AdjustmentPending, models.AdjustmentApproved, models.AdjustmentRejected:
+	default:
+		h.fail(c, response.Validation("status must be pending, approved or rejected", map[string][]string{"status": {fieldMessages["oneof"]}}))
+		return
+	}
+
+	adjustments, err := h.admins.Adjustments(adminActor(c), models.AdjustmentFilter{
+		WalletID: walletID,
+		Status:   status,
+		Limit:    limit,
+	})
+	if err != nil {
+		h.fail(c, adminFailure(err, "Failed to retrieve adjustments"))
+		return
+	}
+	if adjustments == nil {
+		adjustments = []models.Adjustment{}
+	}
+
+	response.Success(c, http.StatusOK, gin.H{
+		"adjustments": adjustments,
+	})
+}
+
+// ViewAdjustment returns an adjustment in whatever state it is.
+func (h *AdminHandler) ViewAdjustment(c *gin.Context) {
+	adjustment, err := h.admins.Adjustment(adminActor(c), c.Param("id"))
+	if err != nil {
+		h.fail(c, adminFailure(err, "Failed to retrieve adjustment"))
+		return
+	}
+
+	response.Success(c, http.StatusOK, gin.H{
+		"adjustment": adjustment,
+	})
+}
+
+// ApproveAdjustment approves and posts a pending adjustment.
+func (h *AdminHandler) ApproveAdjustment(c *gin.Context) {
+	h.reviewAdjustment(c, h.admins.ApproveAdjustment)
+}
+
+// RejectAdjustment rejects a pending adjustment.
+func (h *AdminHandler) RejectAdjustment(c *gin.Context) {
+	h.reviewAdjustment(c, h.admins.RejectAdjustment)
+}
+
+func (h *AdminHandler) reviewAdjustment(c *gin.Context, review func(actor *models.AdminKey, id, reason string) (*models.Adjustment, error)) {
+	var req adjustmentReviewRequest
+	if fields := bindRequest(c, &req); fields != nil {
+		h.fail(c, response.Validation("invalid reason", fields))
+		return
+	}
+
+	adjustment, err := review(adminActor(c), c.Param("id"), string(req.Reason))
+	if err != nil {
+		h.fail(c, adminFailure(err, "Failed to review adjustment"))
+		return
+	}
+
+	response.Success(c, http.StatusOK, gin.H{
+		"adjustment": adjustment,
+	})
+}
@@ -0,0 +1,255 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"mini-wallet/models"
+	"mini-wallet/response"
+	"mini-wallet/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	errForbidden            = response.New(response.CodeForbidden, "Your role does not allow this action")
+	errSelfReview           = response.New(response.CodeForbidden, "Adjustments must be reviewed by another admin")
+	errAdjustmentNotFound   = response.New(response.CodeNotFound, "Adjustment not found")
+	errAdjustmentNotPending = response.New(response.CodeAdjustmentNotPending, "Adjustment was already reviewed")
+	errRiskDecisionNotFound = response.New(response.CodeNotFound, "Risk decision not found")
+	errRiskDecisionNotHeld  = response.New(response.CodeRiskDecisionNotHeld, "Transaction is not held for review")
+	errCustomerNotFound     = response.New(response.CodeNotFound, "Customer not found")
+)
+
+// adminActorKey holds the authenticated admin key in the gin context.
+const adminActorKey = "admin_actor"
+
+// adminFailure maps an admin service error onto its API error.
+func adminFailure(err error, internalMessage string) *response.Error {
+	switch {
+	case errors.Is(err, service.ErrInvalidAdminKey):
+		return errInvalidToken
+	case errors.Is(err, service.ErrForbidden):
+		return errForbidden
+	case errors.Is(err, service.ErrReasonRequired):
+		return response.Validation("reason is required", map[string][]string{"reason": {msgMissingField}})
+	case errors.Is(err, service.ErrSelfReview):
+		return errSelfReview
+	case errors.Is(err, service.ErrAdjustmentNotFound):
+		return errAdjustmentNotFound
+	case errors.Is(err, service.ErrAdjustmentNotPending):
+		return errAdjustmentNotPending
+	case errors.Is(err, service.ErrRiskDecisionNotFound):
+		return errRiskDecisionNotFound
+	case errors.Is(err, service.ErrRiskDecisionNotHeld):
+		return errRiskDecisionNotHeld
+	case errors.Is(err, service.ErrCustomerNotFound):
+		return errCustomerNotFound
+	case errors.Is(err, service.ErrReconciliationNotFound):
+		return errReconciliationNotFound
+	case errors.Is(err, service.ErrInvalidKYCTier):
+		return response.Validation("tier must be between 0 and 2", map[string][]string{"tier": {fieldMessages["oneof"]}})
+	case errors.Is(err, service.ErrInvalidAmount):
+		return response.Validation("amount must not be 0", map[string][]string{"amount": {fieldMessages["nonzero"]}})
+	}
+	return walletFailure(err, internalMessage)
+}
+
+// AdminHandler serves the staff API under /admin/v1. Callers authenticate
+// with an admin API key; what they may do depends on the key's role.
+type AdminHandler struct {
+	admins *service.AdminService
+	fail   response.Writer
+}
+
+func NewAdminHandler(admins *service.AdminService) *AdminHandler {
+	return &AdminHandler{
+		admins: admins,
+		fail:   response.V2,
+	}
+}
+
+// Authenticate resolves the "Bearer <key>" Authorization header to an
+// admin key and aborts the request when it is missing or not active.
+func (h *AdminHandler) Authenticate(c *gin.Context) {
+	header := c.GetHeader("Authorization")
+	if header == "" {
+		h.fail(c, errAuthRequired)
+		c.Abort()
+		return
+	}
+	key, ok := strings.CutPrefix(header, "Bearer ")
+	if !ok {
+		h.fail(c, errInvalidToken)
+		c.Abort()
+		return
+	}
+	actor, err := h.admins.Authenticate(key)
+	if err != nil {
+		h.fail(c, adminFailure(err, "Failed to authenticate"))
+		c.Abort()
+		return
+	}
+	c.Set(adminActorKey, actor)
+	c.Next()
+}
+
+func adminActor(c *gin.Context) *models.AdminKey {
+	actor, _ := c.MustGet(adminActorKey).(*models.AdminKey)
+	return actor
+}
+
+// queryLimit reads the limit query parameter, leaving its default and
+// maximum to the service.
+func queryLimit(c *gin.Context) (int, *response.Error) {
+	value := c.Query("limit")
+	if value == "" {
+		return 0, nil
+	}
+	limit, err := strconv.Atoi(value)
+	if err != nil || limit <= 0 {
+		return 0, response.Validation("limit must be a positive integer", map[string][]string{"limit": {fieldMessages["positive"]}})
+	}
+	return limit, nil
+}
+
+// SearchCustomers finds customers whose customer_xid or wallet id starts
+// with the q query parameter.
+func (h *AdminHandler) SearchCustomers(c *gin.Context) {
+	limit, failure := queryLimit(c)
+	if failure != nil {
+		h.fail(c, failure)
+		return
+	}
+
+	customers, err := h.admins.SearchCustomers(adminActor(c), c.Query("q"), limit)
+	if err != nil {
+		h.fail(c, adminFailure(err, "Failed to search customers"))
+		return
+	}
+	if customers == nil {
+		customers = []models.CustomerSummary{}
+	}
+
+	response.Success(c, http.StatusOK, gin.H{
+		"customers": customers,
+	})
+}
+
+// ViewWallet returns any wallet, whatever its status.
+func (h *AdminHandler) ViewWallet(c *gin.Context) {
+	wallet, err := h.admins.Wallet(adminActor(c), c.Param("id"))
+	if err != nil {
+		h.fail(c, adminFailure(err, "Failed to retrieve wallet"))
+		return
+	}
+
+	response.Success(c, http.StatusOK, gin.H{
+		"wallet": wallet,
+	})
+}
+
+// ViewTransactions returns every transaction of any wallet.
+func (h *AdminHandler) ViewTransactions(c *gin.Context) {
+	transactions, err := h.admins.Transactions(adminActor(c), c.Param("id"))
+	if err != nil {
+		h.fail(c, adminFailure(err, "Failed to retrieve transactions"))
+		return
+	}
+	if transactions == nil {
+		transactions = []models.Transaction{}
+	}
+
+	response.Success(c, http.StatusOK, gin.H{
+		"transactions": transactions,
+	})
+}
+
+// ViewStatusHistory returns every status change of a wallet, oldest first.
+func (h *AdminHandler) ViewStatusHistory(c *gin.Context) {
+	changes, err := h.admins.StatusHistory(adminActor(c), c.Param("id"))
+	if err != nil {
+		h.fail(c, adminFailure(err, "Failed to retrieve status history"))
+		return
+	}
+	if changes == nil {
+		changes = []models.WalletStatusChange{}
+	}
+
+	response.Success(c, http.StatusOK, gin.H{
+		"status_history": changes,
+	})
+}
+
+// FreezeWallet blocks withdrawals from a wallet.
+func (h *AdminHandler) FreezeWallet(c *gin.Context) {
+	h.changeStatus(c, h.admins.Freeze)
+}
+
+// UnfreezeWallet lifts a freeze.
+func (h *AdminHandler) UnfreezeWallet(c *gin.Context) {
+	h.changeStatus(c, h.admins.Unfreeze)
+}
+
+// SuspendWallet stops all money movement of a wallet.
+func (h *AdminHandler) SuspendWallet(c *gin.Context) {
+	h.changeStatus(c, h.admins.Suspend)
+}
+
+// ReinstateWallet lifts a suspension.
+func (h *AdminHandler) ReinstateWallet(c *gin.Context) {
+	h.changeStatus(c, h.admins.Reinstate)
+}
+
+// DisableWallet disables a wallet on the customer's behalf.
+func (h *AdminHandler) DisableWallet(c *gin.Context) {
+	h.changeStatus(c, h.admins.ForceDisable)
+}
+
+func (h *AdminHandler) changeStatus(c *gin.Context, change func(actor *models.AdminKey, walletID, reason string) (*models.Wallet, error)) {
+	var req adminActionRequest
+	if fields := bindRequest(c, &req); fields != nil {
+		h.fail(c, response.Validation("reason is required", fields))
+		return
+	}
+
+	wallet, err := change(adminActor(c), c.Param("id"), string(req.Reason))
+	if err != nil {
+		h.fail(c, adminFailure(err, "Failed to update wallet"))
+		return
+	}
+
+	response.Success(c, http.StatusOK, gin.H{
+		"wallet": wallet,
+	})
+}
+
+// ViewAuditLog lists audit entries, newest first, optionally filtered by
+// actor_id, target_id and action.
+func (h *AdminHandler) ViewAuditLog(c *gin.Context) {
+	limit, failure := queryLimit(c)
+	if failure != nil {
+		h.fail(c, failure)
+		return
+	}
+
+	entries, err := h.admins.AuditLog(adminActor(c), models.AuditFilter{
+		ActorID:  c.Query("actor_id"),
+		TargetID: c.Query("target_id"),
+		Action:   c.Query("action"),
+		Limit:    limit,
+	})
+	if err != nil {
+		h.fail(c, adminFailure(err, "Failed to retrieve the audit log"))
+		return
+	}
+	if entries == nil {
+		entries = []models.AuditEntry{}
+	}
+
+	response.Success(c, http.StatusOK, gin.H{
+		"entries": entries,
+	})
+}
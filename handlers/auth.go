@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"mini-wallet/repositories"
+	"mini-wallet/response"
+	"mini-wallet/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	errAuthRequired = response.New(response.CodeAuthRequired, "Authorization token is required")
+	errInvalidToken = response.New(response.CodeInvalidToken, "Invalid token")
+	errTokenRevoked = response.New(response.CodeInvalidToken, "Token revoked, ask support for a new one")
+)
+
+// authorization reads the transaction PIN or step-up token authorizing a
+// withdrawal from the X-Transaction-PIN and X-Step-Up-Token headers, and
+// the one-time code from X-TOTP-Code.
+func authorization(c *gin.Context) service.Authorization {
+	return service.Authorization{
+		PIN:         c.GetHeader("X-Transaction-PIN"),
+		StepUpToken: c.GetHeader("X-Step-Up-Token"),
+		TOTPCode:    c.GetHeader("X-TOTP-Code"),
+	}
+}
+
+// customerFromToken resolves the customer behind the "Token <token>"
+// Authorization header.
+func customerFromToken(c *gin.Context, customerTokenRepo repositories.CustomerTokenRepository) (string, *response.Error) {
+	token := c.GetHeader("Authorization")
+	if token == "" {
+		return "", errAuthRequired
+	}
+	if len(token) <= 6 {
+		return "", errInvalidToken
+	}
+
+	// Extract token without "Token" prefix
+	customerXID, err := customerTokenRepo.GetCustomerXIDByToken(token[6:])
+	if err != nil {
+		return "", errInvalidToken
+	}
+	return customerXID, nil
+}
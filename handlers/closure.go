@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"net/http"
+
+	"mini-wallet/repositories"
+	"mini-wallet/response"
+	"mini-wallet/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ClosureHandler struct {
+	closures          *service.ClosureService
+	customerTokenRepo repositories.CustomerTokenRepository
+	fail              response.Writer
+}
+
+func NewClosureHandler(closures *service.ClosureService, customerTokenRepo repositories.CustomerTokenRepository) *ClosureHandler {
+	return &ClosureHandler{
+		closures:          closures,
+		customerTokenRepo: customerTokenRepo,
+		fail:              response.V1,
+	}
+}
+
+// WithWriter returns a handler sharing h's state that renders errors with w.
+func (h *ClosureHandler) WithWriter(w response.Writer) *ClosureHandler {
+	versioned := *h
+	versioned.fail = w
+	return &versioned
+}
+
+// CloseWallet closes the customer's wallet for good, paying out any
+// remaining balance to payout_destination, which takes the same transaction
+// PIN or step-up token as a withdrawal. The token stops working.
+func (h *ClosureHandler) CloseWallet(c *gin.Context) {
+	customerXID, failure := customerFromToken(c, h.customerTokenRepo)
+	if failure != nil {
+		h.fail(c, failure)
+		return
+	}
+
+	var req closeWalletRequest
+	if fields := bindRequest(c, &req); fields != nil {
+		h.fail(c, response.Validation("Invalid payout_destination", fields))
+		return
+	}
+
+	wallet, closure, err := h.closures.Close(customerXID, string(req.PayoutDestination), authorization(c))
+	if err != nil {
+		h.fail(c, walletFailure(err, "Failed to close wallet"))
+		return
+	}
+
+	response.Success(c, http.StatusOK, gin.H{
+		"wallet": service.ClosedWalletView(wallet, closure),
+	})
+}
@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"mini-wallet/compliance"
+	"mini-wallet/models"
+	"mini-wallet/response"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// ExportComplianceReports exports compliance reports as JSON, or as a CSV
+// download with format=csv, optionally filtered by kind, wallet_id and the
+// from and to bounds of their last transaction.
+func (h *AdminHandler) ExportComplianceReports(c *gin.Context) {
+	limit, failure := queryLimit(c)
+	if failure != nil {
+		h.fail(c, failure)
+		return
+	}
+	filter := models.ComplianceReportFilter{Kind: c.Query("kind"), WalletID: c.Query("wallet_id"), Limit: limit}
+	switch filter.Kind {
+	case "", models.ReportLargeTransaction, models.ReportStructuring:
+	default:
+		h.fail(c, response.Validation("kind must be large_transaction or structuring", map[string][]string{"kind": {fieldMessages["oneof"]}}))
+		return
+	}
+	if _, err := uuid.Parse(filter.WalletID); filter.WalletID != "" && err != nil {
+		h.fail(c, response.Validation("wallet_id must be a UUID", map[string][]string{"wallet_id": {fieldMessages["uuid"]}}))
+		return
+	}
+	bounds := []struct {
+		name string
+		t    *time.Time
+	}{{"from", &filter.From}, {"to", &filter.To}}
+	for _, bound := range bounds {
+		value := c.Query(bound.name)
+		if value == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			h.fail(c, response.Validation(bound.name+" must be an RFC 3339 timestamp", map[string][]string{bound.name: {"Not a valid RFC 3339 timestamp."}}))
+			return
+		}
+		*bound.t = t
+	}
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "csv" {
+		h.fail(c, response.Validation("format must be json or csv", map[string][]string{"format": {fieldMessages["oneof"]}}))
+		return
+	}
+
+	reports, err := h.admins.ComplianceReports(adminActor(c), filter, format)
+	if err != nil {
+		h.fail(c, adminFailure(err, "Failed to export compliance reports"))
+		return
+	}
+
+	if format == "csv" {
+		c.Header("Content-Type", "text/csv")
+		c.Header("Content-Disposition", `attachment; filename="compliance-reports.csv"`)
+		c.Status(http.StatusOK)
+		if err := compliance.WriteCSV(c.Writer, reports); err != nil {
+			log.Printf("Failed to write compliance reports: %v", err)
+		}
+		return
+	}
+	if reports == nil {
+		reports = []models.ComplianceReport{}
+	}
+	response.Success(c, http.StatusOK, gin.H{
+		"reports": reports,
+	})
+}
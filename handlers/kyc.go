@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"mini-wallet/models"
+	"mini-wallet/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ViewKYCProfile returns a customer's KYC profile.
+func (h *AdminHandler) ViewKYCProfile(c *gin.Context) {
+	profile, err := h.admins.KYCProfile(adminActor(c), c.Param("customer_xid"))
+	if err != nil {
+		h.fail(c, adminFailure(err, "Failed to retrieve KYC profile"))
+		return
+	}
+
+	response.Success(c, http.StatusOK, gin.H{
+		"kyc": profile,
+	})
+}
+
+// ChangeKYCTier upgrades or downgrades a customer's KYC tier, recording
+// the documents checked.
+func (h *AdminHandler) ChangeKYCTier(c *gin.Context) {
+	var req kycTierRequest
+	if fields := bindRequest(c, &req); fields != nil {
+		h.fail(c, response.Validation("invalid tier, reason or documents", fields))
+		return
+	}
+	tier, err := strconv.Atoi(string(req.Tier))
+	if err != nil {
+		h.fail(c, response.Validation("invalid tier", map[string][]string{"tier": {fieldMessages["integer"]}}))
+		return
+	}
+	documents := make([]models.KYCDocument, len(req.Documents))
+	for i, document := range req.Documents {
+		documents[i] = models.KYCDocument{Type: document.Type, Reference: document.Reference, ExpiresAt: document.ExpiresAt}
+	}
+
+	profile, err := h.admins.ChangeKYCTier(adminActor(c), c.Param("customer_xid"), tier, string(req.Reason), documents)
+	if err != nil {
+		h.fail(c, adminFailure(err, "Failed to change KYC tier"))
+		return
+	}
+
+	response.Success(c, http.StatusOK, gin.H{
+		"kyc": profile,
+	})
+}
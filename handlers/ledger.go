@@ -0,0 +1,23 @@
+package handlers
+
+import (
+	"net/http"
+
+	"mini-wallet/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// VerifyLedger walks the hash chain of any wallet's transactions and
+// reports the first broken link, if any.
+func (h *AdminHandler) VerifyLedger(c *gin.Context) {
+	report, err := h.admins.VerifyLedger(adminActor(c), c.Param("id"))
+	if err != nil {
+		h.fail(c, adminFailure(err, "Failed to verify transaction chain"))
+		return
+	}
+
+	response.Success(c, http.StatusOK, gin.H{
+		"report": report,
+	})
+}
@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"mini-wallet/models"
+	"mini-wallet/repositories"
+	"mini-wallet/response"
+	"mini-wallet/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type PaymentRequestHandler struct {
+	requests          *service.PaymentRequestService
+	customerTokenRepo repositories.CustomerTokenRepository
+	fail              response.Writer
+}
+
+func NewPaymentRequestHandler(requests *service.PaymentRequestService, customerTokenRepo repositories.CustomerTokenRepository) *PaymentRequestHandler {
+	return &PaymentRequestHandler{
+		requests:          requests,
+		customerTokenRepo: customerTokenRepo,
+		fail:              response.V1,
+	}
+}
+
+// WithWriter returns a handler sharing h's state that renders errors with w.
+func (h *PaymentRequestHandler) WithWriter(w response.Writer) *PaymentRequestHandler {
+	versioned := *h
+	versioned.fail = w
+	return &versioned
+}
+
+// CreatePaymentRequest asks another customer for money.
+func (h *PaymentRequestHandler) CreatePaymentRequest(c *gin.Context) {
+	customerXID, failure := customerFromToken(c, h.customerTokenRepo)
+	if failure != nil {
+		h.fail(c, failure)
+		return
+	}
+
+	var req paymentRequestRequest
+	if fields := bindRequest(c, &req); fields != nil {
+		h.fail(c, response.Validation("Invalid payment request", fields))
+		return
+	}
+	amount, _ := strconv.ParseInt(string(req.Amount), 10, 64)
+	request := &models.PaymentRequest{
+		PayerCustomerXID: string(req.PayerCustomerXID),
+		Amount:           amount,
+		Description:      string(req.Description),
+	}
+	if expiresAt := optionalTime(req.ExpiresAt); expiresAt != nil {
+		request.ExpiresAt = *expiresAt
+	}
+
+	request, err := h.requests.Create(customerXID, request)
+	if err != nil {
+		h.fail(c, walletFailure(err, "Failed to create payment request"))
+		return
+	}
+	response.Success(c, http.StatusCreated, gin.H{"payment_request": request})
+}
+
+// ListPaymentRequests lists the customer's latest payment requests, newest
+// first, optionally filtered by direction and status.
+func (h *PaymentRequestHandler) ListPaymentRequests(c *gin.Context) {
+	customerXID, failure := customerFromToken(c, h.customerTokenRepo)
+	if failure != nil {
+		h.fail(c, failure)
+		return
+	}
+	direction := c.Query("direction")
+	switch direction {
+	case "", models.DirectionIncoming, models.DirectionOutgoing:
+	default:
+		h.fail(c, response.Validation("direction must be incoming or outgoing", map[string][]string{"direction": {fieldMessages["oneof"]}}))
+		return
+	}
+	status := c.Query("status")
+	switch status {
+	case "", models.PaymentRequestPending, models.PaymentRequestPaid, models.PaymentRequestDeclined, models.PaymentRequestExpired, models.PaymentRequestCancelled:
+	default:
+		h.fail(c, response.Validation("status must be pending, paid, declined, expired or cancelled", map[string][]string{"status": {fieldMessages["oneof"]}}))
+		return
+	}
+
+	requests, err := h.requests.PaymentRequests(customerXID, models.PaymentRequestFilter{Direction: direction, Status: status})
+	if err != nil {
+		h.fail(c, walletFailure(err, "Failed to list payment requests"))
+		return
+	}
+	if requests == nil {
+		requests = []models.PaymentRequest{}
+	}
+	response.Success(c, http.StatusOK, gin.H{"payment_requests": requests})
+}
+
+// ViewPaymentRequest shows a payment request by ID to its requester and
+// payer, or by its shareable code to anyone.
+func (h *PaymentRequestHandler) ViewPaymentRequest(c *gin.Context) {
+	h.answer(c, "Failed to fetch payment request", h.requests.PaymentRequest)
+}
+
+// AcceptPaymentRequest pays a payment request. It takes the same
+// transaction PIN or step-up token, and one-time code, as a withdrawal of
+// the requested amount.
+func (h *PaymentRequestHandler) AcceptPaymentRequest(c *gin.Context) {
+	h.answer(c, "Failed to pay payment request", func(customerXID, id string) (*models.PaymentRequest, error) {
+		return h.requests.Accept(customerXID, id, authorization(c))
+	})
+}
+
+// DeclinePaymentRequest refuses a payment request addressed to the
+// customer.
+func (h *PaymentRequestHandler) DeclinePaymentRequest(c *gin.Context) {
+	h.answer(c, "Failed to decline payment request", h.requests.Decline)
+}
+
+// CancelPaymentRequest withdraws one of the customer's pending requests.
+func (h *PaymentRequestHandler) CancelPaymentRequest(c *gin.Context) {
+	h.answer(c, "Failed to cancel payment request", h.requests.Cancel)
+}
+
+// answer applies action to the request named in the path and renders the
+// result.
+func (h *PaymentRequestHandler) answer(c *gin.Context, internalMessage string, action func(customerXID, id string) (*models.PaymentRequest, error)) {
+	customerXID, failure := customerFromToken(c, h.customerTokenRepo)
+	if failure != nil {
+		h.fail(c, failure)
+		return
+	}
+
+	request, err := action(customerXID, c.Param("id"))
+	if err != nil {
+		h.fail(c, walletFailure(err, internalMessage))
+		return
+	}
+	response.Success(c, http.StatusOK, gin.H{"payment_request": request})
+}
@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"net/http"
+
+	"mini-wallet/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ViewPIN tells whether the customer has set a transaction PIN and until
+// when it is locked, if it is.
+func (h *WalletHandler) ViewPIN(c *gin.Context) {
+	customerXID, failure := customerFromToken(c, h.customerTokenRepo)
+	if failure != nil {
+		h.fail(c, failure)
+		return
+	}
+
+	current, err := h.wallets.PIN(customerXID)
+	if err != nil {
+		h.fail(c, walletFailure(err, "Failed to retrieve PIN"))
+		return
+	}
+
+	view := gin.H{"set": current != nil, "locked_until": nil}
+	if current != nil {
+		view["locked_until"] = current.LockedUntil
+	}
+	response.Success(c, http.StatusOK, gin.H{
+		"pin": view,
+	})
+}
+
+// SetPIN sets the customer's transaction PIN, or changes it given the
+// current one.
+func (h *WalletHandler) SetPIN(c *gin.Context) {
+	customerXID, failure := customerFromToken(c, h.customerTokenRepo)
+	if failure != nil {
+		h.fail(c, failure)
+		return
+	}
+
+	var req setPINRequest
+	if fields := bindRequest(c, &req); fields != nil {
+		h.fail(c, response.Validation("pin is required", fields))
+		return
+	}
+
+	if err := h.wallets.SetPIN(customerXID, string(req.PIN), string(req.CurrentPIN)); err != nil {
+		h.fail(c, walletFailure(err, "Failed to set PIN"))
+		return
+	}
+
+	response.Success(c, http.StatusOK, gin.H{
+		"pin": gin.H{"set": true, "locked_until": nil},
+	})
+}
+
+// StepUp exchanges the customer's transaction PIN for a step-up token that
+// authorizes one withdrawal in its place, sent as X-Step-Up-Token.
+func (h *WalletHandler) StepUp(c *gin.Context) {
+	customerXID, failure := customerFromToken(c, h.customerTokenRepo)
+	if failure != nil {
+		h.fail(c, failure)
+		return
+	}
+
+	var req stepUpRequest
+	if fields := bindRequest(c, &req); fields != nil {
+		h.fail(c, response.Validation("pin is required", fields))
+		return
+	}
+
+	token, expiresAt, err := h.wallets.StepUp(customerXID, string(req.PIN))
+	if err != nil {
+		h.fail(c, walletFailure(err, "Failed to issue step-up token"))
+		return
+	}
+
+	response.Success(c, http.StatusCreated, gin.H{
+		"step_up": gin.H{
+			"token":      token,
+			"expires_at": expiresAt,
+		},
+	})
+}
+
+// ResetPIN removes a customer's transaction PIN so they can set a new one
+// without the old.
+func (h *AdminHandler) ResetPIN(c *gin.Context) {
+	var req adminActionRequest
+	if fields := bindRequest(c, &req); fields != nil {
+		h.fail(c, response.Validation("reason is required", fields))
+		return
+	}
+
+	customerXID := c.Param("customer_xid")
+	if err := h.admins.ResetPIN(adminActor(c), customerXID, string(req.Reason)); err != nil {
+		h.fail(c, adminFailure(err, "Failed to reset PIN"))
+		return
+	}
+
+	response.Success(c, http.StatusOK, gin.H{
+		"pin": gin.H{"customer_xid": customerXID, "set": false},
+	})
+}
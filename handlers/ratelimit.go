@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"mini-wallet/config"
+	"mini-wallet/ratelimit"
+	"mini-wallet/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+var errRateLimited = response.New(response.CodeRateLimited, "Too many requests, try again later")
+
+// RateLimitHandler limits the requests of each token, or client IP for
+// requests without one, and the authentication failures of each client
+// IP.
+type RateLimitHandler struct {
+	store ratelimit.Store
+	cfg   config.RateLimitConfig
+	fail  response.Writer
+}
+
+func NewRateLimitHandler(store ratelimit.Store, cfg config.RateLimitConfig) *RateLimitHandler {
+	return &RateLimitHandler{
+		store: store,
+		cfg:   cfg,
+		fail:  response.V1,
+	}
+}
+
+// WithWriter returns a handler sharing h's state that renders errors with w.
+func (h *RateLimitHandler) WithWriter(w response.Writer) *RateLimitHandler {
+	if h == nil {
+		return nil
+	}
+	versioned := *h
+	versioned.fail = w
+	return &versioned
+}
+
+// Limit counts the request against its budget and answers 429 once the
+// budget or the client IP's authentication failures are spent. Requests
+// answered 401 or invalid_totp count as failures, so one-time codes cannot
+// be guessed across many customers either. The RateLimit headers describe
+// the budget the request counted against.
+func (h *RateLimitHandler) Limit(c *gin.Context) {
+	ctx := c.Request.Context()
+	failures := "auth_failures:" + c.ClientIP()
+	res, err := h.store.Take(ctx, failures, h.cfg.AuthFailures, 0, time.Now())
+	if err != nil {
+		// Limits are a safeguard, the API stays up without them
+		log.Printf("Rate limit of %s: %v", failures, err)
+	} else if !res.Allowed {
+		h.refuse(c, res)
+		return
+	}
+
+	budget, limit := h.budget(c)
+	key := budget + ":" + subject(c)
+	res, err = h.store.Take(ctx, key, limit, 1, time.Now())
+	if err != nil {
+		log.Printf("Rate limit of %s: %v", key, err)
+		c.Next()
+		return
+	}
+	setHeaders(c, limit, res)
+	if !res.Allowed {
+		h.refuse(c, res)
+		return
+	}
+
+	c.Next()
+
+	if c.Writer.Status() == http.StatusUnauthorized || response.CodeOf(c) == response.CodeInvalidTOTP {
+		if _, err := h.store.Take(ctx, failures, h.cfg.AuthFailures, 1, time.Now()); err != nil {
+			log.Printf("Rate limit of %s: %v", failures, err)
+		}
+	}
+}
+
+// budget returns the name and limit of the budget the request counts
+// against: its route's own, or the read or write budget.
+func (h *RateLimitHandler) budget(c *gin.Context) (string, ratelimit.Limit) {
+	route := c.Request.Method + " " + routePath(c.FullPath())
+	if limit, ok := h.cfg.Routes[route]; ok {
+		return "route:" + route, limit
+	}
+	if c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead {
+		return "reads", h.cfg.Reads
+	}
+	return "writes", h.cfg.Writes
+}
+
+func (h *RateLimitHandler) refuse(c *gin.Context, res ratelimit.Result) {
+	c.Header("Retry-After", strconv.Itoa(int(res.Reset/time.Second)))
+	h.fail(c, errRateLimited)
+	c.Abort()
+}
+
+// routePath is the registered path of a route with the customer API
+// version left out, so /api/v1 and /api/v2 share their limits.
+func routePath(path string) string {
+	if rest, ok := strings.CutPrefix(path, "/api/"); ok {
+		if _, route, ok := strings.Cut(rest, "/"); ok {
+			return "/" + route
+		}
+	}
+	return path
+}
+
+// subject identifies who a request counts for: the hash of its
+// Authorization header, or its client IP without one. Made-up tokens get
+// budgets of their own, but their 401 answers spend the IP's
+// authentication failures.
+func subject(c *gin.Context) string {
+	if credential := c.GetHeader("Authorization"); credential != "" {
+		sum := sha256.Sum256([]byte(credential))
+		return "token:" + hex.EncodeToString(sum[:16])
+	}
+	return "ip:" + c.ClientIP()
+}
+
+// setHeaders describes the budget in the RateLimit headers of the IETF
+// rate limit headers draft.
+func setHeaders(c *gin.Context, limit ratelimit.Limit, res ratelimit.Result) {
+	c.Header("RateLimit-Policy", strconv.Itoa(limit.Requests)+";w="+strconv.Itoa(int(limit.Window/time.Second)))
+	c.Header("RateLimit-Limit", strconv.Itoa(res.Limit))
+	c.Header("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+	c.Header("RateLimit-Reset", strconv.Itoa(int(res.Reset/time.Second)))
+}
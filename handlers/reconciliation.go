@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"net/http"
+
+	"mini-wallet/models"
+	"mini-wallet/response"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+var errReconciliationNotFound = response.New(response.CodeNotFound, "Reconciliation run not found")
+
+// ViewReconciliationReport returns a reconciliation run and its
+// discrepancies, the latest run unless run_id is given.
+func (h *AdminHandler) ViewReconciliationReport(c *gin.Context) {
+	runID := c.Query("run_id")
+	if _, err := uuid.Parse(runID); runID != "" && err != nil {
+		h.fail(c, response.Validation("run_id must be a UUID", map[string][]string{"run_id": {fieldMessages["uuid"]}}))
+		return
+	}
+
+	run, discrepancies, err := h.admins.ReconciliationReport(adminActor(c), runID)
+	if err != nil {
+		h.fail(c, adminFailure(err, "Failed to retrieve reconciliation run"))
+		return
+	}
+	if discrepancies == nil {
+		discrepancies = []models.BalanceDiscrepancy{}
+	}
+
+	response.Success(c, http.StatusOK, gin.H{
+		"run":           run,
+		"discrepancies": discrepancies,
+	})
+}
@@ -0,0 +1,255 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"mini-wallet/response"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// scalar is a request value that may arrive as a form value or as a JSON
+// string, number or boolean. It is validated in its textual form so every
+// content type produces the same validation errors.
+type scalar string
+
+func (s *scalar) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+	var str string
+	if err := json.Unmarshal(data, &str); err == nil {
+		*s = scalar(str)
+		return nil
+	}
+	// Numbers and booleans keep their literal text
+	*s = scalar(bytes.TrimSpace(data))
+	return nil
+}
+
+type initRequest struct {
+	CustomerXID scalar `form:"customer_xid" json:"customer_xid" binding:"required"`
+}
+
+type transactionRequest struct {
+	Amount      scalar `form:"amount" json:"amount" binding:"required,integer,positive"`
+	ReferenceID scalar `form:"reference_id" json:"reference_id" binding:"required,uuid"`
+}
+
+type disableWalletRequest struct {
+	IsDisabled scalar `form:"is_disabled" json:"is_disabled" binding:"required,boolean"`
+}
+
+// closeWalletRequest names where a remaining balance is paid out.
+type closeWalletRequest struct {
+	PayoutDestination scalar `form:"payout_destination" json:"payout_destination" binding:"max=255"`
+}
+
+// setPINRequest sets the customer's transaction PIN. Changing a PIN takes
+// the current one.
+type setPINRequest struct {
+	PIN        scalar `form:"pin" json:"pin" binding:"required"`
+	CurrentPIN scalar `form:"current_pin" json:"current_pin"`
+}
+
+type stepUpRequest struct {
+	PIN scalar `form:"pin" json:"pin" binding:"required"`
+}
+
+// totpCodeRequest carries a one-time code from the authenticator app, or
+// a recovery code where one is accepted.
+type totpCodeRequest struct {
+	Code scalar `form:"code" json:"code" binding:"required"`
+}
+
+// standingOrderRequest schedules transfers to another customer. start_at
+// defaults to now, and ends_at bounds recurring orders.
+type standingOrderRequest struct {
+	PayeeCustomerXID scalar `form:"payee_customer_xid" json:"payee_customer_xid" binding:"required"`
+	Amount           scalar `form:"amount" json:"amount" binding:"required,integer,positive"`
+	Description      scalar `form:"description" json:"description" binding:"max=140"`
+	Frequency        scalar `form:"frequency" json:"frequency" binding:"required,oneof=once daily weekly monthly"`
+	StartAt          scalar `form:"start_at" json:"start_at" binding:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
+	EndsAt           scalar `form:"ends_at" json:"ends_at" binding:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
+}
+
+// standingOrderUpdateRequest changes an active standing order; empty
+// fields are left as they are.
+type standingOrderUpdateRequest struct {
+	Amount      scalar `form:"amount" json:"amount" binding:"omitempty,integer,positive"`
+	Description scalar `form:"description" json:"description" binding:"max=140"`
+	EndsAt      scalar `form:"ends_at" json:"ends_at" binding:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
+}
+
+// paymentRequestRequest asks another customer for money: the one named by
+// payer_customer_xid, or whoever gets the shareable code when it is empty.
+type paymentRequestRequest struct {
+	PayerCustomerXID scalar `form:"payer_customer_xid" json:"payer_customer_xid"`
+	Amount           scalar `form:"amount" json:"amount" binding:"required,integer,positive"`
+	Description      scalar `form:"description" json:"description" binding:"max=140"`
+	ExpiresAt        scalar `form:"expires_at" json:"expires_at" binding:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
+}
+
+type webhookSubscriptionRequest struct {
+	URL    scalar   `form:"url" json:"url" binding:"required,http_url"`
+	Events []string `form:"events" json:"events" binding:"required,min=1,dive,oneof=wallet.enabled wallet.disabled wallet.frozen wallet.unfrozen wallet.suspended wallet.reinstated wallet.closed deposit.succeeded withdrawal.succeeded adjustment.posted balance.updated transfer.sent transfer.received standing_order.retrying standing_order.failed payment_request.received payment_request.paid payment_request.declined"`
+}
+
+const msgMissingField = "Missing data for required field."
+
+var fieldMessages = map[string]string{
+	"required": msgMissingField,
+	"integer":  "Not a valid integer.",
+	"positive": "Must be greater than 0.",
+	"nonzero":  "Must not be 0.",
+	"uuid":     "Not a valid UUID.",
+	"boolean":  "Not a valid boolean.",
+	"http_url": "Not a valid URL.",
+	"min":      "Must not be empty.",
+	"oneof":    "Not a valid choice.",
+	"max":      "Too long.",
+	"datetime": "Not a valid RFC 3339 date and time.",
+}
+
+func init() {
+	v, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		return
+	}
+	// Report fields by the name clients send
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		if name, _, _ := strings.Cut(field.Tag.Get("form"), ","); name != "" {
+			return name
+		}
+		return field.Name
+	})
+	v.RegisterValidation("integer", func(fl validator.FieldLevel) bool {
+		_, err := strconv.ParseInt(fl.Field().String(), 10, 64)
+		return err == nil
+	})
+	v.RegisterValidation("positive", func(fl validator.FieldLevel) bool {
+		n, err := strconv.ParseInt(fl.Field().String(), 10, 64)
+		return err == nil && n > 0
+	})
+	v.RegisterValidation("nonzero", func(fl validator.FieldLevel) bool {
+		n, err := strconv.ParseInt(fl.Field().String(), 10, 64)
+		return err == nil && n != 0
+	})
+}
+
+// bindRequest fills req from a JSON, URL-encoded or multipart body and
+// returns the messages of every invalid field, or nil when req is valid.
+func bindRequest(c *gin.Context, req any) map[string][]string {
+	var err error
+	switch c.ContentType() {
+	case binding.MIMEJSON:
+		err = c.ShouldBindWith(req, binding.JSON)
+		if errors.Is(err, io.EOF) {
+			// An empty JSON body is treated like an empty form
+			err = binding.Validator.ValidateStruct(req)
+		}
+	case binding.MIMEMultipartPOSTForm:
+		err = c.ShouldBindWith(req, binding.FormMultipart)
+	default:
+		err = c.ShouldBindWith(req, binding.Form)
+	}
+	if err == nil {
+		return nil
+	}
+
+	var validationErrs validator.ValidationErrors
+	if !errors.As(err, &validationErrs) {
+		return map[string][]string{"_schema": {"Invalid request body."}}
+	}
+
+	fields := make(map[string][]string, len(validationErrs))
+	for _, fe := range validationErrs {
+		message, ok := fieldMessages[fe.Tag()]
+		if !ok {
+			message = "Invalid value."
+		}
+		fields[fe.Field()] = append(fields[fe.Field()], message)
+	}
+	return fields
+}
+
+// transactionValidation describes invalid deposit or withdrawal fields,
+// keeping the messages /api/v1 has always used.
+func transactionValidation(fields map[string][]string) *response.Error {
+	message := "invalid amount or reference_id"
+	switch {
+	case hasMessage(fields, "amount", msgMissingField), hasMessage(fields, "reference_id", msgMissingField):
+		message = "amount and reference_id are required"
+	case hasMessage(fields, "amount", fieldMessages["integer"]):
+		message = "invalid amount format"
+	case hasMessage(fields, "amount", fieldMessages["positive"]):
+		message = "amount must be greater than 0"
+	case hasMessage(fields, "reference_id", fieldMessages["uuid"]):
+		message = "reference_id must be a valid UUID"
+	}
+	return response.Validation(message, fields)
+}
+
+// disableValidation describes an invalid is_disabled field.
+func disableValidation(fields map[string][]string) *response.Error {
+	message := "Invalid request: is_disabled must be 'true'"
+	if hasMessage(fields, "is_disabled", msgMissingField) {
+		message = "is_disabled is required"
+	}
+	return response.Validation(message, fields)
+}
+
+func hasMessage(fields map[string][]string, field, message string) bool {
+	for _, m := range fields[field] {
+		if m == message {
+			return true
+		}
+	}
+	return false
+}
+
+type adminActionRequest struct {
+	Reason scalar `form:"reason" json:"reason" binding:"required"`
+}
+
+type adjustmentRequest struct {
+	WalletID scalar `form:"wallet_id" json:"wallet_id" binding:"required,uuid"`
+	Amount   scalar `form:"amount" json:"amount" binding:"required,integer,nonzero"`
+	Reason   scalar `form:"reason" json:"reason" binding:"required"`
+}
+
+// adjustmentReviewRequest carries the reviewer's note, which is only
+// required to reject an adjustment.
+type adjustmentReviewRequest struct {
+	Reason scalar `form:"reason" json:"reason"`
+}
+
+// kycTierRequest moves a customer to another KYC tier. Documents can only
+// be sent as JSON.
+type kycTierRequest struct {
+	Tier      scalar               `form:"tier" json:"tier" binding:"required,integer"`
+	Reason    scalar               `form:"reason" json:"reason" binding:"required"`
+	Documents []kycDocumentRequest `form:"-" json:"documents" binding:"dive"`
+}
+
+// kycDocumentRequest is the metadata of a checked identity document.
+type kycDocumentRequest struct {
+	Type      string     `form:"type" json:"type" binding:"required,oneof=national_id passport driving_license selfie proof_of_address"`
+	Reference string     `form:"reference" json:"reference" binding:"required,max=255"`
+	ExpiresAt *time.Time `form:"expires_at" json:"expires_at"`
+}
+
+// riskReviewRequest carries the analyst's note, which is only required to
+// reject a held transaction.
+type riskReviewRequest struct {
+	Reason scalar `form:"reason" json:"reason"`
+}
@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"net/http"
+
+	"mini-wallet/models"
+	"mini-wallet/response"
+	"mini-wallet/risk"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// ListRiskDecisions lists risk decisions, newest first, optionally filtered
+// by wallet_id, outcome and status.
+func (h *AdminHandler) ListRiskDecisions(c *gin.Context) {
+	limit, failure := queryLimit(c)
+	if failure != nil {
+		h.fail(c, failure)
+		return
+	}
+	walletID := c.Query("wallet_id")
+	if _, err := uuid.Parse(walletID); walletID != "" && err != nil {
+		h.fail(c, response.Validation("wallet_id must be a UUID", map[string][]string{"wallet_id": {fieldMessages["uuid"]}}))
+		return
+	}
+	outcome := c.Query("outcome")
+	switch risk.Outcome(outcome) {
+	case "", risk.Review, risk.Block:
+	default:
+		h.fail(c, response.Validation("outcome must be review or block", map[string][]string{"outcome": {fieldMessages["oneof"]}}))
+		return
+	}
+	status := c.Query("status")
+	switch status {
+	case "", models.RiskHeld, models.RiskReleased, models.RiskRejected:
+	default:
+		h.fail(c, response.Validation("status must be held, released or rejected", map[string][]string{"status": {fieldMessages["oneof"]}}))
+		return
+	}
+
+	decisions, err := h.admins.RiskDecisions(adminActor(c), models.RiskDecisionFilter{
+		WalletID: walletID,
+		Outcome:  outcome,
+		Status:   status,
+		Limit:    limit,
+	})
+	if err != nil {
+		h.fail(c, adminFailure(err, "Failed to retrieve risk decisions"))
+		return
+	}
+	if decisions == nil {
+		decisions = []models.RiskDecision{}
+	}
+
+	response.Success(c, http.StatusOK, gin.H{
+		"decisions": decisions,
+	})
+}
+
+// ViewRiskDecision returns a risk decision in whatever state it is.
+func (h *AdminHandler) ViewRiskDecision(c *gin.Context) {
+	decision, err := h.admins.RiskDecision(adminActor(c), c.Param("id"))
+	if err != nil {
+		h.fail(c, adminFailure(err, "Failed to retrieve risk decision"))
+		return
+	}
+
+	response.Success(c, http.StatusOK, gin.H{
+		"decision": decision,
+	})
+}
+
+// ReleaseRiskDecision posts a transaction held for review.
+func (h *AdminHandler) ReleaseRiskDecision(c *gin.Context) {
+	h.reviewRiskDecision(c, h.admins.ReleaseRiskDecision)
+}
+
+// RejectRiskDecision rejects a transaction held for review.
+func (h *AdminHandler) RejectRiskDecision(c *gin.Context) {
+	h.reviewRiskDecision(c, h.admins.RejectRiskDecision)
+}
+
+func (h *AdminHandler) reviewRiskDecision(c *gin.Context, review func(actor *models.AdminKey, id, reason string) (*models.RiskDecision, error)) {
+	var req riskReviewRequest
+	if fields := bindRequest(c, &req); fields != nil {
+		h.fail(c, response.Validation("invalid reason", fields))
+		return
+	}
+
+	decision, err := review(adminActor(c), c.Param("id"), string(req.Reason))
+	if err != nil {
+		h.fail(c, adminFailure(err, "Failed to review risk decision"))
+		return
+	}
+
+	response.Success(c, http.StatusOK, gin.H{
+		"decision": decision,
+	})
+}
@@ -0,0 +1,165 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"mini-wallet/models"
+	"mini-wallet/repositories"
+	"mini-wallet/response"
+	"mini-wallet/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type StandingOrderHandler struct {
+	orders            *service.StandingOrderService
+	customerTokenRepo repositories.CustomerTokenRepository
+	fail              response.Writer
+}
+
+func NewStandingOrderHandler(orders *service.StandingOrderService, customerTokenRepo repositories.CustomerTokenRepository) *StandingOrderHandler {
+	return &StandingOrderHandler{
+		orders:            orders,
+		customerTokenRepo: customerTokenRepo,
+		fail:              response.V1,
+	}
+}
+
+// WithWriter returns a handler sharing h's state that renders errors with w.
+func (h *StandingOrderHandler) WithWriter(w response.Writer) *StandingOrderHandler {
+	versioned := *h
+	versioned.fail = w
+	return &versioned
+}
+
+// optionalTime converts a time that passed validation, nil when it was
+// left out.
+func optionalTime(value scalar) *time.Time {
+	if value == "" {
+		return nil
+	}
+	t, _ := time.Parse(time.RFC3339, string(value))
+	return &t
+}
+
+// CreateStandingOrder schedules transfers to another customer. It takes
+// the same transaction PIN or step-up token, and one-time code, as a
+// withdrawal of the order's amount.
+func (h *StandingOrderHandler) CreateStandingOrder(c *gin.Context) {
+	customerXID, failure := customerFromToken(c, h.customerTokenRepo)
+	if failure != nil {
+		h.fail(c, failure)
+		return
+	}
+
+	var req standingOrderRequest
+	if fields := bindRequest(c, &req); fields != nil {
+		h.fail(c, response.Validation("Invalid standing order", fields))
+		return
+	}
+	amount, _ := strconv.ParseInt(string(req.Amount), 10, 64)
+	order := &models.StandingOrder{
+		PayeeCustomerXID: string(req.PayeeCustomerXID),
+		Amount:           amount,
+		Description:      string(req.Description),
+		Frequency:        string(req.Frequency),
+		EndsAt:           optionalTime(req.EndsAt),
+	}
+	if start := optionalTime(req.StartAt); start != nil {
+		order.StartAt = *start
+	}
+
+	order, err := h.orders.Create(customerXID, order, authorization(c))
+	if err != nil {
+		h.fail(c, walletFailure(err, "Failed to create standing order"))
+		return
+	}
+	response.Success(c, http.StatusCreated, gin.H{"standing_order": order})
+}
+
+// ListStandingOrders lists the customer's standing orders, newest first.
+func (h *StandingOrderHandler) ListStandingOrders(c *gin.Context) {
+	customerXID, failure := customerFromToken(c, h.customerTokenRepo)
+	if failure != nil {
+		h.fail(c, failure)
+		return
+	}
+
+	orders, err := h.orders.StandingOrders(customerXID)
+	if err != nil {
+		h.fail(c, walletFailure(err, "Failed to list standing orders"))
+		return
+	}
+	if orders == nil {
+		orders = []models.StandingOrder{}
+	}
+	response.Success(c, http.StatusOK, gin.H{"standing_orders": orders})
+}
+
+// ViewStandingOrder shows one of the customer's standing orders.
+func (h *StandingOrderHandler) ViewStandingOrder(c *gin.Context) {
+	customerXID, failure := customerFromToken(c, h.customerTokenRepo)
+	if failure != nil {
+		h.fail(c, failure)
+		return
+	}
+
+	order, err := h.orders.StandingOrder(customerXID, c.Param("id"))
+	if err != nil {
+		h.fail(c, walletFailure(err, "Failed to fetch standing order"))
+		return
+	}
+	response.Success(c, http.StatusOK, gin.H{"standing_order": order})
+}
+
+// UpdateStandingOrder changes the amount, description or end of an active
+// standing order. A new amount takes the same authorization as creating
+// the order.
+func (h *StandingOrderHandler) UpdateStandingOrder(c *gin.Context) {
+	customerXID, failure := customerFromToken(c, h.customerTokenRepo)
+	if failure != nil {
+		h.fail(c, failure)
+		return
+	}
+
+	var req standingOrderUpdateRequest
+	if fields := bindRequest(c, &req); fields != nil {
+		h.fail(c, response.Validation("Invalid standing order", fields))
+		return
+	}
+	var change service.StandingOrderChange
+	if req.Amount != "" {
+		amount, _ := strconv.ParseInt(string(req.Amount), 10, 64)
+		change.Amount = &amount
+	}
+	if req.Description != "" {
+		description := string(req.Description)
+		change.Description = &description
+	}
+	change.EndsAt = optionalTime(req.EndsAt)
+
+	order, err := h.orders.Update(customerXID, c.Param("id"), change, authorization(c))
+	if err != nil {
+		h.fail(c, walletFailure(err, "Failed to update standing order"))
+		return
+	}
+	response.Success(c, http.StatusOK, gin.H{"standing_order": order})
+}
+
+// CancelStandingOrder cancels an active standing order.
+func (h *StandingOrderHandler) CancelStandingOrder(c *gin.Context) {
+	customerXID, failure := customerFromToken(c, h.customerTokenRepo)
+	if failure != nil {
+		h.fail(c, failure)
+		return
+	}
+
+	order, err := h.orders.Cancel(customerXID, c.Param("id"))
+	if err != nil {
+		h.fail(c, walletFailure(err, "Failed to cancel standing order"))
+		return
+	}
+	response.Success(c, http.StatusOK, gin.H{"standing_order": order})
+}
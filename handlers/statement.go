@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"mini-wallet/response"
+	"mini-wallet/statements"
+
+	"github.com/gin-gonic/gin"
+)
+
+// statementWriter defers the statement headers to the first byte written,
+// so failures before the statement starts still render as API errors.
+type statementWriter struct {
+	c           *gin.Context
+	contentType string
+	filename    string
+	started     bool
+}
+
+func (w *statementWriter) Write(p []byte) (int, error) {
+	if !w.started {
+		w.started = true
+		w.c.Header("Content-Type", w.contentType)
+		w.c.Header("Content-Disposition", `attachment; filename="`+w.filename+`"`)
+		w.c.Status(http.StatusOK)
+	}
+	return w.c.Writer.Write(p)
+}
+
+// ViewStatement streams the customer's statement for the period in the
+// from and to query parameters, as CSV (the default), OFX or PDF.
+func (h *WalletHandler) ViewStatement(c *gin.Context) {
+	customerXID, failure := customerFromToken(c, h.customerTokenRepo)
+	if failure != nil {
+		h.fail(c, failure)
+		return
+	}
+
+	format := c.DefaultQuery("format", "csv")
+	period, err := statements.ParsePeriod(c.Query("from"), c.Query("to"), time.Now())
+	if err != nil {
+		var periodErr *statements.PeriodError
+		if errors.As(err, &periodErr) {
+			h.fail(c, response.Validation(err.Error(), map[string][]string{periodErr.Field: {periodErr.Reason}}))
+			return
+		}
+		h.fail(c, response.Validation(err.Error(), nil))
+		return
+	}
+
+	w := &statementWriter{c: c, contentType: statements.ContentType(format), filename: statements.Filename(period, format)}
+	enc, err := statements.NewEncoder(format, w)
+	if err != nil {
+		h.fail(c, response.Validation("format must be csv, ofx or pdf", map[string][]string{"format": {fieldMessages["oneof"]}}))
+		return
+	}
+
+	if err := h.wallets.Statement(customerXID, period, enc); err != nil {
+		if w.started {
+			// The status is already sent, so the statement is cut short
+			log.Printf("Failed to stream statement for %s: %v", customerXID, err)
+			c.Abort()
+			return
+		}
+		h.fail(c, walletFailure(err, "Failed to generate statement"))
+	}
+}
@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"mini-wallet/events"
+	"mini-wallet/repositories"
+	"mini-wallet/response"
+	"mini-wallet/service"
+
+	"github.com/gin-contrib/sse"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// EventStreamHandler streams a customer's wallet events as Server-Sent Events.
+type EventStreamHandler struct {
+	wallets           *service.WalletService
+	transactionRepo   repositories.TransactionRepository
+	customerTokenRepo repositories.CustomerTokenRepository
+	bus               events.Bus
+	heartbeat         time.Duration
+	closed            chan struct{}
+	fail              response.Writer
+}
+
+func NewEventStreamHandler(wallets *service.WalletService, transactionRepo repositories.TransactionRepository, customerTokenRepo repositories.CustomerTokenRepository, bus events.Bus, heartbeat time.Duration) *EventStreamHandler {
+	return &EventStreamHandler{
+		wallets:           wallets,
+		transactionRepo:   transactionRepo,
+		customerTokenRepo: customerTokenRepo,
+		bus:               bus,
+		heartbeat:         heartbeat,
+		closed:            make(chan struct{}),
+		fail:              response.V1,
+	}
+}
+
+// Close ends every open stream so the server can shut down; clients
+// reconnect elsewhere and resume from their last event.
+func (h *EventStreamHandler) Close() {
+	close(h.closed)
+}
+
+// WithWriter returns a handler sharing h's state that renders errors with w.
+func (h *EventStreamHandler) WithWriter(w response.Writer) *EventStreamHandler {
+	versioned := *h
+	versioned.fail = w
+	return &versioned
+}
+
+// Stream sends the customer's events until the client disconnects. Only
+// transaction events carry an id, so a reconnecting client's Last-Event-ID
+// names the last transaction it saw and the ones after it are replayed from
+// the transactions table, followed by the current balance.
+func (h *EventStreamHandler) Stream(c *gin.Context) {
+	customerXID, failure := customerFromToken(c, h.customerTokenRepo)
+	if failure != nil {
+		h.fail(c, failure)
+		return
+	}
+	wallet, err := h.wallets.Wallet(customerXID)
+	if err != nil {
+		h.fail(c, walletFailure(err, ""))
+		return
+	}
+
+	// Subscribe before reading the backlog so nothing recorded in between is lost
+	ctx := c.Request.Context()
+	live, err := h.bus.Subscribe(ctx, customerXID)
+	if err != nil {
+		h.fail(c, response.Internal("Failed to subscribe to wallet events", err))
+		return
+	}
+
+	var backlog []events.Event
+	lastEventID := c.GetHeader("Last-Event-ID")
+	if lastEventID == "" {
+		// EventSource polyfills that cannot set headers pass it as a parameter
+		lastEventID = c.Query("last_event_id")
+	}
+	if _, err := uuid.Parse(lastEventID); err == nil {
+		transactions, err := h.transactionRepo.GetTransactionsAfter(wallet.ID, lastEventID)
+		if err != nil {
+			h.fail(c, response.Internal("Failed to fetch transactions", err))
+			return
+		}
+		for i := range transactions {
+			backlog = append(backlog, service.TransactionEvent(customerXID, &transactions[i]))
+		}
+		backlog = append(backlog, events.New(events.BalanceUpdated, customerXID, wallet.ID, gin.H{
+			"balance": wallet.Balance,
+		}))
+	}
+
+	// The stream outlives the server's write timeout
+	_ = http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{})
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Header("Content-Type", sse.ContentType)
+	c.Status(http.StatusOK)
+
+	replayed := make(map[string]bool, len(backlog))
+	for _, event := range backlog {
+		replayed[event.ID] = true
+		writeStreamEvent(c, event)
+	}
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(h.heartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-h.closed:
+			return
+		case event, ok := <-live:
+			if !ok {
+				return
+			}
+			// Published while the backlog was read
+			if replayed[event.ID] {
+				continue
+			}
+			writeStreamEvent(c, event)
+		case <-heartbeat.C:
+			fmt.Fprint(c.Writer, ": heartbeat\n\n")
+		}
+		c.Writer.Flush()
+	}
+}
+
+func writeStreamEvent(c *gin.Context, event events.Event) {
+	var id string
+	if events.FromTransaction(event.Type) {
+		id = event.ID
+	}
+	c.Render(-1, sse.Event{Id: id, Event: event.Type, Data: event})
+}
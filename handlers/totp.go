@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"net/http"
+
+	"mini-wallet/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ViewTOTP tells whether the customer enrolled an authenticator app, since
+// when it is in effect and how many recovery codes they have left.
+func (h *WalletHandler) ViewTOTP(c *gin.Context) {
+	customerXID, failure := customerFromToken(c, h.customerTokenRepo)
+	if failure != nil {
+		h.fail(c, failure)
+		return
+	}
+
+	enrollment, err := h.wallets.TOTPStatus(customerXID)
+	if err != nil {
+		h.fail(c, walletFailure(err, "Failed to retrieve two-factor authentication"))
+		return
+	}
+
+	view := gin.H{"enrolled": false, "confirmed_at": nil, "recovery_codes_left": 0, "locked_until": nil}
+	if enrollment != nil {
+		view["enrolled"] = enrollment.ConfirmedAt != nil
+		view["confirmed_at"] = enrollment.ConfirmedAt
+		view["recovery_codes_left"] = len(enrollment.RecoveryCodes)
+		view["locked_until"] = enrollment.LockedUntil
+	}
+	response.Success(c, http.StatusOK, gin.H{
+		"totp": view,
+	})
+}
+
+// EnrollTOTP starts an authenticator app enrollment, returning the secret,
+// the otpauth:// URI to show as a QR code and the recovery codes. They are
+// shown only this once.
+func (h *WalletHandler) EnrollTOTP(c *gin.Context) {
+	customerXID, failure := customerFromToken(c, h.customerTokenRepo)
+	if failure != nil {
+		h.fail(c, failure)
+		return
+	}
+
+	setup, err := h.wallets.EnrollTOTP(customerXID)
+	if err != nil {
+		h.fail(c, walletFailure(err, "Failed to enroll authenticator app"))
+		return
+	}
+
+	response.Success(c, http.StatusCreated, gin.H{
+		"totp": setup,
+	})
+}
+
+// ConfirmTOTP puts the enrollment into effect given a first code from the
+// authenticator app.
+func (h *WalletHandler) ConfirmTOTP(c *gin.Context) {
+	customerXID, failure := customerFromToken(c, h.customerTokenRepo)
+	if failure != nil {
+		h.fail(c, failure)
+		return
+	}
+
+	var req totpCodeRequest
+	if fields := bindRequest(c, &req); fields != nil {
+		h.fail(c, response.Validation("code is required", fields))
+		return
+	}
+
+	if err := h.wallets.ConfirmTOTP(customerXID, string(req.Code)); err != nil {
+		h.fail(c, walletFailure(err, "Failed to confirm authenticator app"))
+		return
+	}
+
+	h.ViewTOTP(c)
+}
+
+// RemoveTOTP removes the customer's enrollment, authorized by a one-time
+// code or recovery code in X-TOTP-Code.
+func (h *WalletHandler) RemoveTOTP(c *gin.Context) {
+	customerXID, failure := customerFromToken(c, h.customerTokenRepo)
+	if failure != nil {
+		h.fail(c, failure)
+		return
+	}
+
+	if err := h.wallets.RemoveTOTP(customerXID, authorization(c).TOTPCode); err != nil {
+		h.fail(c, walletFailure(err, "Failed to remove authenticator app"))
+		return
+	}
+
+	response.Success(c, http.StatusOK, gin.H{
+		"totp": gin.H{"enrolled": false, "confirmed_at": nil, "recovery_codes_left": 0},
+	})
+}
+
+// RegenerateRecoveryCodes replaces the customer's recovery codes given a
+// one-time code or one of the old recovery codes.
+func (h *WalletHandler) RegenerateRecoveryCodes(c *gin.Context) {
+	customerXID, failure := customerFromToken(c, h.customerTokenRepo)
+	if failure != nil {
+		h.fail(c, failure)
+		return
+	}
+
+	var req totpCodeRequest
+	if fields := bindRequest(c, &req); fields != nil {
+		h.fail(c, response.Validation("code is required", fields))
+		return
+	}
+
+	codes, err := h.wallets.RegenerateRecoveryCodes(customerXID, string(req.Code))
+	if err != nil {
+		h.fail(c, walletFailure(err, "Failed to regenerate recovery codes"))
+		return
+	}
+
+	response.Success(c, http.StatusCreated, gin.H{
+		"recovery_codes": codes,
+	})
+}
+
+// RotateToken replaces the customer's token with a new one. Customers
+// with an authenticator app authorize it with X-TOTP-Code.
+func (h *WalletHandler) RotateToken(c *gin.Context) {
+	customerXID, failure := customerFromToken(c, h.customerTokenRepo)
+	if failure != nil {
+		h.fail(c, failure)
+		return
+	}
+
+	token, err := h.wallets.RotateToken(customerXID, authorization(c))
+	if err != nil {
+		h.fail(c, walletFailure(err, "Failed to rotate token"))
+		return
+	}
+
+	response.Success(c, http.StatusCreated, gin.H{
+		"token": token,
+	})
+}
@@ -1,264 +1,291 @@
 package handlers
 
 import (
-	"context"
-	"log"
-	"math/rand"
+	"errors"
 	"net/http"
 	"strconv"
 	"time"
 
 	"mini-wallet/models"
 	"mini-wallet/repositories"
+	"mini-wallet/response"
+	"mini-wallet/service"
 
 	"github.com/gin-gonic/gin"
-	"github.com/go-redis/redis/v8"
-	"github.com/google/uuid"
 )
 
+var (
+	errWalletNotFound           = response.New(response.CodeWalletNotFound, "Wallet not found")
+	errWalletDisabled           = response.New(response.CodeWalletDisabled, "Wallet disabled")
+	errWalletAlreadyEnabled     = response.New(response.CodeWalletAlreadyEnabled, "Already enabled")
+	errWalletAlreadyDisabled    = response.New(response.CodeWalletAlreadyDisabled, "Wallet is already disabled")
+	errWalletFrozen             = response.New(response.CodeWalletFrozen, "Wallet is frozen")
+	errWalletNotFrozen          = response.New(response.CodeWalletNotFrozen, "Wallet is not frozen")
+	errWalletSuspended          = response.New(response.CodeWalletSuspended, "Wallet is suspended")
+	errWalletNotSuspended       = response.New(response.CodeWalletNotSuspended, "Wallet is not suspended")
+	errWalletClosed             = response.New(response.CodeWalletClosed, "Wallet is closed")
+	errInvalidTransition        = response.New(response.CodeInvalidTransition, "Wallet status cannot change that way")
+	errDuplicateReference       = response.New(response.CodeDuplicateReference, "duplicate reference_id")
+	errInsufficientBalance      = response.New(response.CodeInsufficientBalance, "Insufficient balance")
+	errBalanceChanged           = response.New(response.CodeBalanceChanged, "Balance changed meanwhile, try again")
+	errTransactionBlocked       = response.New(response.CodeTransactionBlocked, "Transaction declined")
+	errKYCTierRequired          = response.New(response.CodeKYCTierRequired, "Identity verification required")
+	errBalanceLimit             = response.New(response.CodeLimitExceeded, "balance would exceed the limit of your verification level")
+	errPINRequired              = response.New(response.CodePINRequired, "Transaction PIN or step-up token is required")
+	errInvalidPIN               = response.New(response.CodeInvalidPIN, "Invalid transaction PIN")
+	errInvalidStepUpToken       = response.New(response.CodeInvalidStepUpToken, "Invalid or expired step-up token")
+	errPINNotSet                = response.New(response.CodePINNotSet, "Set a transaction PIN first")
+	errPINLocked                = response.New(response.CodePINLocked, "Too many wrong PINs, try again later")
+	errTOTPRequired             = response.New(response.CodeTOTPRequired, "One-time code is required")
+	errInvalidTOTP              = response.New(response.CodeInvalidTOTP, "Invalid one-time code")
+	errTOTPLocked               = response.New(response.CodeTOTPLocked, "Too many wrong one-time codes, try again later")
+	errTOTPAlreadyEnrolled      = response.New(response.CodeTOTPAlreadyEnrolled, "Authenticator app already enrolled")
+	errTOTPNotEnrolled          = response.New(response.CodeTOTPNotEnrolled, "No authenticator app enrolled")
+	errTOTPUnavailable          = response.New(response.CodeNotFound, "Two-factor authentication is not available")
+	errPayeeNotFound            = response.New(response.CodePayeeNotFound, "Payee not found")
+	errPayeeUnavailable         = response.New(response.CodePayeeUnavailable, "Payee cannot receive transfers")
+	errStandingOrderNotFound    = response.New(response.CodeNotFound, "Standing order not found")
+	errStandingOrderInactive    = response.New(response.CodeStandingOrderInactive, "Standing order is no longer active")
+	errPayerNotFound            = response.New(response.CodePayerNotFound, "Payer not found")
+	errOwnPaymentRequest        = response.New(response.CodeForbidden, "You cannot pay or decline your own payment request")
+	errDeclineNotAllowed        = response.New(response.CodeForbidden, "Only the payer a payment request is addressed to can decline it")
+	errPaymentRequestNotFound   = response.New(response.CodeNotFound, "Payment request not found")
+	errPaymentRequestNotPending = response.New(response.CodePaymentRequestNotPending, "Payment request is no longer pending")
+)
+
+// walletFailure maps a wallet service error onto its API error, reporting
+// anything unexpected as an internal error with the given message.
+func walletFailure(err error, internalMessage string) *response.Error {
+	switch {
+	case errors.Is(err, service.ErrInvalidToken):
+		return errInvalidToken
+	case errors.Is(err, service.ErrTokenRevoked):
+		return errTokenRevoked
+	case errors.Is(err, service.ErrWalletNotFound):
+		return errWalletNotFound
+	case errors.Is(err, service.ErrWalletDisabled):
+		return errWalletDisabled
+	case errors.Is(err, service.ErrWalletAlreadyEnabled):
+		return errWalletAlreadyEnabled
+	case errors.Is(err, service.ErrWalletAlreadyDisabled):
+		return errWalletAlreadyDisabled
+	case errors.Is(err, service.ErrWalletFrozen):
+		return errWalletFrozen
+	case errors.Is(err, service.ErrWalletNotFrozen):
+		return errWalletNotFrozen
+	case errors.Is(err, service.ErrWalletSuspended):
+		return errWalletSuspended
+	case errors.Is(err, service.ErrWalletNotSuspended):
+		return errWalletNotSuspended
+	case errors.Is(err, service.ErrWalletClosed):
+		return errWalletClosed
+	case errors.Is(err, service.ErrInvalidTransition):
+		return errInvalidTransition
+	case errors.Is(err, service.ErrInvalidAmount):
+		return transactionValidation(map[string][]string{"amount": {fieldMessages["positive"]}})
+	case errors.Is(err, service.ErrPayoutRequired):
+		return response.Validation("payout_destination is required to pay out the balance",
+			map[string][]string{"payout_destination": {"Required while the wallet has a balance."}})
+	case errors.Is(err, service.ErrDuplicateReference):
+		return errDuplicateReference
+	case errors.Is(err, service.ErrInsufficientBalance):
+		return errInsufficientBalance
+	case errors.Is(err, service.ErrBalanceChanged):
+		return errBalanceChanged
+	case errors.Is(err, service.ErrTransactionBlocked):
+		return errTransactionBlocked
+	case errors.Is(err, service.ErrKYCTierRequired):
+		return errKYCTierRequired
+	case errors.Is(err, service.ErrBalanceLimit):
+		return errBalanceLimit
+	case errors.Is(err, service.ErrPINRequired):
+		return errPINRequired
+	case errors.Is(err, service.ErrInvalidPIN):
+		return errInvalidPIN
+	case errors.Is(err, service.ErrInvalidStepUpToken):
+		return errInvalidStepUpToken
+	case errors.Is(err, service.ErrPINNotSet):
+		return errPINNotSet
+	case errors.Is(err, service.ErrPINLocked):
+		return errPINLocked
+	case errors.Is(err, service.ErrTOTPRequired):
+		return errTOTPRequired
+	case errors.Is(err, service.ErrInvalidTOTP):
+		return errInvalidTOTP
+	case errors.Is(err, service.ErrTOTPLocked):
+		return errTOTPLocked
+	case errors.Is(err, service.ErrTOTPAlreadyEnrolled):
+		return errTOTPAlreadyEnrolled
+	case errors.Is(err, service.ErrTOTPNotEnrolled):
+		return errTOTPNotEnrolled
+	case errors.Is(err, service.ErrTOTPUnavailable):
+		return errTOTPUnavailable
+	case errors.Is(err, service.ErrSelfTransfer):
+		return response.Validation("cannot transfer to your own wallet",
+			map[string][]string{"payee_customer_xid": {"Must not be your own."}})
+	case errors.Is(err, service.ErrPayeeNotFound):
+		return errPayeeNotFound
+	case errors.Is(err, service.ErrPayeeUnavailable):
+		return errPayeeUnavailable
+	case errors.Is(err, service.ErrInvalidFrequency):
+		return response.Validation("frequency must be once, daily, weekly or monthly",
+			map[string][]string{"frequency": {fieldMessages["oneof"]}})
+	case errors.Is(err, service.ErrStartInPast):
+		return response.Validation("start_at must not be in the past",
+			map[string][]string{"start_at": {"Must not be in the past."}})
+	case errors.Is(err, service.ErrEndBeforeStart):
+		return response.Validation("ends_at must not be before start_at",
+			map[string][]string{"ends_at": {"Must not be before start_at."}})
+	case errors.Is(err, service.ErrStandingOrderNotFound):
+		return errStandingOrderNotFound
+	case errors.Is(err, service.ErrStandingOrderInactive):
+		return errStandingOrderInactive
+	case errors.Is(err, service.ErrSelfRequest):
+		return response.Validation("cannot request money from yourself",
+			map[string][]string{"payer_customer_xid": {"Must not be your own."}})
+	case errors.Is(err, service.ErrPayerNotFound):
+		return errPayerNotFound
+	case errors.Is(err, service.ErrInvalidExpiry):
+		return response.Validation("expires_at is out of range",
+			map[string][]string{"expires_at": {"Must be in the future and within the longest expiry allowed."}})
+	case errors.Is(err, service.ErrOwnPaymentRequest):
+		return errOwnPaymentRequest
+	case errors.Is(err, service.ErrDeclineNotAllowed):
+		return errDeclineNotAllowed
+	case errors.Is(err, service.ErrPaymentRequestNotFound):
+		return errPaymentRequestNotFound
+	case errors.Is(err, service.ErrPaymentRequestNotPending):
+		return errPaymentRequestNotPending
+	case errors.Is(err, service.ErrInvalidPINFormat):
+		return response.Validation("PIN must be 6 digits, not repeated or sequential",
+			map[string][]string{"pin": {"Must be 6 digits, not all the same or in sequence."}})
+	}
+	return response.Internal(internalMessage, err)
+}
+
 type WalletHandler struct {
-	walletRepo        repositories.WalletRepository
-	transactionRepo   repositories.TransactionRepository
+	wallets           *service.WalletService
 	customerTokenRepo repositories.CustomerTokenRepository
-	redisClient       *redis.Client
+	fail              response.Writer
 }
 
-func NewWalletHandler(walletRepo repositories.WalletRepository, transactionRepo repositories.TransactionRepository, customerTokenRepo repositories.CustomerTokenRepository, redisClient *redis.Client) *WalletHandler {
+func NewWalletHandler(wallets *service.WalletService, customerTokenRepo repositories.CustomerTokenRepository) *WalletHandler {
 	return &WalletHandler{
-		walletRepo:        walletRepo,
-		transactionRepo:   transactionRepo,
+		wallets:           wallets,
 		customerTokenRepo: customerTokenRepo,
-		redisClient:       redisClient,
+		fail:              response.V1,
 	}
 }
 
-func (h *WalletHandler) EnableWallet(c *gin.Context) {
-	// Get Authorization token from header
-	token := c.GetHeader("Authorization")
-	if token == "" {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"status": "fail",
-			"data": gin.H{
-				"error": "Authorization token is required",
-			},
-		})
-		return
-	}
-
-	// Extract token without "Token" prefix
-	token = token[6:]
+// WithWriter returns a handler sharing h's state that renders errors with w.
+func (h *WalletHandler) WithWriter(w response.Writer) *WalletHandler {
+	versioned := *h
+	versioned.fail = w
+	return &versioned
+}
 
-	// Get customer_xid by token
-	customerXID, err := h.customerTokenRepo.GetCustomerXIDByToken(token)
+// parseAmount converts an amount that passed validation.
+func parseAmount(req transactionRequest) (int64, *response.Error) {
+	amount, err := strconv.ParseInt(string(req.Amount), 10, 64)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"status": "fail",
-			"data": gin.H{
-				"error": "Invalid token",
-			},
-		})
+		return 0, transactionValidation(map[string][]string{"amount": {fieldMessages["integer"]}})
 	}
+	return amount, nil
+}
 
-	// Check if wallet exists
-	wallet, err := h.walletRepo.GetWalletByCustomerXID(customerXID)
-	if err != nil {
-		// Create wallet if not exists
-		wallet = &models.Wallet{
-			ID:        uuid.New().String(),
-			OwnedBy:   customerXID,
-			Status:    "enabled",
-			EnabledAt: time.Now().UTC(),
-			Balance:   0,
-		}
-
-		if err := h.walletRepo.CreateWallet(wallet); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"status":  "error",
-				"message": err.Error(),
-			})
-			return
-		}
-	} else {
-		// Fail if wallet is already enabled
-		if wallet.Status == "enabled" {
-			c.JSON(http.StatusBadRequest, gin.H{
-				"status": "fail",
-				"data": gin.H{
-					"error": "Already enabled",
-				},
-			})
-			return
-		}
-
-		// Enable wallet
-		wallet.Status = "enabled"
-		wallet.EnabledAt = time.Now().UTC()
-		if err := h.walletRepo.UpdateWalletStatus(wallet.ID, "enabled", wallet.EnabledAt); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"status":  "error",
-				"message": err.Error(),
-			})
-			return
-		}
+// recordedStatus is 201 for a posted transaction and 202 for one the risk
+// rules hold for review.
+func recordedStatus(transaction *models.Transaction) int {
+	if transaction.Status == "pending" {
+		return http.StatusAccepted
 	}
-
-	// Return response
-	c.JSON(http.StatusCreated, gin.H{
-		"status": "success",
-		"data": gin.H{
-			"wallet": gin.H{
-				"id":         wallet.ID,
-				"owned_by":   wallet.OwnedBy,
-				"status":     wallet.Status,
-				"enabled_at": wallet.EnabledAt,
-				"balance":    wallet.Balance,
-			},
-		},
-	})
-
+	return http.StatusCreated
 }
 
-func (h *WalletHandler) ViewWalletBalance(c *gin.Context) {
-	ctx := context.Background()
-
-	// Get Authorization token from header
-	token := c.GetHeader("Authorization")
-	if token == "" {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"status": "fail",
-			"data": gin.H{
-				"error": "Authorization token is required",
-			},
-		})
+func (h *WalletHandler) EnableWallet(c *gin.Context) {
+	customerXID, failure := customerFromToken(c, h.customerTokenRepo)
+	if failure != nil {
+		h.fail(c, failure)
 		return
 	}
-	token = token[6:]
 
-	// Get customerXID by token
-	customerXID, err := h.customerTokenRepo.GetCustomerXIDByToken(token)
+	wallet, err := h.wallets.Enable(customerXID)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"status": "fail",
-			"data": gin.H{
-				"error": "Invalid token",
-			},
-		})
+		h.fail(c, walletFailure(err, ""))
 		return
 	}
 
-	wallet, err := h.walletRepo.GetWalletByCustomerXID(customerXID)
-	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{
-			"status": "fail",
-			"data": gin.H{
-				"error": "Wallet not found",
-			},
-		})
-		return
-	}
+	response.Success(c, http.StatusCreated, gin.H{
+		"wallet": service.EnabledWalletView(wallet),
+	})
+}
 
-	// wallet is disabled
-	if wallet.Status == "disabled" {
-		c.JSON(http.StatusNotFound, gin.H{
-			"status": "fail",
-			"data": gin.H{
-				"error": "Wallet disabled",
-			},
-		})
+func (h *WalletHandler) ViewWalletBalance(c *gin.Context) {
+	customerXID, failure := customerFromToken(c, h.customerTokenRepo)
+	if failure != nil {
+		h.fail(c, failure)
 		return
 	}
 
-	// Calculate balance from transactions for consistency
-	transactions, err := h.transactionRepo.GetTransactionsByWalletID(wallet.ID)
+	wallet, err := h.wallets.Balance(customerXID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Failed to fetch transactions"})
+		h.fail(c, walletFailure(err, "Failed to fetch balance"))
 		return
 	}
-	var balance int64
-	for _, t := range transactions {
-		if t.Type == "deposit" {
-			balance += t.Amount
-		} else if t.Type == "withdrawal" {
-			balance -= t.Amount
-		}
-	}
 
-	// Update Redis cache
-	cacheKey := "wallet_balance:" + customerXID
-	if err := h.redisClient.Set(ctx, cacheKey, wallet.Balance, 15*time.Second).Err(); err != nil {
-		log.Println("Failed to update Redis cache:", err)
-	}
-
-	// Fetch from db
-	c.JSON(http.StatusOK, gin.H{
-		"status": "success",
-		"data": gin.H{
-			"wallet": gin.H{
-				"id":         wallet.ID,
-				"owned_by":   wallet.OwnedBy,
-				"status":     wallet.Status,
-				"enabled_at": wallet.EnabledAt,
-				"balance":    wallet.Balance,
-			},
-		},
+	response.Success(c, http.StatusOK, gin.H{
+		"wallet": service.EnabledWalletView(wallet),
 	})
 }
 
-func (h *WalletHandler) ViewWalletTransactions(c *gin.Context) {
-	// Get Authorization token from header
-	token := c.GetHeader("Authorization")
-	if token == "" {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"status": "fail",
-			"data": gin.H{
-				"error": "Authorization token is required",
-			},
-		})
+// ViewBalanceAt returns the balance the customer's wallet had at the RFC
+// 3339 time in the at query parameter.
+func (h *WalletHandler) ViewBalanceAt(c *gin.Context) {
+	customerXID, failure := customerFromToken(c, h.customerTokenRepo)
+	if failure != nil {
+		h.fail(c, failure)
 		return
 	}
-	token = token[6:]
 
-	// Get customerXID by token
-	customerXID, err := h.customerTokenRepo.GetCustomerXIDByToken(token)
+	at, err := time.Parse(time.RFC3339, c.Query("at"))
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"status": "fail",
-			"data": gin.H{
-				"error": "Invalid token",
-			},
-		})
+		message := "Not a valid RFC 3339 timestamp."
+		if c.Query("at") == "" {
+			message = msgMissingField
+		}
+		h.fail(c, response.Validation("at must be an RFC 3339 timestamp", map[string][]string{"at": {message}}))
+		return
+	}
+	if at.After(time.Now()) {
+		h.fail(c, response.Validation("at must not be in the future", map[string][]string{"at": {"Must not be in the future."}}))
 		return
 	}
 
-	wallet, err := h.walletRepo.GetWalletByCustomerXID(customerXID)
+	wallet, balance, err := h.wallets.BalanceAt(customerXID, at)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{
-			"status": "fail",
-			"data": gin.H{
-				"error": "Wallet not found",
-			},
-		})
+		h.fail(c, walletFailure(err, "Failed to compute balance"))
 		return
 	}
 
-	// wallet is disabled
-	if wallet.Status == "disabled" {
-		c.JSON(http.StatusNotFound, gin.H{
-			"status": "fail",
-			"data": gin.H{
-				"error": "Wallet disabled",
-			},
-		})
+	response.Success(c, http.StatusOK, gin.H{
+		"balance": gin.H{
+			"wallet_id": wallet.ID,
+			"at":        at.UTC(),
+			"balance":   balance,
+		},
+	})
+}
+
+func (h *WalletHandler) ViewWalletTransactions(c *gin.Context) {
+	customerXID, failure := customerFromToken(c, h.customerTokenRepo)
+	if failure != nil {
+		h.fail(c, failure)
 		return
 	}
 
-	// Get list transaction from the wallet
-	transactions, err := h.transactionRepo.GetTransactionsByWalletID(wallet.ID)
+	transactions, err := h.wallets.History(customerXID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"status":  "error",
-			"message": "Failed to retrieve transactions",
-		})
+		h.fail(c, walletFailure(err, "Failed to retrieve transactions"))
 		return
 	}
 
@@ -274,409 +301,115 @@ func (h *WalletHandler) ViewWalletTransactions(c *gin.Context) {
 		})
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"status": "success",
-		"data": gin.H{
-			"transactions": transactionsDTO,
-		},
+	response.Success(c, http.StatusOK, gin.H{
+		"transactions": transactionsDTO,
 	})
-
 }
 
 func (h *WalletHandler) Deposit(c *gin.Context) {
-	ctx := context.Background()
-
-	// Get Authorization token from header
-	token := c.GetHeader("Authorization")
-	if token == "" {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"status": "fail",
-			"data": gin.H{
-				"error": "Authorization token is required",
-			},
-		})
+	customerXID, failure := customerFromToken(c, h.customerTokenRepo)
+	if failure != nil {
+		h.fail(c, failure)
 		return
 	}
-	token = token[6:]
 
-	// Get customerXID by token
-	customerXID, err := h.customerTokenRepo.GetCustomerXIDByToken(token)
-	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"status": "fail",
-			"data": gin.H{
-				"error": "Invalid token",
-			},
-		})
+	// Deposits have always checked the wallet before the body
+	if _, err := h.wallets.Wallet(customerXID); err != nil {
+		h.fail(c, walletFailure(err, ""))
 		return
 	}
 
-	wallet, err := h.walletRepo.GetWalletByCustomerXID(customerXID)
-	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{
-			"status": "fail",
-			"data": gin.H{
-				"error": "Wallet not found",
-			},
-		})
+	var req transactionRequest
+	if fields := bindRequest(c, &req); fields != nil {
+		h.fail(c, transactionValidation(fields))
 		return
 	}
-
-	// wallet is disabled
-	if wallet.Status == "disabled" {
-		c.JSON(http.StatusNotFound, gin.H{
-			"status": "fail",
-			"data": gin.H{
-				"error": "Wallet disabled",
-			},
-		})
-		return
-	}
-
-	// parse form data
-	amountStr := c.PostForm("amount")
-	referenceID := c.PostForm("reference_id")
-
-	if amountStr == "" || referenceID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"status": "fail", "data": gin.H{"error": "amount and reference_id are required"}})
+	amount, failure := parseAmount(req)
+	if failure != nil {
+		h.fail(c, failure)
 		return
 	}
 
-	// Convert amount to int64
-	amount, err := strconv.ParseInt(amountStr, 10, 64)
+	transaction, err := h.wallets.Deposit(customerXID, amount, string(req.ReferenceID))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"status": "fail", "data": gin.H{"error": "invalid amount format"}})
+		h.fail(c, walletFailure(err, "Failed to record transaction"))
 		return
 	}
 
-	// Check if the referenceId already exists
-	if _, err := h.transactionRepo.GetTransactionByReferenceID(referenceID); err == nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"status": "fail",
-			"data": gin.H{
-				"reference_id": "duplicate reference_id",
-			},
-		})
-		return
-	}
-
-	// Create the transaction
-	transaction := models.Transaction{
-		ID:           uuid.New().String(),
-		WalletID:     wallet.ID,
-		Type:         "deposit",
-		Status:       "success",
-		Amount:       amount,
-		ReferenceID:  referenceID,
-		TransactedAt: time.Now().UTC(),
-	}
-
-	// Record the transaction
-	err = h.transactionRepo.CreateTransaction(&transaction)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Failed to record transaction"})
-		return
-	}
-
-	// Defer balance update with random delay up to 5 seconds
-	go func(walletID string, customerXID string) {
-		delay := time.Duration(rand.Intn(5)) * time.Second
-		time.Sleep(delay)
-
-		// Acquire lock to ensure atomic balance update
-		lockKey := "lock:wallet:" + customerXID
-		lock := h.redisClient.SetNX(ctx, lockKey, "1", 15*time.Second)
-		if err := lock.Err(); err != nil || !lock.Val() {
-			log.Printf("Failed to acquire lock for balance update of wallet %s", walletID)
-			return
-		}
-		defer h.redisClient.Del(ctx, lockKey)
-
-		// Calculate balance from transactions
-		transactions, err := h.transactionRepo.GetTransactionsByWalletID(walletID)
-		if err != nil {
-			log.Printf("Failed to fetch transactions for wallet %s: %v", walletID, err)
-			return
-		}
-		var newBalance int64
-		for _, t := range transactions {
-			if t.Type == "deposit" {
-				newBalance += t.Amount
-			} else if t.Type == "withdrawal" {
-				newBalance -= t.Amount
-			}
-		}
-
-		// Update database balance
-		if err := h.walletRepo.UpdateWalletBalance(walletID, newBalance); err != nil {
-			log.Printf("Failed to update database balance for wallet %s: %v", walletID, err)
-			return
-		}
-
-		// Update redis balance
-		cacheKey := "wallet_balance:" + customerXID
-		if err := h.redisClient.Set(ctx, cacheKey, newBalance, 15*time.Second).Err(); err != nil {
-			log.Printf("Failed to update Redis balance for %s: %v", cacheKey, err)
-			h.redisClient.Del(ctx, cacheKey)
-		}
-	}(wallet.ID, customerXID)
-
-	c.JSON(http.StatusCreated, gin.H{
-		"status": "success",
-		"data": gin.H{
-			"deposit": gin.H{
-				"id":           transaction.ID,
-				"deposited_by": customerXID,
-				"status":       transaction.Status,
-				"deposited_at": transaction.TransactedAt,
-				"amount":       transaction.Amount,
-				"reference_id": transaction.ReferenceID,
-			},
-		},
+	response.Success(c, recordedStatus(transaction), gin.H{
+		"deposit": service.TransactionView(customerXID, transaction),
 	})
 }
 
 func (h *WalletHandler) Withdraw(c *gin.Context) {
-	ctx := context.Background()
-
-	// Get Authorization token from header
-	token := c.GetHeader("Authorization")
-	if token == "" {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"status": "fail",
-			"data": gin.H{
-				"error": "Authorization token is required",
-			},
-		})
-		return
-	}
-	token = token[6:]
-
-	// Parse form data
-	amountStr := c.PostForm("amount")
-	referenceID := c.PostForm("reference_id")
-	if amountStr == "" || referenceID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"status": "fail", "data": gin.H{"error": "amount and reference_id are required"}})
-		return
-	}
-
-	// Get customerXID by token
-	customerXID, err := h.customerTokenRepo.GetCustomerXIDByToken(token)
-	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"status": "fail",
-			"data": gin.H{
-				"error": "Invalid token",
-			},
-		})
-		return
-	}
-
-	wallet, err := h.walletRepo.GetWalletByCustomerXID(customerXID)
-	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{
-			"status": "fail",
-			"data": gin.H{
-				"error": "Wallet not found",
-			},
-		})
-		return
-	}
-
-	// wallet is disabled
-	if wallet.Status == "disabled" {
-		c.JSON(http.StatusNotFound, gin.H{
-			"status": "fail",
-			"data": gin.H{
-				"error": "Wallet disabled",
-			},
-		})
+	// Withdrawals have always validated the body before the token
+	if c.GetHeader("Authorization") == "" {
+		h.fail(c, errAuthRequired)
 		return
 	}
-
-	// convert amount to int64
-	amount, err := strconv.ParseInt(amountStr, 10, 64)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"status": "fail", "data": gin.H{"error": "invalid amount format"}})
+	var req transactionRequest
+	if fields := bindRequest(c, &req); fields != nil {
+		h.fail(c, transactionValidation(fields))
 		return
 	}
 
-	// Check if the referenceId already exists
-	if _, err := h.transactionRepo.GetTransactionByReferenceID(referenceID); err == nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"status": "fail",
-			"data": gin.H{
-				"reference_id": "duplicate reference_id",
-			},
-		})
+	customerXID, failure := customerFromToken(c, h.customerTokenRepo)
+	if failure != nil {
+		h.fail(c, failure)
 		return
 	}
 
-	// Ensure sufficient balance
-	if wallet.Balance < amount {
-		c.JSON(http.StatusBadRequest, gin.H{"status": "fail", "data": gin.H{"error": "Insufficient balance"}})
+	amount, failure := parseAmount(req)
+	if failure != nil {
+		h.fail(c, failure)
 		return
 	}
 
-	// Create the transaction
-	transaction := models.Transaction{
-		ID:           uuid.New().String(),
-		WalletID:     wallet.ID,
-		Type:         "withdrawal",
-		Status:       "success",
-		Amount:       amount,
-		ReferenceID:  referenceID,
-		TransactedAt: time.Now().UTC(),
-	}
-
-	// Record the transaction
-	err = h.transactionRepo.CreateTransaction(&transaction)
+	transaction, err := h.wallets.Withdraw(customerXID, amount, string(req.ReferenceID), authorization(c))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Failed to record transaction"})
+		h.fail(c, walletFailure(err, "Failed to record transaction"))
 		return
 	}
 
-	// Defer balance update with a random delay up to 5 seconds
-	go func(walletID string, customerXID string) {
-		delay := time.Duration(rand.Intn(5)) * time.Second // Random delay between 0-5 seconds
-		time.Sleep(delay)
-
-		// Acquire lock to ensure atomic balance update
-		lockKey := "lock:wallet:" + customerXID
-		lock := h.redisClient.SetNX(ctx, lockKey, "1", 15*time.Second)
-		if err := lock.Err(); err != nil || !lock.Val() {
-			log.Printf("Failed to acquire lock for balance update of wallet %s", walletID)
-			return
-		}
-		defer h.redisClient.Del(ctx, lockKey)
-
-		// Calculate balance from transactions
-		transactions, err := h.transactionRepo.GetTransactionsByWalletID(walletID)
-		if err != nil {
-			log.Printf("Failed to fetch transactions for wallet %s: %v", walletID, err)
-			return
-		}
-		var newBalance int64
-		for _, t := range transactions {
-			if t.Type == "deposit" {
-				newBalance += t.Amount
-			} else if t.Type == "withdrawal" {
-				newBalance -= t.Amount
-			}
-		}
-
-		// Update database balance
-		if err := h.walletRepo.UpdateWalletBalance(walletID, newBalance); err != nil {
-			log.Printf("Failed to update database balance for wallet %s: %v", walletID, err)
-			return
-		}
-
-		// Update Redis balance
-		cacheKey := "wallet_balance:" + customerXID
-		if err := h.redisClient.Set(ctx, cacheKey, newBalance, 15*time.Second).Err(); err != nil {
-			log.Printf("Failed to update Redis balance for %s: %v", cacheKey, err)
-			h.redisClient.Del(ctx, cacheKey)
-		}
-	}(wallet.ID, customerXID)
-
-	c.JSON(http.StatusCreated, gin.H{
-		"status": "success",
-		"data": gin.H{
-			"withdrawal": gin.H{
-				"id":           transaction.ID,
-				"withdrawn_by": customerXID,
-				"status":       transaction.Status,
-				"withdrawn_at": transaction.TransactedAt,
-				"amount":       transaction.Amount,
-				"reference_id": transaction.ReferenceID,
-			},
-		},
+	response.Success(c, recordedStatus(transaction), gin.H{
+		"withdrawal": service.TransactionView(customerXID, transaction),
 	})
 }
 
 func (h *WalletHandler) DisableWallet(c *gin.Context) {
-	// Get Authorization token from header
-	token := c.GetHeader("Authorization")
-	if token == "" {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"status": "fail",
-			"data":   gin.H{"error": "Authorization token is required"},
-		})
-		return
-	}
-	token = token[6:]
-
-	// Get customerXID by token
-	customerXID, err := h.customerTokenRepo.GetCustomerXIDByToken(token)
-	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"status": "fail",
-			"data":   gin.H{"error": "Invalid token"},
-		})
-		return
-	}
-
-	// Fetch the customer's wallet
-	wallet, err := h.walletRepo.GetWalletByCustomerXID(customerXID)
-	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{
-			"status": "fail",
-			"data":   gin.H{"error": "Wallet not found"},
-		})
+	customerXID, failure := customerFromToken(c, h.customerTokenRepo)
+	if failure != nil {
+		h.fail(c, failure)
 		return
 	}
 
-	// Check if wallet is already disabled
-	if wallet.Status == "disabled" {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"status": "fail",
-			"data":   gin.H{"error": "Wallet is already disabled"},
-		})
+	// The wallet state has always been checked before the body
+	if _, err := h.wallets.Wallet(customerXID); err != nil {
+		if errors.Is(err, service.ErrWalletDisabled) {
+			err = service.ErrWalletAlreadyDisabled
+		}
+		h.fail(c, walletFailure(err, ""))
 		return
 	}
 
-	// Read `is_disabled` from form-data
-	isDisabled := c.PostForm("is_disabled")
-	if isDisabled == "" {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"status": "fail",
-			"data":   gin.H{"error": "is_disabled is required"},
-		})
+	// Read `is_disabled` from the request body
+	var req disableWalletRequest
+	if fields := bindRequest(c, &req); fields != nil {
+		h.fail(c, disableValidation(fields))
 		return
 	}
-
-	if isDisabled != "true" {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"status": "fail",
-			"data":   gin.H{"error": "Invalid request: is_disabled must be 'true'"},
-		})
+	if isDisabled, _ := strconv.ParseBool(string(req.IsDisabled)); !isDisabled {
+		h.fail(c, disableValidation(map[string][]string{"is_disabled": {"Must be true."}}))
 		return
 	}
 
-	// Disable wallet
-	disabledAt := time.Now().UTC()
-	if err := h.walletRepo.UpdateWalletStatus(wallet.ID, "disabled", disabledAt); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"status":  "error",
-			"message": "Failed to disable wallet",
-		})
+	wallet, err := h.wallets.Disable(customerXID, authorization(c))
+	if err != nil {
+		h.fail(c, walletFailure(err, "Failed to disable wallet"))
 		return
 	}
 
-	wallet.Status = "disabled"
-	wallet.DisabledAt = disabledAt
-
-	// Respond with success
-	c.JSON(http.StatusOK, gin.H{
-		"status": "success",
-		"data": gin.H{
-			"wallet": gin.H{
-				"id":          wallet.ID,
-				"owned_by":    customerXID,
-				"status":      wallet.Status,
-				"disabled_at": wallet.DisabledAt.Format(time.RFC3339),
-				"balance":     wallet.Balance,
-			},
-		},
+	response.Success(c, http.StatusOK, gin.H{
+		"wallet": service.DisabledWalletView(wallet),
 	})
 }
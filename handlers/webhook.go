@@ -0,0 +1,206 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	"mini-wallet/models"
+	"mini-wallet/repositories"
+	"mini-wallet/response"
+	"mini-wallet/webhooks"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const deliveryHistoryLimit = 100
+
+var errSubscriptionNotFound = response.New(response.CodeNotFound, "Webhook subscription not found")
+
+type WebhookHandler struct {
+	webhookRepo       repositories.WebhookRepository
+	customerTokenRepo repositories.CustomerTokenRepository
+	fail              response.Writer
+}
+
+func NewWebhookHandler(webhookRepo repositories.WebhookRepository, customerTokenRepo repositories.CustomerTokenRepository) *WebhookHandler {
+	return &WebhookHandler{
+		webhookRepo:       webhookRepo,
+		customerTokenRepo: customerTokenRepo,
+		fail:              response.V1,
+	}
+}
+
+// WithWriter returns a handler sharing h's state that renders errors with w.
+func (h *WebhookHandler) WithWriter(w response.Writer) *WebhookHandler {
+	versioned := *h
+	versioned.fail = w
+	return &versioned
+}
+
+// Subscribe registers a URL for the given event types. The signing secret is
+// only ever returned here.
+func (h *WebhookHandler) Subscribe(c *gin.Context) {
+	customerXID, failure := customerFromToken(c, h.customerTokenRepo)
+	if failure != nil {
+		h.fail(c, failure)
+		return
+	}
+
+	var req webhookSubscriptionRequest
+	if fields := bindRequest(c, &req); fields != nil {
+		h.fail(c, response.Validation("invalid webhook subscription", fields))
+		return
+	}
+
+	secret, err := webhooks.NewSecret()
+	if err != nil {
+		h.fail(c, response.Internal("Failed to generate webhook secret", err))
+		return
+	}
+	subscription := models.WebhookSubscription{
+		ID:          uuid.New().String(),
+		CustomerXID: customerXID,
+		URL:         string(req.URL),
+		Secret:      secret,
+		EventTypes:  uniqueStrings(req.Events),
+		CreatedAt:   time.Now().UTC(),
+	}
+	if err := h.webhookRepo.CreateSubscription(&subscription); err != nil {
+		h.fail(c, response.Internal("Failed to create webhook subscription", err))
+		return
+	}
+
+	response.Success(c, http.StatusCreated, gin.H{
+		"subscription": gin.H{
+			"id":         subscription.ID,
+			"url":        subscription.URL,
+			"events":     subscription.EventTypes,
+			"secret":     subscription.Secret,
+			"created_at": subscription.CreatedAt,
+		},
+	})
+}
+
+func (h *WebhookHandler) ListSubscriptions(c *gin.Context) {
+	customerXID, failure := customerFromToken(c, h.customerTokenRepo)
+	if failure != nil {
+		h.fail(c, failure)
+		return
+	}
+
+	subscriptions, err := h.webhookRepo.ListSubscriptionsByCustomerXID(customerXID)
+	if err != nil {
+		h.fail(c, response.Internal("Failed to retrieve webhook subscriptions", err))
+		return
+	}
+	if subscriptions == nil {
+		subscriptions = []models.WebhookSubscription{}
+	}
+
+	response.Success(c, http.StatusOK, gin.H{
+		"subscriptions": subscriptions,
+	})
+}
+
+func (h *WebhookHandler) Unsubscribe(c *gin.Context) {
+	customerXID, failure := customerFromToken(c, h.customerTokenRepo)
+	if failure != nil {
+		h.fail(c, failure)
+		return
+	}
+
+	err := h.webhookRepo.DeleteSubscription(c.Param("id"), customerXID)
+	if errors.Is(err, sql.ErrNoRows) {
+		h.fail(c, errSubscriptionNotFound)
+		return
+	}
+	if err != nil {
+		h.fail(c, response.Internal("Failed to delete webhook subscription", err))
+		return
+	}
+
+	response.Success(c, http.StatusOK, gin.H{
+		"subscription": gin.H{
+			"id": c.Param("id"),
+		},
+	})
+}
+
+// ListDeliveries returns the most recent deliveries of a subscription.
+func (h *WebhookHandler) ListDeliveries(c *gin.Context) {
+	subscription, failure := h.ownedSubscription(c)
+	if failure != nil {
+		h.fail(c, failure)
+		return
+	}
+
+	deliveries, err := h.webhookRepo.ListDeliveriesBySubscriptionID(subscription.ID, deliveryHistoryLimit)
+	if err != nil {
+		h.fail(c, response.Internal("Failed to retrieve webhook deliveries", err))
+		return
+	}
+	if deliveries == nil {
+		deliveries = []models.WebhookDelivery{}
+	}
+
+	response.Success(c, http.StatusOK, gin.H{
+		"deliveries": deliveries,
+	})
+}
+
+// Redeliver queues a delivery again, whether it was delivered or dead-lettered.
+func (h *WebhookHandler) Redeliver(c *gin.Context) {
+	subscription, failure := h.ownedSubscription(c)
+	if failure != nil {
+		h.fail(c, failure)
+		return
+	}
+
+	delivery, err := h.webhookRepo.GetDelivery(c.Param("delivery_id"))
+	if err != nil || delivery.SubscriptionID != subscription.ID {
+		h.fail(c, response.New(response.CodeNotFound, "Webhook delivery not found"))
+		return
+	}
+	if err := h.webhookRepo.Redeliver(delivery.ID); err != nil {
+		h.fail(c, response.Internal("Failed to queue webhook delivery", err))
+		return
+	}
+
+	response.Success(c, http.StatusAccepted, gin.H{
+		"delivery": gin.H{
+			"id":     delivery.ID,
+			"status": models.DeliveryPending,
+		},
+	})
+}
+
+// ownedSubscription loads the :id subscription if it belongs to the caller.
+func (h *WebhookHandler) ownedSubscription(c *gin.Context) (*models.WebhookSubscription, *response.Error) {
+	customerXID, failure := customerFromToken(c, h.customerTokenRepo)
+	if failure != nil {
+		return nil, failure
+	}
+	subscription, err := h.webhookRepo.GetSubscription(c.Param("id"))
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && subscription.CustomerXID != customerXID) {
+		return nil, errSubscriptionNotFound
+	}
+	if err != nil {
+		return nil, response.Internal("Failed to retrieve webhook subscription", err)
+	}
+	return subscription, nil
+}
+
+func uniqueStrings(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	unique := make([]string, 0, len(values))
+	for _, v := range values {
+		if !seen[v] {
+			seen[v] = true
+			unique = append(unique, v)
+		}
+	}
+	return unique
+}
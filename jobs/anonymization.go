@@ -0,0 +1,85 @@
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+
+	"mini-wallet/repositories"
+
+	"github.com/google/uuid"
+)
+
+// anonymizeBatch bounds the closures anonymized per query, so a large
+// backlog is worked through without loading it at once.
+const anonymizeBatch = 100
+
+// Anonymizer removes the customer's personal data from wallets whose
+// retention period after closure has ended. The transaction log is kept,
+// but nothing links it to the customer anymore.
+type Anonymizer struct {
+	closureRepo repositories.ClosureRepository
+}
+
+func NewAnonymizer(closureRepo repositories.ClosureRepository) *Anonymizer {
+	return &Anonymizer{closureRepo: closureRepo}
+}
+
+// Schedule anonymizes due wallets every interval until ctx is done.
+func (a *Anonymizer) Schedule(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			anonymized, err := a.Run(ctx, time.Now().UTC())
+			if err != nil {
+				log.Println("Anonymizing closed wallets failed:", err)
+				continue
+			}
+			if anonymized > 0 {
+				log.Printf("Anonymized %d closed wallets", anonymized)
+			}
+		}
+	}
+}
+
+// Run anonymizes every closed wallet whose retention ended before now and
+// returns how many were anonymized. Each customer_xid is replaced with a
+// random pseudonym.
+func (a *Anonymizer) Run(ctx context.Context, now time.Time) (int, error) {
+	anonymized := 0
+	for {
+		closures, err := a.closureRepo.ListDueClosures(now, anonymizeBatch)
+		if err != nil {
+			return anonymized, err
+		}
+		progressed := false
+		for _, closure := range closures {
+			if err := ctx.Err(); err != nil {
+				return anonymized, err
+			}
+			err := a.closureRepo.AnonymizeClosure(&closure, uuid.New().String(), time.Now().UTC())
+			switch {
+			case errors.Is(err, sql.ErrNoRows):
+				// Another instance got to it first
+				progressed = true
+			case err != nil:
+				log.Printf("Failed to anonymize wallet %s: %v", closure.WalletID, err)
+			default:
+				anonymized++
+				progressed = true
+			}
+		}
+		// Stop at the last batch, or when every closure of a batch failed
+		// and would only be listed again
+		if len(closures) < anonymizeBatch || !progressed {
+			return anonymized, nil
+		}
+	}
+}
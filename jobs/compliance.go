@@ -0,0 +1,109 @@
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+
+	"mini-wallet/compliance"
+	"mini-wallet/models"
+	"mini-wallet/repositories"
+
+	"github.com/google/uuid"
+)
+
+// complianceLag keeps a scan clear of transactions still being written, so
+// one committed late with an earlier timestamp is not skipped.
+const complianceLag = time.Minute
+
+// ComplianceScanner reports large transactions and structuring. Each scan
+// covers the transactions recorded since the previous one, looking back
+// one structuring window further so series straddling scans are found.
+type ComplianceScanner struct {
+	walletRepo     repositories.WalletRepository
+	complianceRepo repositories.ComplianceRepository
+	rules          compliance.Rules
+}
+
+func NewComplianceScanner(walletRepo repositories.WalletRepository, complianceRepo repositories.ComplianceRepository, rules compliance.Rules) *ComplianceScanner {
+	return &ComplianceScanner{walletRepo: walletRepo, complianceRepo: complianceRepo, rules: rules}
+}
+
+// Schedule scans every interval until ctx is done.
+func (s *ComplianceScanner) Schedule(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			scan, err := s.Run(ctx, time.Now().UTC().Add(-complianceLag))
+			if err != nil {
+				log.Println("Compliance scan failed:", err)
+				continue
+			}
+			if scan.Reports > 0 {
+				log.Printf("Compliance scan %s filed %d reports", scan.ID, scan.Reports)
+			}
+		}
+	}
+}
+
+// Run scans the transactions recorded after the previous scan up to until,
+// or every transaction up to until on the first scan.
+func (s *ComplianceScanner) Run(ctx context.Context, until time.Time) (*models.ComplianceScan, error) {
+	scan := &models.ComplianceScan{ID: uuid.New().String(), WindowEnd: until}
+	previous, err := s.complianceRepo.GetLatestScan()
+	switch {
+	case err == nil:
+		scan.WindowStart = previous.WindowEnd
+	case !errors.Is(err, sql.ErrNoRows):
+		return nil, err
+	}
+
+	lookback := scan.WindowStart
+	if !lookback.IsZero() {
+		lookback = lookback.Add(-s.rules.StructuringWindow)
+	}
+	transactions, err := s.complianceRepo.ListTransactionsFrom(s.rules.StructuringFloor, lookback, until)
+	if err != nil {
+		return nil, err
+	}
+
+	owners := make(map[string]string)
+	for _, report := range compliance.Detect(transactions, scan.WindowStart, s.rules) {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		owner, ok := owners[report.WalletID]
+		if !ok {
+			wallet, err := s.walletRepo.GetWalletByID(report.WalletID)
+			if err != nil {
+				return nil, err
+			}
+			owner = wallet.OwnedBy
+			owners[report.WalletID] = owner
+		}
+		report.ID = uuid.New().String()
+		report.CustomerXID = owner
+		report.CreatedAt = time.Now().UTC()
+		created, err := s.complianceRepo.CreateReport(&report)
+		if err != nil {
+			return nil, err
+		}
+		if created {
+			scan.Reports++
+		}
+	}
+
+	// The scan is recorded last, so a failed one is retried from the same start
+	scan.CreatedAt = time.Now().UTC()
+	if err := s.complianceRepo.CreateScan(scan); err != nil {
+		return nil, err
+	}
+	return scan, nil
+}
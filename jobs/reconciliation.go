@@ -0,0 +1,167 @@
+// Package jobs contains background work that runs alongside the API and can
+// also be triggered from walletctl.
+package jobs
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"mini-wallet/models"
+	"mini-wallet/repositories"
+	"mini-wallet/service"
+
+	"github.com/google/uuid"
+)
+
+// DefaultSettleGrace covers the API's default settlement delay plus lock TTL.
+const DefaultSettleGrace = 20 * time.Second
+
+type ReconcilerOptions struct {
+	// AutoCorrect overwrites a drifted stored balance with the derived one.
+	AutoCorrect bool
+	// SettleGrace skips wallets with transactions younger than this, since
+	// their balance may legitimately not be settled yet.
+	SettleGrace time.Duration
+}
+
+// Reconciler compares every wallet's stored balance with the balance derived
+// from its transactions and records each discrepancy.
+type Reconciler struct {
+	walletRepo         repositories.WalletRepository
+	transactionRepo    repositories.TransactionRepository
+	reconciliationRepo repositories.ReconciliationRepository
+	wallets            *service.WalletService
+	opts               ReconcilerOptions
+}
+
+// NewReconciler creates a Reconciler. Corrections go through wallets, so
+// they are serialised with the API's balance settlement when it has Redis.
+func NewReconciler(walletRepo repositories.WalletRepository, transactionRepo repositories.TransactionRepository, reconciliationRepo repositories.ReconciliationRepository, wallets *service.WalletService, opts ReconcilerOptions) *Reconciler {
+	return &Reconciler{
+		walletRepo:         walletRepo,
+		transactionRepo:    transactionRepo,
+		reconciliationRepo: reconciliationRepo,
+		wallets:            wallets,
+		opts:               opts,
+	}
+}
+
+// Schedule runs the reconciliation every interval until ctx is done.
+func (r *Reconciler) Schedule(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			run, err := r.Run(ctx)
+			if err != nil {
+				log.Println("Balance reconciliation failed:", err)
+				continue
+			}
+			log.Printf("Balance reconciliation %s checked %d wallets, found %d discrepancies", run.ID, run.WalletsChecked, run.Discrepancies)
+		}
+	}
+}
+
+// Run performs a single reconciliation pass over every wallet.
+func (r *Reconciler) Run(ctx context.Context) (*models.ReconciliationRun, error) {
+	run := &models.ReconciliationRun{
+		ID:          uuid.New().String(),
+		StartedAt:   time.Now().UTC(),
+		AutoCorrect: r.opts.AutoCorrect,
+	}
+	if err := r.reconciliationRepo.CreateRun(run); err != nil {
+		return nil, err
+	}
+
+	wallets, err := r.walletRepo.ListWallets()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, wallet := range wallets {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		discrepancy, checked, err := r.reconcile(ctx, wallet)
+		if err != nil {
+			log.Printf("Failed to reconcile wallet %s: %v", wallet.ID, err)
+			continue
+		}
+		if !checked {
+			continue
+		}
+		run.WalletsChecked++
+		if discrepancy == nil {
+			continue
+		}
+
+		discrepancy.RunID = run.ID
+		if err := r.reconciliationRepo.CreateDiscrepancy(discrepancy); err != nil {
+			return nil, err
+		}
+		run.Discrepancies++
+	}
+
+	finishedAt := time.Now().UTC()
+	run.FinishedAt = &finishedAt
+	if err := r.reconciliationRepo.FinishRun(run); err != nil {
+		return nil, err
+	}
+	return run, nil
+}
+
+// reconcile checks one wallet. It reports checked=false when the wallet was
+// skipped because recent transactions may still be settling.
+func (r *Reconciler) reconcile(ctx context.Context, wallet models.Wallet) (*models.BalanceDiscrepancy, bool, error) {
+	transactions, err := r.transactionRepo.GetTransactionsByWalletID(wallet.ID)
+	if err != nil {
+		return nil, false, err
+	}
+	settledBefore := time.Now().UTC().Add(-r.opts.SettleGrace)
+	for _, t := range transactions {
+		if t.TransactedAt.After(settledBefore) {
+			return nil, false, nil
+		}
+	}
+
+	derived := models.Balance(transactions)
+	if derived == wallet.Balance {
+		return nil, true, nil
+	}
+
+	discrepancy := &models.BalanceDiscrepancy{
+		ID:             uuid.New().String(),
+		WalletID:       wallet.ID,
+		StoredBalance:  wallet.Balance,
+		DerivedBalance: derived,
+		Drift:          derived - wallet.Balance,
+		DetectedAt:     time.Now().UTC(),
+	}
+	if r.opts.AutoCorrect {
+		corrected, err := r.correct(ctx, wallet)
+		if err != nil {
+			log.Printf("Failed to correct balance of wallet %s: %v", wallet.ID, err)
+		}
+		discrepancy.Corrected = corrected
+	}
+	return discrepancy, true, nil
+}
+
+// correct rewrites the stored balance under the same lock the API uses for
+// balance settlement, so the two never interleave.
+func (r *Reconciler) correct(ctx context.Context, wallet models.Wallet) (bool, error) {
+	if _, err := r.wallets.RecomputeBalance(ctx, wallet.ID, wallet.OwnedBy); err != nil {
+		if errors.Is(err, service.ErrWalletLocked) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
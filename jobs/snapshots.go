@@ -0,0 +1,129 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"mini-wallet/models"
+	"mini-wallet/repositories"
+	"mini-wallet/service"
+)
+
+// snapshotDelay lets transactions stamped just before midnight finish
+// writing before the day is snapshotted.
+const snapshotDelay = time.Minute
+
+// Snapshotter records each wallet's balance at every UTC midnight, so
+// point-in-time balances only replay the transactions of part of a day.
+type Snapshotter struct {
+	walletRepo      repositories.WalletRepository
+	transactionRepo repositories.TransactionRepository
+	snapshotRepo    repositories.SnapshotRepository
+	wallets         *service.WalletService
+}
+
+func NewSnapshotter(walletRepo repositories.WalletRepository, transactionRepo repositories.TransactionRepository, snapshotRepo repositories.SnapshotRepository, wallets *service.WalletService) *Snapshotter {
+	return &Snapshotter{
+		walletRepo:      walletRepo,
+		transactionRepo: transactionRepo,
+		snapshotRepo:    snapshotRepo,
+		wallets:         wallets,
+	}
+}
+
+// midnight is the start of t's UTC day.
+func midnight(t time.Time) time.Time {
+	return t.UTC().Truncate(24 * time.Hour)
+}
+
+// Schedule snapshots every wallet shortly after each UTC midnight until ctx
+// is done. It starts with the latest midnight, so a restart never skips one.
+func (s *Snapshotter) Schedule(ctx context.Context) {
+	asOf := midnight(time.Now())
+	for {
+		if wait := time.Until(asOf.Add(snapshotDelay)); wait > 0 {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(wait):
+			}
+		}
+
+		taken, err := s.Run(ctx, asOf)
+		if err != nil {
+			log.Printf("Balance snapshots as of %s failed: %v", asOf.Format(time.RFC3339), err)
+		} else {
+			log.Printf("Took %d balance snapshots as of %s", taken, asOf.Format(time.RFC3339))
+		}
+		asOf = asOf.Add(24 * time.Hour)
+	}
+}
+
+// Run snapshots every wallet as of the given time and returns how many
+// snapshots were taken.
+func (s *Snapshotter) Run(ctx context.Context, asOf time.Time) (int, error) {
+	wallets, err := s.walletRepo.ListWallets()
+	if err != nil {
+		return 0, err
+	}
+
+	taken := 0
+	for _, wallet := range wallets {
+		if err := ctx.Err(); err != nil {
+			return taken, err
+		}
+		if _, err := s.wallets.Snapshot(wallet.ID, asOf); err != nil {
+			log.Printf("Failed to snapshot wallet %s: %v", wallet.ID, err)
+			continue
+		}
+		taken++
+	}
+	return taken, nil
+}
+
+// Backfill replays the wallet's transactions recorded before until in one
+// pass and snapshots the balance at the midnight closing each day that had
+// transactions. It returns how many snapshots were written.
+func (s *Snapshotter) Backfill(ctx context.Context, walletID string, until time.Time) (int, error) {
+	var balance int64
+	var dayEnd time.Time
+	written := 0
+	save := func() error {
+		err := s.snapshotRepo.SaveSnapshot(&models.BalanceSnapshot{
+			WalletID:  walletID,
+			AsOf:      dayEnd,
+			Balance:   balance,
+			CreatedAt: time.Now().UTC(),
+		})
+		if err == nil {
+			written++
+		}
+		return err
+	}
+
+	err := s.transactionRepo.StreamTransactions(walletID, time.Time{}, until, func(t models.Transaction) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		end := midnight(t.TransactedAt).Add(24 * time.Hour)
+		if !dayEnd.IsZero() && end.After(dayEnd) {
+			if err := save(); err != nil {
+				return err
+			}
+		}
+		dayEnd = end
+		balance += t.SignedAmount()
+		return nil
+	})
+	if err != nil {
+		return written, err
+	}
+	// The current day is still open and is left to the daily job
+	if !dayEnd.IsZero() && !dayEnd.After(until) {
+		if err := save(); err != nil {
+			return written, err
+		}
+	}
+	return written, nil
+}
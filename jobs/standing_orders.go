@@ -0,0 +1,52 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"mini-wallet/service"
+)
+
+// StandingOrderScheduler runs due standing orders. Several schedulers, in
+// one or more processes, may run at once since orders are claimed with a
+// lease.
+type StandingOrderScheduler struct {
+	orders    *service.StandingOrderService
+	batchSize int
+}
+
+func NewStandingOrderScheduler(orders *service.StandingOrderService, batchSize int) *StandingOrderScheduler {
+	return &StandingOrderScheduler{orders: orders, batchSize: batchSize}
+}
+
+// Schedule runs due orders every interval until ctx is done.
+func (s *StandingOrderScheduler) Schedule(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.Run(ctx)
+		}
+	}
+}
+
+// Run claims and runs batches of due orders until one comes back short.
+// An order that missed runs while no scheduler was running catches up one
+// run per claim.
+func (s *StandingOrderScheduler) Run(ctx context.Context) {
+	for ctx.Err() == nil {
+		claimed, err := s.orders.RunDue(time.Now().UTC(), s.batchSize)
+		if err != nil {
+			log.Println("Running standing orders failed:", err)
+			return
+		}
+		if claimed < s.batchSize {
+			return
+		}
+	}
+}
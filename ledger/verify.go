@@ -0,0 +1,72 @@
+// Package ledger verifies the per-wallet hash chain of the transaction log,
+// which makes rows edited or deleted outside the API detectable.
+package ledger
+
+import (
+	"errors"
+	"fmt"
+
+	"mini-wallet/models"
+	"mini-wallet/repositories"
+)
+
+// Break is the first link of a chain that does not verify.
+type Break struct {
+	Sequence      int64  `json:"sequence"`
+	TransactionID string `json:"transaction_id"`
+	Reason        string `json:"reason"`
+}
+
+// Report is the outcome of verifying one wallet's chain. Head is the last
+// verified link; recording it elsewhere lets a later verification detect
+// transactions removed from the end of the chain, which leave no gap.
+type Report struct {
+	WalletID string `json:"wallet_id"`
+	Valid    bool   `json:"valid"`
+	// Verified counts the links checked before the chain ended or broke.
+	Verified int64 `json:"verified"`
+	// Unsealed counts transactions written before the chain existed.
+	Unsealed     int64  `json:"unsealed"`
+	HeadSequence int64  `json:"head_sequence"`
+	HeadHash     string `json:"head_hash"`
+	Break        *Break `json:"break"`
+}
+
+// errBroken stops the walk at the first broken link.
+var errBroken = errors.New("chain broken")
+
+// Verify walks the wallet's chain and reports the first broken link: a
+// missing hash after the chain started, a gap in the sequence, a previous
+// hash that does not match, or content that no longer matches its hash.
+func Verify(transactionRepo repositories.TransactionRepository, walletID string) (*Report, error) {
+	report := &Report{WalletID: walletID, Valid: true}
+	brk := func(t models.Transaction, reason string) error {
+		report.Valid = false
+		report.Break = &Break{Sequence: t.Sequence, TransactionID: t.ID, Reason: reason}
+		return errBroken
+	}
+
+	err := transactionRepo.StreamChain(walletID, func(t models.Transaction) error {
+		switch {
+		case t.Hash == "" && report.Verified == 0:
+			report.Unsealed++
+			return nil
+		case t.Hash == "":
+			return brk(t, "transaction has no hash after the chain started")
+		case t.Sequence != report.HeadSequence+1:
+			return brk(t, fmt.Sprintf("sequence %d follows %d, transactions are missing", t.Sequence, report.HeadSequence))
+		case t.PrevHash != report.HeadHash:
+			return brk(t, "previous hash does not match the preceding transaction")
+		case t.ChainHash() != t.Hash:
+			return brk(t, "content does not match its hash")
+		default:
+			report.Verified++
+			report.HeadSequence, report.HeadHash = t.Sequence, t.Hash
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errBroken) {
+		return nil, err
+	}
+	return report, nil
+}
@@ -3,38 +3,52 @@ package main
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"log"
+	"net"
 	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
+	"mini-wallet/auth"
+	"mini-wallet/compliance"
+	"mini-wallet/config"
+	"mini-wallet/events"
+	"mini-wallet/grpcapi"
 	"mini-wallet/handlers"
+	"mini-wallet/jobs"
+	"mini-wallet/ratelimit"
 	"mini-wallet/repositories"
+	"mini-wallet/risk"
+	"mini-wallet/server"
+	"mini-wallet/service"
+	"mini-wallet/webhooks"
 
-	"github.com/gin-gonic/gin"
 	"github.com/go-redis/redis/v8"
-	"github.com/joho/godotenv"
 	_ "github.com/lib/pq"
+	"google.golang.org/grpc"
 )
 
 func main() {
-	err := godotenv.Load()
+	cfg, err := config.Load(os.Args[0], os.Args[1:])
 	if err != nil {
-		log.Fatal("Error loading .env file")
-	}
-
-	// Get the connection string from environment variables
-	connectionString := os.Getenv("DATABASE_URL")
-	if connectionString == "" {
-		log.Fatal("DATABASE_URL environment variable is not set")
+		log.Fatal("Invalid configuration: ", err)
 	}
 
 	// Open a connection to the database
-	db, err := sql.Open("postgres", connectionString)
+	db, err := sql.Open("postgres", cfg.Database.URL)
 	if err != nil {
 		log.Fatal("Failed to connect to the database:", err)
 	}
 	defer db.Close()
 
+	db.SetMaxOpenConns(cfg.Database.MaxOpenConns)
+	db.SetMaxIdleConns(cfg.Database.MaxIdleConns)
+	db.SetConnMaxLifetime(cfg.Database.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(cfg.Database.ConnMaxIdleTime)
+
 	// Test the database connection
 	err = db.Ping()
 	if err != nil {
@@ -42,17 +56,15 @@ func main() {
 	}
 	log.Println("Successfully connected to the database!")
 
-	// Init Redis connection
-	redisURL := os.Getenv("REDIS_URL")
-	if redisURL == "" {
-		log.Fatal("REDIS_URL environment variable is not set")
-	}
-
 	// Parse and connect to Redis
-	opt, err := redis.ParseURL(redisURL)
+	opt, err := redis.ParseURL(cfg.Redis.URL)
 	if err != nil {
 		log.Fatal("Invalid Redis URL:", err)
 	}
+	opt.PoolSize = cfg.Redis.PoolSize
+	opt.DialTimeout = cfg.Redis.DialTimeout
+	opt.ReadTimeout = cfg.Redis.ReadTimeout
+	opt.WriteTimeout = cfg.Redis.WriteTimeout
 
 	redisClient := redis.NewClient(opt)
 
@@ -67,27 +79,191 @@ func main() {
 	// Initialize repositories
 	walletRepo := repositories.NewWalletRepository(db)
 	transactionRepo := repositories.NewTransactionRepository(db)
+	snapshotRepo := repositories.NewSnapshotRepository(db)
 	customerTokenRepo := repositories.NewCustomerTokenRepository(db)
+	reconciliationRepo := repositories.NewReconciliationRepository(db)
+	webhookRepo := repositories.NewWebhookRepository(db)
+	adminKeyRepo := repositories.NewAdminKeyRepository(db)
+	auditRepo := repositories.NewAuditRepository(db)
+	adjustmentRepo := repositories.NewAdjustmentRepository(db, transactionRepo)
+	closureRepo := repositories.NewClosureRepository(db, transactionRepo)
+	riskRepo := repositories.NewRiskRepository(db, transactionRepo)
+	complianceRepo := repositories.NewComplianceRepository(db)
+	kycRepo := repositories.NewKYCRepository(db)
+	pinRepo := repositories.NewPINRepository(db)
+	standingOrderRepo := repositories.NewStandingOrderRepository(db, transactionRepo)
+	paymentRequestRepo := repositories.NewPaymentRequestRepository(db, transactionRepo)
+
+	// Customers can enroll an authenticator app once its secrets can be
+	// encrypted
+	var totpRepo repositories.TOTPRepository
+	if cfg.Wallet.TOTP.EncryptionKey != "" {
+		key, err := auth.ParseKey(cfg.Wallet.TOTP.EncryptionKey)
+		if err != nil {
+			log.Fatal("Invalid TOTP encryption key: ", err)
+		}
+		cipher, err := auth.NewCipher(key)
+		if err != nil {
+			log.Fatal("Invalid TOTP encryption key: ", err)
+		}
+		totpRepo = repositories.NewTOTPRepository(db, cipher)
+	}
+
+	// Deposits and withdrawals are checked against the risk rules, if any
+	var riskEngine *risk.Engine
+	if cfg.Risk.RulesFile != "" {
+		riskEngine, err = risk.Load(cfg.Risk.RulesFile)
+		if err != nil {
+			log.Fatal("Invalid risk rules: ", err)
+		}
+	}
+
+	// Wallet events are delivered to webhook subscribers and live streams
+	var bus events.Bus = events.NewRedisBus(redisClient)
+	if cfg.Events.Bus == config.BusMemory {
+		bus = events.NewLocalBus()
+	}
+	publisher := events.Multi{webhooks.NewDispatcher(webhookRepo), bus}
+
+	// The wallet rules are shared by the REST and gRPC APIs and the jobs
+	wallets := service.NewWalletService(walletRepo, transactionRepo, snapshotRepo, customerTokenRepo, redisClient, publisher, riskEngine, riskRepo, kycRepo, pinRepo, totpRepo, cfg.Wallet)
+	closures := service.NewClosureService(wallets, closureRepo, cfg.Wallet.ClosureRetention)
+	standingOrders := service.NewStandingOrderService(wallets, standingOrderRepo, cfg.StandingOrders)
+	paymentRequests := service.NewPaymentRequestService(wallets, paymentRequestRepo, cfg.PaymentRequests)
+	admins := service.NewAdminService(wallets, walletRepo, transactionRepo, customerTokenRepo, adminKeyRepo, auditRepo, adjustmentRepo, complianceRepo, reconciliationRepo)
 
 	// Initialize handlers
-	walletHandler := handlers.NewWalletHandler(walletRepo, transactionRepo, customerTokenRepo, redisClient)
-	initHandler := handlers.NewInitHandler(walletRepo, customerTokenRepo)
+	walletHandler := handlers.NewWalletHandler(wallets, customerTokenRepo)
+	closureHandler := handlers.NewClosureHandler(closures, customerTokenRepo)
+	standingOrderHandler := handlers.NewStandingOrderHandler(standingOrders, customerTokenRepo)
+	paymentRequestHandler := handlers.NewPaymentRequestHandler(paymentRequests, customerTokenRepo)
+	initHandler := handlers.NewInitHandler(wallets)
+	webhookHandler := handlers.NewWebhookHandler(webhookRepo, customerTokenRepo)
+	eventStreamHandler := handlers.NewEventStreamHandler(wallets, transactionRepo, customerTokenRepo, bus, cfg.Events.Heartbeat)
+	adminHandler := handlers.NewAdminHandler(admins)
+	var rateLimitHandler *handlers.RateLimitHandler
+	if cfg.RateLimit.Enabled {
+		// Requests are counted per instance while Redis is unreachable
+		var store ratelimit.Store = ratelimit.NewLocalStore()
+		if cfg.RateLimit.Store == config.RateLimitRedis {
+			store = ratelimit.Fallback(ratelimit.NewRedisStore(redisClient), store)
+		}
+		rateLimitHandler = handlers.NewRateLimitHandler(store, cfg.RateLimit)
+	}
+
+	// Start background jobs
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+	if cfg.Jobs.ReconciliationEnabled {
+		reconciler := jobs.NewReconciler(walletRepo, transactionRepo, reconciliationRepo, wallets, jobs.ReconcilerOptions{
+			AutoCorrect: cfg.Jobs.ReconciliationAutoCorrect,
+			SettleGrace: cfg.Wallet.MaxSettleDelay + cfg.Wallet.LockTTL,
+		})
+		go reconciler.Schedule(jobsCtx, cfg.Jobs.ReconciliationInterval)
+	}
+	if cfg.Jobs.SnapshotsEnabled {
+		snapshotter := jobs.NewSnapshotter(walletRepo, transactionRepo, snapshotRepo, wallets)
+		go snapshotter.Schedule(jobsCtx)
+	}
+	if cfg.Jobs.AnonymizationEnabled {
+		anonymizer := jobs.NewAnonymizer(closureRepo)
+		go anonymizer.Schedule(jobsCtx, time.Hour)
+	}
+	if cfg.Jobs.ComplianceEnabled {
+		scanner := jobs.NewComplianceScanner(walletRepo, complianceRepo, compliance.Rules{
+			Threshold:         cfg.Compliance.ReportThreshold,
+			StructuringFloor:  cfg.Compliance.StructuringFloor,
+			StructuringWindow: cfg.Compliance.StructuringWindow,
+			StructuringCount:  cfg.Compliance.StructuringCount,
+		})
+		go scanner.Schedule(jobsCtx, time.Hour)
+	}
+	if cfg.StandingOrders.Enabled {
+		scheduler := jobs.NewStandingOrderScheduler(standingOrders, cfg.StandingOrders.BatchSize)
+		go scheduler.Schedule(jobsCtx, cfg.StandingOrders.PollInterval)
+	}
+	if cfg.Webhooks.Workers > 0 {
+		worker := webhooks.NewWorker(webhookRepo, webhooks.WorkerOptions{
+			Workers:      cfg.Webhooks.Workers,
+			PollInterval: cfg.Webhooks.PollInterval,
+			BatchSize:    cfg.Webhooks.BatchSize,
+			Timeout:      cfg.Webhooks.Timeout,
+			MaxAttempts:  cfg.Webhooks.MaxAttempts,
+			BackoffBase:  cfg.Webhooks.BackoffBase,
+			BackoffMax:   cfg.Webhooks.BackoffMax,
+		})
+		go worker.Run(jobsCtx)
+	}
 
 	// Initialize the Gin router
-	router := gin.Default()
+	router := server.NewRouter(cfg.Server, server.Handlers{
+		Init:           initHandler,
+		Wallet:         walletHandler,
+		Closure:        closureHandler,
+		StandingOrder:  standingOrderHandler,
+		PaymentRequest: paymentRequestHandler,
+		Webhook:        webhookHandler,
+		Events:         eventStreamHandler,
+		Admin:          adminHandler,
+		RateLimit:      rateLimitHandler,
+	})
 
-	// Define API endpoints
-	router.POST("/api/v1/init", initHandler.Init)
-	router.POST("/api/v1/wallet", walletHandler.EnableWallet)
-	router.GET("/api/v1/wallet", walletHandler.ViewWalletBalance)
-	router.GET("/api/v1/wallet/transactions", walletHandler.ViewWalletTransactions)
-	router.POST("/api/v1/wallet/deposits", walletHandler.Deposit)
-	router.POST("/api/v1/wallet/withdrawals", walletHandler.Withdraw)
-	router.PATCH("/api/v1/wallet", walletHandler.DisableWallet)
+	httpServer := &http.Server{
+		Addr:         cfg.Server.Addr,
+		Handler:      router,
+		ReadTimeout:  cfg.Server.ReadTimeout,
+		WriteTimeout: cfg.Server.WriteTimeout,
+		IdleTimeout:  cfg.Server.IdleTimeout,
+	}
+	httpServer.RegisterOnShutdown(eventStreamHandler.Close)
 
 	// Start the server
-	log.Println("Starting server on :8080...")
-	if err := http.ListenAndServe(":8080", router); err != nil {
-		log.Fatal("Failed to start the server:", err)
+	go func() {
+		log.Printf("Starting server on %s...", cfg.Server.Addr)
+		if err := httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal("Failed to start the server:", err)
+		}
+	}()
+
+	// Serve gRPC alongside HTTP
+	var grpcServer *grpc.Server
+	if cfg.Server.GRPCAddr != "" {
+		listener, err := net.Listen("tcp", cfg.Server.GRPCAddr)
+		if err != nil {
+			log.Fatal("Failed to listen for gRPC:", err)
+		}
+		grpcServer = grpcapi.NewServer(wallets)
+		go func() {
+			log.Printf("Starting gRPC server on %s...", cfg.Server.GRPCAddr)
+			if err := grpcServer.Serve(listener); err != nil {
+				log.Fatal("Failed to start the gRPC server:", err)
+			}
+		}()
+	}
+
+	// Wait for an interrupt and drain in-flight requests
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	<-stop
+	stopJobs()
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	defer cancel()
+	if grpcServer != nil {
+		stopped := make(chan struct{})
+		go func() {
+			grpcServer.GracefulStop()
+			close(stopped)
+		}()
+		defer func() {
+			select {
+			case <-stopped:
+			case <-shutdownCtx.Done():
+				grpcServer.Stop()
+			}
+		}()
+	}
+	if err := httpServer.Shutdown(shutdownCtx); err != nil {
+		log.Println("Failed to shut down the server gracefully:", err)
 	}
 }
@@ -0,0 +1,39 @@
+package models
+
+import (
+	"time"
+)
+
+// Adjustment statuses. Only pending adjustments can be reviewed.
+const (
+	AdjustmentPending  = "pending"
+	AdjustmentApproved = "approved"
+	AdjustmentRejected = "rejected"
+)
+
+// Adjustment is a manual credit or debit of a wallet. One admin requests
+// it and another reviews it; approval posts an adjustment transaction,
+// whose ID is kept in TransactionID.
+type Adjustment struct {
+	ID       string `db:"id" json:"id"`
+	WalletID string `db:"wallet_id" json:"wallet_id"`
+	// Amount is positive for a credit and negative for a debit.
+	Amount          int64      `db:"amount" json:"amount"`
+	Reason          string     `db:"reason" json:"reason"`
+	Status          string     `db:"status" json:"status"`
+	RequestedBy     string     `db:"requested_by" json:"requested_by"`
+	RequestedByName string     `db:"requested_by_name" json:"requested_by_name"`
+	CreatedAt       time.Time  `db:"created_at" json:"created_at"`
+	ReviewedBy      string     `db:"reviewed_by" json:"reviewed_by,omitempty"`
+	ReviewedByName  string     `db:"reviewed_by_name" json:"reviewed_by_name,omitempty"`
+	ReviewNote      string     `db:"review_note" json:"review_note,omitempty"`
+	ReviewedAt      *time.Time `db:"reviewed_at" json:"reviewed_at"`
+	TransactionID   string     `db:"transaction_id" json:"transaction_id,omitempty"`
+}
+
+// AdjustmentFilter narrows an adjustment listing; empty fields match everything.
+type AdjustmentFilter struct {
+	WalletID string
+	Status   string
+	Limit    int
+}
@@ -0,0 +1,47 @@
+package models
+
+import (
+	"time"
+)
+
+// AdminKey is an admin API credential. The key itself is only shown when it
+// is created; KeyHash is what authenticates requests.
+type AdminKey struct {
+	ID        string     `db:"id" json:"id"`
+	Name      string     `db:"name" json:"name"`
+	Role      string     `db:"role" json:"role"`
+	KeyHash   string     `db:"key_hash" json:"-"`
+	CreatedAt time.Time  `db:"created_at" json:"created_at"`
+	RevokedAt *time.Time `db:"revoked_at" json:"revoked_at"`
+}
+
+// AuditEntry records one admin action, who took it and why.
+type AuditEntry struct {
+	ID         string         `db:"id" json:"id"`
+	ActorID    string         `db:"actor_id" json:"actor_id"`
+	ActorName  string         `db:"actor_name" json:"actor_name"`
+	ActorRole  string         `db:"actor_role" json:"actor_role"`
+	Action     string         `db:"action" json:"action"`
+	TargetType string         `db:"target_type" json:"target_type"`
+	TargetID   string         `db:"target_id" json:"target_id"`
+	Reason     string         `db:"reason" json:"reason"`
+	Details    map[string]any `db:"details" json:"details"`
+	CreatedAt  time.Time      `db:"created_at" json:"created_at"`
+}
+
+// AuditFilter narrows an audit log listing; empty fields match everything.
+type AuditFilter struct {
+	ActorID  string
+	TargetID string
+	Action   string
+	Limit    int
+}
+
+// CustomerSummary is a customer as admins find them, with their wallet
+// when they have one.
+type CustomerSummary struct {
+	CustomerXID    string     `json:"customer_xid"`
+	CreatedAt      time.Time  `json:"created_at"`
+	TokenRevokedAt *time.Time `json:"token_revoked_at"`
+	Wallet         *Wallet    `json:"wallet"`
+}
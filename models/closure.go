@@ -0,0 +1,20 @@
+package models
+
+import (
+	"time"
+)
+
+// WalletClosure records how a wallet was closed. A remaining balance is paid
+// out by a final withdrawal, whose ID is kept in PayoutTransactionID. The
+// customer's personal data is anonymized once AnonymizeAfter has passed.
+type WalletClosure struct {
+	ID                  string     `db:"id" json:"id"`
+	WalletID            string     `db:"wallet_id" json:"wallet_id"`
+	CustomerXID         string     `db:"customer_xid" json:"customer_xid"`
+	PayoutAmount        int64      `db:"payout_amount" json:"payout_amount"`
+	PayoutDestination   string     `db:"payout_destination" json:"payout_destination,omitempty"`
+	PayoutTransactionID string     `db:"payout_transaction_id" json:"payout_transaction_id,omitempty"`
+	ClosedAt            time.Time  `db:"closed_at" json:"closed_at"`
+	AnonymizeAfter      time.Time  `db:"anonymize_after" json:"anonymize_after"`
+	AnonymizedAt        *time.Time `db:"anonymized_at" json:"anonymized_at"`
+}
@@ -0,0 +1,50 @@
+package models
+
+import (
+	"time"
+)
+
+// Compliance report kinds.
+const (
+	// ReportLargeTransaction is one deposit or withdrawal at or above the
+	// reporting threshold.
+	ReportLargeTransaction = "large_transaction"
+	// ReportStructuring is a series of deposits or withdrawals just below
+	// the threshold within a short window, as when a large amount is split
+	// to stay under it.
+	ReportStructuring = "structuring"
+)
+
+// ComplianceReport flags transactions of one wallet for the regulator.
+// TransactionIDs are oldest first.
+type ComplianceReport struct {
+	ID                string    `db:"id" json:"id"`
+	Kind              string    `db:"kind" json:"kind"`
+	WalletID          string    `db:"wallet_id" json:"wallet_id"`
+	CustomerXID       string    `db:"customer_xid" json:"customer_xid"`
+	TransactionIDs    []string  `db:"transaction_ids" json:"transaction_ids"`
+	TotalAmount       int64     `db:"total_amount" json:"total_amount"`
+	FirstTransactedAt time.Time `db:"first_transacted_at" json:"first_transacted_at"`
+	LastTransactedAt  time.Time `db:"last_transacted_at" json:"last_transacted_at"`
+	CreatedAt         time.Time `db:"created_at" json:"created_at"`
+}
+
+// ComplianceReportFilter narrows a report listing; empty fields match
+// everything. From and To bound the last transaction of a report.
+type ComplianceReportFilter struct {
+	Kind     string
+	WalletID string
+	From     time.Time
+	To       time.Time
+	Limit    int
+}
+
+// ComplianceScan is one pass of the compliance job over the transactions
+// recorded in (WindowStart, WindowEnd].
+type ComplianceScan struct {
+	ID          string    `db:"id" json:"id"`
+	WindowStart time.Time `db:"window_start" json:"window_start"`
+	WindowEnd   time.Time `db:"window_end" json:"window_end"`
+	Reports     int       `db:"reports" json:"reports"`
+	CreatedAt   time.Time `db:"created_at" json:"created_at"`
+}
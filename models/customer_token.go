@@ -3,8 +3,9 @@ package models
 import "time"
 
 type CustomerToken struct {
-	ID			string		`db:"id"`
-	CustomerXID	string		`db:"customer_xid"`
-	Token		string		`db:"token"`
-	CreatedAt	time.Time	`db:"created_at"`
-}
\ No newline at end of file
+	ID          string     `db:"id"`
+	CustomerXID string     `db:"customer_xid"`
+	Token       string     `db:"token"`
+	CreatedAt   time.Time  `db:"created_at"`
+	RevokedAt   *time.Time `db:"revoked_at"`
+}
@@ -0,0 +1,43 @@
+package models
+
+import (
+	"time"
+)
+
+// KYC tiers. Customers start at KYCTierBasic; staff move them up once
+// their identity is verified.
+const (
+	KYCTierBasic    = 0
+	KYCTierVerified = 1
+	KYCTierFull     = 2
+)
+
+// KYC verification statuses. A customer downgraded to KYCTierBasic after
+// being verified is revoked rather than unverified.
+const (
+	KYCUnverified = "unverified"
+	KYCVerified   = "verified"
+	KYCRevoked    = "revoked"
+)
+
+// KYCDocument describes an identity document staff checked. Only its
+// metadata is kept; the document itself stays in the document store
+// under Reference.
+type KYCDocument struct {
+	Type      string     `json:"type"`
+	Reference string     `json:"reference"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	AddedAt   time.Time  `json:"added_at"`
+}
+
+// KYCProfile is what is known of a customer's identity. Its tier decides
+// what the customer's wallet may do. Customers without a stored profile
+// have the zero profile at KYCTierBasic.
+type KYCProfile struct {
+	CustomerXID string        `db:"customer_xid" json:"customer_xid"`
+	Tier        int           `db:"tier" json:"tier"`
+	Status      string        `db:"status" json:"status"`
+	VerifiedAt  *time.Time    `db:"verified_at" json:"verified_at"`
+	Documents   []KYCDocument `db:"documents" json:"documents"`
+	UpdatedAt   time.Time     `db:"updated_at" json:"updated_at"`
+}
@@ -0,0 +1,60 @@
+package models
+
+import (
+	"time"
+)
+
+// Payment request statuses. Only pending requests can be paid, declined or
+// cancelled; the others are final.
+const (
+	PaymentRequestPending   = "pending"
+	PaymentRequestPaid      = "paid"
+	PaymentRequestDeclined  = "declined"
+	PaymentRequestExpired   = "expired"
+	PaymentRequestCancelled = "cancelled"
+)
+
+// Payment request directions, as seen by one customer: incoming requests
+// ask them for money, outgoing requests are theirs.
+const (
+	DirectionIncoming = "incoming"
+	DirectionOutgoing = "outgoing"
+)
+
+// PaymentRequest asks for Amount to be paid into its customer's wallet,
+// WalletID. It is addressed to PayerCustomerXID, or carries a shareable
+// Code that lets whoever holds it pay; the payer of such a request is set
+// once it is paid. TransactionID is the payer's withdrawal.
+type PaymentRequest struct {
+	ID               string     `db:"id" json:"id"`
+	CustomerXID      string     `db:"customer_xid" json:"customer_xid"`
+	WalletID         string     `db:"wallet_id" json:"wallet_id"`
+	PayerCustomerXID string     `db:"payer_customer_xid" json:"payer_customer_xid,omitempty"`
+	Code             string     `db:"code" json:"code,omitempty"`
+	Amount           int64      `db:"amount" json:"amount"`
+	Description      string     `db:"description" json:"description"`
+	Status           string     `db:"status" json:"status"`
+	ExpiresAt        time.Time  `db:"expires_at" json:"expires_at"`
+	TransactionID    string     `db:"transaction_id" json:"transaction_id,omitempty"`
+	RespondedAt      *time.Time `db:"responded_at" json:"responded_at"`
+	CreatedAt        time.Time  `db:"created_at" json:"created_at"`
+}
+
+// Expire reports a pending request whose expiry passed by now as expired.
+// Requests are not stored as expired; they are seen so once read.
+func (r *PaymentRequest) Expire(now time.Time) {
+	if r.Status == PaymentRequestPending && !now.Before(r.ExpiresAt) {
+		r.Status = PaymentRequestExpired
+	}
+}
+
+// PaymentRequestFilter narrows a customer's payment request listing; empty
+// fields match everything.
+type PaymentRequestFilter struct {
+	CustomerXID string
+	Direction   string
+	Status      string
+	// Now tells pending requests from expired ones.
+	Now   time.Time
+	Limit int
+}
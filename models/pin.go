@@ -0,0 +1,27 @@
+package models
+
+import (
+	"time"
+)
+
+// TransactionPIN is a customer's transaction PIN, which authorizes money
+// leaving their wallet. Only its hash is kept. FailedAttempts counts the
+// wrong attempts since the last right one; once they reach the configured
+// limit the PIN is locked until LockedUntil.
+type TransactionPIN struct {
+	CustomerXID    string     `db:"customer_xid" json:"customer_xid"`
+	Hash           string     `db:"hash" json:"-"`
+	FailedAttempts int        `db:"failed_attempts" json:"failed_attempts"`
+	LockedUntil    *time.Time `db:"locked_until" json:"locked_until"`
+	UpdatedAt      time.Time  `db:"updated_at" json:"updated_at"`
+}
+
+// StepUpToken stands in for the customer's PIN once, until ExpiresAt.
+// Only the hash of the token handed to the customer is kept.
+type StepUpToken struct {
+	TokenHash   string     `db:"token_hash"`
+	CustomerXID string     `db:"customer_xid"`
+	ExpiresAt   time.Time  `db:"expires_at"`
+	UsedAt      *time.Time `db:"used_at"`
+	CreatedAt   time.Time  `db:"created_at"`
+}
@@ -0,0 +1,25 @@
+package models
+
+import (
+	"time"
+)
+
+type ReconciliationRun struct {
+	ID             string     `db:"id" json:"id"`
+	StartedAt      time.Time  `db:"started_at" json:"started_at"`
+	FinishedAt     *time.Time `db:"finished_at" json:"finished_at"`
+	WalletsChecked int        `db:"wallets_checked" json:"wallets_checked"`
+	Discrepancies  int        `db:"discrepancies" json:"discrepancies"`
+	AutoCorrect    bool       `db:"auto_correct" json:"auto_correct"`
+}
+
+type BalanceDiscrepancy struct {
+	ID             string    `db:"id" json:"id"`
+	RunID          string    `db:"run_id" json:"run_id"`
+	WalletID       string    `db:"wallet_id" json:"wallet_id"`
+	StoredBalance  int64     `db:"stored_balance" json:"stored_balance"`
+	DerivedBalance int64     `db:"derived_balance" json:"derived_balance"`
+	Drift          int64     `db:"drift" json:"drift"`
+	Corrected      bool      `db:"corrected" json:"corrected"`
+	DetectedAt     time.Time `db:"detected_at" json:"detected_at"`
+}
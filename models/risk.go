@@ -0,0 +1,50 @@
+package models
+
+import (
+	"time"
+)
+
+// Risk decision statuses. Only held decisions are reviewed; decisions
+// that allowed or blocked the transaction have no status.
+const (
+	RiskHeld     = "held"
+	RiskReleased = "released"
+	RiskRejected = "rejected"
+)
+
+// RiskMatch is a risk rule that tripped on a transaction.
+type RiskMatch struct {
+	Rule    string `json:"rule"`
+	Outcome string `json:"outcome"`
+	Reason  string `json:"reason"`
+}
+
+// RiskDecision records the risk rules that tripped on a deposit or
+// withdrawal. A held transaction is not posted until an analyst releases
+// it; it is then posted with TransactionID, reserved when it was held.
+type RiskDecision struct {
+	ID              string      `db:"id" json:"id"`
+	WalletID        string      `db:"wallet_id" json:"wallet_id"`
+	CustomerXID     string      `db:"customer_xid" json:"customer_xid"`
+	TransactionID   string      `db:"transaction_id" json:"transaction_id"`
+	TransactionType string      `db:"transaction_type" json:"transaction_type"`
+	Amount          int64       `db:"amount" json:"amount"`
+	ReferenceID     string      `db:"reference_id" json:"reference_id"`
+	Outcome         string      `db:"outcome" json:"outcome"`
+	Matches         []RiskMatch `db:"matches" json:"matches"`
+	Status          string      `db:"status" json:"status,omitempty"`
+	CreatedAt       time.Time   `db:"created_at" json:"created_at"`
+	ReviewedBy      string      `db:"reviewed_by" json:"reviewed_by,omitempty"`
+	ReviewedByName  string      `db:"reviewed_by_name" json:"reviewed_by_name,omitempty"`
+	ReviewNote      string      `db:"review_note" json:"review_note,omitempty"`
+	ReviewedAt      *time.Time  `db:"reviewed_at" json:"reviewed_at"`
+}
+
+// RiskDecisionFilter narrows a risk decision listing; empty fields match
+// everything.
+type RiskDecisionFilter struct {
+	WalletID string
+	Outcome  string
+	Status   string
+	Limit    int
+}
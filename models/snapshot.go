@@ -0,0 +1,14 @@
+package models
+
+import (
+	"time"
+)
+
+// BalanceSnapshot records a wallet's balance from the transactions recorded
+// before AsOf.
+type BalanceSnapshot struct {
+	WalletID  string    `db:"wallet_id" json:"wallet_id"`
+	AsOf      time.Time `db:"as_of" json:"as_of"`
+	Balance   int64     `db:"balance" json:"balance"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+}
@@ -0,0 +1,84 @@
+package models
+
+import (
+	"time"
+)
+
+// Standing order frequencies. A one-off order runs once, at its start.
+const (
+	FrequencyOnce    = "once"
+	FrequencyDaily   = "daily"
+	FrequencyWeekly  = "weekly"
+	FrequencyMonthly = "monthly"
+)
+
+// Standing order statuses. Only active orders run; the others are final.
+const (
+	StandingOrderActive    = "active"
+	StandingOrderCompleted = "completed"
+	StandingOrderFailed    = "failed"
+	StandingOrderCancelled = "cancelled"
+)
+
+// StandingOrder moves Amount from its customer's wallet, WalletID, to the
+// payee's wallet on a schedule: once at StartAt, or every day, week or
+// month from StartAt until EndsAt. Runs counts the occurrences executed or
+// given up so far, and NextRunAt is the one due next, empty once the order
+// is final. A run that finds too little balance is attempted again at
+// NextAttemptAt, later than NextRunAt.
+type StandingOrder struct {
+	ID               string     `db:"id" json:"id"`
+	CustomerXID      string     `db:"customer_xid" json:"customer_xid"`
+	WalletID         string     `db:"wallet_id" json:"wallet_id"`
+	PayeeCustomerXID string     `db:"payee_customer_xid" json:"payee_customer_xid"`
+	Amount           int64      `db:"amount" json:"amount"`
+	Description      string     `db:"description" json:"description"`
+	Frequency        string     `db:"frequency" json:"frequency"`
+	StartAt          time.Time  `db:"start_at" json:"start_at"`
+	EndsAt           *time.Time `db:"ends_at" json:"ends_at"`
+	Status           string     `db:"status" json:"status"`
+	Runs             int        `db:"runs" json:"runs"`
+	NextRunAt        *time.Time `db:"next_run_at" json:"next_run_at"`
+	NextAttemptAt    *time.Time `db:"next_attempt_at" json:"next_attempt_at"`
+	// Attempts counts the failed attempts of the next run.
+	Attempts  int        `db:"attempts" json:"attempts"`
+	LastError string     `db:"last_error" json:"last_error,omitempty"`
+	LastRunAt *time.Time `db:"last_run_at" json:"last_run_at"`
+	// LeaseID identifies the scheduler's claim on the order while it runs
+	// it; changes made under an older claim are refused.
+	LeaseID   string    `db:"lease_id" json:"-"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt time.Time `db:"updated_at" json:"updated_at"`
+}
+
+// Occurrence returns the time of the order's nth run, counting from 0.
+// Monthly orders keep the day of StartAt, falling on the last day of
+// months too short for it.
+func (o *StandingOrder) Occurrence(n int) time.Time {
+	switch o.Frequency {
+	case FrequencyDaily:
+		return o.StartAt.AddDate(0, 0, n)
+	case FrequencyWeekly:
+		return o.StartAt.AddDate(0, 0, 7*n)
+	case FrequencyMonthly:
+		year, month, day := o.StartAt.Date()
+		hour, minute, second := o.StartAt.Clock()
+		// Day 0 of the following month is the last day of this one
+		last := time.Date(year, month+time.Month(n)+1, 0, 0, 0, 0, 0, o.StartAt.Location()).Day()
+		return time.Date(year, month+time.Month(n), min(day, last), hour, minute, second, o.StartAt.Nanosecond(), o.StartAt.Location())
+	}
+	return o.StartAt
+}
+
+// Next returns the occurrence following the runs so far, or nil when the
+// schedule has none left.
+func (o *StandingOrder) Next() *time.Time {
+	if o.Frequency == FrequencyOnce && o.Runs > 0 {
+		return nil
+	}
+	next := o.Occurrence(o.Runs)
+	if o.EndsAt != nil && next.After(*o.EndsAt) {
+		return nil
+	}
+	return &next
+}
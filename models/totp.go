@@ -0,0 +1,31 @@
+package models
+
+import (
+	"time"
+)
+
+// TOTPEnrollment is a customer's authenticator app enrollment. It takes
+// effect once the customer confirms it with a first code. The secret is
+// kept encrypted and the recovery codes as hashes.
+type TOTPEnrollment struct {
+	CustomerXID string `db:"customer_xid" json:"customer_xid"`
+	Secret      []byte `db:"secret" json:"-"`
+	// LastCounter is the time step of the last code accepted; codes of
+	// that step or earlier are refused as replays.
+	LastCounter   int64    `db:"last_counter" json:"-"`
+	RecoveryCodes []string `db:"recovery_codes" json:"-"`
+	// FailedAttempts counts wrong codes in a row; enough of them lock the
+	// enrollment until LockedUntil.
+	FailedAttempts int        `db:"failed_attempts" json:"-"`
+	LockedUntil    *time.Time `db:"locked_until" json:"locked_until"`
+	ConfirmedAt    *time.Time `db:"confirmed_at" json:"confirmed_at"`
+	CreatedAt      time.Time  `db:"created_at" json:"created_at"`
+}
+
+// TOTPSetup is what a customer needs to add an enrollment to their
+// authenticator app. It is shown once.
+type TOTPSetup struct {
+	Secret          string   `json:"secret"`
+	ProvisioningURI string   `json:"provisioning_uri"`
+	RecoveryCodes   []string `json:"recovery_codes"`
+}
@@ -1,24 +1,72 @@
 package models
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
 	"time"
 )
 
 type Transaction struct {
-	ID          string    `db:"id" json:"id"`
-    WalletID    string    `db:"wallet_id" json:"wallet_id"`
-    Type        string    `db:"type" json:"type"` // 'deposit' or 'withdrawal'
-    Status      string    `db:"status" json:"status"`
-    Amount      int64     `db:"amount" json:"amount"`
-    ReferenceID string    `db:"reference_id" json:"reference_id"`
-    TransactedAt   time.Time `db:"created_at" json:"transacted_at"`
+	ID           string    `db:"id" json:"id"`
+	WalletID     string    `db:"wallet_id" json:"wallet_id"`
+	Type         string    `db:"type" json:"type"` // 'deposit', 'withdrawal', 'adjustment', 'transfer_out' or 'transfer_in'
+	Status       string    `db:"status" json:"status"`
+	Amount       int64     `db:"amount" json:"amount"`
+	ReferenceID  string    `db:"reference_id" json:"reference_id"`
+	TransactedAt time.Time `db:"created_at" json:"transacted_at"`
+	// Sequence, PrevHash and Hash link the transaction into its wallet's
+	// hash chain. The repository sets them when the transaction is written;
+	// they are empty for transactions written before the chain existed.
+	Sequence int64  `db:"sequence" json:"sequence,omitempty"`
+	PrevHash string `db:"prev_hash" json:"prev_hash,omitempty"`
+	Hash     string `db:"hash" json:"hash,omitempty"`
+}
+
+// Transfer moves money between two customers' wallets: a transfer_out from
+// the payer's and a transfer_in to the payee's, posted together or not at
+// all. Neither is a cash movement, so they are kept apart from deposits and
+// withdrawals.
+type Transfer struct {
+	Out *Transaction
+	In  *Transaction
 }
 
 type TransactionDTO struct {
-	ID          string    `json:"id"`
-	Status      string    `json:"status"`
+	ID           string    `json:"id"`
+	Status       string    `json:"status"`
 	TransactedAt time.Time `json:"transacted_at"`
-	Type        string    `json:"type"`
-	Amount      int64     `json:"amount"`
-	ReferenceID string    `json:"reference_id"`
-}
\ No newline at end of file
+	Type         string    `json:"type"`
+	Amount       int64     `json:"amount"`
+	ReferenceID  string    `json:"reference_id"`
+}
+
+// ChainHash computes the transaction's hash chain link: a hex SHA-256 over
+// the previous link, the sequence and every content field.
+func (t Transaction) ChainHash() string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\n%d\n%s\n%s\n%s\n%s\n%d\n%s\n%s", t.PrevHash, t.Sequence, t.ID, t.WalletID, t.Type, t.Status,
+		t.Amount, t.ReferenceID, t.TransactedAt.UTC().Format(time.RFC3339Nano))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// SignedAmount is the transaction's effect on the wallet balance. Adjustment
+// amounts are stored signed, negative for debits.
+func (t Transaction) SignedAmount() int64 {
+	switch t.Type {
+	case "deposit", "adjustment", "transfer_in":
+		return t.Amount
+	case "withdrawal", "transfer_out":
+		return -t.Amount
+	}
+	return 0
+}
+
+// Balance derives a wallet balance from its transaction log.
+func Balance(transactions []Transaction) int64 {
+	var balance int64
+	for _, t := range transactions {
+		balance += t.SignedAmount()
+	}
+	return balance
+}
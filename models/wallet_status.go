@@ -0,0 +1,38 @@
+package models
+
+import (
+	"time"
+)
+
+// Wallet statuses. A wallet starts pending and moves between them as the
+// service's status rules allow; closed is final.
+const (
+	WalletPending   = "pending"
+	WalletEnabled   = "enabled"
+	WalletFrozen    = "frozen"
+	WalletSuspended = "suspended"
+	WalletDisabled  = "disabled"
+	WalletClosed    = "closed"
+)
+
+// WalletStatuses lists every wallet status in a stable order.
+var WalletStatuses = []string{WalletPending, WalletEnabled, WalletFrozen, WalletSuspended, WalletDisabled, WalletClosed}
+
+// Who changed a wallet's status.
+const (
+	ActorCustomer = "customer"
+	ActorAdmin    = "admin"
+	ActorSystem   = "system"
+)
+
+// WalletStatusChange is one entry of a wallet's status history.
+type WalletStatusChange struct {
+	ID         string    `db:"id" json:"id"`
+	WalletID   string    `db:"wallet_id" json:"wallet_id"`
+	FromStatus string    `db:"from_status" json:"from_status"`
+	ToStatus   string    `db:"to_status" json:"to_status"`
+	Reason     string    `db:"reason" json:"reason"`
+	ActorType  string    `db:"actor_type" json:"actor_type"`
+	ActorID    string    `db:"actor_id" json:"actor_id"`
+	ChangedAt  time.Time `db:"changed_at" json:"changed_at"`
+}
@@ -0,0 +1,41 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type WebhookSubscription struct {
+	ID          string    `db:"id" json:"id"`
+	CustomerXID string    `db:"customer_xid" json:"customer_xid"`
+	URL         string    `db:"url" json:"url"`
+	Secret      string    `db:"secret" json:"-"`
+	EventTypes  []string  `db:"event_types" json:"events"`
+	CreatedAt   time.Time `db:"created_at" json:"created_at"`
+}
+
+// Delivery statuses
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	DeliveryDead      = "dead"
+)
+
+type WebhookDelivery struct {
+	ID             string          `db:"id" json:"id"`
+	SubscriptionID string          `db:"subscription_id" json:"subscription_id"`
+	EventID        string          `db:"event_id" json:"event_id"`
+	EventType      string          `db:"event_type" json:"event_type"`
+	Payload        json.RawMessage `db:"payload" json:"payload"`
+	Status         string          `db:"status" json:"status"`
+	Attempts       int             `db:"attempts" json:"attempts"`
+	NextAttemptAt  time.Time       `db:"next_attempt_at" json:"next_attempt_at"`
+	LastError      string          `db:"last_error" json:"last_error,omitempty"`
+	LastStatusCode int             `db:"last_status_code" json:"last_status_code,omitempty"`
+	CreatedAt      time.Time       `db:"created_at" json:"created_at"`
+	DeliveredAt    *time.Time      `db:"delivered_at" json:"delivered_at"`
+
+	// URL and Secret are joined from the subscription when claiming work.
+	URL    string `db:"-" json:"-"`
+	Secret string `db:"-" json:"-"`
+}
@@ -0,0 +1,118 @@
+// Package pin hashes and checks customers' transaction PINs, and issues
+// the step-up tokens that stand in for a PIN for a short while.
+package pin
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// Length is the number of digits of a PIN.
+const Length = 6
+
+// Argon2id parameters, from the OWASP password storage recommendations.
+const (
+	argonTime    = 2
+	argonMemory  = 19 * 1024
+	argonThreads = 1
+	argonKeyLen  = 32
+	saltLen      = 16
+)
+
+// Valid reports whether p is Length digits and not trivially guessable:
+// one repeated digit, or an ascending or descending run such as 123456.
+func Valid(p string) bool {
+	if len(p) != Length {
+		return false
+	}
+	for _, r := range p {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	repeated, ascending, descending := true, true, true
+	for i := 1; i < len(p); i++ {
+		step := int(p[i]) - int(p[i-1])
+		repeated = repeated && step == 0
+		ascending = ascending && step == 1
+		descending = descending && step == -1
+	}
+	return !repeated && !ascending && !descending
+}
+
+// Hash returns the argon2id hash of p in the PHC string format, with a
+// random salt.
+func Hash(p string) (string, error) {
+	salt := make([]byte, saltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := argon2.IDKey([]byte(p), salt, argonTime, argonMemory, argonThreads, argonKeyLen)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version, argonMemory, argonTime, argonThreads,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+// Verify reports whether p matches hash, which was returned by Hash. The
+// parameters are read from hash, so hashes stay verifiable when they change.
+func Verify(hash, p string) bool {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return false
+	}
+	var version int
+	var memory, iterations uint32
+	var threads uint8
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return false
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &memory, &iterations, &threads); err != nil {
+		return false
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return false
+	}
+	want, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return false
+	}
+	got := argon2.IDKey([]byte(p), salt, iterations, memory, threads, uint32(len(want)))
+	return subtle.ConstantTimeCompare(got, want) == 1
+}
+
+// Lockout is how long a PIN stays locked after failures consecutive wrong
+// attempts: nothing below maxAttempts, then base, doubling with each
+// further failure up to max.
+func Lockout(failures, maxAttempts int, base, max time.Duration) time.Duration {
+	if failures < maxAttempts {
+		return 0
+	}
+	lockout := base
+	for i := maxAttempts; i < failures && lockout < max; i++ {
+		lockout *= 2
+	}
+	return min(lockout, max)
+}
+
+// NewToken returns a random step-up token. Only its HashToken is stored.
+func NewToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// HashToken is the stored form of a step-up token.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
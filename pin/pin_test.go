@@ -0,0 +1,68 @@
+package pin
+
+import (
+	"testing"
+	"time"
+)
+
+func TestValid(t *testing.T) {
+	tests := []struct {
+		pin  string
+		want bool
+	}{
+		{"294751", true},
+		{"100000", true},
+		{"12345", false},
+		{"1234567", false},
+		{"12a456", false},
+		{"777777", false},
+		{"123456", false},
+		{"987654", false},
+	}
+	for _, tt := range tests {
+		if got := Valid(tt.pin); got != tt.want {
+			t.Errorf("Valid(%q) = %v, want %v", tt.pin, got, tt.want)
+		}
+	}
+}
+
+func TestHashAndVerify(t *testing.T) {
+	hash, err := Hash("294751")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !Verify(hash, "294751") {
+		t.Error("Verify rejected the PIN it was hashed from")
+	}
+	if Verify(hash, "294752") {
+		t.Error("Verify accepted a wrong PIN")
+	}
+	if other, _ := Hash("294751"); other == hash {
+		t.Error("two hashes of one PIN are equal, want a random salt")
+	}
+	for _, malformed := range []string{"", "294751", "$argon2id$v=19$m=1,t=1,p=1$!!$!!", "$bcrypt$v=19$m=1,t=1,p=1$AA$AA"} {
+		if Verify(malformed, "294751") {
+			t.Errorf("Verify accepted malformed hash %q", malformed)
+		}
+	}
+}
+
+func TestLockout(t *testing.T) {
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{0, 0},
+		{4, 0},
+		{5, time.Minute},
+		{6, 2 * time.Minute},
+		{8, 8 * time.Minute},
+		{20, time.Hour},
+		{1000, time.Hour},
+	}
+	for _, tt := range tests {
+		if got := Lockout(tt.failures, 5, time.Minute, time.Hour); got != tt.want {
+			t.Errorf("Lockout(%d) = %v, want %v", tt.failures, got, tt.want)
+		}
+	}
+}
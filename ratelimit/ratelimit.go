@@ -0,0 +1,90 @@
+// Package ratelimit counts requests against sliding-window budgets, in
+// Redis for limits shared by every instance or in memory for one.
+package ratelimit
+
+import (
+	"context"
+	"log"
+	"math"
+	"sync/atomic"
+	"time"
+)
+
+// Limit allows Requests within any Window.
+type Limit struct {
+	Requests int           `yaml:"requests"`
+	Window   time.Duration `yaml:"window"`
+}
+
+// Result is the state of a budget after a request was counted against it.
+type Result struct {
+	Allowed bool
+	Limit   int
+	// Remaining is how many more requests the budget allows now.
+	Remaining int
+	// Reset is how long until the current window ends and the budget
+	// regains room, rounded up to a second.
+	Reset time.Duration
+}
+
+// Store counts requests against budgets.
+type Store interface {
+	// Take counts cost requests against the budget of key when it has room
+	// for one more. A cost of 0 tells whether it has without counting.
+	Take(ctx context.Context, key string, limit Limit, cost int, now time.Time) (Result, error)
+}
+
+// The sliding window is approximated from two fixed windows: the count of
+// the previous one weighs in proportion to how much of it the sliding
+// window still covers. This keeps two counters per key, whatever the limit.
+
+// window returns the index of the fixed window now falls in, the weight of
+// the previous window and the time left in the current one.
+func window(limit Limit, now time.Time) (index int64, weight float64, left time.Duration) {
+	size := limit.Window.Nanoseconds()
+	elapsed := now.UnixNano() % size
+	return now.UnixNano() / size, 1 - float64(elapsed)/float64(size), time.Duration(size - elapsed)
+}
+
+// allows reports whether the weighted count leaves room for one more
+// request.
+func allows(limit Limit, previous, current int, weight float64) bool {
+	return float64(previous)*weight+float64(current)+1 <= float64(limit.Requests)
+}
+
+// result describes the budget once current includes the request, if it
+// was allowed.
+func result(limit Limit, allowed bool, previous, current int, weight float64, left time.Duration) Result {
+	used := int(math.Ceil(float64(previous)*weight + float64(current)))
+	return Result{
+		Allowed:   allowed,
+		Limit:     limit.Requests,
+		Remaining: max(limit.Requests-used, 0),
+		Reset:     (left + time.Second - 1).Truncate(time.Second),
+	}
+}
+
+// Fallback counts in primary, and in secondary while primary fails, so
+// requests stay limited per instance when Redis is unreachable.
+func Fallback(primary, secondary Store) Store {
+	return &fallback{primary: primary, secondary: secondary}
+}
+
+type fallback struct {
+	primary, secondary Store
+	failing            atomic.Bool
+}
+
+func (f *fallback) Take(ctx context.Context, key string, limit Limit, cost int, now time.Time) (Result, error) {
+	res, err := f.primary.Take(ctx, key, limit, cost, now)
+	if err == nil {
+		if f.failing.Swap(false) {
+			log.Println("Rate limits are shared again")
+		}
+		return res, nil
+	}
+	if !f.failing.Swap(true) {
+		log.Printf("Rate limits fall back to this instance: %v", err)
+	}
+	return f.secondary.Take(ctx, key, limit, cost, now)
+}
@@ -0,0 +1,83 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestLocalStoreSlidingWindow(t *testing.T) {
+	store := NewLocalStore()
+	limit := Limit{Requests: 3, Window: time.Minute}
+	start := time.Unix(600, 0)
+	take := func(at time.Duration, cost int) Result {
+		t.Helper()
+		res, err := store.Take(context.Background(), "key", limit, cost, start.Add(at))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return res
+	}
+
+	for want := 2; want >= 0; want-- {
+		if res := take(0, 1); !res.Allowed || res.Remaining != want {
+			t.Fatalf("request = %+v, want allowed with %d remaining", res, want)
+		}
+	}
+	if res := take(10*time.Second, 1); res.Allowed || res.Reset != 50*time.Second {
+		t.Errorf("request over the limit = %+v, want refused until the window ends in 50s", res)
+	}
+	if res := take(10*time.Second, 0); res.Allowed {
+		t.Errorf("peek over the limit = %+v, want refused", res)
+	}
+
+	// Halfway through the next window, half the previous requests count
+	if res := take(90*time.Second, 1); !res.Allowed || res.Remaining != 0 {
+		t.Errorf("request halfway = %+v, want allowed with none remaining", res)
+	}
+	if res := take(90*time.Second, 1); res.Allowed {
+		t.Errorf("second request halfway = %+v, want refused", res)
+	}
+
+	if res := take(180*time.Second, 1); !res.Allowed || res.Remaining != 2 {
+		t.Errorf("request two windows later = %+v, want a fresh budget", res)
+	}
+}
+
+func TestLocalStorePeekDoesNotCount(t *testing.T) {
+	store := NewLocalStore()
+	limit := Limit{Requests: 1, Window: time.Minute}
+	now := time.Now()
+
+	for i := 0; i < 3; i++ {
+		if res, _ := store.Take(context.Background(), "key", limit, 0, now); !res.Allowed {
+			t.Fatalf("peek %d = %+v, want allowed", i, res)
+		}
+	}
+	if res, _ := store.Take(context.Background(), "key", limit, 1, now); !res.Allowed {
+		t.Errorf("request after peeks = %+v, want allowed", res)
+	}
+	if res, _ := store.Take(context.Background(), "other", limit, 1, now); !res.Allowed {
+		t.Errorf("request of another key = %+v, want a budget of its own", res)
+	}
+}
+
+type failingStore struct{}
+
+func (failingStore) Take(context.Context, string, Limit, int, time.Time) (Result, error) {
+	return Result{}, errors.New("connection refused")
+}
+
+func TestFallback(t *testing.T) {
+	store := Fallback(failingStore{}, NewLocalStore())
+	limit := Limit{Requests: 1, Window: time.Minute}
+	now := time.Now()
+
+	if res, err := store.Take(context.Background(), "key", limit, 1, now); err != nil || !res.Allowed {
+		t.Fatalf("first request = %+v, %v, want allowed by the fallback", res, err)
+	}
+	if res, err := store.Take(context.Background(), "key", limit, 1, now); err != nil || res.Allowed {
+		t.Errorf("second request = %+v, %v, want refused by the fallback", res, err)
+	}
+}
@@ -0,0 +1,110 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// LocalStore counts within this process only, for deployments running a
+// single instance and as the fallback of RedisStore.
+type LocalStore struct {
+	mu       sync.Mutex
+	counters map[string]*counter
+	swept    time.Time
+}
+
+// counter holds the counts of the current and the previous fixed window.
+type counter struct {
+	index             int64
+	previous, current int
+	expires           time.Time
+}
+
+func NewLocalStore() *LocalStore {
+	return &LocalStore{counters: make(map[string]*counter)}
+}
+
+func (s *LocalStore) Take(_ context.Context, key string, limit Limit, cost int, now time.Time) (Result, error) {
+	index, weight, left := window(limit, now)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sweep(now)
+	c, ok := s.counters[key]
+	if !ok {
+		c = &counter{index: index}
+		s.counters[key] = c
+	}
+	switch {
+	case c.index == index-1:
+		c.index, c.previous, c.current = index, c.current, 0
+	case c.index < index-1:
+		c.index, c.previous, c.current = index, 0, 0
+	}
+	allowed := allows(limit, c.previous, c.current, weight)
+	if allowed {
+		c.current += cost
+	}
+	// Both windows are over once the next one ends
+	c.expires = now.Add(left + limit.Window)
+	return result(limit, allowed, c.previous, c.current, weight, left), nil
+}
+
+// sweep drops the counters of keys idle for two windows, once a minute.
+func (s *LocalStore) sweep(now time.Time) {
+	if now.Sub(s.swept) < time.Minute {
+		return
+	}
+	s.swept = now
+	for key, c := range s.counters {
+		if now.After(c.expires) {
+			delete(s.counters, key)
+		}
+	}
+}
+
+// RedisStore counts in Redis so every instance shares the budgets. Each
+// key keeps one counter per fixed window, expiring once the window after
+// it ends.
+type RedisStore struct {
+	client *redis.Client
+}
+
+func NewRedisStore(client *redis.Client) *RedisStore {
+	return &RedisStore{client: client}
+}
+
+// takeScript reads both counters and increments the current one by
+// ARGV[3] when the weighted count ARGV[2] leaves room under ARGV[1], in
+// one step so concurrent requests cannot overshoot the limit.
+var takeScript = redis.NewScript(`
+local previous = tonumber(redis.call('GET', KEYS[1]) or '0')
+local current = tonumber(redis.call('GET', KEYS[2]) or '0')
+local allowed = 0
+if previous * tonumber(ARGV[2]) + current + 1 <= tonumber(ARGV[1]) then
+	allowed = 1
+	if tonumber(ARGV[3]) > 0 then
+		current = redis.call('INCRBY', KEYS[2], ARGV[3])
+		redis.call('PEXPIRE', KEYS[2], ARGV[4])
+	end
+end
+return {allowed, previous, current}
+`)
+
+func (s *RedisStore) Take(ctx context.Context, key string, limit Limit, cost int, now time.Time) (Result, error) {
+	index, weight, left := window(limit, now)
+	keys := []string{
+		fmt.Sprintf("ratelimit:%s:%d", key, index-1),
+		fmt.Sprintf("ratelimit:%s:%d", key, index),
+	}
+	ttl := (left + limit.Window).Milliseconds()
+	values, err := takeScript.Run(ctx, s.client, keys, limit.Requests, weight, cost, ttl).Int64Slice()
+	if err != nil {
+		return Result{}, err
+	}
+	return result(limit, values[0] == 1, int(values[1]), int(values[2]), weight, left), nil
+}
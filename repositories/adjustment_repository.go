@@ -0,0 +1,144 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"mini-wallet/models"
+)
+
+type AdjustmentRepository interface {
+	CreateAdjustment(adjustment *models.Adjustment) error
+	GetAdjustment(id string) (*models.Adjustment, error)
+	// ListAdjustments returns the adjustments matching filter, newest first.
+	ListAdjustments(filter models.AdjustmentFilter) ([]models.Adjustment, error)
+	// ApproveAdjustment records the review of a pending adjustment, posts
+	// transaction, applies it to the stored balance and writes entry to the
+	// audit log in one database transaction. It returns sql.ErrNoRows when
+	// the adjustment is no longer pending, and ErrInsufficientBalance when
+	// a debit exceeds the balance.
+	ApproveAdjustment(adjustment *models.Adjustment, transaction *models.Transaction, entry *models.AuditEntry) error
+	// RejectAdjustment records the review of a pending adjustment, or
+	// returns sql.ErrNoRows when it is no longer pending.
+	RejectAdjustment(adjustment *models.Adjustment) error
+}
+
+type adjustmentRepository struct {
+	db              *sql.DB
+	transactionRepo TransactionRepository
+}
+
+// NewAdjustmentRepository posts approved adjustments through transactionRepo,
+// so they join the wallet's hash chain like any other transaction.
+func NewAdjustmentRepository(db *sql.DB, transactionRepo TransactionRepository) AdjustmentRepository {
+	return &adjustmentRepository{db: db, transactionRepo: transactionRepo}
+}
+
+const adjustmentColumns = `id, wallet_id, amount, reason, status, requested_by, requested_by_name, created_at,
+	reviewed_by, reviewed_by_name, review_note, reviewed_at, transaction_id`
+
+func scanAdjustment(scan func(dest ...any) error) (*models.Adjustment, error) {
+	var adjustment models.Adjustment
+	var reviewedBy, reviewedByName, reviewNote, transactionID sql.NullString
+	var reviewedAt sql.NullTime
+	err := scan(&adjustment.ID, &adjustment.WalletID, &adjustment.Amount, &adjustment.Reason, &adjustment.Status,
+		&adjustment.RequestedBy, &adjustment.RequestedByName, &adjustment.CreatedAt,
+		&reviewedBy, &reviewedByName, &reviewNote, &reviewedAt, &transactionID)
+	if err != nil {
+		return nil, err
+	}
+	adjustment.ReviewedBy = reviewedBy.String
+	adjustment.ReviewedByName = reviewedByName.String
+	adjustment.ReviewNote = reviewNote.String
+	adjustment.TransactionID = transactionID.String
+	if reviewedAt.Valid {
+		adjustment.ReviewedAt = &reviewedAt.Time
+	}
+	return &adjustment, nil
+}
+
+func (r *adjustmentRepository) CreateAdjustment(adjustment *models.Adjustment) error {
+	query := `INSERT INTO balance_adjustments (id, wallet_id, amount, reason, status, requested_by, requested_by_name, created_at)
+			  VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+	_, err := r.db.Exec(query, adjustment.ID, adjustment.WalletID, adjustment.Amount, adjustment.Reason, adjustment.Status,
+		adjustment.RequestedBy, adjustment.RequestedByName, adjustment.CreatedAt)
+	return err
+}
+
+func (r *adjustmentRepository) GetAdjustment(id string) (*models.Adjustment, error) {
+	query := `SELECT ` + adjustmentColumns + ` FROM balance_adjustments WHERE id = $1`
+	return scanAdjustment(r.db.QueryRow(query, id).Scan)
+}
+
+func (r *adjustmentRepository) ListAdjustments(filter models.AdjustmentFilter) ([]models.Adjustment, error) {
+	var conditions []string
+	var args []any
+	if filter.WalletID != "" {
+		args = append(args, filter.WalletID)
+		conditions = append(conditions, fmt.Sprintf("wallet_id = $%d", len(args)))
+	}
+	if filter.Status != "" {
+		args = append(args, filter.Status)
+		conditions = append(conditions, fmt.Sprintf("status = $%d", len(args)))
+	}
+	query := `SELECT ` + adjustmentColumns + ` FROM balance_adjustments`
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	args = append(args, filter.Limit)
+	query += fmt.Sprintf(" ORDER BY created_at DESC LIMIT $%d", len(args))
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var adjustments []models.Adjustment
+	for rows.Next() {
+		adjustment, err := scanAdjustment(rows.Scan)
+		if err != nil {
+			return nil, err
+		}
+		adjustments = append(adjustments, *adjustment)
+	}
+	return adjustments, rows.Err()
+}
+
+// review moves a pending adjustment to its reviewed status. Matching on the
+// pending status makes concurrent reviews of one adjustment fail but one.
+func review(exec func(query string, args ...any) (sql.Result, error), adjustment *models.Adjustment) error {
+	query := `UPDATE balance_adjustments
+			  SET status = $1, reviewed_by = $2, reviewed_by_name = $3, review_note = $4, reviewed_at = $5, transaction_id = $6
+			  WHERE id = $7 AND status = 'pending'`
+	transactionID := sql.NullString{String: adjustment.TransactionID, Valid: adjustment.TransactionID != ""}
+	return affectingOne(exec(query, adjustment.Status, adjustment.ReviewedBy, adjustment.ReviewedByName, adjustment.ReviewNote,
+		adjustment.ReviewedAt, transactionID, adjustment.ID))
+}
+
+func (r *adjustmentRepository) ApproveAdjustment(adjustment *models.Adjustment, transaction *models.Transaction, entry *models.AuditEntry) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := review(tx.Exec, adjustment); err != nil {
+		return err
+	}
+	if err := r.transactionRepo.CreateTransactionWithTx(tx, transaction); err != nil {
+		return err
+	}
+	if err := applyBalance(tx, transaction.WalletID, transaction.Amount); err != nil {
+		return err
+	}
+	if err := insertAuditEntry(tx.Exec, entry); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *adjustmentRepository) RejectAdjustment(adjustment *models.Adjustment) error {
+	return review(r.db.Exec, adjustment)
+}
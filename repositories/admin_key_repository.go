@@ -0,0 +1,73 @@
+package repositories
+
+import (
+	"database/sql"
+
+	"mini-wallet/models"
+)
+
+type AdminKeyRepository interface {
+	CreateKey(key *models.AdminKey) error
+	// GetActiveKeyByHash returns the unrevoked key with the given hash, or
+	// sql.ErrNoRows.
+	GetActiveKeyByHash(keyHash string) (*models.AdminKey, error)
+	ListKeys() ([]models.AdminKey, error)
+	RevokeKey(id string) error
+}
+
+type adminKeyRepository struct {
+	db *sql.DB
+}
+
+func NewAdminKeyRepository(db *sql.DB) AdminKeyRepository {
+	return &adminKeyRepository{db: db}
+}
+
+func (r *adminKeyRepository) CreateKey(key *models.AdminKey) error {
+	query := `INSERT INTO admin_api_keys (id, name, role, key_hash, created_at) VALUES ($1, $2, $3, $4, $5)`
+	_, err := r.db.Exec(query, key.ID, key.Name, key.Role, key.KeyHash, key.CreatedAt)
+	return err
+}
+
+func (r *adminKeyRepository) GetActiveKeyByHash(keyHash string) (*models.AdminKey, error) {
+	query := `SELECT id, name, role, key_hash, created_at, revoked_at FROM admin_api_keys
+			  WHERE key_hash = $1 AND revoked_at IS NULL`
+	return scanAdminKey(r.db.QueryRow(query, keyHash).Scan)
+}
+
+func (r *adminKeyRepository) ListKeys() ([]models.AdminKey, error) {
+	query := `SELECT id, name, role, key_hash, created_at, revoked_at FROM admin_api_keys ORDER BY created_at`
+	rows, err := r.db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []models.AdminKey
+	for rows.Next() {
+		key, err := scanAdminKey(rows.Scan)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, *key)
+	}
+	return keys, rows.Err()
+}
+
+func (r *adminKeyRepository) RevokeKey(id string) error {
+	query := `UPDATE admin_api_keys SET revoked_at = NOW() WHERE id = $1 AND revoked_at IS NULL`
+	return execAffectingOne(r.db, query, id)
+}
+
+func scanAdminKey(scan func(dest ...any) error) (*models.AdminKey, error) {
+	var key models.AdminKey
+	var revokedAt sql.NullTime
+	err := scan(&key.ID, &key.Name, &key.Role, &key.KeyHash, &key.CreatedAt, &revokedAt)
+	if err != nil {
+		return nil, err
+	}
+	if revokedAt.Valid {
+		key.RevokedAt = &revokedAt.Time
+	}
+	return &key, nil
+}
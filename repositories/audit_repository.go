@@ -0,0 +1,81 @@
+package repositories
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"mini-wallet/models"
+)
+
+type AuditRepository interface {
+	CreateEntry(entry *models.AuditEntry) error
+	// ListEntries returns the entries matching filter, newest first.
+	ListEntries(filter models.AuditFilter) ([]models.AuditEntry, error)
+}
+
+type auditRepository struct {
+	db *sql.DB
+}
+
+func NewAuditRepository(db *sql.DB) AuditRepository {
+	return &auditRepository{db: db}
+}
+
+func (r *auditRepository) CreateEntry(entry *models.AuditEntry) error {
+	return insertAuditEntry(r.db.Exec, entry)
+}
+
+// insertAuditEntry writes entry through exec, so other repositories can
+// audit a change in the database transaction that makes it.
+func insertAuditEntry(exec func(query string, args ...any) (sql.Result, error), entry *models.AuditEntry) error {
+	details, err := json.Marshal(entry.Details)
+	if err != nil {
+		return err
+	}
+	query := `INSERT INTO admin_audit_log (id, actor_id, actor_name, actor_role, action, target_type, target_id, reason, details, created_at)
+			  VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`
+	_, err = exec(query, entry.ID, entry.ActorID, entry.ActorName, entry.ActorRole, entry.Action, entry.TargetType, entry.TargetID,
+		entry.Reason, details, entry.CreatedAt)
+	return err
+}
+
+func (r *auditRepository) ListEntries(filter models.AuditFilter) ([]models.AuditEntry, error) {
+	var conditions []string
+	var args []any
+	for column, value := range map[string]string{"actor_id": filter.ActorID, "target_id": filter.TargetID, "action": filter.Action} {
+		if value != "" {
+			args = append(args, value)
+			conditions = append(conditions, fmt.Sprintf("%s = $%d", column, len(args)))
+		}
+	}
+	query := `SELECT id, actor_id, actor_name, actor_role, action, target_type, target_id, reason, details, created_at FROM admin_audit_log`
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	args = append(args, filter.Limit)
+	query += fmt.Sprintf(" ORDER BY created_at DESC LIMIT $%d", len(args))
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []models.AuditEntry
+	for rows.Next() {
+		var entry models.AuditEntry
+		var details []byte
+		err := rows.Scan(&entry.ID, &entry.ActorID, &entry.ActorName, &entry.ActorRole, &entry.Action, &entry.TargetType, &entry.TargetID,
+			&entry.Reason, &details, &entry.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(details, &entry.Details); err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
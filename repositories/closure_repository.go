@@ -0,0 +1,190 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"mini-wallet/models"
+)
+
+type ClosureRepository interface {
+	// CloseWallet records the closure in one database transaction: it moves
+	// the wallet to closed as change describes, appends the payout to the
+	// transaction log when there is one, zeroes the stored balance, revokes
+	// the customer's tokens and cancels their standing orders and the
+	// pending payment requests they sent or were sent. It returns
+	// sql.ErrNoRows when the wallet is no longer in change.FromStatus, and
+	// ErrBalanceChanged when the balance derived from the transaction log
+	// under the wallet's lock is not closure.PayoutAmount.
+	CloseWallet(wallet *models.Wallet, change *models.WalletStatusChange, payout *models.Transaction, closure *models.WalletClosure) error
+	// GetClosure returns the closure of a wallet, or sql.ErrNoRows.
+	GetClosure(walletID string) (*models.WalletClosure, error)
+	// ListDueClosures returns up to limit closures not yet anonymized whose
+	// retention ended before the given time, oldest first.
+	ListDueClosures(before time.Time, limit int) ([]models.WalletClosure, error)
+	// AnonymizeClosure replaces the customer_xid of a closed wallet with
+	// pseudonym wherever it is kept, standing orders and payment requests
+	// to and from the customer included, deletes the customer's tokens, KYC
+	// profile, transaction PIN, authenticator app enrollment, webhook
+	// subscriptions, payout destination and the descriptions of their
+	// standing orders and payment requests, and marks the closure
+	// anonymized. It returns sql.ErrNoRows when it already was.
+	AnonymizeClosure(closure *models.WalletClosure, pseudonym string, at time.Time) error
+}
+
+type closureRepository struct {
+	db              *sql.DB
+	transactionRepo TransactionRepository
+}
+
+func NewClosureRepository(db *sql.DB, transactionRepo TransactionRepository) ClosureRepository {
+	return &closureRepository{db: db, transactionRepo: transactionRepo}
+}
+
+const closureColumns = `id, wallet_id, customer_xid, payout_amount, payout_destination, payout_transaction_id, closed_at, anonymize_after, anonymized_at`
+
+func scanClosure(scan func(dest ...any) error) (*models.WalletClosure, error) {
+	var closure models.WalletClosure
+	var transactionID sql.NullString
+	var anonymizedAt sql.NullTime
+	err := scan(&closure.ID, &closure.WalletID, &closure.CustomerXID, &closure.PayoutAmount, &closure.PayoutDestination,
+		&transactionID, &closure.ClosedAt, &closure.AnonymizeAfter, &anonymizedAt)
+	if err != nil {
+		return nil, err
+	}
+	closure.PayoutTransactionID = transactionID.String
+	if anonymizedAt.Valid {
+		closure.AnonymizedAt = &anonymizedAt.Time
+	}
+	return &closure, nil
+}
+
+func (r *closureRepository) CloseWallet(wallet *models.Wallet, change *models.WalletStatusChange, payout *models.Transaction, closure *models.WalletClosure) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Postings take the wallet's lock before its row, and so does the
+	// closure; once closed, the wallet refuses credits
+	if _, err := tx.Exec(`SELECT pg_advisory_xact_lock(hashtext($1))`, wallet.ID); err != nil {
+		return err
+	}
+	if err := updateWalletStatus(tx, wallet, change); err != nil {
+		return err
+	}
+	// The balance is zeroed only when the log holds exactly the payout
+	query := `UPDATE wallets SET balance = 0 WHERE id = $1
+			  AND (SELECT COALESCE(SUM(` + signedAmountSQL + `), 0) FROM transactions WHERE wallet_id = $1) = $2`
+	err = affectingOne(tx.Exec(query, wallet.ID, closure.PayoutAmount))
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrBalanceChanged
+	}
+	if err != nil {
+		return err
+	}
+	if payout != nil {
+		if err := r.transactionRepo.CreateTransactionWithTx(tx, payout); err != nil {
+			return err
+		}
+	}
+	query = `UPDATE customer_tokens SET revoked_at = $1 WHERE customer_xid = $2 AND revoked_at IS NULL`
+	if _, err := tx.Exec(query, closure.ClosedAt, closure.CustomerXID); err != nil {
+		return err
+	}
+	query = `UPDATE standing_orders SET status = $1, next_run_at = NULL, next_attempt_at = NULL, lease_id = NULL, lease_until = NULL,
+			 updated_at = $2 WHERE customer_xid = $3 AND status = $4`
+	if _, err := tx.Exec(query, models.StandingOrderCancelled, closure.ClosedAt, closure.CustomerXID, models.StandingOrderActive); err != nil {
+		return err
+	}
+	query = `UPDATE payment_requests SET status = $1, responded_at = $2
+			 WHERE (customer_xid = $3 OR payer_customer_xid = $3) AND status = $4`
+	if _, err := tx.Exec(query, models.PaymentRequestCancelled, closure.ClosedAt, closure.CustomerXID, models.PaymentRequestPending); err != nil {
+		return err
+	}
+
+	query = `INSERT INTO wallet_closures (` + closureColumns + `) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NULL)`
+	transactionID := sql.NullString{String: closure.PayoutTransactionID, Valid: closure.PayoutTransactionID != ""}
+	_, err = tx.Exec(query, closure.ID, closure.WalletID, closure.CustomerXID, closure.PayoutAmount, closure.PayoutDestination,
+		transactionID, closure.ClosedAt, closure.AnonymizeAfter)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *closureRepository) GetClosure(walletID string) (*models.WalletClosure, error) {
+	query := `SELECT ` + closureColumns + ` FROM wallet_closures WHERE wallet_id = $1`
+	return scanClosure(r.db.QueryRow(query, walletID).Scan)
+}
+
+func (r *closureRepository) ListDueClosures(before time.Time, limit int) ([]models.WalletClosure, error) {
+	query := `SELECT ` + closureColumns + ` FROM wallet_closures
+			  WHERE anonymized_at IS NULL AND anonymize_after <= $1 ORDER BY anonymize_after LIMIT $2`
+	rows, err := r.db.Query(query, before, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var closures []models.WalletClosure
+	for rows.Next() {
+		closure, err := scanClosure(rows.Scan)
+		if err != nil {
+			return nil, err
+		}
+		closures = append(closures, *closure)
+	}
+	return closures, rows.Err()
+}
+
+func (r *closureRepository) AnonymizeClosure(closure *models.WalletClosure, pseudonym string, at time.Time) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `UPDATE wallet_closures SET customer_xid = $1, payout_destination = '', anonymized_at = $2
+			  WHERE id = $3 AND anonymized_at IS NULL`
+	result, err := tx.Exec(query, pseudonym, at, closure.ID)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+
+	statements := []struct {
+		query string
+		args  []any
+	}{
+		{`UPDATE wallets SET owned_by = $1 WHERE id = $2`, []any{pseudonym, closure.WalletID}},
+		{`UPDATE wallet_status_history SET actor_id = $1 WHERE wallet_id = $2 AND actor_type = 'customer'`, []any{pseudonym, closure.WalletID}},
+		{`UPDATE risk_decisions SET customer_xid = $1 WHERE wallet_id = $2`, []any{pseudonym, closure.WalletID}},
+		{`UPDATE compliance_reports SET customer_xid = $1 WHERE wallet_id = $2`, []any{pseudonym, closure.WalletID}},
+		{`UPDATE standing_orders SET customer_xid = $1, description = '' WHERE customer_xid = $2`, []any{pseudonym, closure.CustomerXID}},
+		{`UPDATE standing_orders SET payee_customer_xid = $1 WHERE payee_customer_xid = $2`, []any{pseudonym, closure.CustomerXID}},
+		{`UPDATE payment_requests SET customer_xid = $1, description = '' WHERE customer_xid = $2`, []any{pseudonym, closure.CustomerXID}},
+		{`UPDATE payment_requests SET payer_customer_xid = $1 WHERE payer_customer_xid = $2`, []any{pseudonym, closure.CustomerXID}},
+		// Deliveries, which carry the customer_xid in their payload, go with their subscription
+		{`DELETE FROM webhook_subscriptions WHERE customer_xid = $1`, []any{closure.CustomerXID}},
+		{`DELETE FROM customer_tokens WHERE customer_xid = $1`, []any{closure.CustomerXID}},
+		{`DELETE FROM kyc_profiles WHERE customer_xid = $1`, []any{closure.CustomerXID}},
+		{`DELETE FROM transaction_pins WHERE customer_xid = $1`, []any{closure.CustomerXID}},
+		{`DELETE FROM step_up_tokens WHERE customer_xid = $1`, []any{closure.CustomerXID}},
+		{`DELETE FROM totp_enrollments WHERE customer_xid = $1`, []any{closure.CustomerXID}},
+	}
+	for _, stmt := range statements {
+		if _, err := tx.Exec(stmt.query, stmt.args...); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,132 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"mini-wallet/models"
+
+	"github.com/lib/pq"
+)
+
+type ComplianceRepository interface {
+	// ListTransactionsFrom returns the deposits and withdrawals of at least
+	// minAmount recorded in (from, to], ordered by wallet and then time.
+	// Transfers between wallets are not cash movements and are left out.
+	ListTransactionsFrom(minAmount int64, from, to time.Time) ([]models.Transaction, error)
+	// CreateReport stores a report unless one of the same kind already
+	// starts with the same transaction, and reports whether it was stored.
+	CreateReport(report *models.ComplianceReport) (bool, error)
+	// ListReports returns the reports matching filter, newest transaction first.
+	ListReports(filter models.ComplianceReportFilter) ([]models.ComplianceReport, error)
+	CreateScan(scan *models.ComplianceScan) error
+	GetLatestScan() (*models.ComplianceScan, error)
+}
+
+type complianceRepository struct {
+	db *sql.DB
+}
+
+func NewComplianceRepository(db *sql.DB) ComplianceRepository {
+	return &complianceRepository{db: db}
+}
+
+func (r *complianceRepository) ListTransactionsFrom(minAmount int64, from, to time.Time) ([]models.Transaction, error) {
+	query := `SELECT id, wallet_id, type, status, amount, reference_id, transacted_at FROM transactions
+			  WHERE type IN ('deposit', 'withdrawal') AND amount >= $1 AND transacted_at > $2 AND transacted_at <= $3
+			  ORDER BY wallet_id, transacted_at, id`
+	rows, err := r.db.Query(query, minAmount, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var transactions []models.Transaction
+	for rows.Next() {
+		var transaction models.Transaction
+		err := rows.Scan(&transaction.ID, &transaction.WalletID, &transaction.Type, &transaction.Status, &transaction.Amount, &transaction.ReferenceID, &transaction.TransactedAt)
+		if err != nil {
+			return nil, err
+		}
+		transactions = append(transactions, transaction)
+	}
+	return transactions, rows.Err()
+}
+
+func (r *complianceRepository) CreateReport(report *models.ComplianceReport) (bool, error) {
+	query := `INSERT INTO compliance_reports (id, kind, wallet_id, customer_xid, first_transaction_id, transaction_ids, total_amount,
+				first_transacted_at, last_transacted_at, created_at)
+			  VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+			  ON CONFLICT (kind, wallet_id, first_transaction_id) DO NOTHING`
+	result, err := r.db.Exec(query, report.ID, report.Kind, report.WalletID, report.CustomerXID, report.TransactionIDs[0],
+		pq.Array(report.TransactionIDs), report.TotalAmount, report.FirstTransactedAt, report.LastTransactedAt, report.CreatedAt)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+func (r *complianceRepository) ListReports(filter models.ComplianceReportFilter) ([]models.ComplianceReport, error) {
+	var conditions []string
+	var args []any
+	if filter.Kind != "" {
+		args = append(args, filter.Kind)
+		conditions = append(conditions, fmt.Sprintf("kind = $%d", len(args)))
+	}
+	if filter.WalletID != "" {
+		args = append(args, filter.WalletID)
+		conditions = append(conditions, fmt.Sprintf("wallet_id = $%d", len(args)))
+	}
+	if !filter.From.IsZero() {
+		args = append(args, filter.From)
+		conditions = append(conditions, fmt.Sprintf("last_transacted_at >= $%d", len(args)))
+	}
+	if !filter.To.IsZero() {
+		args = append(args, filter.To)
+		conditions = append(conditions, fmt.Sprintf("last_transacted_at < $%d", len(args)))
+	}
+	query := `SELECT id, kind, wallet_id, customer_xid, transaction_ids, total_amount, first_transacted_at, last_transacted_at, created_at
+			  FROM compliance_reports`
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	args = append(args, filter.Limit)
+	query += fmt.Sprintf(" ORDER BY last_transacted_at DESC LIMIT $%d", len(args))
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var reports []models.ComplianceReport
+	for rows.Next() {
+		var report models.ComplianceReport
+		err := rows.Scan(&report.ID, &report.Kind, &report.WalletID, &report.CustomerXID, pq.Array(&report.TransactionIDs),
+			&report.TotalAmount, &report.FirstTransactedAt, &report.LastTransactedAt, &report.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		reports = append(reports, report)
+	}
+	return reports, rows.Err()
+}
+
+func (r *complianceRepository) CreateScan(scan *models.ComplianceScan) error {
+	query := `INSERT INTO compliance_scans (id, window_start, window_end, reports, created_at) VALUES ($1, $2, $3, $4, $5)`
+	_, err := r.db.Exec(query, scan.ID, scan.WindowStart, scan.WindowEnd, scan.Reports, scan.CreatedAt)
+	return err
+}
+
+func (r *complianceRepository) GetLatestScan() (*models.ComplianceScan, error) {
+	var scan models.ComplianceScan
+	query := `SELECT id, window_start, window_end, reports, created_at FROM compliance_scans ORDER BY window_end DESC LIMIT 1`
+	err := r.db.QueryRow(query).Scan(&scan.ID, &scan.WindowStart, &scan.WindowEnd, &scan.Reports, &scan.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &scan, nil
+}
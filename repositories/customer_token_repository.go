@@ -1,7 +1,13 @@
 package repositories
 
 import (
+	"crypto/sha1"
 	"database/sql"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"mini-wallet/models"
 )
 
 type CustomerTokenRepository interface {
@@ -9,6 +15,12 @@ type CustomerTokenRepository interface {
 	GetCustomerXIDByToken(token string) (string, error)
 	CustomerExists(customerXID string) (bool, error)
 	GetToken(customerXID string) (string, error)
+	GetCustomerToken(customerXID string) (*models.CustomerToken, error)
+	RotateToken(customerXID, token string) error
+	RevokeToken(customerXID string) error
+	// SearchCustomers returns up to limit customers whose customer_xid or
+	// wallet id starts with query, newest first, with their wallets.
+	SearchCustomers(query string, limit int) ([]models.CustomerSummary, error)
 }
 
 type customerTokenRepository struct {
@@ -27,7 +39,7 @@ func (r *customerTokenRepository) CreateToken(customerXID, token string) error {
 
 func (r *customerTokenRepository) GetCustomerXIDByToken(token string) (string, error) {
 	var customerXID string
-	query := `SELECT customer_xid FROM customer_tokens WHERE token = $1`
+	query := `SELECT customer_xid FROM customer_tokens WHERE token = $1 AND revoked_at IS NULL`
 	err := r.db.QueryRow(query, token).Scan(&customerXID)
 	if err != nil {
 		return "", err
@@ -44,7 +56,80 @@ func (r *customerTokenRepository) CustomerExists(customerXID string) (bool, erro
 
 func (r *customerTokenRepository) GetToken(customerXID string) (string, error) {
 	var token string
-	query := `SELECT token FROM customer_tokens WHERE customer_xid = $1`
+	query := `SELECT token FROM customer_tokens WHERE customer_xid = $1 AND revoked_at IS NULL`
 	err := r.db.QueryRow(query, customerXID).Scan(&token)
 	return token, err
 }
+
+func (r *customerTokenRepository) GetCustomerToken(customerXID string) (*models.CustomerToken, error) {
+	var customerToken models.CustomerToken
+	var revokedAt sql.NullTime
+	query := `SELECT id, customer_xid, token, created_at, revoked_at FROM customer_tokens WHERE customer_xid = $1`
+	err := r.db.QueryRow(query, customerXID).Scan(&customerToken.ID, &customerToken.CustomerXID, &customerToken.Token, &customerToken.CreatedAt, &revokedAt)
+	if err != nil {
+		return nil, err
+	}
+	if revokedAt.Valid {
+		customerToken.RevokedAt = &revokedAt.Time
+	}
+	return &customerToken, nil
+}
+
+// RotateToken replaces the customer's token, reinstating it if it was revoked.
+func (r *customerTokenRepository) RotateToken(customerXID, token string) error {
+	query := `UPDATE customer_tokens SET token = $1, created_at = NOW(), revoked_at = NULL WHERE customer_xid = $2`
+	return execAffectingOne(r.db, query, token, customerXID)
+}
+
+func (r *customerTokenRepository) RevokeToken(customerXID string) error {
+	query := `UPDATE customer_tokens SET revoked_at = $1 WHERE customer_xid = $2 AND revoked_at IS NULL`
+	return execAffectingOne(r.db, query, time.Now().UTC(), customerXID)
+}
+
+// GenerateToken derives a new opaque token for the customer.
+func GenerateToken(customerXID string) string {
+	hash := sha1.New()
+	hash.Write([]byte(customerXID + time.Now().String()))
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+func (r *customerTokenRepository) SearchCustomers(search string, limit int) ([]models.CustomerSummary, error) {
+	query := `SELECT t.customer_xid, t.created_at, t.revoked_at, w.id, w.status, w.enabled_at, w.disabled_at, w.balance
+			  FROM customer_tokens t LEFT JOIN wallets w ON w.owned_by = t.customer_xid
+			  WHERE t.customer_xid::text LIKE $1 || '%' OR w.id::text LIKE $1 || '%'
+			  ORDER BY t.created_at DESC LIMIT $2`
+	// Match the input literally rather than as a pattern
+	prefix := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(strings.ToLower(search))
+	rows, err := r.db.Query(query, prefix, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var customers []models.CustomerSummary
+	for rows.Next() {
+		var customer models.CustomerSummary
+		var revokedAt, enabledAt, disabledAt sql.NullTime
+		var walletID, status sql.NullString
+		var balance sql.NullInt64
+		err := rows.Scan(&customer.CustomerXID, &customer.CreatedAt, &revokedAt, &walletID, &status, &enabledAt, &disabledAt, &balance)
+		if err != nil {
+			return nil, err
+		}
+		if revokedAt.Valid {
+			customer.TokenRevokedAt = &revokedAt.Time
+		}
+		if walletID.Valid {
+			customer.Wallet = &models.Wallet{
+				ID:         walletID.String,
+				OwnedBy:    customer.CustomerXID,
+				Status:     status.String,
+				EnabledAt:  enabledAt.Time,
+				DisabledAt: disabledAt.Time,
+				Balance:    balance.Int64,
+			}
+		}
+		customers = append(customers, customer)
+	}
+	return customers, rows.Err()
+}
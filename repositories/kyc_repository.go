@@ -0,0 +1,74 @@
+package repositories
+
+import (
+	"database/sql"
+	"encoding/json"
+
+	"mini-wallet/models"
+)
+
+type KYCRepository interface {
+	// CreateProfile stores profile unless the customer already has one.
+	CreateProfile(profile *models.KYCProfile) error
+	GetProfile(customerXID string) (*models.KYCProfile, error)
+	// SaveProfile stores profile, replacing the customer's current one.
+	SaveProfile(profile *models.KYCProfile) error
+}
+
+type kycRepository struct {
+	db *sql.DB
+}
+
+func NewKYCRepository(db *sql.DB) KYCRepository {
+	return &kycRepository{db: db}
+}
+
+func (r *kycRepository) CreateProfile(profile *models.KYCProfile) error {
+	documents, err := marshalDocuments(profile.Documents)
+	if err != nil {
+		return err
+	}
+	query := `INSERT INTO kyc_profiles (customer_xid, tier, status, verified_at, documents, updated_at)
+			  VALUES ($1, $2, $3, $4, $5, $6) ON CONFLICT (customer_xid) DO NOTHING`
+	_, err = r.db.Exec(query, profile.CustomerXID, profile.Tier, profile.Status, profile.VerifiedAt, documents, profile.UpdatedAt)
+	return err
+}
+
+func (r *kycRepository) GetProfile(customerXID string) (*models.KYCProfile, error) {
+	var profile models.KYCProfile
+	var verifiedAt sql.NullTime
+	var documents []byte
+	query := `SELECT customer_xid, tier, status, verified_at, documents, updated_at FROM kyc_profiles WHERE customer_xid = $1`
+	err := r.db.QueryRow(query, customerXID).Scan(&profile.CustomerXID, &profile.Tier, &profile.Status, &verifiedAt, &documents, &profile.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(documents, &profile.Documents); err != nil {
+		return nil, err
+	}
+	if verifiedAt.Valid {
+		profile.VerifiedAt = &verifiedAt.Time
+	}
+	return &profile, nil
+}
+
+func (r *kycRepository) SaveProfile(profile *models.KYCProfile) error {
+	documents, err := marshalDocuments(profile.Documents)
+	if err != nil {
+		return err
+	}
+	query := `INSERT INTO kyc_profiles (customer_xid, tier, status, verified_at, documents, updated_at)
+			  VALUES ($1, $2, $3, $4, $5, $6)
+			  ON CONFLICT (customer_xid) DO UPDATE SET tier = $2, status = $3, verified_at = $4, documents = $5, updated_at = $6`
+	_, err = r.db.Exec(query, profile.CustomerXID, profile.Tier, profile.Status, profile.VerifiedAt, documents, profile.UpdatedAt)
+	return err
+}
+
+// marshalDocuments encodes documents as a JSON array, empty rather than
+// null when there are none.
+func marshalDocuments(documents []models.KYCDocument) ([]byte, error) {
+	if documents == nil {
+		documents = []models.KYCDocument{}
+	}
+	return json.Marshal(documents)
+}
@@ -0,0 +1,152 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"mini-wallet/models"
+)
+
+type PaymentRequestRepository interface {
+	CreatePaymentRequest(request *models.PaymentRequest) error
+	// GetPaymentRequest returns a request by ID, or sql.ErrNoRows.
+	GetPaymentRequest(id string) (*models.PaymentRequest, error)
+	// GetPaymentRequestByCode returns the request carrying a shareable
+	// code, or sql.ErrNoRows.
+	GetPaymentRequestByCode(code string) (*models.PaymentRequest, error)
+	// ListPaymentRequests returns the requests matching filter, newest
+	// first. Pending requests past their expiry count as expired.
+	ListPaymentRequests(filter models.PaymentRequestFilter) ([]models.PaymentRequest, error)
+	// PayPaymentRequest marks the request paid and posts its transfer in
+	// one database transaction. It returns sql.ErrNoRows when the request
+	// is no longer pending or expired meanwhile.
+	PayPaymentRequest(request *models.PaymentRequest, transfer *models.Transfer) error
+	// RespondPaymentRequest saves a pending request as declined or
+	// cancelled, or returns sql.ErrNoRows when it no longer is pending or
+	// expired meanwhile.
+	RespondPaymentRequest(request *models.PaymentRequest) error
+}
+
+type paymentRequestRepository struct {
+	db              *sql.DB
+	transactionRepo TransactionRepository
+}
+
+// NewPaymentRequestRepository posts paid requests through transactionRepo,
+// so they join the wallets' hash chains like any other transaction.
+func NewPaymentRequestRepository(db *sql.DB, transactionRepo TransactionRepository) PaymentRequestRepository {
+	return &paymentRequestRepository{db: db, transactionRepo: transactionRepo}
+}
+
+const paymentRequestColumns = `id, customer_xid, wallet_id, payer_customer_xid, code, amount, description, status, expires_at,
+	transaction_id, responded_at, created_at`
+
+func scanPaymentRequest(scan func(dest ...any) error) (*models.PaymentRequest, error) {
+	var request models.PaymentRequest
+	var payer, code, transactionID sql.NullString
+	var respondedAt sql.NullTime
+	err := scan(&request.ID, &request.CustomerXID, &request.WalletID, &payer, &code, &request.Amount, &request.Description,
+		&request.Status, &request.ExpiresAt, &transactionID, &respondedAt, &request.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	request.PayerCustomerXID = payer.String
+	request.Code = code.String
+	request.TransactionID = transactionID.String
+	request.RespondedAt = nullTime(respondedAt)
+	return &request, nil
+}
+
+func (r *paymentRequestRepository) CreatePaymentRequest(request *models.PaymentRequest) error {
+	query := `INSERT INTO payment_requests (id, customer_xid, wallet_id, payer_customer_xid, code, amount, description, status,
+			  expires_at, created_at)
+			  VALUES ($1, $2, $3, NULLIF($4, ''), NULLIF($5, ''), $6, $7, $8, $9, $10)`
+	_, err := r.db.Exec(query, request.ID, request.CustomerXID, request.WalletID, request.PayerCustomerXID, request.Code,
+		request.Amount, request.Description, request.Status, request.ExpiresAt, request.CreatedAt)
+	return err
+}
+
+func (r *paymentRequestRepository) GetPaymentRequest(id string) (*models.PaymentRequest, error) {
+	query := `SELECT ` + paymentRequestColumns + ` FROM payment_requests WHERE id = $1`
+	return scanPaymentRequest(r.db.QueryRow(query, id).Scan)
+}
+
+func (r *paymentRequestRepository) GetPaymentRequestByCode(code string) (*models.PaymentRequest, error) {
+	query := `SELECT ` + paymentRequestColumns + ` FROM payment_requests WHERE code = $1`
+	return scanPaymentRequest(r.db.QueryRow(query, code).Scan)
+}
+
+func (r *paymentRequestRepository) ListPaymentRequests(filter models.PaymentRequestFilter) ([]models.PaymentRequest, error) {
+	args := []any{filter.CustomerXID}
+	var conditions []string
+	switch filter.Direction {
+	case models.DirectionIncoming:
+		conditions = append(conditions, "payer_customer_xid = $1")
+	case models.DirectionOutgoing:
+		conditions = append(conditions, "customer_xid = $1")
+	default:
+		conditions = append(conditions, "(customer_xid = $1 OR payer_customer_xid = $1)")
+	}
+	switch filter.Status {
+	case "":
+	case models.PaymentRequestPending:
+		args = append(args, models.PaymentRequestPending, filter.Now)
+		conditions = append(conditions, fmt.Sprintf("status = $%d AND expires_at > $%d", len(args)-1, len(args)))
+	case models.PaymentRequestExpired:
+		args = append(args, models.PaymentRequestPending, filter.Now)
+		conditions = append(conditions, fmt.Sprintf("status = $%d AND expires_at <= $%d", len(args)-1, len(args)))
+	default:
+		args = append(args, filter.Status)
+		conditions = append(conditions, fmt.Sprintf("status = $%d", len(args)))
+	}
+	args = append(args, filter.Limit)
+	query := `SELECT ` + paymentRequestColumns + ` FROM payment_requests WHERE ` + strings.Join(conditions, " AND ") +
+		fmt.Sprintf(" ORDER BY created_at DESC LIMIT $%d", len(args))
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var requests []models.PaymentRequest
+	for rows.Next() {
+		request, err := scanPaymentRequest(rows.Scan)
+		if err != nil {
+			return nil, err
+		}
+		requests = append(requests, *request)
+	}
+	return requests, rows.Err()
+}
+
+// respond moves a pending request to its final status. Matching on the
+// pending status and the expiry makes concurrent responses to one request
+// fail but one, and late ones fail.
+func respond(exec func(query string, args ...any) (sql.Result, error), request *models.PaymentRequest) error {
+	query := `UPDATE payment_requests SET status = $1, payer_customer_xid = NULLIF($2, ''), transaction_id = NULLIF($3, '')::uuid,
+			  responded_at = $4 WHERE id = $5 AND status = $6 AND expires_at > $4`
+	return affectingOne(exec(query, request.Status, request.PayerCustomerXID, request.TransactionID, request.RespondedAt,
+		request.ID, models.PaymentRequestPending))
+}
+
+func (r *paymentRequestRepository) PayPaymentRequest(request *models.PaymentRequest, transfer *models.Transfer) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := respond(tx.Exec, request); err != nil {
+		return err
+	}
+	if err := postTransfer(tx, r.transactionRepo, transfer); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *paymentRequestRepository) RespondPaymentRequest(request *models.PaymentRequest) error {
+	return respond(r.db.Exec, request)
+}
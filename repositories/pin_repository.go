@@ -0,0 +1,140 @@
+package repositories
+
+import (
+	"database/sql"
+	"time"
+
+	"mini-wallet/models"
+)
+
+type PINRepository interface {
+	// GetPIN returns the customer's PIN, or sql.ErrNoRows when they have
+	// not set one.
+	GetPIN(customerXID string) (*models.TransactionPIN, error)
+	// SetPIN stores pin, replacing the customer's current one along with
+	// its failed attempts, lock and unused step-up tokens.
+	SetPIN(pin *models.TransactionPIN) error
+	// DeletePIN removes the customer's PIN and step-up tokens. It returns
+	// sql.ErrNoRows when they had no PIN.
+	DeletePIN(customerXID string) error
+	// ClaimAttempt counts an attempt at the customer's PIN before it is
+	// checked, and returns the PIN with the count. It returns sql.ErrNoRows
+	// when the PIN is locked at the given time.
+	ClaimAttempt(customerXID string, at time.Time) (*models.TransactionPIN, error)
+	LockPIN(customerXID string, until time.Time) error
+	// ResetAttempts clears the failed attempts and lock of the PIN.
+	ResetAttempts(customerXID string) error
+	CreateStepUpToken(token *models.StepUpToken) error
+	// CheckStepUpToken returns sql.ErrNoRows unless the customer's token
+	// with the given hash is unused and unexpired at the given time. It
+	// leaves the token as it is.
+	CheckStepUpToken(tokenHash, customerXID string, at time.Time) error
+	// ConsumeStepUpToken marks the customer's token with the given hash
+	// used, or returns sql.ErrNoRows when it is unknown, used or expired at
+	// the given time.
+	ConsumeStepUpToken(tokenHash, customerXID string, at time.Time) error
+}
+
+type pinRepository struct {
+	db *sql.DB
+}
+
+func NewPINRepository(db *sql.DB) PINRepository {
+	return &pinRepository{db: db}
+}
+
+const pinColumns = `customer_xid, hash, failed_attempts, locked_until, updated_at`
+
+func scanPIN(scan func(dest ...any) error) (*models.TransactionPIN, error) {
+	var pin models.TransactionPIN
+	var lockedUntil sql.NullTime
+	if err := scan(&pin.CustomerXID, &pin.Hash, &pin.FailedAttempts, &lockedUntil, &pin.UpdatedAt); err != nil {
+		return nil, err
+	}
+	if lockedUntil.Valid {
+		pin.LockedUntil = &lockedUntil.Time
+	}
+	return &pin, nil
+}
+
+func (r *pinRepository) GetPIN(customerXID string) (*models.TransactionPIN, error) {
+	query := `SELECT ` + pinColumns + ` FROM transaction_pins WHERE customer_xid = $1`
+	return scanPIN(r.db.QueryRow(query, customerXID).Scan)
+}
+
+func (r *pinRepository) SetPIN(pin *models.TransactionPIN) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `INSERT INTO transaction_pins (` + pinColumns + `) VALUES ($1, $2, 0, NULL, $3)
+			  ON CONFLICT (customer_xid) DO UPDATE SET hash = $2, failed_attempts = 0, locked_until = NULL, updated_at = $3`
+	if _, err := tx.Exec(query, pin.CustomerXID, pin.Hash, pin.UpdatedAt); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM step_up_tokens WHERE customer_xid = $1`, pin.CustomerXID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *pinRepository) DeletePIN(customerXID string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`DELETE FROM transaction_pins WHERE customer_xid = $1`, customerXID)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+	if _, err := tx.Exec(`DELETE FROM step_up_tokens WHERE customer_xid = $1`, customerXID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *pinRepository) ClaimAttempt(customerXID string, at time.Time) (*models.TransactionPIN, error) {
+	query := `UPDATE transaction_pins SET failed_attempts = failed_attempts + 1
+			  WHERE customer_xid = $1 AND (locked_until IS NULL OR locked_until <= $2)
+			  RETURNING ` + pinColumns
+	return scanPIN(r.db.QueryRow(query, customerXID, at).Scan)
+}
+
+func (r *pinRepository) LockPIN(customerXID string, until time.Time) error {
+	_, err := r.db.Exec(`UPDATE transaction_pins SET locked_until = $1 WHERE customer_xid = $2`, until, customerXID)
+	return err
+}
+
+func (r *pinRepository) ResetAttempts(customerXID string) error {
+	_, err := r.db.Exec(`UPDATE transaction_pins SET failed_attempts = 0, locked_until = NULL WHERE customer_xid = $1`, customerXID)
+	return err
+}
+
+func (r *pinRepository) CreateStepUpToken(token *models.StepUpToken) error {
+	query := `INSERT INTO step_up_tokens (token_hash, customer_xid, expires_at, created_at) VALUES ($1, $2, $3, $4)`
+	_, err := r.db.Exec(query, token.TokenHash, token.CustomerXID, token.ExpiresAt, token.CreatedAt)
+	return err
+}
+
+func (r *pinRepository) CheckStepUpToken(tokenHash, customerXID string, at time.Time) error {
+	query := `SELECT 1 FROM step_up_tokens WHERE token_hash = $1 AND customer_xid = $2 AND used_at IS NULL AND expires_at > $3`
+	var found int
+	return r.db.QueryRow(query, tokenHash, customerXID, at).Scan(&found)
+}
+
+func (r *pinRepository) ConsumeStepUpToken(tokenHash, customerXID string, at time.Time) error {
+	query := `UPDATE step_up_tokens SET used_at = $1
+			  WHERE token_hash = $2 AND customer_xid = $3 AND used_at IS NULL AND expires_at > $1`
+	return execAffectingOne(r.db, query, at, tokenHash, customerXID)
+}
@@ -0,0 +1,91 @@
+package repositories
+
+import (
+	"database/sql"
+
+	"mini-wallet/models"
+)
+
+type ReconciliationRepository interface {
+	CreateRun(run *models.ReconciliationRun) error
+	FinishRun(run *models.ReconciliationRun) error
+	GetLatestRun() (*models.ReconciliationRun, error)
+	GetRun(id string) (*models.ReconciliationRun, error)
+	CreateDiscrepancy(discrepancy *models.BalanceDiscrepancy) error
+	GetDiscrepanciesByRunID(runID string) ([]models.BalanceDiscrepancy, error)
+}
+
+type reconciliationRepository struct {
+	db *sql.DB
+}
+
+func NewReconciliationRepository(db *sql.DB) ReconciliationRepository {
+	return &reconciliationRepository{db: db}
+}
+
+func (r *reconciliationRepository) CreateRun(run *models.ReconciliationRun) error {
+	query := `INSERT INTO reconciliation_runs (id, started_at, wallets_checked, discrepancies, auto_correct)
+			  VALUES ($1, $2, $3, $4, $5)`
+	_, err := r.db.Exec(query, run.ID, run.StartedAt, run.WalletsChecked, run.Discrepancies, run.AutoCorrect)
+	return err
+}
+
+func (r *reconciliationRepository) FinishRun(run *models.ReconciliationRun) error {
+	query := `UPDATE reconciliation_runs SET finished_at = $1, wallets_checked = $2, discrepancies = $3 WHERE id = $4`
+	_, err := r.db.Exec(query, run.FinishedAt, run.WalletsChecked, run.Discrepancies, run.ID)
+	return err
+}
+
+func (r *reconciliationRepository) GetLatestRun() (*models.ReconciliationRun, error) {
+	query := `SELECT id, started_at, finished_at, wallets_checked, discrepancies, auto_correct FROM reconciliation_runs
+			  ORDER BY started_at DESC LIMIT 1`
+	return scanRun(r.db.QueryRow(query))
+}
+
+func (r *reconciliationRepository) GetRun(id string) (*models.ReconciliationRun, error) {
+	query := `SELECT id, started_at, finished_at, wallets_checked, discrepancies, auto_correct FROM reconciliation_runs
+			  WHERE id = $1`
+	return scanRun(r.db.QueryRow(query, id))
+}
+
+func scanRun(row *sql.Row) (*models.ReconciliationRun, error) {
+	var run models.ReconciliationRun
+	var finishedAt sql.NullTime
+	err := row.Scan(&run.ID, &run.StartedAt, &finishedAt, &run.WalletsChecked, &run.Discrepancies, &run.AutoCorrect)
+	if err != nil {
+		return nil, err
+	}
+	if finishedAt.Valid {
+		run.FinishedAt = &finishedAt.Time
+	}
+	return &run, nil
+}
+
+func (r *reconciliationRepository) CreateDiscrepancy(discrepancy *models.BalanceDiscrepancy) error {
+	query := `INSERT INTO balance_discrepancies (id, run_id, wallet_id, stored_balance, derived_balance, drift, corrected, detected_at)
+			  VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+	_, err := r.db.Exec(query, discrepancy.ID, discrepancy.RunID, discrepancy.WalletID, discrepancy.StoredBalance,
+		discrepancy.DerivedBalance, discrepancy.Drift, discrepancy.Corrected, discrepancy.DetectedAt)
+	return err
+}
+
+func (r *reconciliationRepository) GetDiscrepanciesByRunID(runID string) ([]models.BalanceDiscrepancy, error) {
+	var discrepancies []models.BalanceDiscrepancy
+	query := `SELECT id, run_id, wallet_id, stored_balance, derived_balance, drift, corrected, detected_at
+			  FROM balance_discrepancies WHERE run_id = $1 ORDER BY detected_at`
+	rows, err := r.db.Query(query, runID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var d models.BalanceDiscrepancy
+		err := rows.Scan(&d.ID, &d.RunID, &d.WalletID, &d.StoredBalance, &d.DerivedBalance, &d.Drift, &d.Corrected, &d.DetectedAt)
+		if err != nil {
+			return nil, err
+		}
+		discrepancies = append(discrepancies, d)
+	}
+	return discrepancies, rows.Err()
+}
@@ -0,0 +1,81 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+
+	"mini-wallet/models"
+)
+
+// ErrInsufficientBalance is returned when a posting would take a wallet's
+// stored balance below zero.
+var ErrInsufficientBalance = errors.New("insufficient balance")
+
+// ErrWalletClosed is returned when a posting would credit a closed wallet.
+var ErrWalletClosed = errors.New("wallet closed")
+
+// ErrBalanceChanged is returned when a wallet's balance moved after it was
+// read, so an amount derived from it no longer holds.
+var ErrBalanceChanged = errors.New("balance changed")
+
+// execAffectingOne runs an UPDATE or DELETE and reports sql.ErrNoRows when
+// nothing matched, so callers can tell a missing row from a no-op.
+func execAffectingOne(db *sql.DB, query string, args ...any) error {
+	return affectingOne(db.Exec(query, args...))
+}
+
+// affectingOne reports sql.ErrNoRows when the statement behind result
+// matched nothing.
+func affectingOne(result sql.Result, err error) error {
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// applyBalance adds change to the wallet's stored balance within tx. The
+// guards are evaluated against the row as locked by the update, so a debit
+// racing another one cannot take the balance below zero, and a credit
+// racing the wallet's closure cannot land after its payout; they return
+// ErrInsufficientBalance and ErrWalletClosed instead.
+func applyBalance(tx *sql.Tx, walletID string, change int64) error {
+	query := `UPDATE wallets SET balance = balance + $1
+			  WHERE id = $2 AND ($1 >= 0 OR balance + $1 >= 0) AND ($1 <= 0 OR status <> 'closed')`
+	err := affectingOne(tx.Exec(query, change, walletID))
+	switch {
+	case errors.Is(err, sql.ErrNoRows) && change > 0:
+		return ErrWalletClosed
+	case errors.Is(err, sql.ErrNoRows):
+		return ErrInsufficientBalance
+	}
+	return err
+}
+
+// postTransfer appends both legs of transfer to their wallets' hash chains
+// and applies them to the stored balances within tx. The wallets are
+// written in ID order, so transfers crossing each other cannot deadlock.
+// It returns ErrInsufficientBalance when the outgoing leg would take the
+// payer's balance below zero, and ErrWalletClosed when the payee's wallet
+// was closed meanwhile.
+func postTransfer(tx *sql.Tx, transactionRepo TransactionRepository, transfer *models.Transfer) error {
+	legs := []*models.Transaction{transfer.Out, transfer.In}
+	if transfer.In.WalletID < transfer.Out.WalletID {
+		legs[0], legs[1] = legs[1], legs[0]
+	}
+	for _, leg := range legs {
+		if err := transactionRepo.CreateTransactionWithTx(tx, leg); err != nil {
+			return err
+		}
+		if err := applyBalance(tx, leg.WalletID, leg.SignedAmount()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
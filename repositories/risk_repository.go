@@ -0,0 +1,185 @@
+package repositories
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"mini-wallet/models"
+)
+
+type RiskRepository interface {
+	CreateDecision(decision *models.RiskDecision) error
+	GetDecision(id string) (*models.RiskDecision, error)
+	// GetHeldDecisionByReference returns the held decision for referenceID,
+	// so a held transaction cannot be submitted twice.
+	GetHeldDecisionByReference(referenceID string) (*models.RiskDecision, error)
+	// ListDecisions returns the decisions matching filter, newest first.
+	ListDecisions(filter models.RiskDecisionFilter) ([]models.RiskDecision, error)
+	// ReleaseDecision records the review of a held decision, posts
+	// transaction and writes entry to the audit log in one database
+	// transaction. It returns sql.ErrNoRows when the decision is no longer
+	// held, and ErrInsufficientBalance when a withdrawal takes the balance
+	// derived from the transaction log below zero.
+	ReleaseDecision(decision *models.RiskDecision, transaction *models.Transaction, entry *models.AuditEntry) error
+	// RejectDecision records the review of a held decision, or returns
+	// sql.ErrNoRows when it is no longer held.
+	RejectDecision(decision *models.RiskDecision) error
+}
+
+type riskRepository struct {
+	db              *sql.DB
+	transactionRepo TransactionRepository
+}
+
+// NewRiskRepository posts released transactions through transactionRepo,
+// so they join the wallet's hash chain like any other transaction.
+func NewRiskRepository(db *sql.DB, transactionRepo TransactionRepository) RiskRepository {
+	return &riskRepository{db: db, transactionRepo: transactionRepo}
+}
+
+const riskDecisionColumns = `id, wallet_id, customer_xid, transaction_id, transaction_type, amount, reference_id, outcome, matches,
+	status, created_at, reviewed_by, reviewed_by_name, review_note, reviewed_at`
+
+func scanRiskDecision(scan func(dest ...any) error) (*models.RiskDecision, error) {
+	var decision models.RiskDecision
+	var matches []byte
+	var status, reviewedBy, reviewedByName, reviewNote sql.NullString
+	var reviewedAt sql.NullTime
+	err := scan(&decision.ID, &decision.WalletID, &decision.CustomerXID, &decision.TransactionID, &decision.TransactionType,
+		&decision.Amount, &decision.ReferenceID, &decision.Outcome, &matches,
+		&status, &decision.CreatedAt, &reviewedBy, &reviewedByName, &reviewNote, &reviewedAt)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(matches, &decision.Matches); err != nil {
+		return nil, err
+	}
+	decision.Status = status.String
+	decision.ReviewedBy = reviewedBy.String
+	decision.ReviewedByName = reviewedByName.String
+	decision.ReviewNote = reviewNote.String
+	if reviewedAt.Valid {
+		decision.ReviewedAt = &reviewedAt.Time
+	}
+	return &decision, nil
+}
+
+func (r *riskRepository) CreateDecision(decision *models.RiskDecision) error {
+	matches, err := json.Marshal(decision.Matches)
+	if err != nil {
+		return err
+	}
+	query := `INSERT INTO risk_decisions (id, wallet_id, customer_xid, transaction_id, transaction_type, amount, reference_id, outcome, matches, status, created_at)
+			  VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`
+	status := sql.NullString{String: decision.Status, Valid: decision.Status != ""}
+	_, err = r.db.Exec(query, decision.ID, decision.WalletID, decision.CustomerXID, decision.TransactionID, decision.TransactionType,
+		decision.Amount, decision.ReferenceID, decision.Outcome, matches, status, decision.CreatedAt)
+	return err
+}
+
+func (r *riskRepository) GetDecision(id string) (*models.RiskDecision, error) {
+	query := `SELECT ` + riskDecisionColumns + ` FROM risk_decisions WHERE id = $1`
+	return scanRiskDecision(r.db.QueryRow(query, id).Scan)
+}
+
+func (r *riskRepository) GetHeldDecisionByReference(referenceID string) (*models.RiskDecision, error) {
+	query := `SELECT ` + riskDecisionColumns + ` FROM risk_decisions WHERE reference_id = $1 AND status = 'held'`
+	return scanRiskDecision(r.db.QueryRow(query, referenceID).Scan)
+}
+
+func (r *riskRepository) ListDecisions(filter models.RiskDecisionFilter) ([]models.RiskDecision, error) {
+	var conditions []string
+	var args []any
+	if filter.WalletID != "" {
+		args = append(args, filter.WalletID)
+		conditions = append(conditions, fmt.Sprintf("wallet_id = $%d", len(args)))
+	}
+	if filter.Outcome != "" {
+		args = append(args, filter.Outcome)
+		conditions = append(conditions, fmt.Sprintf("outcome = $%d", len(args)))
+	}
+	if filter.Status != "" {
+		args = append(args, filter.Status)
+		conditions = append(conditions, fmt.Sprintf("status = $%d", len(args)))
+	}
+	query := `SELECT ` + riskDecisionColumns + ` FROM risk_decisions`
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	args = append(args, filter.Limit)
+	query += fmt.Sprintf(" ORDER BY created_at DESC LIMIT $%d", len(args))
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var decisions []models.RiskDecision
+	for rows.Next() {
+		decision, err := scanRiskDecision(rows.Scan)
+		if err != nil {
+			return nil, err
+		}
+		decisions = append(decisions, *decision)
+	}
+	return decisions, rows.Err()
+}
+
+// reviewDecision moves a held decision to its reviewed status. Matching on
+// the held status makes concurrent reviews of one decision fail but one.
+func reviewDecision(exec func(query string, args ...any) (sql.Result, error), decision *models.RiskDecision) error {
+	query := `UPDATE risk_decisions
+			  SET status = $1, reviewed_by = $2, reviewed_by_name = $3, review_note = $4, reviewed_at = $5
+			  WHERE id = $6 AND status = 'held'`
+	result, err := exec(query, decision.Status, decision.ReviewedBy, decision.ReviewedByName, decision.ReviewNote,
+		decision.ReviewedAt, decision.ID)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (r *riskRepository) ReleaseDecision(decision *models.RiskDecision, transaction *models.Transaction, entry *models.AuditEntry) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := reviewDecision(tx.Exec, decision); err != nil {
+		return err
+	}
+	if err := r.transactionRepo.CreateTransactionWithTx(tx, transaction); err != nil {
+		return err
+	}
+	if transaction.SignedAmount() < 0 {
+		// The stored balance may still be settling; the log, read under
+		// the wallet's lock taken above, is not
+		var balance int64
+		query := `SELECT COALESCE(SUM(` + signedAmountSQL + `), 0) FROM transactions WHERE wallet_id = $1`
+		if err := tx.QueryRow(query, transaction.WalletID).Scan(&balance); err != nil {
+			return err
+		}
+		if balance < 0 {
+			return ErrInsufficientBalance
+		}
+	}
+	if err := insertAuditEntry(tx.Exec, entry); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *riskRepository) RejectDecision(decision *models.RiskDecision) error {
+	return reviewDecision(r.db.Exec, decision)
+}
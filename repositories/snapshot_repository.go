@@ -0,0 +1,42 @@
+package repositories
+
+import (
+	"database/sql"
+	"time"
+
+	"mini-wallet/models"
+)
+
+type SnapshotRepository interface {
+	// SaveSnapshot stores a snapshot, replacing one of the same wallet and time.
+	SaveSnapshot(snapshot *models.BalanceSnapshot) error
+	// GetLatestSnapshot returns the wallet's newest snapshot taken as of at
+	// or earlier, or sql.ErrNoRows when there is none.
+	GetLatestSnapshot(walletID string, at time.Time) (*models.BalanceSnapshot, error)
+}
+
+type snapshotRepository struct {
+	db *sql.DB
+}
+
+func NewSnapshotRepository(db *sql.DB) SnapshotRepository {
+	return &snapshotRepository{db: db}
+}
+
+func (r *snapshotRepository) SaveSnapshot(snapshot *models.BalanceSnapshot) error {
+	query := `INSERT INTO balance_snapshots (wallet_id, as_of, balance, created_at) VALUES ($1, $2, $3, $4)
+			  ON CONFLICT (wallet_id, as_of) DO UPDATE SET balance = EXCLUDED.balance, created_at = EXCLUDED.created_at`
+	_, err := r.db.Exec(query, snapshot.WalletID, snapshot.AsOf, snapshot.Balance, snapshot.CreatedAt)
+	return err
+}
+
+func (r *snapshotRepository) GetLatestSnapshot(walletID string, at time.Time) (*models.BalanceSnapshot, error) {
+	var snapshot models.BalanceSnapshot
+	query := `SELECT wallet_id, as_of, balance, created_at FROM balance_snapshots
+			  WHERE wallet_id = $1 AND as_of <= $2 ORDER BY as_of DESC LIMIT 1`
+	err := r.db.QueryRow(query, walletID, at).Scan(&snapshot.WalletID, &snapshot.AsOf, &snapshot.Balance, &snapshot.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &snapshot, nil
+}
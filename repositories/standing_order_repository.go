@@ -0,0 +1,174 @@
+package repositories
+
+import (
+	"database/sql"
+	"time"
+
+	"mini-wallet/models"
+)
+
+type StandingOrderRepository interface {
+	CreateStandingOrder(order *models.StandingOrder) error
+	// GetStandingOrder returns one of the customer's orders, or
+	// sql.ErrNoRows.
+	GetStandingOrder(id, customerXID string) (*models.StandingOrder, error)
+	// ListStandingOrders returns the customer's orders, newest first.
+	ListStandingOrders(customerXID string) ([]models.StandingOrder, error)
+	// UpdateStandingOrder saves the amount, description and end of an
+	// active order and drops any scheduler's claim on it, so a run in
+	// progress under the old terms is not saved. It returns sql.ErrNoRows
+	// when the order is no longer active.
+	UpdateStandingOrder(order *models.StandingOrder) error
+	// CancelStandingOrder cancels an active order and drops any claim on
+	// it, or returns sql.ErrNoRows when it is no longer active.
+	CancelStandingOrder(id, customerXID string, at time.Time) error
+	// ClaimDueStandingOrders leases up to limit active orders whose next
+	// attempt is due at now and that no other scheduler holds, giving each
+	// a new LeaseID valid for lease.
+	ClaimDueStandingOrders(now time.Time, limit int, lease time.Duration) ([]models.StandingOrder, error)
+	// CompleteRun saves the order after a run and posts the run's transfer
+	// in one database transaction. It returns sql.ErrNoRows when the claim
+	// the order was run under is gone.
+	CompleteRun(order *models.StandingOrder, transfer *models.Transfer) error
+	// SaveAttempt saves the order after an attempt that posted nothing and
+	// releases the claim, or returns sql.ErrNoRows when the claim is gone.
+	SaveAttempt(order *models.StandingOrder) error
+}
+
+type standingOrderRepository struct {
+	db              *sql.DB
+	transactionRepo TransactionRepository
+}
+
+// NewStandingOrderRepository posts the transfers of standing orders
+// through transactionRepo, so they join the wallets' hash chains like any
+// other transaction.
+func NewStandingOrderRepository(db *sql.DB, transactionRepo TransactionRepository) StandingOrderRepository {
+	return &standingOrderRepository{db: db, transactionRepo: transactionRepo}
+}
+
+const standingOrderColumns = `id, customer_xid, wallet_id, payee_customer_xid, amount, description, frequency, start_at, ends_at, status,
+	runs, next_run_at, next_attempt_at, attempts, last_error, last_run_at, lease_id, created_at, updated_at`
+
+func scanStandingOrder(scan func(dest ...any) error) (*models.StandingOrder, error) {
+	var order models.StandingOrder
+	var endsAt, nextRunAt, nextAttemptAt, lastRunAt sql.NullTime
+	var lastError, leaseID sql.NullString
+	err := scan(&order.ID, &order.CustomerXID, &order.WalletID, &order.PayeeCustomerXID, &order.Amount, &order.Description, &order.Frequency,
+		&order.StartAt, &endsAt, &order.Status, &order.Runs, &nextRunAt, &nextAttemptAt, &order.Attempts, &lastError,
+		&lastRunAt, &leaseID, &order.CreatedAt, &order.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	order.EndsAt = nullTime(endsAt)
+	order.NextRunAt = nullTime(nextRunAt)
+	order.NextAttemptAt = nullTime(nextAttemptAt)
+	order.LastRunAt = nullTime(lastRunAt)
+	order.LastError = lastError.String
+	order.LeaseID = leaseID.String
+	return &order, nil
+}
+
+func nullTime(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
+
+func (r *standingOrderRepository) CreateStandingOrder(order *models.StandingOrder) error {
+	query := `INSERT INTO standing_orders (id, customer_xid, wallet_id, payee_customer_xid, amount, description, frequency, start_at,
+			  ends_at, status, runs, next_run_at, next_attempt_at, attempts, created_at, updated_at)
+			  VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)`
+	_, err := r.db.Exec(query, order.ID, order.CustomerXID, order.WalletID, order.PayeeCustomerXID, order.Amount, order.Description, order.Frequency,
+		order.StartAt, order.EndsAt, order.Status, order.Runs, order.NextRunAt, order.NextAttemptAt, order.Attempts,
+		order.CreatedAt, order.UpdatedAt)
+	return err
+}
+
+func (r *standingOrderRepository) GetStandingOrder(id, customerXID string) (*models.StandingOrder, error) {
+	query := `SELECT ` + standingOrderColumns + ` FROM standing_orders WHERE id = $1 AND customer_xid = $2`
+	return scanStandingOrder(r.db.QueryRow(query, id, customerXID).Scan)
+}
+
+func (r *standingOrderRepository) ListStandingOrders(customerXID string) ([]models.StandingOrder, error) {
+	query := `SELECT ` + standingOrderColumns + ` FROM standing_orders WHERE customer_xid = $1 ORDER BY created_at DESC`
+	return r.list(query, customerXID)
+}
+
+func (r *standingOrderRepository) list(query string, args ...any) ([]models.StandingOrder, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var orders []models.StandingOrder
+	for rows.Next() {
+		order, err := scanStandingOrder(rows.Scan)
+		if err != nil {
+			return nil, err
+		}
+		orders = append(orders, *order)
+	}
+	return orders, rows.Err()
+}
+
+func (r *standingOrderRepository) UpdateStandingOrder(order *models.StandingOrder) error {
+	query := `UPDATE standing_orders SET amount = $1, description = $2, ends_at = $3, lease_id = NULL, lease_until = NULL, updated_at = $4
+			  WHERE id = $5 AND customer_xid = $6 AND status = $7`
+	return execAffectingOne(r.db, query, order.Amount, order.Description, order.EndsAt, order.UpdatedAt,
+		order.ID, order.CustomerXID, models.StandingOrderActive)
+}
+
+func (r *standingOrderRepository) CancelStandingOrder(id, customerXID string, at time.Time) error {
+	query := `UPDATE standing_orders SET status = $1, next_run_at = NULL, next_attempt_at = NULL, lease_id = NULL, lease_until = NULL,
+			  updated_at = $2 WHERE id = $3 AND customer_xid = $4 AND status = $5`
+	return execAffectingOne(r.db, query, models.StandingOrderCancelled, at, id, customerXID, models.StandingOrderActive)
+}
+
+func (r *standingOrderRepository) ClaimDueStandingOrders(now time.Time, limit int, lease time.Duration) ([]models.StandingOrder, error) {
+	query := `WITH due AS (
+				SELECT id FROM standing_orders
+				WHERE status = $1 AND next_attempt_at <= $2 AND (lease_until IS NULL OR lease_until <= $2)
+				ORDER BY next_attempt_at
+				LIMIT $3
+				FOR UPDATE SKIP LOCKED
+			  )
+			  UPDATE standing_orders o SET lease_id = gen_random_uuid(), lease_until = $2 + $4 * INTERVAL '1 millisecond'
+			  FROM due WHERE o.id = due.id
+			  RETURNING o.` + standingOrderColumns
+	return r.list(query, models.StandingOrderActive, now, limit, lease.Milliseconds())
+}
+
+// saveRun saves the order's schedule and releases its claim. Matching on
+// the lease makes a scheduler whose claim expired or was dropped fail.
+func saveRun(exec func(query string, args ...any) (sql.Result, error), order *models.StandingOrder) error {
+	query := `UPDATE standing_orders SET status = $1, runs = $2, next_run_at = $3, next_attempt_at = $4, attempts = $5,
+			  last_error = NULLIF($6, ''), last_run_at = $7, lease_id = NULL, lease_until = NULL, updated_at = $8
+			  WHERE id = $9 AND lease_id = $10`
+	return affectingOne(exec(query, order.Status, order.Runs, order.NextRunAt, order.NextAttemptAt, order.Attempts,
+		order.LastError, order.LastRunAt, order.UpdatedAt, order.ID, order.LeaseID))
+}
+
+func (r *standingOrderRepository) CompleteRun(order *models.StandingOrder, transfer *models.Transfer) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Saving first locks the order, so a change made meanwhile either
+	// waits for the run or makes it fail before anything is posted
+	if err := saveRun(tx.Exec, order); err != nil {
+		return err
+	}
+	if err := postTransfer(tx, r.transactionRepo, transfer); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *standingOrderRepository) SaveAttempt(order *models.StandingOrder) error {
+	return saveRun(r.db.Exec, order)
+}
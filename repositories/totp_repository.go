@@ -0,0 +1,130 @@
+package repositories
+
+import (
+	"database/sql"
+	"time"
+
+	"mini-wallet/auth"
+	"mini-wallet/models"
+
+	"github.com/lib/pq"
+)
+
+type TOTPRepository interface {
+	// GetEnrollment returns the customer's enrollment, or sql.ErrNoRows.
+	GetEnrollment(customerXID string) (*models.TOTPEnrollment, error)
+	// SaveEnrollment stores an unconfirmed enrollment, replacing an earlier
+	// unconfirmed one. It returns sql.ErrNoRows when the customer has a
+	// confirmed enrollment.
+	SaveEnrollment(enrollment *models.TOTPEnrollment) error
+	// ConfirmEnrollment puts the enrollment into effect, accepting the
+	// code of time step counter. It returns sql.ErrNoRows when there is no
+	// unconfirmed enrollment.
+	ConfirmEnrollment(customerXID string, counter int64, at time.Time) error
+	// UseCounter accepts a code of time step counter, or returns
+	// sql.ErrNoRows when a code of that step or a later one was accepted.
+	UseCounter(customerXID string, counter int64) error
+	// UseRecoveryCode removes the recovery code with the given hash, or
+	// returns sql.ErrNoRows when the customer has no such code left.
+	UseRecoveryCode(customerXID, codeHash string) error
+	ReplaceRecoveryCodes(customerXID string, codeHashes []string) error
+	// ClaimAttempt counts an attempt at a code before it is checked and
+	// returns the wrong attempts in a row including it, or sql.ErrNoRows
+	// while the enrollment is locked at the given time.
+	ClaimAttempt(customerXID string, at time.Time) (int, error)
+	LockCodes(customerXID string, until time.Time) error
+	// ResetAttempts clears the failed attempts and lock of the enrollment.
+	ResetAttempts(customerXID string) error
+	// DeleteEnrollment removes the enrollment, or returns sql.ErrNoRows
+	// when there is none.
+	DeleteEnrollment(customerXID string) error
+}
+
+// totpRepository encrypts secrets with cipher before they reach the
+// database, bound to their customer.
+type totpRepository struct {
+	db     *sql.DB
+	cipher *auth.Cipher
+}
+
+func NewTOTPRepository(db *sql.DB, cipher *auth.Cipher) TOTPRepository {
+	return &totpRepository{db: db, cipher: cipher}
+}
+
+func (r *totpRepository) GetEnrollment(customerXID string) (*models.TOTPEnrollment, error) {
+	var enrollment models.TOTPEnrollment
+	var secret string
+	var lockedUntil, confirmedAt sql.NullTime
+	query := `SELECT customer_xid, secret, last_counter, recovery_codes, failed_attempts, locked_until, confirmed_at, created_at
+			  FROM totp_enrollments WHERE customer_xid = $1`
+	err := r.db.QueryRow(query, customerXID).Scan(&enrollment.CustomerXID, &secret, &enrollment.LastCounter,
+		pq.Array(&enrollment.RecoveryCodes), &enrollment.FailedAttempts, &lockedUntil, &confirmedAt, &enrollment.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if enrollment.Secret, err = r.cipher.Open(secret, []byte(customerXID)); err != nil {
+		return nil, err
+	}
+	if lockedUntil.Valid {
+		enrollment.LockedUntil = &lockedUntil.Time
+	}
+	if confirmedAt.Valid {
+		enrollment.ConfirmedAt = &confirmedAt.Time
+	}
+	return &enrollment, nil
+}
+
+func (r *totpRepository) SaveEnrollment(enrollment *models.TOTPEnrollment) error {
+	secret, err := r.cipher.Seal(enrollment.Secret, []byte(enrollment.CustomerXID))
+	if err != nil {
+		return err
+	}
+	query := `INSERT INTO totp_enrollments (customer_xid, secret, last_counter, recovery_codes, created_at) VALUES ($1, $2, 0, $3, $4)
+			  ON CONFLICT (customer_xid) DO UPDATE SET secret = $2, last_counter = 0, recovery_codes = $3, created_at = $4
+			  WHERE totp_enrollments.confirmed_at IS NULL`
+	return execAffectingOne(r.db, query, enrollment.CustomerXID, secret, pq.Array(enrollment.RecoveryCodes), enrollment.CreatedAt)
+}
+
+func (r *totpRepository) ConfirmEnrollment(customerXID string, counter int64, at time.Time) error {
+	query := `UPDATE totp_enrollments SET confirmed_at = $1, last_counter = $2 WHERE customer_xid = $3 AND confirmed_at IS NULL`
+	return execAffectingOne(r.db, query, at, counter, customerXID)
+}
+
+func (r *totpRepository) UseCounter(customerXID string, counter int64) error {
+	query := `UPDATE totp_enrollments SET last_counter = $1 WHERE customer_xid = $2 AND last_counter < $1`
+	return execAffectingOne(r.db, query, counter, customerXID)
+}
+
+func (r *totpRepository) UseRecoveryCode(customerXID, codeHash string) error {
+	query := `UPDATE totp_enrollments SET recovery_codes = array_remove(recovery_codes, $1)
+			  WHERE customer_xid = $2 AND $1 = ANY(recovery_codes)`
+	return execAffectingOne(r.db, query, codeHash, customerXID)
+}
+
+func (r *totpRepository) ReplaceRecoveryCodes(customerXID string, codeHashes []string) error {
+	query := `UPDATE totp_enrollments SET recovery_codes = $1 WHERE customer_xid = $2`
+	return execAffectingOne(r.db, query, pq.Array(codeHashes), customerXID)
+}
+
+func (r *totpRepository) ClaimAttempt(customerXID string, at time.Time) (int, error) {
+	query := `UPDATE totp_enrollments SET failed_attempts = failed_attempts + 1
+			  WHERE customer_xid = $1 AND (locked_until IS NULL OR locked_until <= $2)
+			  RETURNING failed_attempts`
+	var attempts int
+	err := r.db.QueryRow(query, customerXID, at).Scan(&attempts)
+	return attempts, err
+}
+
+func (r *totpRepository) LockCodes(customerXID string, until time.Time) error {
+	_, err := r.db.Exec(`UPDATE totp_enrollments SET locked_until = $1 WHERE customer_xid = $2`, until, customerXID)
+	return err
+}
+
+func (r *totpRepository) ResetAttempts(customerXID string) error {
+	_, err := r.db.Exec(`UPDATE totp_enrollments SET failed_attempts = 0, locked_until = NULL WHERE customer_xid = $1`, customerXID)
+	return err
+}
+
+func (r *totpRepository) DeleteEnrollment(customerXID string) error {
+	return execAffectingOne(r.db, `DELETE FROM totp_enrollments WHERE customer_xid = $1`, customerXID)
+}
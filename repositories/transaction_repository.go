@@ -3,11 +3,31 @@ package repositories
 import (
 	"database/sql"
 	"mini-wallet/models"
+	"time"
 )
 
 type TransactionRepository interface {
+	// CreateTransaction and CreateTransactionWithTx append the transaction
+	// to its wallet's hash chain, setting its Sequence, PrevHash and Hash.
 	CreateTransaction(transaction *models.Transaction) error
 	GetTransactionByReferenceID(referenceID string) (*models.Transaction, error)
 	GetTransactionsByWalletID(walletID string) ([]models.Transaction, error)
+	// GetTransactionsSince returns the wallet's transactions recorded after
+	// since, in any order.
+	GetTransactionsSince(walletID string, since time.Time) ([]models.Transaction, error)
+	// GetTransactionsAfter returns the wallet's transactions recorded after
+	// the given one, oldest first. It returns none when afterID is unknown.
+	GetTransactionsAfter(walletID, afterID string) ([]models.Transaction, error)
+	// StreamTransactions calls fn with each of the wallet's transactions
+	// recorded in [from, to), oldest first, without loading them all. It
+	// stops at the first error fn returns.
+	StreamTransactions(walletID string, from, to time.Time, fn func(models.Transaction) error) error
+	// BalanceChange is the net effect on the balance of the wallet's
+	// transactions recorded in [from, to).
+	BalanceChange(walletID string, from, to time.Time) (int64, error)
 	CreateTransactionWithTx(tx *sql.Tx, transaction *models.Transaction) error
+	// StreamChain calls fn with every transaction of the wallet in chain
+	// order, including its chain fields. Transactions written before the
+	// chain existed come first.
+	StreamChain(walletID string, fn func(models.Transaction) error) error
 }
@@ -2,22 +2,33 @@ package repositories
 
 import (
 	"database/sql"
+	"errors"
 	"mini-wallet/models"
+	"time"
 )
 
 type transactionRepository struct {
 	db *sql.DB
 }
 
+// signedAmountSQL mirrors models.Transaction.SignedAmount.
+const signedAmountSQL = `CASE WHEN type IN ('deposit', 'adjustment', 'transfer_in') THEN amount
+	WHEN type IN ('withdrawal', 'transfer_out') THEN -amount ELSE 0 END`
+
 func NewTransactionRepository(db *sql.DB) TransactionRepository {
 	return &transactionRepository{db: db}
 }
 
 func (r *transactionRepository) CreateTransaction(transaction *models.Transaction) error {
-	query := `INSERT INTO transactions (id, wallet_id, type, status, amount, reference_id, transacted_at)
-			  VALUES ($1, $2, $3, $4, $5, $6, $7)`
-	_, err := r.db.Exec(query, transaction.ID, transaction.WalletID, transaction.Type, transaction.Status, transaction.Amount, transaction.ReferenceID, transaction.TransactedAt)
-	return err
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	if err := r.CreateTransactionWithTx(tx, transaction); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
 }
 
 func (r *transactionRepository) GetTransactionByReferenceID(referenceID string) (*models.Transaction, error) {
@@ -31,9 +42,19 @@ func (r *transactionRepository) GetTransactionByReferenceID(referenceID string)
 }
 
 func (r *transactionRepository) GetTransactionsByWalletID(walletID string) ([]models.Transaction, error) {
-	var transactions []models.Transaction
 	query := `SELECT id, wallet_id, type, status, amount, reference_id, transacted_at FROM transactions WHERE wallet_id = $1`
-	rows, err := r.db.Query(query, walletID)
+	return r.queryTransactions(query, walletID)
+}
+
+func (r *transactionRepository) GetTransactionsSince(walletID string, since time.Time) ([]models.Transaction, error) {
+	query := `SELECT id, wallet_id, type, status, amount, reference_id, transacted_at FROM transactions
+			  WHERE wallet_id = $1 AND transacted_at > $2`
+	return r.queryTransactions(query, walletID, since)
+}
+
+func (r *transactionRepository) queryTransactions(query string, args ...any) ([]models.Transaction, error) {
+	var transactions []models.Transaction
+	rows, err := r.db.Query(query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -50,10 +71,109 @@ func (r *transactionRepository) GetTransactionsByWalletID(walletID string) ([]mo
 	return transactions, nil
 }
 
+func (r *transactionRepository) GetTransactionsAfter(walletID, afterID string) ([]models.Transaction, error) {
+	query := `SELECT id, wallet_id, type, status, amount, reference_id, transacted_at FROM transactions
+			  WHERE wallet_id = $1 AND (transacted_at, id) > (
+				SELECT transacted_at, id FROM transactions WHERE id = $2 AND wallet_id = $1
+			  )
+			  ORDER BY transacted_at, id`
+	rows, err := r.db.Query(query, walletID, afterID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var transactions []models.Transaction
+	for rows.Next() {
+		var transaction models.Transaction
+		err := rows.Scan(&transaction.ID, &transaction.WalletID, &transaction.Type, &transaction.Status, &transaction.Amount, &transaction.ReferenceID, &transaction.TransactedAt)
+		if err != nil {
+			return nil, err
+		}
+		transactions = append(transactions, transaction)
+	}
+	return transactions, rows.Err()
+}
+
+func (r *transactionRepository) StreamTransactions(walletID string, from, to time.Time, fn func(models.Transaction) error) error {
+	query := `SELECT id, wallet_id, type, status, amount, reference_id, transacted_at FROM transactions
+			  WHERE wallet_id = $1 AND transacted_at >= $2 AND transacted_at < $3
+			  ORDER BY transacted_at, id`
+	rows, err := r.db.Query(query, walletID, from, to)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var transaction models.Transaction
+		err := rows.Scan(&transaction.ID, &transaction.WalletID, &transaction.Type, &transaction.Status, &transaction.Amount, &transaction.ReferenceID, &transaction.TransactedAt)
+		if err != nil {
+			return err
+		}
+		if err := fn(transaction); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+func (r *transactionRepository) BalanceChange(walletID string, from, to time.Time) (int64, error) {
+	query := `SELECT COALESCE(SUM(` + signedAmountSQL + `), 0)
+			  FROM transactions WHERE wallet_id = $1 AND transacted_at >= $2 AND transacted_at < $3`
+	var change int64
+	err := r.db.QueryRow(query, walletID, from, to).Scan(&change)
+	return change, err
+}
+
 func (r *transactionRepository) CreateTransactionWithTx(tx *sql.Tx, transaction *models.Transaction) error {
-	query := `INSERT INTO transactions (id, wallet_id, status, transacted_at, type, amount, reference_id)
-              VALUES ($1, $2, $3, $4, $5, $6, $7)`
-	_, err := tx.Exec(query, transaction.ID, transaction.WalletID, transaction.Status, transaction.TransactedAt,
-		transaction.Type, transaction.Amount, transaction.ReferenceID)
+	// Writers of one wallet take turns, so each link sees the previous one
+	if _, err := tx.Exec(`SELECT pg_advisory_xact_lock(hashtext($1))`, transaction.WalletID); err != nil {
+		return err
+	}
+	var sequence int64
+	var prevHash string
+	err := tx.QueryRow(`SELECT sequence, hash FROM transactions WHERE wallet_id = $1 AND sequence IS NOT NULL
+			  ORDER BY sequence DESC LIMIT 1`, transaction.WalletID).Scan(&sequence, &prevHash)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+
+	// The hash covers the time as PostgreSQL stores it
+	transaction.TransactedAt = transaction.TransactedAt.Truncate(time.Microsecond)
+	transaction.Sequence = sequence + 1
+	transaction.PrevHash = prevHash
+	transaction.Hash = transaction.ChainHash()
+
+	query := `INSERT INTO transactions (id, wallet_id, status, transacted_at, type, amount, reference_id, sequence, prev_hash, hash)
+              VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`
+	_, err = tx.Exec(query, transaction.ID, transaction.WalletID, transaction.Status, transaction.TransactedAt,
+		transaction.Type, transaction.Amount, transaction.ReferenceID, transaction.Sequence, transaction.PrevHash, transaction.Hash)
 	return err
 }
+
+func (r *transactionRepository) StreamChain(walletID string, fn func(models.Transaction) error) error {
+	query := `SELECT id, wallet_id, type, status, amount, reference_id, transacted_at, sequence, prev_hash, hash FROM transactions
+			  WHERE wallet_id = $1 ORDER BY sequence NULLS FIRST, transacted_at, id`
+	rows, err := r.db.Query(query, walletID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var transaction models.Transaction
+		var sequence sql.NullInt64
+		var prevHash, hash sql.NullString
+		err := rows.Scan(&transaction.ID, &transaction.WalletID, &transaction.Type, &transaction.Status, &transaction.Amount, &transaction.ReferenceID, &transaction.TransactedAt,
+			&sequence, &prevHash, &hash)
+		if err != nil {
+			return err
+		}
+		transaction.Sequence, transaction.PrevHash, transaction.Hash = sequence.Int64, prevHash.String, hash.String
+		if err := fn(transaction); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
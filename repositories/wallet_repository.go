@@ -3,16 +3,22 @@ package repositories
 import (
 	"database/sql"
 	"mini-wallet/models"
-	"time"
 )
 
 type WalletRepository interface {
 	GetWalletByCustomerXID(customerXID string) (*models.Wallet, error)
 	CreateWallet(wallet *models.Wallet) error
 	GetWalletByID(id string) (*models.Wallet, error)
+	ListWallets() ([]models.Wallet, error)
 	UpdateWallet(wallet *models.Wallet) error
-	UpdateWalletStatus(walletID string, status string, enabledAt time.Time) error
+	// UpdateWalletStatus stores the wallet's status and its enabled and
+	// disabled times, and appends change to the status history, in one
+	// database transaction. It returns sql.ErrNoRows when the wallet is no
+	// longer in change.FromStatus.
+	UpdateWalletStatus(wallet *models.Wallet, change *models.WalletStatusChange) error
+	// ListStatusChanges returns the wallet's status history, oldest first.
+	ListStatusChanges(walletID string) ([]models.WalletStatusChange, error)
 	UpdateWalletBalance(walletID string, newBalance int64) error
 	WithTransaction(fn func(tx *sql.Tx) error) error
 	UpdateWalletBalanceWithTx(tx *sql.Tx, walletID string, balance int64) error
-}
\ No newline at end of file
+}
@@ -3,7 +3,8 @@ package repositories
 import (
 	"database/sql"
 	"mini-wallet/models"
-	"time"
+
+	"github.com/google/uuid"
 )
 
 type walletRepository struct {
@@ -11,13 +12,13 @@ type walletRepository struct {
 }
 
 func NewWalletRepository(db *sql.DB) WalletRepository {
-	return &walletRepository{db:db}
+	return &walletRepository{db: db}
 }
 
 func (r *walletRepository) GetWalletByCustomerXID(customerXID string) (*models.Wallet, error) {
 	var wallet models.Wallet
-	query := `SELECT id, owned_by, status, enabled_at, balance FROM wallets WHERE owned_by = $1`
-	err := r.db.QueryRow(query, customerXID).Scan(&wallet.ID, &wallet.OwnedBy, &wallet.Status, &wallet.EnabledAt, &wallet.Balance)
+	query := `SELECT id, owned_by, status, enabled_at, disabled_at, balance FROM wallets WHERE owned_by = $1`
+	err := r.db.QueryRow(query, customerXID).Scan(&wallet.ID, &wallet.OwnedBy, &wallet.Status, &wallet.EnabledAt, &wallet.DisabledAt, &wallet.Balance)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil // No wallet found
@@ -35,6 +36,10 @@ func (r *walletRepository) CreateWallet(wallet *models.Wallet) error {
 }
 
 func (r *walletRepository) GetWalletByID(id string) (*models.Wallet, error) {
+	// The id column is a UUID, anything else cannot name a wallet
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, sql.ErrNoRows
+	}
 	var wallet models.Wallet
 	query := `SELECT id, owned_by, status, enabled_at, disabled_at, balance FROM wallets
 	WHERE id = $1`
@@ -45,12 +50,76 @@ func (r *walletRepository) GetWalletByID(id string) (*models.Wallet, error) {
 	return &wallet, nil
 }
 
-func (r *walletRepository) UpdateWalletStatus(walletID string, status string, enabledAt time.Time) error {
-	query := `UPDATE wallets SET status = $1, enabled_at = $2 WHERE id = $3`
-	_, err := r.db.Exec(query, status, enabledAt, walletID)
+func (r *walletRepository) ListWallets() ([]models.Wallet, error) {
+	var wallets []models.Wallet
+	query := `SELECT id, owned_by, status, enabled_at, disabled_at, balance FROM wallets ORDER BY owned_by`
+	rows, err := r.db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var wallet models.Wallet
+		err := rows.Scan(&wallet.ID, &wallet.OwnedBy, &wallet.Status, &wallet.EnabledAt, &wallet.DisabledAt, &wallet.Balance)
+		if err != nil {
+			return nil, err
+		}
+		wallets = append(wallets, wallet)
+	}
+	return wallets, rows.Err()
+}
+
+func (r *walletRepository) UpdateWalletStatus(wallet *models.Wallet, change *models.WalletStatusChange) error {
+	return r.WithTransaction(func(tx *sql.Tx) error {
+		return updateWalletStatus(tx, wallet, change)
+	})
+}
+
+// updateWalletStatus stores the wallet's status within tx and appends change
+// to its history, failing with sql.ErrNoRows when the wallet has left
+// change.FromStatus.
+func updateWalletStatus(tx *sql.Tx, wallet *models.Wallet, change *models.WalletStatusChange) error {
+	query := `UPDATE wallets SET status = $1, enabled_at = $2, disabled_at = $3 WHERE id = $4 AND status = $5`
+	result, err := tx.Exec(query, wallet.Status, wallet.EnabledAt, wallet.DisabledAt, wallet.ID, change.FromStatus)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+
+	query = `INSERT INTO wallet_status_history (id, wallet_id, from_status, to_status, reason, actor_type, actor_id, changed_at)
+			 VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+	_, err = tx.Exec(query, change.ID, change.WalletID, change.FromStatus, change.ToStatus, change.Reason, change.ActorType, change.ActorID, change.ChangedAt)
 	return err
 }
 
+func (r *walletRepository) ListStatusChanges(walletID string) ([]models.WalletStatusChange, error) {
+	query := `SELECT id, wallet_id, from_status, to_status, reason, actor_type, actor_id, changed_at
+			  FROM wallet_status_history WHERE wallet_id = $1 ORDER BY changed_at, id`
+	rows, err := r.db.Query(query, walletID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var changes []models.WalletStatusChange
+	for rows.Next() {
+		var change models.WalletStatusChange
+		err := rows.Scan(&change.ID, &change.WalletID, &change.FromStatus, &change.ToStatus, &change.Reason, &change.ActorType, &change.ActorID, &change.ChangedAt)
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, change)
+	}
+	return changes, rows.Err()
+}
+
 func (r *walletRepository) UpdateWallet(wallet *models.Wallet) error {
 	query := `UPDATE wallets SET status = $1, enabled_at = $2, disabled_at = $3, balance = $4 WHERE id = $5`
 	_, err := r.db.Exec(query, wallet.Status, wallet.EnabledAt, wallet.DisabledAt, wallet.Balance, wallet.ID)
@@ -67,26 +136,26 @@ func (r *walletRepository) UpdateWalletBalance(walletID string, newBalance int64
 }
 
 func (r *walletRepository) UpdateWalletBalanceWithTx(tx *sql.Tx, walletID string, balance int64) error {
-    query := `UPDATE wallets SET balance = $1 WHERE id = $2`
-    _, err := tx.Exec(query, balance, walletID)
-    return err
+	query := `UPDATE wallets SET balance = $1 WHERE id = $2`
+	_, err := tx.Exec(query, balance, walletID)
+	return err
 }
 
 func (r *walletRepository) WithTransaction(fn func(tx *sql.Tx) error) error {
-    tx, err := r.db.Begin()
-    if err != nil {
-        return err
-    }
-
-    // Execute the provided function within the transaction
-    if err := fn(tx); err != nil {
-        // Rollback on error
-        if rollbackErr := tx.Rollback(); rollbackErr != nil {
-            return rollbackErr
-        }
-        return err
-    }
-
-    // Commit the transaction if no errors
-    return tx.Commit()
-}
\ No newline at end of file
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	// Execute the provided function within the transaction
+	if err := fn(tx); err != nil {
+		// Rollback on error
+		if rollbackErr := tx.Rollback(); rollbackErr != nil {
+			return rollbackErr
+		}
+		return err
+	}
+
+	// Commit the transaction if no errors
+	return tx.Commit()
+}
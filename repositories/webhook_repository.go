@@ -0,0 +1,195 @@
+package repositories
+
+import (
+	"database/sql"
+	"time"
+
+	"mini-wallet/models"
+
+	"github.com/lib/pq"
+)
+
+type WebhookRepository interface {
+	CreateSubscription(subscription *models.WebhookSubscription) error
+	GetSubscription(id string) (*models.WebhookSubscription, error)
+	ListSubscriptionsByCustomerXID(customerXID string) ([]models.WebhookSubscription, error)
+	DeleteSubscription(id, customerXID string) error
+	// EnqueueForEvent queues one delivery per subscription of the customer
+	// interested in eventType and reports how many were queued.
+	EnqueueForEvent(customerXID, eventID, eventType string, payload []byte) (int, error)
+	// ClaimDueDeliveries leases up to limit pending deliveries that are due,
+	// pushing their next attempt past lease so no other worker picks them up.
+	ClaimDueDeliveries(limit int, lease time.Duration) ([]models.WebhookDelivery, error)
+	MarkDelivered(id string, statusCode int) error
+	MarkFailed(id string, attempts int, statusCode int, lastError string, nextAttemptAt time.Time, dead bool) error
+	GetDelivery(id string) (*models.WebhookDelivery, error)
+	ListDeliveriesBySubscriptionID(subscriptionID string, limit int) ([]models.WebhookDelivery, error)
+	// Redeliver puts a delivery back in the queue with a fresh attempt budget.
+	Redeliver(id string) error
+}
+
+type webhookRepository struct {
+	db *sql.DB
+}
+
+func NewWebhookRepository(db *sql.DB) WebhookRepository {
+	return &webhookRepository{db: db}
+}
+
+func (r *webhookRepository) CreateSubscription(subscription *models.WebhookSubscription) error {
+	query := `INSERT INTO webhook_subscriptions (id, customer_xid, url, secret, event_types, created_at)
+			  VALUES ($1, $2, $3, $4, $5, $6)`
+	_, err := r.db.Exec(query, subscription.ID, subscription.CustomerXID, subscription.URL, subscription.Secret,
+		pq.Array(subscription.EventTypes), subscription.CreatedAt)
+	return err
+}
+
+func (r *webhookRepository) GetSubscription(id string) (*models.WebhookSubscription, error) {
+	var s models.WebhookSubscription
+	query := `SELECT id, customer_xid, url, secret, event_types, created_at FROM webhook_subscriptions WHERE id = $1`
+	err := r.db.QueryRow(query, id).Scan(&s.ID, &s.CustomerXID, &s.URL, &s.Secret, pq.Array(&s.EventTypes), &s.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+func (r *webhookRepository) ListSubscriptionsByCustomerXID(customerXID string) ([]models.WebhookSubscription, error) {
+	var subscriptions []models.WebhookSubscription
+	query := `SELECT id, customer_xid, url, secret, event_types, created_at FROM webhook_subscriptions
+			  WHERE customer_xid = $1 ORDER BY created_at`
+	rows, err := r.db.Query(query, customerXID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var s models.WebhookSubscription
+		if err := rows.Scan(&s.ID, &s.CustomerXID, &s.URL, &s.Secret, pq.Array(&s.EventTypes), &s.CreatedAt); err != nil {
+			return nil, err
+		}
+		subscriptions = append(subscriptions, s)
+	}
+	return subscriptions, rows.Err()
+}
+
+func (r *webhookRepository) DeleteSubscription(id, customerXID string) error {
+	query := `DELETE FROM webhook_subscriptions WHERE id = $1 AND customer_xid = $2`
+	return execAffectingOne(r.db, query, id, customerXID)
+}
+
+func (r *webhookRepository) EnqueueForEvent(customerXID, eventID, eventType string, payload []byte) (int, error) {
+	query := `INSERT INTO webhook_deliveries (id, subscription_id, event_id, event_type, payload, status, attempts, next_attempt_at, created_at)
+			  SELECT gen_random_uuid(), s.id, $2, $3, $4, $5, 0, NOW(), NOW()
+			  FROM webhook_subscriptions s
+			  WHERE s.customer_xid = $1 AND $3 = ANY(s.event_types)`
+	result, err := r.db.Exec(query, customerXID, eventID, eventType, payload, models.DeliveryPending)
+	if err != nil {
+		return 0, err
+	}
+	queued, err := result.RowsAffected()
+	return int(queued), err
+}
+
+const deliveryColumns = `d.id, d.subscription_id, d.event_id, d.event_type, d.payload, d.status, d.attempts, d.next_attempt_at,
+	d.last_error, d.last_status_code, d.created_at, d.delivered_at`
+
+func scanDelivery(scan func(dest ...any) error, extra ...any) (*models.WebhookDelivery, error) {
+	var d models.WebhookDelivery
+	var lastError sql.NullString
+	var lastStatusCode sql.NullInt64
+	var deliveredAt sql.NullTime
+	dest := []any{&d.ID, &d.SubscriptionID, &d.EventID, &d.EventType, &d.Payload, &d.Status, &d.Attempts, &d.NextAttemptAt,
+		&lastError, &lastStatusCode, &d.CreatedAt, &deliveredAt}
+	if err := scan(append(dest, extra...)...); err != nil {
+		return nil, err
+	}
+	d.LastError = lastError.String
+	d.LastStatusCode = int(lastStatusCode.Int64)
+	if deliveredAt.Valid {
+		d.DeliveredAt = &deliveredAt.Time
+	}
+	return &d, nil
+}
+
+func (r *webhookRepository) ClaimDueDeliveries(limit int, lease time.Duration) ([]models.WebhookDelivery, error) {
+	query := `WITH due AS (
+				SELECT id FROM webhook_deliveries
+				WHERE status = $1 AND next_attempt_at <= NOW()
+				ORDER BY next_attempt_at
+				LIMIT $2
+				FOR UPDATE SKIP LOCKED
+			  ), claimed AS (
+				UPDATE webhook_deliveries d SET next_attempt_at = NOW() + $3 * INTERVAL '1 millisecond'
+				FROM due WHERE d.id = due.id
+				RETURNING d.*
+			  )
+			  SELECT ` + deliveryColumns + `, s.url, s.secret
+			  FROM claimed d JOIN webhook_subscriptions s ON s.id = d.subscription_id`
+	rows, err := r.db.Query(query, models.DeliveryPending, limit, lease.Milliseconds())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deliveries []models.WebhookDelivery
+	for rows.Next() {
+		var url, secret string
+		d, err := scanDelivery(rows.Scan, &url, &secret)
+		if err != nil {
+			return nil, err
+		}
+		d.URL, d.Secret = url, secret
+		deliveries = append(deliveries, *d)
+	}
+	return deliveries, rows.Err()
+}
+
+func (r *webhookRepository) MarkDelivered(id string, statusCode int) error {
+	query := `UPDATE webhook_deliveries SET status = $1, attempts = attempts + 1, last_status_code = $2, last_error = NULL,
+			  delivered_at = NOW() WHERE id = $3`
+	_, err := r.db.Exec(query, models.DeliveryDelivered, statusCode, id)
+	return err
+}
+
+func (r *webhookRepository) MarkFailed(id string, attempts int, statusCode int, lastError string, nextAttemptAt time.Time, dead bool) error {
+	status := models.DeliveryPending
+	if dead {
+		status = models.DeliveryDead
+	}
+	query := `UPDATE webhook_deliveries SET status = $1, attempts = $2, last_status_code = NULLIF($3, 0), last_error = $4,
+			  next_attempt_at = $5 WHERE id = $6`
+	_, err := r.db.Exec(query, status, attempts, statusCode, lastError, nextAttemptAt, id)
+	return err
+}
+
+func (r *webhookRepository) GetDelivery(id string) (*models.WebhookDelivery, error) {
+	query := `SELECT ` + deliveryColumns + ` FROM webhook_deliveries d WHERE d.id = $1`
+	return scanDelivery(r.db.QueryRow(query, id).Scan)
+}
+
+func (r *webhookRepository) ListDeliveriesBySubscriptionID(subscriptionID string, limit int) ([]models.WebhookDelivery, error) {
+	query := `SELECT ` + deliveryColumns + ` FROM webhook_deliveries d
+			  WHERE d.subscription_id = $1 ORDER BY d.created_at DESC LIMIT $2`
+	rows, err := r.db.Query(query, subscriptionID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deliveries []models.WebhookDelivery
+	for rows.Next() {
+		d, err := scanDelivery(rows.Scan)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, *d)
+	}
+	return deliveries, rows.Err()
+}
+
+func (r *webhookRepository) Redeliver(id string) error {
+	query := `UPDATE webhook_deliveries SET status = $1, attempts = 0, next_attempt_at = NOW(), delivered_at = NULL WHERE id = $2`
+	return execAffectingOne(r.db, query, models.DeliveryPending, id)
+}
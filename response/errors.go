@@ -0,0 +1,224 @@
+package response
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// Code is a stable, machine-readable error identifier.
+type Code string
+
+// Error codes and the HTTP status /api/v2 returns for each. /api/v1 answers
+// with the status in v1Status where it historically differed.
+//
+//	auth_required                401  no Authorization header
+//	invalid_token                401  unknown or revoked token
+//	forbidden                    403  credential's role does not allow the action
+//	validation_failed            400  malformed or missing input, see fields
+//	not_found                    404  requested resource does not exist
+//	wallet_not_found             404  customer has no wallet
+//	wallet_disabled              409  operation needs an enabled wallet
+//	wallet_already_enabled       409
+//	wallet_already_disabled      409
+//	wallet_frozen                409  wallet is frozen by staff
+//	wallet_not_frozen            409  unfreezing a wallet that is not frozen
+//	wallet_suspended             409  wallet is suspended by staff
+//	wallet_not_suspended         409  reinstating a wallet that is not suspended
+//	wallet_closed                409  wallet is closed for good
+//	kyc_tier_required            403  customer's KYC tier does not allow the operation
+//	pin_required                 403  withdrawal needs the transaction PIN or a step-up token
+//	invalid_pin                  403  wrong transaction PIN
+//	invalid_step_up_token        403  unknown, used or expired step-up token
+//	pin_not_set                  409  customer has no transaction PIN
+//	pin_locked                   429  transaction PIN locked after too many wrong attempts
+//	totp_required                403  operation needs a one-time code or recovery code
+//	invalid_totp                 403  wrong, reused or expired one-time code
+//	totp_locked                  429  one-time codes locked after too many wrong attempts
+//	totp_already_enrolled        409  customer already has an authenticator app in effect
+//	totp_not_enrolled            409  customer has no authenticator app enrolled
+//	invalid_status_transition    409  the wallet's status cannot change that way
+//	adjustment_not_pending       409  adjustment was already approved or rejected
+//	risk_decision_not_held       409  risk decision was already released or rejected
+//	standing_order_inactive      409  standing order was completed, failed or cancelled
+//	payment_request_not_pending  409  payment request was paid, declined, cancelled or expired
+//	duplicate_reference          409  reference_id was already used
+//	payee_not_found              404  transfer payee has no wallet
+//	payer_not_found              404  payment request payer has no wallet
+//	insufficient_balance         422  withdrawal or transfer exceeds the balance
+//	balance_changed              409  balance moved while the request was processed, retry it
+//	payee_unavailable            422  transfer payee's wallet cannot receive it
+//	limit_exceeded               422  amount exceeds a configured limit
+//	transaction_blocked          422  risk rules blocked the transaction
+//	rate_limited                 429  too many requests, see Retry-After
+//	internal_error               500
+const (
+	CodeAuthRequired             Code = "auth_required"
+	CodeInvalidToken             Code = "invalid_token"
+	CodeForbidden                Code = "forbidden"
+	CodeValidation               Code = "validation_failed"
+	CodeNotFound                 Code = "not_found"
+	CodeWalletNotFound           Code = "wallet_not_found"
+	CodeWalletDisabled           Code = "wallet_disabled"
+	CodeWalletAlreadyEnabled     Code = "wallet_already_enabled"
+	CodeWalletAlreadyDisabled    Code = "wallet_already_disabled"
+	CodeWalletFrozen             Code = "wallet_frozen"
+	CodeWalletNotFrozen          Code = "wallet_not_frozen"
+	CodeWalletSuspended          Code = "wallet_suspended"
+	CodeWalletNotSuspended       Code = "wallet_not_suspended"
+	CodeWalletClosed             Code = "wallet_closed"
+	CodeKYCTierRequired          Code = "kyc_tier_required"
+	CodePINRequired              Code = "pin_required"
+	CodeInvalidPIN               Code = "invalid_pin"
+	CodeInvalidStepUpToken       Code = "invalid_step_up_token"
+	CodePINNotSet                Code = "pin_not_set"
+	CodePINLocked                Code = "pin_locked"
+	CodeTOTPRequired             Code = "totp_required"
+	CodeInvalidTOTP              Code = "invalid_totp"
+	CodeTOTPLocked               Code = "totp_locked"
+	CodeTOTPAlreadyEnrolled      Code = "totp_already_enrolled"
+	CodeTOTPNotEnrolled          Code = "totp_not_enrolled"
+	CodeInvalidTransition        Code = "invalid_status_transition"
+	CodeAdjustmentNotPending     Code = "adjustment_not_pending"
+	CodeRiskDecisionNotHeld      Code = "risk_decision_not_held"
+	CodeStandingOrderInactive    Code = "standing_order_inactive"
+	CodePaymentRequestNotPending Code = "payment_request_not_pending"
+	CodePayeeNotFound            Code = "payee_not_found"
+	CodePayerNotFound            Code = "payer_not_found"
+	CodePayeeUnavailable         Code = "payee_unavailable"
+	CodeDuplicateReference       Code = "duplicate_reference"
+	CodeInsufficientBalance      Code = "insufficient_balance"
+	CodeBalanceChanged           Code = "balance_changed"
+	CodeLimitExceeded            Code = "limit_exceeded"
+	CodeTransactionBlocked       Code = "transaction_blocked"
+	CodeRateLimited              Code = "rate_limited"
+	CodeInternal                 Code = "internal_error"
+)
+
+var statusByCode = map[Code]int{
+	CodeAuthRequired:             http.StatusUnauthorized,
+	CodeInvalidToken:             http.StatusUnauthorized,
+	CodeForbidden:                http.StatusForbidden,
+	CodeValidation:               http.StatusBadRequest,
+	CodeNotFound:                 http.StatusNotFound,
+	CodeWalletNotFound:           http.StatusNotFound,
+	CodeWalletDisabled:           http.StatusConflict,
+	CodeWalletAlreadyEnabled:     http.StatusConflict,
+	CodeWalletAlreadyDisabled:    http.StatusConflict,
+	CodeWalletFrozen:             http.StatusConflict,
+	CodeWalletNotFrozen:          http.StatusConflict,
+	CodeWalletSuspended:          http.StatusConflict,
+	CodeWalletNotSuspended:       http.StatusConflict,
+	CodeWalletClosed:             http.StatusConflict,
+	CodeKYCTierRequired:          http.StatusForbidden,
+	CodePINRequired:              http.StatusForbidden,
+	CodeInvalidPIN:               http.StatusForbidden,
+	CodeInvalidStepUpToken:       http.StatusForbidden,
+	CodePINNotSet:                http.StatusConflict,
+	CodePINLocked:                http.StatusTooManyRequests,
+	CodeTOTPRequired:             http.StatusForbidden,
+	CodeInvalidTOTP:              http.StatusForbidden,
+	CodeTOTPLocked:               http.StatusTooManyRequests,
+	CodeTOTPAlreadyEnrolled:      http.StatusConflict,
+	CodeTOTPNotEnrolled:          http.StatusConflict,
+	CodeInvalidTransition:        http.StatusConflict,
+	CodeAdjustmentNotPending:     http.StatusConflict,
+	CodeRiskDecisionNotHeld:      http.StatusConflict,
+	CodeStandingOrderInactive:    http.StatusConflict,
+	CodePaymentRequestNotPending: http.StatusConflict,
+	CodePayeeNotFound:            http.StatusNotFound,
+	CodePayerNotFound:            http.StatusNotFound,
+	CodePayeeUnavailable:         http.StatusUnprocessableEntity,
+	CodeDuplicateReference:       http.StatusConflict,
+	CodeInsufficientBalance:      http.StatusUnprocessableEntity,
+	CodeBalanceChanged:           http.StatusConflict,
+	CodeLimitExceeded:            http.StatusUnprocessableEntity,
+	CodeTransactionBlocked:       http.StatusUnprocessableEntity,
+	CodeRateLimited:              http.StatusTooManyRequests,
+	CodeInternal:                 http.StatusInternalServerError,
+}
+
+var v1Status = map[Code]int{
+	CodeWalletDisabled:           http.StatusNotFound,
+	CodeWalletAlreadyEnabled:     http.StatusBadRequest,
+	CodeWalletAlreadyDisabled:    http.StatusBadRequest,
+	CodeWalletFrozen:             http.StatusBadRequest,
+	CodeWalletSuspended:          http.StatusBadRequest,
+	CodeWalletClosed:             http.StatusNotFound,
+	CodeKYCTierRequired:          http.StatusBadRequest,
+	CodePINRequired:              http.StatusBadRequest,
+	CodeInvalidPIN:               http.StatusBadRequest,
+	CodeInvalidStepUpToken:       http.StatusBadRequest,
+	CodePINNotSet:                http.StatusBadRequest,
+	CodePINLocked:                http.StatusBadRequest,
+	CodeTOTPRequired:             http.StatusBadRequest,
+	CodeInvalidTOTP:              http.StatusBadRequest,
+	CodeTOTPLocked:               http.StatusBadRequest,
+	CodeTOTPAlreadyEnrolled:      http.StatusBadRequest,
+	CodeTOTPNotEnrolled:          http.StatusBadRequest,
+	CodeInvalidTransition:        http.StatusBadRequest,
+	CodeDuplicateReference:       http.StatusBadRequest,
+	CodeInsufficientBalance:      http.StatusBadRequest,
+	CodeBalanceChanged:           http.StatusBadRequest,
+	CodeLimitExceeded:            http.StatusBadRequest,
+	CodeTransactionBlocked:       http.StatusBadRequest,
+	CodeStandingOrderInactive:    http.StatusBadRequest,
+	CodePayeeUnavailable:         http.StatusBadRequest,
+	CodePaymentRequestNotPending: http.StatusBadRequest,
+}
+
+// Error is a request failure with everything needed to render it.
+type Error struct {
+	Status  int
+	Code    Code
+	Message string
+	// Fields holds per-field validation messages.
+	Fields map[string][]string
+	// Err is the underlying cause, it is never shown by V2.
+	Err error
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%s: %s: %v", e.Code, e.Message, e.Err)
+	}
+	return fmt.Sprintf("%s: %s", e.Code, e.Message)
+}
+
+func (e *Error) Unwrap() error { return e.Err }
+
+// publicMessage is the message V2 shows, never the underlying cause.
+func (e *Error) publicMessage() string {
+	if e.Message != "" {
+		return e.Message
+	}
+	switch e.Code {
+	case CodeValidation:
+		return "Request validation failed"
+	case CodeInternal:
+		return "Internal server error"
+	}
+	return string(e.Code)
+}
+
+// New creates an Error with the status mapped to code.
+func New(code Code, message string) *Error {
+	status, ok := statusByCode[code]
+	if !ok {
+		status = http.StatusInternalServerError
+	}
+	return &Error{Status: status, Code: code, Message: message}
+}
+
+// Validation creates a validation error with per-field messages.
+func Validation(message string, fields map[string][]string) *Error {
+	e := New(CodeValidation, message)
+	e.Fields = fields
+	return e
+}
+
+// Internal creates a server error, message is shown to clients and err is kept for logs.
+func Internal(message string, err error) *Error {
+	e := New(CodeInternal, message)
+	e.Err = err
+	return e
+}
@@ -0,0 +1,110 @@
+// Package response renders API responses in JSend-style envelopes.
+//
+// Every response has a "status" of "success", "fail" (the client must change
+// the request) or "error" (the server could not handle it):
+//
+//	{"status": "success", "data": {...}}
+//	{"status": "fail", "data": {"error": {"code": "...", "message": "...", "fields": {...}}}}
+//	{"status": "error", "code": "internal_error", "message": "..."}
+//
+// /api/v1 keeps its historical shapes through V1, /api/v2 uses V2.
+package response
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Writer renders a failed request in the shape of one API version.
+type Writer func(c *gin.Context, err *Error)
+
+// codeKey holds the code of the error a request was answered with in the
+// gin context.
+const codeKey = "response_code"
+
+// CodeOf returns the code of the error V1 or V2 answered c with, or "" when
+// it did not fail.
+func CodeOf(c *gin.Context) Code {
+	code, _ := c.Get(codeKey)
+	failure, _ := code.(Code)
+	return failure
+}
+
+// Success writes a success envelope around data. It is identical in every version.
+func Success(c *gin.Context, status int, data any) {
+	c.JSON(status, gin.H{
+		"status": "success",
+		"data":   data,
+	})
+}
+
+// V2 writes the typed fail/error envelopes of /api/v2.
+func V2(c *gin.Context, err *Error) {
+	c.Set(codeKey, err.Code)
+	if err.Status >= http.StatusInternalServerError {
+		c.JSON(err.Status, gin.H{
+			"status":  "error",
+			"code":    err.Code,
+			"message": err.publicMessage(),
+		})
+		return
+	}
+
+	body := gin.H{
+		"code":    err.Code,
+		"message": err.publicMessage(),
+	}
+	if len(err.Fields) > 0 {
+		body["fields"] = err.Fields
+	}
+	c.JSON(err.Status, gin.H{
+		"status": "fail",
+		"data": gin.H{
+			"error": body,
+		},
+	})
+}
+
+// V1 writes the envelopes /api/v1 has always returned, including its status
+// codes, so existing clients see byte-identical responses.
+func V1(c *gin.Context, err *Error) {
+	c.Set(codeKey, err.Code)
+	status := err.Status
+	if legacy, ok := v1Status[err.Code]; ok {
+		status = legacy
+	}
+
+	switch {
+	case status >= http.StatusInternalServerError:
+		message := err.Message
+		if message == "" && err.Err != nil {
+			message = err.Err.Error()
+		}
+		c.JSON(status, gin.H{
+			"status":  "error",
+			"message": message,
+		})
+	case err.Code == CodeDuplicateReference:
+		c.JSON(status, gin.H{
+			"status": "fail",
+			"data": gin.H{
+				"reference_id": "duplicate reference_id",
+			},
+		})
+	case err.Message == "" && len(err.Fields) > 0:
+		c.JSON(status, gin.H{
+			"status": "fail",
+			"data": gin.H{
+				"error": err.Fields,
+			},
+		})
+	default:
+		c.JSON(status, gin.H{
+			"status": "fail",
+			"data": gin.H{
+				"error": err.Message,
+			},
+		})
+	}
+}
@@ -0,0 +1,129 @@
+// Package risk decides whether a deposit or withdrawal may be posted, by
+// evaluating configurable rules against the transaction and the wallet's
+// recent history.
+package risk
+
+import (
+	"time"
+
+	"mini-wallet/models"
+)
+
+// Outcome is what a rule, or the engine as a whole, decides. Outcomes are
+// ordered by severity.
+type Outcome string
+
+const (
+	Allow  Outcome = "allow"
+	Review Outcome = "review"
+	Block  Outcome = "block"
+)
+
+var severity = map[Outcome]int{Allow: 0, Review: 1, Block: 2}
+
+// Valid reports whether o is a known outcome.
+func (o Outcome) Valid() bool {
+	_, ok := severity[o]
+	return ok
+}
+
+// Input is a transaction about to be posted, with what the rules may need
+// to know about its wallet.
+type Input struct {
+	Type   string
+	Amount int64
+	Now    time.Time
+	// TokenCreatedAt is when the customer's current token was issued.
+	TokenCreatedAt time.Time
+	// History holds the wallet's posted transactions within the engine's
+	// Lookback before Now, in any order.
+	History []models.Transaction
+}
+
+// Rule is one check of the engine. Match reports whether the input trips
+// the rule, and why.
+type Rule interface {
+	Match(in *Input) (bool, string)
+}
+
+// Windowed is implemented by rules that only look at the history within
+// Window before the transaction, none at all when it is 0. Rules without
+// it are given the whole history.
+type Windowed interface {
+	Window() time.Duration
+}
+
+// Match is a rule that tripped.
+type Match struct {
+	Rule    string  `json:"rule"`
+	Outcome Outcome `json:"outcome"`
+	Reason  string  `json:"reason"`
+}
+
+// Decision is the engine's verdict: the most severe outcome of the rules
+// that matched, or Allow when none did.
+type Decision struct {
+	Outcome Outcome
+	Matches []Match
+}
+
+// named is a rule with the name and outcome it was configured with.
+type named struct {
+	name    string
+	outcome Outcome
+	rule    Rule
+}
+
+// Engine evaluates every rule against each transaction. The zero Engine
+// has no rules and allows everything.
+type Engine struct {
+	rules []named
+}
+
+// Add appends a rule that decides outcome when it matches.
+func (e *Engine) Add(name string, outcome Outcome, rule Rule) {
+	e.rules = append(e.rules, named{name: name, outcome: outcome, rule: rule})
+}
+
+// Empty reports whether the engine has no rules, so callers can skip
+// gathering its input.
+func (e *Engine) Empty() bool {
+	return e == nil || len(e.rules) == 0
+}
+
+// Lookback is how far back the rules look into the wallet's history: the
+// longest window of the rules, or false when a rule needs the whole
+// history.
+func (e *Engine) Lookback() (time.Duration, bool) {
+	if e == nil {
+		return 0, true
+	}
+	var lookback time.Duration
+	for _, r := range e.rules {
+		windowed, ok := r.rule.(Windowed)
+		if !ok {
+			return 0, false
+		}
+		lookback = max(lookback, windowed.Window())
+	}
+	return lookback, true
+}
+
+// Evaluate runs every rule, so the decision lists all that matched.
+func (e *Engine) Evaluate(in *Input) Decision {
+	decision := Decision{Outcome: Allow}
+	if e == nil {
+		return decision
+	}
+	for _, r := range e.rules {
+		matched, reason := r.rule.Match(in)
+		if !matched {
+			continue
+		}
+		decision.Matches = append(decision.Matches, Match{Rule: r.name, Outcome: r.outcome, Reason: reason})
+		if severity[r.outcome] > severity[decision.Outcome] {
+			decision.Outcome = r.outcome
+		}
+	}
+	return decision
+}
@@ -0,0 +1,256 @@
+package risk
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// RuleConfig is one rule of a rules file. Kind selects the check; the
+// remaining fields are the settings of that kind.
+type RuleConfig struct {
+	Name    string  `yaml:"name"`
+	Kind    string  `yaml:"kind"`
+	Outcome Outcome `yaml:"outcome"`
+	// Type limits the rule to deposits or withdrawals; empty matches both.
+	Type       string        `yaml:"type"`
+	MaxCount   int           `yaml:"max_count"`
+	Window     time.Duration `yaml:"window"`
+	Multiplier float64       `yaml:"multiplier"`
+	MinHistory int           `yaml:"min_history"`
+	MinAmount  int64         `yaml:"min_amount"`
+	TokenAge   time.Duration `yaml:"token_age"`
+	Ratio      float64       `yaml:"ratio"`
+}
+
+// Factory builds the rule of one kind from its settings.
+type Factory func(cfg RuleConfig) (Rule, error)
+
+var kinds = map[string]Factory{
+	"velocity":              newVelocity,
+	"amount_anomaly":        newAmountAnomaly,
+	"new_token":             newNewToken,
+	"deposit_then_withdraw": newDepositThenWithdraw,
+}
+
+// Register makes a rule kind available to rules files, replacing a
+// built-in kind of the same name.
+func Register(kind string, factory Factory) {
+	kinds[kind] = factory
+}
+
+// Load builds an engine from a YAML rules file.
+func Load(path string) (*Engine, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading risk rules: %w", err)
+	}
+	return Parse(data)
+}
+
+// Parse builds an engine from the YAML of a rules file, reporting every
+// invalid rule at once.
+func Parse(data []byte) (*Engine, error) {
+	var file struct {
+		Rules []RuleConfig `yaml:"rules"`
+	}
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("parsing risk rules: %w", err)
+	}
+
+	engine := &Engine{}
+	var errs []error
+	names := make(map[string]bool)
+	for i, cfg := range file.Rules {
+		if cfg.Name == "" {
+			cfg.Name = fmt.Sprintf("rule %d", i+1)
+		}
+		if names[cfg.Name] {
+			errs = append(errs, fmt.Errorf("%s: duplicate name", cfg.Name))
+			continue
+		}
+		names[cfg.Name] = true
+		rule, err := build(cfg)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", cfg.Name, err))
+			continue
+		}
+		engine.Add(cfg.Name, cfg.Outcome, rule)
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return engine, nil
+}
+
+func build(cfg RuleConfig) (Rule, error) {
+	factory, ok := kinds[cfg.Kind]
+	if !ok {
+		return nil, fmt.Errorf("unknown kind %q", cfg.Kind)
+	}
+	if cfg.Outcome != Review && cfg.Outcome != Block {
+		return nil, errors.New("outcome must be review or block")
+	}
+	if cfg.Type != "" && cfg.Type != "deposit" && cfg.Type != "withdrawal" {
+		return nil, errors.New("type must be deposit or withdrawal")
+	}
+	return factory(cfg)
+}
+
+// applies reports whether a rule limited to ruleType covers transactionType.
+func applies(ruleType, transactionType string) bool {
+	return ruleType == "" || ruleType == transactionType
+}
+
+// velocity trips when the wallet already made MaxCount transactions of the
+// type within Window.
+type velocity struct {
+	kind     string
+	maxCount int
+	window   time.Duration
+}
+
+func newVelocity(cfg RuleConfig) (Rule, error) {
+	if cfg.MaxCount <= 0 || cfg.Window <= 0 {
+		return nil, errors.New("velocity needs a positive max_count and window")
+	}
+	return &velocity{kind: cfg.Type, maxCount: cfg.MaxCount, window: cfg.Window}, nil
+}
+
+func (r *velocity) Window() time.Duration { return r.window }
+
+func (r *velocity) Match(in *Input) (bool, string) {
+	if !applies(r.kind, in.Type) {
+		return false, ""
+	}
+	since := in.Now.Add(-r.window)
+	count := 0
+	for _, t := range in.History {
+		if t.Type == in.Type && t.TransactedAt.After(since) {
+			count++
+		}
+	}
+	if count < r.maxCount {
+		return false, ""
+	}
+	return true, fmt.Sprintf("%d %ss in the last %s", count, in.Type, r.window)
+}
+
+// defaultAnomalyWindow is the history amount_anomaly averages when its
+// window is not set.
+const defaultAnomalyWindow = 30 * 24 * time.Hour
+
+// amountAnomaly trips when the amount exceeds Multiplier times the average
+// of the wallet's transactions of the type within Window, once there are at
+// least MinHistory of them.
+type amountAnomaly struct {
+	kind       string
+	multiplier float64
+	minHistory int
+	window     time.Duration
+}
+
+func newAmountAnomaly(cfg RuleConfig) (Rule, error) {
+	if cfg.Multiplier <= 1 {
+		return nil, errors.New("amount_anomaly needs a multiplier above 1")
+	}
+	if cfg.Window < 0 {
+		return nil, errors.New("amount_anomaly needs a window not below 0")
+	}
+	if cfg.MinHistory <= 0 {
+		cfg.MinHistory = 1
+	}
+	if cfg.Window == 0 {
+		cfg.Window = defaultAnomalyWindow
+	}
+	return &amountAnomaly{kind: cfg.Type, multiplier: cfg.Multiplier, minHistory: cfg.MinHistory, window: cfg.Window}, nil
+}
+
+func (r *amountAnomaly) Window() time.Duration { return r.window }
+
+func (r *amountAnomaly) Match(in *Input) (bool, string) {
+	if !applies(r.kind, in.Type) {
+		return false, ""
+	}
+	since := in.Now.Add(-r.window)
+	var count, total int64
+	for _, t := range in.History {
+		if t.Type == in.Type && t.TransactedAt.After(since) {
+			count++
+			total += t.Amount
+		}
+	}
+	if count < int64(r.minHistory) {
+		return false, ""
+	}
+	average := float64(total) / float64(count)
+	if float64(in.Amount) <= r.multiplier*average {
+		return false, ""
+	}
+	return true, fmt.Sprintf("amount %d is over %g times the average %s of %.0f", in.Amount, r.multiplier, in.Type, average)
+}
+
+// newToken trips on amounts of at least MinAmount from a token issued less
+// than TokenAge ago.
+type newToken struct {
+	kind      string
+	tokenAge  time.Duration
+	minAmount int64
+}
+
+func newNewToken(cfg RuleConfig) (Rule, error) {
+	if cfg.TokenAge <= 0 || cfg.MinAmount < 0 {
+		return nil, errors.New("new_token needs a positive token_age and a min_amount not below 0")
+	}
+	return &newToken{kind: cfg.Type, tokenAge: cfg.TokenAge, minAmount: cfg.MinAmount}, nil
+}
+
+// Window is 0, the rule needs no history.
+func (r *newToken) Window() time.Duration { return 0 }
+
+func (r *newToken) Match(in *Input) (bool, string) {
+	if !applies(r.kind, in.Type) || in.TokenCreatedAt.IsZero() || in.Amount < r.minAmount {
+		return false, ""
+	}
+	age := in.Now.Sub(in.TokenCreatedAt)
+	if age >= r.tokenAge {
+		return false, ""
+	}
+	return true, fmt.Sprintf("%s of %d with a token issued %s ago", in.Type, in.Amount, age.Round(time.Second))
+}
+
+// depositThenWithdraw trips on a withdrawal of at least Ratio of the
+// deposits made within Window, such as funds passing straight through.
+type depositThenWithdraw struct {
+	window time.Duration
+	ratio  float64
+}
+
+func newDepositThenWithdraw(cfg RuleConfig) (Rule, error) {
+	if cfg.Window <= 0 || cfg.Ratio <= 0 || cfg.Ratio > 1 {
+		return nil, errors.New("deposit_then_withdraw needs a positive window and a ratio in (0, 1]")
+	}
+	return &depositThenWithdraw{window: cfg.Window, ratio: cfg.Ratio}, nil
+}
+
+func (r *depositThenWithdraw) Window() time.Duration { return r.window }
+
+func (r *depositThenWithdraw) Match(in *Input) (bool, string) {
+	if in.Type != "withdrawal" {
+		return false, ""
+	}
+	since := in.Now.Add(-r.window)
+	var deposited int64
+	for _, t := range in.History {
+		if t.Type == "deposit" && t.TransactedAt.After(since) {
+			deposited += t.Amount
+		}
+	}
+	if deposited == 0 || float64(in.Amount) < r.ratio*float64(deposited) {
+		return false, ""
+	}
+	return true, fmt.Sprintf("withdrawal of %d after deposits of %d in the last %s", in.Amount, deposited, r.window)
+}
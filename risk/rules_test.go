@@ -0,0 +1,174 @@
+package risk
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"mini-wallet/models"
+)
+
+var now = time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+func transaction(kind string, amount int64, ago time.Duration) models.Transaction {
+	return models.Transaction{Type: kind, Amount: amount, TransactedAt: now.Add(-ago)}
+}
+
+func TestParseReportsEveryInvalidRule(t *testing.T) {
+	_, err := Parse([]byte(`
+rules:
+  - name: a
+    kind: velocity
+    outcome: block
+  - name: a
+    kind: velocity
+    max_count: 1
+    window: 1m
+    outcome: block
+  - name: b
+    kind: teleport
+    outcome: review
+  - name: c
+    kind: new_token
+    token_age: 1h
+    outcome: allow
+`))
+	if err == nil {
+		t.Fatal("Parse succeeded, want errors")
+	}
+	for _, want := range []string{"a: velocity needs", "a: duplicate name", `b: unknown kind "teleport"`, "c: outcome must be review or block"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not mention %q", err, want)
+		}
+	}
+}
+
+func TestRules(t *testing.T) {
+	tests := []struct {
+		name  string
+		rules string
+		in    Input
+		want  Outcome
+	}{
+		{
+			name:  "velocity trips at max_count",
+			rules: "kind: velocity\n    type: withdrawal\n    max_count: 2\n    window: 10m",
+			in:    Input{Type: "withdrawal", Amount: 10, History: []models.Transaction{transaction("withdrawal", 10, time.Minute), transaction("withdrawal", 10, 5*time.Minute)}},
+			want:  Block,
+		},
+		{
+			name:  "velocity ignores older transactions",
+			rules: "kind: velocity\n    type: withdrawal\n    max_count: 2\n    window: 10m",
+			in:    Input{Type: "withdrawal", Amount: 10, History: []models.Transaction{transaction("withdrawal", 10, time.Minute), transaction("withdrawal", 10, time.Hour)}},
+			want:  Allow,
+		},
+		{
+			name:  "velocity ignores the other type",
+			rules: "kind: velocity\n    type: withdrawal\n    max_count: 1\n    window: 10m",
+			in:    Input{Type: "deposit", Amount: 10, History: []models.Transaction{transaction("withdrawal", 10, time.Minute)}},
+			want:  Allow,
+		},
+		{
+			name:  "amount anomaly trips above the multiple of the average",
+			rules: "kind: amount_anomaly\n    multiplier: 3\n    min_history: 2",
+			in:    Input{Type: "deposit", Amount: 301, History: []models.Transaction{transaction("deposit", 50, time.Hour), transaction("deposit", 150, time.Hour)}},
+			want:  Block,
+		},
+		{
+			name:  "amount anomaly needs min_history",
+			rules: "kind: amount_anomaly\n    multiplier: 3\n    min_history: 2",
+			in:    Input{Type: "deposit", Amount: 1000, History: []models.Transaction{transaction("deposit", 50, time.Hour)}},
+			want:  Allow,
+		},
+		{
+			name:  "amount anomaly averages within the window",
+			rules: "kind: amount_anomaly\n    multiplier: 3\n    window: 24h",
+			in:    Input{Type: "deposit", Amount: 301, History: []models.Transaction{transaction("deposit", 100, time.Hour), transaction("deposit", 1000, 48*time.Hour)}},
+			want:  Block,
+		},
+		{
+			name:  "new token trips on a large amount",
+			rules: "kind: new_token\n    token_age: 24h\n    min_amount: 500",
+			in:    Input{Type: "withdrawal", Amount: 500, TokenCreatedAt: now.Add(-time.Hour)},
+			want:  Block,
+		},
+		{
+			name:  "new token allows an old token",
+			rules: "kind: new_token\n    token_age: 24h\n    min_amount: 500",
+			in:    Input{Type: "withdrawal", Amount: 500, TokenCreatedAt: now.Add(-48 * time.Hour)},
+			want:  Allow,
+		},
+		{
+			name:  "deposit then withdraw trips on a pass-through",
+			rules: "kind: deposit_then_withdraw\n    window: 1h\n    ratio: 0.9",
+			in:    Input{Type: "withdrawal", Amount: 950, History: []models.Transaction{transaction("deposit", 1000, 10*time.Minute)}},
+			want:  Block,
+		},
+		{
+			name:  "deposit then withdraw allows a partial withdrawal",
+			rules: "kind: deposit_then_withdraw\n    window: 1h\n    ratio: 0.9",
+			in:    Input{Type: "withdrawal", Amount: 100, History: []models.Transaction{transaction("deposit", 1000, 10*time.Minute)}},
+			want:  Allow,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine, err := Parse([]byte("rules:\n  - outcome: block\n    " + tt.rules + "\n"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.in.Now = now
+			decision := engine.Evaluate(&tt.in)
+			if decision.Outcome != tt.want {
+				t.Errorf("outcome = %q, want %q (matches %+v)", decision.Outcome, tt.want, decision.Matches)
+			}
+		})
+	}
+}
+
+func TestMostSevereOutcomeWins(t *testing.T) {
+	engine := &Engine{}
+	always := ruleFunc(func(*Input) (bool, string) { return true, "always" })
+	engine.Add("review", Review, always)
+	engine.Add("block", Block, always)
+	engine.Add("never", Review, ruleFunc(func(*Input) (bool, string) { return false, "" }))
+
+	decision := engine.Evaluate(&Input{Now: now})
+	if decision.Outcome != Block || len(decision.Matches) != 2 {
+		t.Errorf("decision = %+v, want block with both matches", decision)
+	}
+	if (*Engine)(nil).Evaluate(&Input{}).Outcome != Allow {
+		t.Error("a nil engine should allow")
+	}
+}
+
+func TestLookback(t *testing.T) {
+	engine, err := Parse([]byte(`
+rules:
+  - kind: velocity
+    max_count: 1
+    window: 10m
+    outcome: block
+  - kind: deposit_then_withdraw
+    window: 1h
+    ratio: 0.9
+    outcome: review
+  - kind: new_token
+    token_age: 24h
+    outcome: review
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lookback, ok := engine.Lookback(); !ok || lookback != time.Hour {
+		t.Errorf("Lookback = %s, %v, want the longest window of 1h", lookback, ok)
+	}
+	engine.Add("custom", Review, ruleFunc(func(*Input) (bool, string) { return false, "" }))
+	if _, ok := engine.Lookback(); ok {
+		t.Error("Lookback is bounded, want the whole history for a rule without a window")
+	}
+}
+
+type ruleFunc func(in *Input) (bool, string)
+
+func (f ruleFunc) Match(in *Input) (bool, string) { return f(in) }
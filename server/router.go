@@ -0,0 +1,143 @@
+// Package server assembles the HTTP router from the handlers.
+package server
+
+import (
+	"net/http"
+
+	"mini-wallet/config"
+	"mini-wallet/docs"
+	"mini-wallet/handlers"
+	"mini-wallet/response"
+
+	"github.com/gin-gonic/gin"
+	httpSwagger "github.com/swaggo/http-swagger"
+)
+
+type Handlers struct {
+	Init           *handlers.InitHandler
+	Wallet         *handlers.WalletHandler
+	Closure        *handlers.ClosureHandler
+	Webhook        *handlers.WebhookHandler
+	Events         *handlers.EventStreamHandler
+	StandingOrder  *handlers.StandingOrderHandler
+	PaymentRequest *handlers.PaymentRequestHandler
+	// Admin serves the staff API under /admin/v1.
+	Admin *handlers.AdminHandler
+	// RateLimit is optional, requests are only limited when set.
+	RateLimit *handlers.RateLimitHandler
+}
+
+// NewRouter registers every API route under /api/v1 and /api/v2, the staff
+// API under /admin/v1, plus the OpenAPI specification and Swagger UI under
+// /docs.
+func NewRouter(cfg config.ServerConfig, h Handlers) *gin.Engine {
+	router := gin.Default()
+	// Validated with the configuration
+	_ = router.SetTrustedProxies(cfg.Proxies())
+	router.Use(func(c *gin.Context) {
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, cfg.MaxBodyBytes)
+		c.Next()
+	})
+
+	// /api/v2 shares the handlers but renders typed errors
+	registerAPI(limited(router.Group("/api/v1"), h.RateLimit), h)
+	registerAPI(limited(router.Group("/api/v2"), h.RateLimit.WithWriter(response.V2)), Handlers{
+		Init:           h.Init.WithWriter(response.V2),
+		Wallet:         h.Wallet.WithWriter(response.V2),
+		Closure:        h.Closure.WithWriter(response.V2),
+		StandingOrder:  h.StandingOrder.WithWriter(response.V2),
+		PaymentRequest: h.PaymentRequest.WithWriter(response.V2),
+		Webhook:        h.Webhook.WithWriter(response.V2),
+		Events:         h.Events.WithWriter(response.V2),
+	})
+
+	registerAdmin(limited(router.Group("/admin/v1"), h.RateLimit.WithWriter(response.V2)), h.Admin)
+
+	swaggerUI := httpSwagger.Handler(httpSwagger.URL("/docs/openapi.yaml"))
+	router.GET("/docs/*any", func(c *gin.Context) {
+		if c.Param("any") == "/openapi.yaml" {
+			c.Data(http.StatusOK, "application/yaml", docs.OpenAPI)
+			return
+		}
+		swaggerUI(c.Writer, c.Request)
+	})
+
+	return router
+}
+
+// limited applies the rate limits to the group's routes, ahead of their
+// authentication so failures are counted too.
+func limited(group *gin.RouterGroup, h *handlers.RateLimitHandler) *gin.RouterGroup {
+	if h != nil {
+		group.Use(h.Limit)
+	}
+	return group
+}
+
+func registerAPI(api *gin.RouterGroup, h Handlers) {
+	api.POST("/init", h.Init.Init)
+	api.POST("/wallet", h.Wallet.EnableWallet)
+	api.GET("/wallet", h.Wallet.ViewWalletBalance)
+	api.GET("/wallet/transactions", h.Wallet.ViewWalletTransactions)
+	api.GET("/wallet/statement", h.Wallet.ViewStatement)
+	api.GET("/wallet/balance", h.Wallet.ViewBalanceAt)
+	api.GET("/wallet/events", h.Events.Stream)
+	api.POST("/wallet/deposits", h.Wallet.Deposit)
+	api.POST("/wallet/withdrawals", h.Wallet.Withdraw)
+	api.PATCH("/wallet", h.Wallet.DisableWallet)
+	api.POST("/wallet/close", h.Closure.CloseWallet)
+	api.GET("/wallet/pin", h.Wallet.ViewPIN)
+	api.POST("/wallet/pin", h.Wallet.SetPIN)
+	api.POST("/wallet/pin/step-up", h.Wallet.StepUp)
+	api.GET("/wallet/totp", h.Wallet.ViewTOTP)
+	api.POST("/wallet/totp", h.Wallet.EnrollTOTP)
+	api.DELETE("/wallet/totp", h.Wallet.RemoveTOTP)
+	api.POST("/wallet/totp/confirm", h.Wallet.ConfirmTOTP)
+	api.POST("/wallet/totp/recovery-codes", h.Wallet.RegenerateRecoveryCodes)
+	api.POST("/wallet/token/rotate", h.Wallet.RotateToken)
+	api.GET("/wallet/standing-orders", h.StandingOrder.ListStandingOrders)
+	api.POST("/wallet/standing-orders", h.StandingOrder.CreateStandingOrder)
+	api.GET("/wallet/standing-orders/:id", h.StandingOrder.ViewStandingOrder)
+	api.PATCH("/wallet/standing-orders/:id", h.StandingOrder.UpdateStandingOrder)
+	api.DELETE("/wallet/standing-orders/:id", h.StandingOrder.CancelStandingOrder)
+	api.GET("/wallet/payment-requests", h.PaymentRequest.ListPaymentRequests)
+	api.POST("/wallet/payment-requests", h.PaymentRequest.CreatePaymentRequest)
+	api.GET("/wallet/payment-requests/:id", h.PaymentRequest.ViewPaymentRequest)
+	api.DELETE("/wallet/payment-requests/:id", h.PaymentRequest.CancelPaymentRequest)
+	api.POST("/wallet/payment-requests/:id/accept", h.PaymentRequest.AcceptPaymentRequest)
+	api.POST("/wallet/payment-requests/:id/decline", h.PaymentRequest.DeclinePaymentRequest)
+	api.POST("/webhooks", h.Webhook.Subscribe)
+	api.GET("/webhooks", h.Webhook.ListSubscriptions)
+	api.DELETE("/webhooks/:id", h.Webhook.Unsubscribe)
+	api.GET("/webhooks/:id/deliveries", h.Webhook.ListDeliveries)
+	api.POST("/webhooks/:id/deliveries/:delivery_id/redeliver", h.Webhook.Redeliver)
+}
+
+func registerAdmin(api *gin.RouterGroup, h *handlers.AdminHandler) {
+	api.Use(h.Authenticate)
+	api.GET("/customers", h.SearchCustomers)
+	api.GET("/customers/:customer_xid/kyc", h.ViewKYCProfile)
+	api.POST("/customers/:customer_xid/kyc/tier", h.ChangeKYCTier)
+	api.POST("/customers/:customer_xid/pin/reset", h.ResetPIN)
+	api.GET("/wallets/:id", h.ViewWallet)
+	api.GET("/wallets/:id/transactions", h.ViewTransactions)
+	api.GET("/wallets/:id/status-history", h.ViewStatusHistory)
+	api.GET("/wallets/:id/ledger/verify", h.VerifyLedger)
+	api.POST("/wallets/:id/freeze", h.FreezeWallet)
+	api.POST("/wallets/:id/unfreeze", h.UnfreezeWallet)
+	api.POST("/wallets/:id/suspend", h.SuspendWallet)
+	api.POST("/wallets/:id/reinstate", h.ReinstateWallet)
+	api.POST("/wallets/:id/disable", h.DisableWallet)
+	api.POST("/adjustments", h.RequestAdjustment)
+	api.GET("/adjustments", h.ListAdjustments)
+	api.GET("/adjustments/:id", h.ViewAdjustment)
+	api.POST("/adjustments/:id/approve", h.ApproveAdjustment)
+	api.POST("/adjustments/:id/reject", h.RejectAdjustment)
+	api.GET("/risk/decisions", h.ListRiskDecisions)
+	api.GET("/risk/decisions/:id", h.ViewRiskDecision)
+	api.POST("/risk/decisions/:id/release", h.ReleaseRiskDecision)
+	api.POST("/risk/decisions/:id/reject", h.RejectRiskDecision)
+	api.GET("/compliance/reports", h.ExportComplianceReports)
+	api.GET("/reconciliation/report", h.ViewReconciliationReport)
+	api.GET("/audit", h.ViewAuditLog)
+}
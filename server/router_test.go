@@ -0,0 +1,201 @@
+package server
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"mini-wallet/config"
+	"mini-wallet/docs"
+	"mini-wallet/events"
+	"mini-wallet/handlers"
+	"mini-wallet/ratelimit"
+	"mini-wallet/response"
+	"mini-wallet/service"
+
+	"github.com/gin-gonic/gin"
+	"gopkg.in/yaml.v3"
+)
+
+var pathParam = regexp.MustCompile(`[:*](\w+)`)
+
+func testRouter() *gin.Engine {
+	return testRouterLimitedBy(nil)
+}
+
+func testRouterLimitedBy(rateLimit *handlers.RateLimitHandler) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	gin.DefaultWriter = io.Discard
+	cfg := config.Default()
+	wallets := service.NewWalletService(nil, nil, nil, nil, nil, events.Nop{}, nil, nil, nil, nil, nil, cfg.Wallet)
+	return NewRouter(cfg.Server, Handlers{
+		Init:           handlers.NewInitHandler(wallets),
+		Wallet:         handlers.NewWalletHandler(wallets, nil),
+		Closure:        handlers.NewClosureHandler(service.NewClosureService(wallets, nil, 0), nil),
+		StandingOrder:  handlers.NewStandingOrderHandler(service.NewStandingOrderService(wallets, nil, cfg.StandingOrders), nil),
+		PaymentRequest: handlers.NewPaymentRequestHandler(service.NewPaymentRequestService(wallets, nil, cfg.PaymentRequests), nil),
+		Webhook:        handlers.NewWebhookHandler(nil, nil),
+		Events:         handlers.NewEventStreamHandler(wallets, nil, nil, events.NewLocalBus(), time.Second),
+		Admin:          handlers.NewAdminHandler(service.NewAdminService(wallets, nil, nil, nil, nil, nil, nil, nil, nil)),
+		RateLimit:      rateLimit,
+	})
+}
+
+func specOperations(t *testing.T) map[string]map[string]bool {
+	t.Helper()
+	var spec struct {
+		Paths map[string]map[string]any `yaml:"paths"`
+	}
+	if err := yaml.Unmarshal(docs.OpenAPI, &spec); err != nil {
+		t.Fatalf("parsing openapi.yaml: %v", err)
+	}
+	operations := make(map[string]map[string]bool, len(spec.Paths))
+	for path, item := range spec.Paths {
+		operations[path] = map[string]bool{}
+		for method := range item {
+			operations[path][strings.ToUpper(method)] = true
+		}
+	}
+	return operations
+}
+
+func TestSpecCoversEveryRoute(t *testing.T) {
+	operations := specOperations(t)
+	for _, route := range testRouter().Routes() {
+		if strings.HasPrefix(route.Path, "/docs/") {
+			continue
+		}
+		path := pathParam.ReplaceAllString(route.Path, "{$1}")
+		if !operations[path][route.Method] {
+			t.Errorf("%s %s is registered but missing from docs/openapi.yaml", route.Method, path)
+		}
+	}
+}
+
+func TestSpecHasNoUnregisteredRoutes(t *testing.T) {
+	registered := map[string]bool{}
+	for _, route := range testRouter().Routes() {
+		registered[route.Method+" "+pathParam.ReplaceAllString(route.Path, "{$1}")] = true
+	}
+	for path, methods := range specOperations(t) {
+		for method := range methods {
+			if method == "PARAMETERS" {
+				continue
+			}
+			if !registered[method+" "+path] {
+				t.Errorf("%s %s is documented but not registered", method, path)
+			}
+		}
+	}
+}
+
+func TestDocsServed(t *testing.T) {
+	router := testRouter()
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/docs/openapi.yaml", nil))
+	if rec.Code != http.StatusOK || rec.Body.String() != string(docs.OpenAPI) {
+		t.Errorf("GET /docs/openapi.yaml = %d, want the embedded specification", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/docs/index.html", nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "openapi.yaml") {
+		t.Errorf("GET /docs/index.html = %d, want Swagger UI pointing at the specification", rec.Code)
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	cfg := config.Default().RateLimit
+	cfg.AuthFailures = ratelimit.Limit{Requests: 100, Window: time.Minute}
+	cfg.Reads = ratelimit.Limit{Requests: 2, Window: time.Minute}
+	cfg.Routes = map[string]ratelimit.Limit{"POST /wallet/deposits": {Requests: 1, Window: time.Minute}}
+	router := testRouterLimitedBy(handlers.NewRateLimitHandler(ratelimit.NewLocalStore(), cfg))
+	serve := func(method, path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(method, path, nil))
+		return rec
+	}
+
+	// Both API versions share the read budget
+	if rec := serve(http.MethodGet, "/api/v1/wallet"); rec.Header().Get("RateLimit-Remaining") != "1" || rec.Header().Get("RateLimit-Limit") != "2" {
+		t.Errorf("first read headers = %v, want 1 of 2 remaining", rec.Header())
+	}
+	serve(http.MethodGet, "/api/v2/wallet")
+	rec := serve(http.MethodGet, "/api/v2/wallet/transactions")
+	if rec.Code != http.StatusTooManyRequests || !strings.Contains(rec.Body.String(), "rate_limited") || rec.Header().Get("Retry-After") == "" {
+		t.Errorf("third read = %d %s, want 429 rate_limited with Retry-After", rec.Code, rec.Body)
+	}
+	if rec := serve(http.MethodGet, "/api/v1/wallet"); rec.Code != http.StatusTooManyRequests {
+		t.Errorf("/api/v1 read = %d, want 429 too", rec.Code)
+	}
+
+	// Writes and routes with a budget of their own count apart
+	if rec := serve(http.MethodPatch, "/api/v2/wallet"); rec.Code == http.StatusTooManyRequests {
+		t.Error("write refused, want the write budget untouched by reads")
+	}
+	serve(http.MethodPost, "/api/v2/wallet/deposits")
+	if rec := serve(http.MethodPost, "/api/v2/wallet/deposits"); rec.Code != http.StatusTooManyRequests || rec.Header().Get("RateLimit-Policy") != "1;w=60" {
+		t.Errorf("second deposit = %d %v, want 429 under the route's own limit", rec.Code, rec.Header())
+	}
+}
+
+func TestRateLimitAuthFailures(t *testing.T) {
+	cfg := config.Default().RateLimit
+	cfg.AuthFailures = ratelimit.Limit{Requests: 2, Window: time.Minute}
+	router := testRouterLimitedBy(handlers.NewRateLimitHandler(ratelimit.NewLocalStore(), cfg))
+	serve := func(method, path string) int {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(method, path, nil))
+		return rec.Code
+	}
+
+	// Failures add up across routes and budgets
+	if code := serve(http.MethodGet, "/api/v1/wallet"); code != http.StatusUnauthorized {
+		t.Fatalf("read without a token = %d, want 401", code)
+	}
+	if code := serve(http.MethodGet, "/admin/v1/audit"); code != http.StatusUnauthorized {
+		t.Fatalf("staff read without a key = %d, want 401", code)
+	}
+	if code := serve(http.MethodPost, "/api/v2/wallet/deposits"); code != http.StatusTooManyRequests {
+		t.Errorf("after two failures = %d, want 429", code)
+	}
+}
+
+func TestRateLimitCountsInvalidTOTP(t *testing.T) {
+	cfg := config.Default().RateLimit
+	cfg.AuthFailures = ratelimit.Limit{Requests: 2, Window: time.Minute}
+	limiter := handlers.NewRateLimitHandler(ratelimit.NewLocalStore(), cfg).WithWriter(response.V2)
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/totp", limiter.Limit, func(c *gin.Context) {
+		response.V2(c, response.New(response.CodeInvalidTOTP, "Invalid one-time code"))
+	})
+	router.POST("/pin", limiter.Limit, func(c *gin.Context) {
+		response.V2(c, response.New(response.CodeInvalidPIN, "Invalid transaction PIN"))
+	})
+	serve := func(path string) int {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, path, nil))
+		return rec.Code
+	}
+
+	// Wrong PINs have a lockout of their own and spend nothing
+	for range 3 {
+		if code := serve("/pin"); code != http.StatusForbidden {
+			t.Fatalf("wrong PIN = %d, want 403", code)
+		}
+	}
+	for range 2 {
+		if code := serve("/totp"); code != http.StatusForbidden {
+			t.Fatalf("wrong one-time code = %d, want 403", code)
+		}
+	}
+	if code := serve("/totp"); code != http.StatusTooManyRequests {
+		t.Errorf("after two wrong one-time codes = %d, want 429", code)
+	}
+}
@@ -0,0 +1,194 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"mini-wallet/admin"
+	"mini-wallet/models"
+	"mini-wallet/repositories"
+
+	"github.com/google/uuid"
+)
+
+// RequestAdjustment asks for a manual credit (positive amount) or debit
+// (negative amount) of a wallet. Nothing is posted until another admin
+// approves it.
+func (s *AdminService) RequestAdjustment(actor *models.AdminKey, walletID string, amount int64, reason string) (*models.Adjustment, error) {
+	if err := authorize(actor, admin.PermAdjustmentsWrite); err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(reason) == "" {
+		return nil, ErrReasonRequired
+	}
+	if amount == 0 {
+		return nil, ErrInvalidAmount
+	}
+	wallet, err := s.wallets.walletByID(walletID)
+	if err != nil {
+		return nil, err
+	}
+	if err := Allow(wallet.Status, OpAdjust); err != nil {
+		return nil, err
+	}
+
+	adjustment := &models.Adjustment{
+		ID:              uuid.New().String(),
+		WalletID:        wallet.ID,
+		Amount:          amount,
+		Reason:          reason,
+		Status:          models.AdjustmentPending,
+		RequestedBy:     actor.ID,
+		RequestedByName: actor.Name,
+		CreatedAt:       time.Now().UTC(),
+	}
+	if err := s.adjustmentRepo.CreateAdjustment(adjustment); err != nil {
+		return nil, err
+	}
+	if err := s.record(actor, AuditAdjustmentRequest, auditTargetAdjustment, adjustment.ID, reason, map[string]any{"wallet_id": wallet.ID, "amount": amount}); err != nil {
+		return nil, err
+	}
+	return adjustment, nil
+}
+
+// ApproveAdjustment approves a pending adjustment requested by another
+// admin and posts it: the adjustment transaction, the balance change and
+// the audit entry are written together or not at all. A debit must be
+// covered by the balance at that moment.
+func (s *AdminService) ApproveAdjustment(actor *models.AdminKey, id, note string) (*models.Adjustment, error) {
+	adjustment, err := s.reviewable(actor, id)
+	if err != nil {
+		return nil, err
+	}
+	wallet, err := s.wallets.walletByID(adjustment.WalletID)
+	if err != nil {
+		return nil, err
+	}
+	if err := Allow(wallet.Status, OpAdjust); err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	transaction := &models.Transaction{
+		ID:       uuid.New().String(),
+		WalletID: wallet.ID,
+		Type:     "adjustment",
+		Status:   "success",
+		Amount:   adjustment.Amount,
+		// The adjustment ID keeps a transaction from being posted twice
+		ReferenceID:  adjustment.ID,
+		TransactedAt: now,
+	}
+	adjustment.Status = models.AdjustmentApproved
+	adjustment.ReviewedBy, adjustment.ReviewedByName, adjustment.ReviewNote = actor.ID, actor.Name, note
+	adjustment.ReviewedAt = &now
+	adjustment.TransactionID = transaction.ID
+	entry := auditEntry(actor, AuditAdjustmentApprove, auditTargetAdjustment, adjustment.ID, note, map[string]any{"wallet_id": wallet.ID, "amount": adjustment.Amount, "transaction_id": transaction.ID})
+	if err := s.adjustmentRepo.ApproveAdjustment(adjustment, transaction, entry); err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrAdjustmentNotPending
+		case errors.Is(err, repositories.ErrInsufficientBalance):
+			return nil, ErrInsufficientBalance
+		case errors.Is(err, repositories.ErrWalletClosed):
+			return nil, ErrWalletClosed
+		}
+		return nil, err
+	}
+
+	// The stored balance already includes the adjustment; settling refreshes
+	// the cache and tells subscribers
+	s.wallets.publisher.Publish(context.Background(), TransactionEvent(wallet.OwnedBy, transaction))
+	go s.wallets.settleBalance(wallet.ID, wallet.OwnedBy)
+	return adjustment, nil
+}
+
+// RejectAdjustment rejects a pending adjustment requested by another admin.
+func (s *AdminService) RejectAdjustment(actor *models.AdminKey, id, reason string) (*models.Adjustment, error) {
+	adjustment, err := s.reviewable(actor, id)
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(reason) == "" {
+		return nil, ErrReasonRequired
+	}
+
+	now := time.Now().UTC()
+	adjustment.Status = models.AdjustmentRejected
+	adjustment.ReviewedBy, adjustment.ReviewedByName, adjustment.ReviewNote = actor.ID, actor.Name, reason
+	adjustment.ReviewedAt = &now
+	if err := s.adjustmentRepo.RejectAdjustment(adjustment); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrAdjustmentNotPending
+		}
+		return nil, err
+	}
+	if err := s.record(actor, AuditAdjustmentReject, auditTargetAdjustment, adjustment.ID, reason, map[string]any{"wallet_id": adjustment.WalletID, "amount": adjustment.Amount}); err != nil {
+		return nil, err
+	}
+	return adjustment, nil
+}
+
+// reviewable returns a pending adjustment actor may review: anyone allowed
+// to write adjustments except the admin who requested it.
+func (s *AdminService) reviewable(actor *models.AdminKey, id string) (*models.Adjustment, error) {
+	if err := authorize(actor, admin.PermAdjustmentsWrite); err != nil {
+		return nil, err
+	}
+	adjustment, err := s.adjustment(id)
+	if err != nil {
+		return nil, err
+	}
+	if adjustment.RequestedBy == actor.ID {
+		return nil, ErrSelfReview
+	}
+	if adjustment.Status != models.AdjustmentPending {
+		return nil, ErrAdjustmentNotPending
+	}
+	return adjustment, nil
+}
+
+func (s *AdminService) adjustment(id string) (*models.Adjustment, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, ErrAdjustmentNotFound
+	}
+	adjustment, err := s.adjustmentRepo.GetAdjustment(id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrAdjustmentNotFound
+	}
+	return adjustment, err
+}
+
+// Adjustment returns an adjustment in whatever state it is.
+func (s *AdminService) Adjustment(actor *models.AdminKey, id string) (*models.Adjustment, error) {
+	if err := authorize(actor, admin.PermWalletsRead); err != nil {
+		return nil, err
+	}
+	adjustment, err := s.adjustment(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.record(actor, AuditAdjustmentView, auditTargetAdjustment, id, "", nil); err != nil {
+		return nil, err
+	}
+	return adjustment, nil
+}
+
+// Adjustments lists adjustments matching filter, newest first.
+func (s *AdminService) Adjustments(actor *models.AdminKey, filter models.AdjustmentFilter) ([]models.Adjustment, error) {
+	if err := authorize(actor, admin.PermWalletsRead); err != nil {
+		return nil, err
+	}
+	filter.Limit = clampLimit(filter.Limit)
+	adjustments, err := s.adjustmentRepo.ListAdjustments(filter)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.record(actor, AuditAdjustmentView, auditTargetWallet, filter.WalletID, "", map[string]any{"status": filter.Status, "results": len(adjustments)}); err != nil {
+		return nil, err
+	}
+	return adjustments, nil
+}
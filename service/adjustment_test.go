@@ -0,0 +1,100 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"mini-wallet/admin"
+	"mini-wallet/models"
+)
+
+func TestApprovedAdjustmentIsPosted(t *testing.T) {
+	f, admins, audit := newAdminFixture(enabledWallet(100))
+	// Settlement derives the balance from the log, so it starts with the deposit
+	f.transactions.CreateTransaction(&models.Transaction{ID: "deposit-1", WalletID: "wallet-1", Type: "deposit", Amount: 100})
+
+	adjustment, err := admins.RequestAdjustment(actor(admin.RoleOperator), "wallet-1", -40, "duplicated top-up")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if adjustment.Status != models.AdjustmentPending {
+		t.Errorf("status = %q, want pending", adjustment.Status)
+	}
+	if history, _ := f.service.History(customer); len(history) != 1 {
+		t.Fatalf("history has %d transactions before approval, want only the deposit", len(history))
+	}
+
+	if _, err := admins.ApproveAdjustment(actor(admin.RoleOperator), adjustment.ID, ""); !errors.Is(err, ErrSelfReview) {
+		t.Errorf("self approval error = %v, want ErrSelfReview", err)
+	}
+	approved, err := admins.ApproveAdjustment(otherActor(admin.RoleOperator), adjustment.ID, "checked")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if approved.Status != models.AdjustmentApproved || approved.ReviewedBy != "key-2" || approved.TransactionID == "" {
+		t.Errorf("adjustment = %+v, want approved by key-2 with its transaction", approved)
+	}
+
+	history, _ := f.service.History(customer)
+	if len(history) != 2 || history[1].Type != "adjustment" || history[1].Amount != -40 || history[1].ReferenceID != adjustment.ID {
+		t.Errorf("history = %+v, want a -40 adjustment referencing the request", history)
+	}
+	eventually(t, func() bool { return f.wallets.wallet(customer).Balance == 60 })
+
+	if _, err := admins.RejectAdjustment(otherActor(admin.RoleOperator), adjustment.ID, "too late"); !errors.Is(err, ErrAdjustmentNotPending) {
+		t.Errorf("reviewing twice error = %v, want ErrAdjustmentNotPending", err)
+	}
+	if got := audit.actions(); len(got) != 2 || got[0] != AuditAdjustmentRequest || got[1] != AuditAdjustmentApprove {
+		t.Errorf("audited %v, want request then approve", got)
+	}
+}
+
+func TestRejectedAdjustmentPostsNothing(t *testing.T) {
+	f, admins, _ := newAdminFixture(enabledWallet(100))
+
+	adjustment, err := admins.RequestAdjustment(actor(admin.RoleOperator), "wallet-1", 500, "goodwill")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := admins.RejectAdjustment(otherActor(admin.RoleOperator), adjustment.ID, ""); !errors.Is(err, ErrReasonRequired) {
+		t.Errorf("reject without reason error = %v, want ErrReasonRequired", err)
+	}
+	rejected, err := admins.RejectAdjustment(otherActor(admin.RoleOperator), adjustment.ID, "not eligible")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rejected.Status != models.AdjustmentRejected || rejected.ReviewNote != "not eligible" {
+		t.Errorf("adjustment = %+v, want rejected with the note", rejected)
+	}
+	if history, _ := f.service.History(customer); len(history) != 0 {
+		t.Errorf("history has %d transactions, want none", len(history))
+	}
+}
+
+func TestAdjustmentRules(t *testing.T) {
+	_, admins, audit := newAdminFixture(enabledWallet(100))
+
+	if _, err := admins.RequestAdjustment(actor(admin.RoleSupport), "wallet-1", 10, "goodwill"); !errors.Is(err, ErrForbidden) {
+		t.Errorf("support request error = %v, want ErrForbidden", err)
+	}
+	if _, err := admins.RequestAdjustment(actor(admin.RoleOperator), "wallet-1", 0, "goodwill"); !errors.Is(err, ErrInvalidAmount) {
+		t.Errorf("zero amount error = %v, want ErrInvalidAmount", err)
+	}
+	if _, err := admins.RequestAdjustment(actor(admin.RoleOperator), "missing", 10, "goodwill"); !errors.Is(err, ErrWalletNotFound) {
+		t.Errorf("missing wallet error = %v, want ErrWalletNotFound", err)
+	}
+
+	overdraw, err := admins.RequestAdjustment(actor(admin.RoleOperator), "wallet-1", -101, "chargeback")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := admins.ApproveAdjustment(otherActor(admin.RoleOperator), overdraw.ID, ""); !errors.Is(err, ErrInsufficientBalance) {
+		t.Errorf("overdrawing approval error = %v, want ErrInsufficientBalance", err)
+	}
+	if stored, _ := admins.Adjustment(actor(admin.RoleOperator), overdraw.ID); stored.Status != models.AdjustmentPending {
+		t.Errorf("overdrawing adjustment status = %q, want it still pending", stored.Status)
+	}
+	if got := audit.actions(); len(got) != 2 || got[1] != AuditAdjustmentView {
+		t.Errorf("audited %v, want the request and the view but no approval", got)
+	}
+}
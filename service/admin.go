@@ -0,0 +1,285 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"mini-wallet/admin"
+	"mini-wallet/models"
+	"mini-wallet/repositories"
+
+	"github.com/google/uuid"
+)
+
+// Audit actions recorded by the AdminService.
+const (
+	AuditCustomersSearch      = "customers.search"
+	AuditWalletView           = "wallet.view"
+	AuditTransactionsView     = "wallet.transactions.view"
+	AuditWalletFreeze         = "wallet.freeze"
+	AuditWalletUnfreeze       = "wallet.unfreeze"
+	AuditWalletSuspend        = "wallet.suspend"
+	AuditWalletReinstate      = "wallet.reinstate"
+	AuditWalletDisable        = "wallet.force_disable"
+	AuditStatusHistoryView    = "wallet.status_history.view"
+	AuditLogView              = "audit.view"
+	AuditAdminKeyCreate       = "admin_key.create"
+	AuditAdminKeyRevoke       = "admin_key.revoke"
+	AuditAdjustmentRequest    = "adjustment.request"
+	AuditAdjustmentApprove    = "adjustment.approve"
+	AuditAdjustmentReject     = "adjustment.reject"
+	AuditAdjustmentView       = "adjustment.view"
+	AuditRiskDecisionView     = "risk_decision.view"
+	AuditRiskRelease          = "risk_decision.release"
+	AuditRiskReject           = "risk_decision.reject"
+	AuditComplianceExport     = "compliance_reports.export"
+	AuditKYCView              = "kyc.view"
+	AuditKYCTierChange        = "kyc.tier_change"
+	AuditPINReset             = "pin.reset"
+	AuditLedgerVerify         = "ledger.verify"
+	AuditReconciliationView   = "reconciliation.view"
+	auditTargetWallet         = "wallet"
+	auditTargetAdjustment     = "adjustment"
+	auditTargetRiskDecision   = "risk_decision"
+	auditTargetCustomer       = "customer"
+	auditTargetAdminKey       = "admin_key"
+	auditTargetReconciliation = "reconciliation_run"
+	defaultAdminQueryLimit    = 50
+	maxAdminQueryLimit        = 200
+	// maxComplianceExport bounds a compliance report export, which is
+	// larger than other listings since it goes to the regulator whole.
+	maxComplianceExport = 10000
+)
+
+// AdminService carries out staff actions. Every action checks the actor's
+// role and is written to the audit log with the actor and reason; reads
+// are audited too, since they expose customer data.
+type AdminService struct {
+	wallets            *WalletService
+	walletRepo         repositories.WalletRepository
+	transactionRepo    repositories.TransactionRepository
+	customerTokenRepo  repositories.CustomerTokenRepository
+	adminKeyRepo       repositories.AdminKeyRepository
+	auditRepo          repositories.AuditRepository
+	adjustmentRepo     repositories.AdjustmentRepository
+	complianceRepo     repositories.ComplianceRepository
+	reconciliationRepo repositories.ReconciliationRepository
+}
+
+func NewAdminService(wallets *WalletService, walletRepo repositories.WalletRepository, transactionRepo repositories.TransactionRepository, customerTokenRepo repositories.CustomerTokenRepository, adminKeyRepo repositories.AdminKeyRepository, auditRepo repositories.AuditRepository, adjustmentRepo repositories.AdjustmentRepository, complianceRepo repositories.ComplianceRepository, reconciliationRepo repositories.ReconciliationRepository) *AdminService {
+	return &AdminService{
+		wallets:            wallets,
+		walletRepo:         walletRepo,
+		transactionRepo:    transactionRepo,
+		customerTokenRepo:  customerTokenRepo,
+		adminKeyRepo:       adminKeyRepo,
+		auditRepo:          auditRepo,
+		adjustmentRepo:     adjustmentRepo,
+		complianceRepo:     complianceRepo,
+		reconciliationRepo: reconciliationRepo,
+	}
+}
+
+// Authenticate returns the active admin key matching key.
+func (s *AdminService) Authenticate(key string) (*models.AdminKey, error) {
+	adminKey, err := s.adminKeyRepo.GetActiveKeyByHash(admin.HashKey(key))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrInvalidAdminKey
+	}
+	return adminKey, err
+}
+
+// CreateKey issues an admin key with role and returns it with the key in
+// clear, which is not stored. createdBy names who issued it in the audit log.
+func (s *AdminService) CreateKey(name string, role admin.Role, createdBy string) (*models.AdminKey, string, error) {
+	if !role.Valid() {
+		return nil, "", &Error{KindInvalid, "unknown role " + string(role)}
+	}
+	key, err := admin.GenerateKey()
+	if err != nil {
+		return nil, "", err
+	}
+	adminKey := &models.AdminKey{
+		ID:        uuid.New().String(),
+		Name:      name,
+		Role:      string(role),
+		KeyHash:   admin.HashKey(key),
+		CreatedAt: time.Now().UTC(),
+	}
+	if err := s.adminKeyRepo.CreateKey(adminKey); err != nil {
+		return nil, "", err
+	}
+	err = s.record(&models.AdminKey{Name: createdBy}, AuditAdminKeyCreate, auditTargetAdminKey, adminKey.ID, "", map[string]any{"name": name, "role": role})
+	return adminKey, key, err
+}
+
+// RevokeKey revokes an admin key. revokedBy names who revoked it in the audit log.
+func (s *AdminService) RevokeKey(id, revokedBy, reason string) error {
+	if err := s.adminKeyRepo.RevokeKey(id); err != nil {
+		return err
+	}
+	return s.record(&models.AdminKey{Name: revokedBy}, AuditAdminKeyRevoke, auditTargetAdminKey, id, reason, nil)
+}
+
+// SearchCustomers finds customers by a customer_xid or wallet id prefix.
+func (s *AdminService) SearchCustomers(actor *models.AdminKey, query string, limit int) ([]models.CustomerSummary, error) {
+	if err := authorize(actor, admin.PermCustomersRead); err != nil {
+		return nil, err
+	}
+	customers, err := s.customerTokenRepo.SearchCustomers(strings.TrimSpace(query), clampLimit(limit))
+	if err != nil {
+		return nil, err
+	}
+	if err := s.record(actor, AuditCustomersSearch, auditTargetCustomer, "", "", map[string]any{"query": query, "results": len(customers)}); err != nil {
+		return nil, err
+	}
+	return customers, nil
+}
+
+// Wallet returns any wallet, whatever its status.
+func (s *AdminService) Wallet(actor *models.AdminKey, walletID string) (*models.Wallet, error) {
+	if err := authorize(actor, admin.PermWalletsRead); err != nil {
+		return nil, err
+	}
+	wallet, err := s.wallets.walletByID(walletID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.record(actor, AuditWalletView, auditTargetWallet, walletID, "", nil); err != nil {
+		return nil, err
+	}
+	return wallet, nil
+}
+
+// Transactions returns the transactions of any wallet.
+func (s *AdminService) Transactions(actor *models.AdminKey, walletID string) ([]models.Transaction, error) {
+	if err := authorize(actor, admin.PermWalletsRead); err != nil {
+		return nil, err
+	}
+	if _, err := s.wallets.walletByID(walletID); err != nil {
+		return nil, err
+	}
+	transactions, err := s.transactionRepo.GetTransactionsByWalletID(walletID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.record(actor, AuditTransactionsView, auditTargetWallet, walletID, "", nil); err != nil {
+		return nil, err
+	}
+	return transactions, nil
+}
+
+// StatusHistory returns the status changes of any wallet, oldest first.
+func (s *AdminService) StatusHistory(actor *models.AdminKey, walletID string) ([]models.WalletStatusChange, error) {
+	if err := authorize(actor, admin.PermWalletsRead); err != nil {
+		return nil, err
+	}
+	changes, err := s.wallets.StatusHistory(walletID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.record(actor, AuditStatusHistoryView, auditTargetWallet, walletID, "", nil); err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
+
+// Freeze freezes a wallet, see WalletService.Freeze.
+func (s *AdminService) Freeze(actor *models.AdminKey, walletID, reason string) (*models.Wallet, error) {
+	return s.changeStatus(actor, admin.PermWalletsFreeze, AuditWalletFreeze, walletID, reason, s.wallets.Freeze)
+}
+
+// Unfreeze unfreezes a wallet, see WalletService.Unfreeze.
+func (s *AdminService) Unfreeze(actor *models.AdminKey, walletID, reason string) (*models.Wallet, error) {
+	return s.changeStatus(actor, admin.PermWalletsFreeze, AuditWalletUnfreeze, walletID, reason, s.wallets.Unfreeze)
+}
+
+// Suspend suspends a wallet, see WalletService.Suspend.
+func (s *AdminService) Suspend(actor *models.AdminKey, walletID, reason string) (*models.Wallet, error) {
+	return s.changeStatus(actor, admin.PermWalletsSuspend, AuditWalletSuspend, walletID, reason, s.wallets.Suspend)
+}
+
+// Reinstate reinstates a suspended wallet, see WalletService.Reinstate.
+func (s *AdminService) Reinstate(actor *models.AdminKey, walletID, reason string) (*models.Wallet, error) {
+	return s.changeStatus(actor, admin.PermWalletsSuspend, AuditWalletReinstate, walletID, reason, s.wallets.Reinstate)
+}
+
+// ForceDisable disables a wallet, see WalletService.ForceDisable.
+func (s *AdminService) ForceDisable(actor *models.AdminKey, walletID, reason string) (*models.Wallet, error) {
+	return s.changeStatus(actor, admin.PermWalletsDisable, AuditWalletDisable, walletID, reason, s.wallets.ForceDisable)
+}
+
+func (s *AdminService) changeStatus(actor *models.AdminKey, permission admin.Permission, action, walletID, reason string, change func(walletID string, by Actor, reason string) (*models.Wallet, error)) (*models.Wallet, error) {
+	if err := authorize(actor, permission); err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(reason) == "" {
+		return nil, ErrReasonRequired
+	}
+	wallet, err := change(walletID, Actor{models.ActorAdmin, actor.ID}, reason)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.record(actor, action, auditTargetWallet, walletID, reason, map[string]any{"status": wallet.Status}); err != nil {
+		return nil, err
+	}
+	return wallet, nil
+}
+
+// AuditLog lists audit entries matching filter, newest first.
+func (s *AdminService) AuditLog(actor *models.AdminKey, filter models.AuditFilter) ([]models.AuditEntry, error) {
+	if err := authorize(actor, admin.PermAuditRead); err != nil {
+		return nil, err
+	}
+	filter.Limit = clampLimit(filter.Limit)
+	entries, err := s.auditRepo.ListEntries(filter)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.record(actor, AuditLogView, "", "", "", map[string]any{"actor_id": filter.ActorID, "target_id": filter.TargetID, "action": filter.Action}); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+func authorize(actor *models.AdminKey, permission admin.Permission) error {
+	if actor == nil || !admin.Role(actor.Role).Can(permission) {
+		return ErrForbidden
+	}
+	return nil
+}
+
+func clampLimit(limit int) int {
+	if limit <= 0 {
+		return defaultAdminQueryLimit
+	}
+	return min(limit, maxAdminQueryLimit)
+}
+
+// record writes an audit entry. A read whose entry cannot be written fails,
+// so customer data is never shown unaudited.
+func (s *AdminService) record(actor *models.AdminKey, action, targetType, targetID, reason string, details map[string]any) error {
+	return s.auditRepo.CreateEntry(auditEntry(actor, action, targetType, targetID, reason, details))
+}
+
+// auditEntry describes what actor did, for changes audited by the
+// repository writing them.
+func auditEntry(actor *models.AdminKey, action, targetType, targetID, reason string, details map[string]any) *models.AuditEntry {
+	if details == nil {
+		details = map[string]any{}
+	}
+	return &models.AuditEntry{
+		ID:         uuid.New().String(),
+		ActorID:    actor.ID,
+		ActorName:  actor.Name,
+		ActorRole:  actor.Role,
+		Action:     action,
+		TargetType: targetType,
+		TargetID:   targetID,
+		Reason:     reason,
+		Details:    details,
+		CreatedAt:  time.Now().UTC(),
+	}
+}
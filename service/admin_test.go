@@ -0,0 +1,168 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"mini-wallet/admin"
+	"mini-wallet/events"
+	"mini-wallet/models"
+)
+
+func newAdminFixture(wallets ...models.Wallet) (*fixture, *AdminService, *mockAuditRepo) {
+	f := newFixture(wallets...)
+	audit := &mockAuditRepo{}
+	adjustments := &mockAdjustmentRepo{adjustments: make(map[string]models.Adjustment), wallets: f.wallets, transactions: f.transactions, audit: audit}
+	return f, NewAdminService(f.service, f.wallets, f.transactions, f.tokens, nil, audit, adjustments, &mockComplianceRepo{}, &mockReconciliationRepo{}), audit
+}
+
+func actor(role admin.Role) *models.AdminKey {
+	return &models.AdminKey{ID: "key-1", Name: "alice", Role: string(role)}
+}
+
+// otherActor is a second admin, for actions needing a different reviewer.
+func otherActor(role admin.Role) *models.AdminKey {
+	return &models.AdminKey{ID: "key-2", Name: "bob", Role: string(role)}
+}
+
+func TestFreezeBlocksWithdrawalsOnly(t *testing.T) {
+	f, admins, audit := newAdminFixture(enabledWallet(100))
+
+	wallet, err := admins.Freeze(actor(admin.RoleSupport), "wallet-1", "chargeback")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wallet.Status != "frozen" {
+		t.Errorf("status = %q, want frozen", wallet.Status)
+	}
+	if _, err := f.service.Withdraw(customer, 10, reference, Authorization{}); !errors.Is(err, ErrWalletFrozen) {
+		t.Errorf("Withdraw error = %v, want ErrWalletFrozen", err)
+	}
+	if _, err := f.service.Deposit(customer, 10, reference); err != nil {
+		t.Errorf("Deposit error = %v, want deposits to be accepted", err)
+	}
+	if _, err := f.service.Disable(customer, Authorization{}); !errors.Is(err, ErrWalletFrozen) {
+		t.Errorf("Disable error = %v, want ErrWalletFrozen", err)
+	}
+
+	if _, err := admins.Unfreeze(actor(admin.RoleSupport), "wallet-1", "resolved"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := admins.Unfreeze(actor(admin.RoleSupport), "wallet-1", "resolved"); !errors.Is(err, ErrWalletNotFrozen) {
+		t.Errorf("second Unfreeze error = %v, want ErrWalletNotFrozen", err)
+	}
+
+	if got := audit.actions(); len(got) != 2 || got[0] != AuditWalletFreeze || got[1] != AuditWalletUnfreeze {
+		t.Errorf("audited %v, want freeze then unfreeze", got)
+	}
+	if audit.entries[0].Reason != "chargeback" || audit.entries[0].ActorID != "key-1" {
+		t.Errorf("audit entry = %+v, want the actor and reason", audit.entries[0])
+	}
+	if got := f.events.types(); len(got) < 2 || got[0] != events.WalletFrozen {
+		t.Errorf("published %v, want wallet.frozen first", got)
+	}
+}
+
+func TestAdminRoles(t *testing.T) {
+	tests := []struct {
+		role       admin.Role
+		freeze     bool
+		disable    bool
+		audit      bool
+		compliance bool
+	}{
+		{admin.RoleViewer, false, false, false, false},
+		{admin.RoleSupport, true, false, false, false},
+		{admin.RoleOperator, true, true, false, false},
+		{admin.RoleAuditor, false, false, true, true},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.role), func(t *testing.T) {
+			_, admins, _ := newAdminFixture(enabledWallet(0))
+			if _, err := admins.Wallet(actor(tt.role), "wallet-1"); err != nil {
+				t.Errorf("Wallet error = %v, every role can view wallets", err)
+			}
+
+			check := func(action string, allowed bool, err error) {
+				t.Helper()
+				if allowed != !errors.Is(err, ErrForbidden) {
+					t.Errorf("%s error = %v, want allowed: %v", action, err, allowed)
+				}
+			}
+			_, err := admins.Freeze(actor(tt.role), "wallet-1", "reason")
+			check("Freeze", tt.freeze, err)
+			_, err = admins.ForceDisable(actor(tt.role), "wallet-1", "reason")
+			check("ForceDisable", tt.disable, err)
+			_, err = admins.AuditLog(actor(tt.role), models.AuditFilter{})
+			check("AuditLog", tt.audit, err)
+			_, err = admins.ComplianceReports(actor(tt.role), models.ComplianceReportFilter{}, "csv")
+			check("ComplianceReports", tt.compliance, err)
+			_, err = admins.VerifyLedger(actor(tt.role), "wallet-1")
+			check("VerifyLedger", tt.audit, err)
+		})
+	}
+}
+
+func TestAdminActionsNeedAReason(t *testing.T) {
+	f, admins, audit := newAdminFixture(enabledWallet(0))
+
+	if _, err := admins.ForceDisable(actor(admin.RoleOperator), "wallet-1", " "); !errors.Is(err, ErrReasonRequired) {
+		t.Errorf("ForceDisable error = %v, want ErrReasonRequired", err)
+	}
+	if status := f.wallets.wallet(customer).Status; status != "enabled" {
+		t.Errorf("status = %q, want the wallet left enabled", status)
+	}
+	if len(audit.entries) != 0 {
+		t.Errorf("audited %v, want nothing for a rejected action", audit.actions())
+	}
+}
+
+func TestComplianceExportIsClampedAndAudited(t *testing.T) {
+	_, admins, audit := newAdminFixture()
+	reports := admins.complianceRepo.(*mockComplianceRepo)
+
+	if _, err := admins.ComplianceReports(actor(admin.RoleAuditor), models.ComplianceReportFilter{}, "json"); err != nil {
+		t.Fatal(err)
+	}
+	if reports.filter.Limit != defaultAdminQueryLimit {
+		t.Errorf("default limit = %d, want %d", reports.filter.Limit, defaultAdminQueryLimit)
+	}
+	if _, err := admins.ComplianceReports(actor(admin.RoleAuditor), models.ComplianceReportFilter{Limit: 1 << 20}, "csv"); err != nil {
+		t.Fatal(err)
+	}
+	if reports.filter.Limit != maxComplianceExport {
+		t.Errorf("limit = %d, want it clamped to %d", reports.filter.Limit, maxComplianceExport)
+	}
+	if got := audit.actions(); len(got) != 2 || got[1] != AuditComplianceExport {
+		t.Errorf("audited %v, want both exports", got)
+	}
+}
+
+func TestLedgerReportsAreAudited(t *testing.T) {
+	_, admins, audit := newAdminFixture(enabledWallet(0))
+	admins.reconciliationRepo.(*mockReconciliationRepo).runs = []models.ReconciliationRun{{ID: "run-1"}, {ID: "run-2"}}
+
+	report, err := admins.VerifyLedger(actor(admin.RoleAuditor), "wallet-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !report.Valid {
+		t.Errorf("report = %+v, want an empty chain to verify", report)
+	}
+	run, _, err := admins.ReconciliationReport(actor(admin.RoleAuditor), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if run.ID != "run-2" {
+		t.Errorf("run = %q, want the latest run", run.ID)
+	}
+	if _, _, err := admins.ReconciliationReport(actor(admin.RoleAuditor), "run-3"); !errors.Is(err, ErrReconciliationNotFound) {
+		t.Errorf("ReconciliationReport error = %v, want ErrReconciliationNotFound", err)
+	}
+	if _, _, err := admins.ReconciliationReport(actor(admin.RoleViewer), ""); !errors.Is(err, ErrForbidden) {
+		t.Errorf("viewer ReconciliationReport error = %v, want ErrForbidden", err)
+	}
+	if got := audit.actions(); len(got) != 2 || got[0] != AuditLedgerVerify || got[1] != AuditReconciliationView {
+		t.Errorf("audited %v, want the verification and the report", got)
+	}
+}
@@ -0,0 +1,118 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"mini-wallet/events"
+	"mini-wallet/models"
+	"mini-wallet/repositories"
+
+	"github.com/google/uuid"
+)
+
+// ClosureService closes wallets for good on their customer's request.
+type ClosureService struct {
+	wallets     *WalletService
+	closureRepo repositories.ClosureRepository
+	// retention is how long the customer's personal data is kept after
+	// the wallet is closed.
+	retention time.Duration
+}
+
+func NewClosureService(wallets *WalletService, closureRepo repositories.ClosureRepository, retention time.Duration) *ClosureService {
+	return &ClosureService{
+		wallets:     wallets,
+		closureRepo: closureRepo,
+		retention:   retention,
+	}
+}
+
+// Close closes the customer's wallet. A remaining balance is paid out to
+// destination by a final withdrawal, which auth must authorize like any
+// other, so a destination is required unless the balance is zero. The
+// wallet's customer tokens are revoked and its personal data is scheduled
+// for anonymization after the retention period.
+func (s *ClosureService) Close(customerXID, destination string, auth Authorization) (*models.Wallet, *models.WalletClosure, error) {
+	wallet, err := s.wallets.walletRepo.GetWalletByCustomerXID(customerXID)
+	if err != nil || wallet == nil {
+		return nil, nil, ErrWalletNotFound
+	}
+	if err := CanTransition(wallet.Status, models.WalletClosed, true); err != nil {
+		return nil, nil, err
+	}
+
+	// The stored balance may still be settling, so the payout is taken
+	// from the transaction log; closing checks it again under the wallet's
+	// lock
+	transactions, err := s.wallets.transactionRepo.GetTransactionsByWalletID(wallet.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	balance := models.Balance(transactions)
+	destination = strings.TrimSpace(destination)
+	if balance > 0 && destination == "" {
+		return nil, nil, ErrPayoutRequired
+	}
+	if balance > 0 {
+		if err := s.wallets.authorizeWithdrawal(customerXID, balance, auth); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	now := time.Now().UTC()
+	closure := &models.WalletClosure{
+		ID:                uuid.New().String(),
+		WalletID:          wallet.ID,
+		CustomerXID:       customerXID,
+		PayoutDestination: destination,
+		ClosedAt:          now,
+		AnonymizeAfter:    now.Add(s.retention),
+	}
+	var payout *models.Transaction
+	if balance > 0 {
+		payout = &models.Transaction{
+			ID:           uuid.New().String(),
+			WalletID:     wallet.ID,
+			Type:         "withdrawal",
+			Status:       "success",
+			Amount:       balance,
+			ReferenceID:  uuid.New().String(),
+			TransactedAt: now,
+		}
+		closure.PayoutAmount, closure.PayoutTransactionID = balance, payout.ID
+	}
+	change := &models.WalletStatusChange{
+		ID:         uuid.New().String(),
+		WalletID:   wallet.ID,
+		FromStatus: wallet.Status,
+		ToStatus:   models.WalletClosed,
+		Reason:     "closed by the customer",
+		ActorType:  models.ActorCustomer,
+		ActorID:    customerXID,
+		ChangedAt:  now,
+	}
+	closed := *wallet
+	closed.Status, closed.DisabledAt, closed.Balance = models.WalletClosed, now, 0
+	if err := s.closureRepo.CloseWallet(&closed, change, payout, closure); err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			// Another request changed the status since it was read
+			return nil, nil, ErrInvalidTransition
+		case errors.Is(err, repositories.ErrBalanceChanged):
+			return nil, nil, ErrBalanceChanged
+		}
+		return nil, nil, err
+	}
+
+	ctx := context.Background()
+	if payout != nil {
+		s.wallets.publisher.Publish(ctx, TransactionEvent(customerXID, payout))
+	}
+	s.wallets.publisher.Publish(ctx, events.New(events.WalletClosed, customerXID, wallet.ID, ClosedWalletView(&closed, closure)))
+	s.wallets.cacheBalance(ctx, customerXID, 0)
+	return &closed, closure, nil
+}
@@ -0,0 +1,133 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"mini-wallet/events"
+	"mini-wallet/models"
+)
+
+const retention = 24 * time.Hour
+
+func newClosureFixture(wallets ...models.Wallet) (*fixture, *ClosureService, *mockClosureRepo) {
+	f := newFixture(wallets...)
+	closures := &mockClosureRepo{closures: make(map[string]models.WalletClosure), wallets: f.wallets, transactions: f.transactions, tokens: f.tokens}
+	return f, NewClosureService(f.service, closures, retention), closures
+}
+
+func TestCloseWalletPaysOutTheBalance(t *testing.T) {
+	f, closures, repo := newClosureFixture(enabledWallet(100))
+	f.tokens.tokens[customer] = "token-1"
+	f.transactions.CreateTransaction(&models.Transaction{ID: "deposit-1", WalletID: "wallet-1", Type: "deposit", Amount: 100})
+
+	if _, _, err := closures.Close(customer, " ", Authorization{}); !errors.Is(err, ErrPayoutRequired) {
+		t.Fatalf("Close without a destination error = %v, want ErrPayoutRequired", err)
+	}
+	wallet, closure, err := closures.Close(customer, "BCA 1234567890", Authorization{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wallet.Status != models.WalletClosed || wallet.Balance != 0 {
+		t.Errorf("wallet = %+v, want closed with no balance", wallet)
+	}
+	if closure.PayoutAmount != 100 || closure.PayoutDestination != "BCA 1234567890" || closure.PayoutTransactionID == "" {
+		t.Errorf("closure = %+v, want a payout of 100 to the destination", closure)
+	}
+	if !closure.AnonymizeAfter.Equal(closure.ClosedAt.Add(retention)) {
+		t.Errorf("anonymize_after = %v, want the retention after %v", closure.AnonymizeAfter, closure.ClosedAt)
+	}
+	if _, ok := repo.closures["wallet-1"]; !ok {
+		t.Error("closure was not stored")
+	}
+
+	transactions, _ := f.transactions.GetTransactionsByWalletID("wallet-1")
+	if models.Balance(transactions) != 0 || transactions[len(transactions)-1].ID != closure.PayoutTransactionID {
+		t.Errorf("transactions = %+v, want the payout to empty the wallet", transactions)
+	}
+	if got := f.events.types(); len(got) != 2 || got[0] != events.WithdrawalSucceeded || got[1] != events.WalletClosed {
+		t.Errorf("published %v, want the payout then wallet.closed", got)
+	}
+
+	if _, err := f.service.Authenticate("token-1"); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Authenticate error = %v, want the token revoked", err)
+	}
+	if _, err := f.service.Init(customer); !errors.Is(err, ErrWalletClosed) {
+		t.Errorf("Init error = %v, want ErrWalletClosed", err)
+	}
+	if _, err := f.service.Enable(customer); !errors.Is(err, ErrWalletClosed) {
+		t.Errorf("Enable error = %v, want ErrWalletClosed", err)
+	}
+	if _, _, err := closures.Close(customer, "", Authorization{}); !errors.Is(err, ErrWalletClosed) {
+		t.Errorf("second Close error = %v, want ErrWalletClosed", err)
+	}
+}
+
+func TestCloseWalletRules(t *testing.T) {
+	frozen := enabledWallet(0)
+	frozen.Status = models.WalletFrozen
+	pending := disabledWallet()
+	pending.Status = models.WalletPending
+
+	tests := []struct {
+		name    string
+		wallet  models.Wallet
+		wantErr error
+	}{
+		{"closes an empty enabled wallet", enabledWallet(0), nil},
+		{"closes a disabled wallet", disabledWallet(), nil},
+		{"closes a pending wallet", pending, nil},
+		{"rejects a frozen wallet", frozen, ErrWalletFrozen},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, closures, _ := newClosureFixture(tt.wallet)
+
+			_, closure, err := closures.Close(customer, "", Authorization{})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Close error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				if stored := f.wallets.wallet(customer); stored.Status != tt.wallet.Status {
+					t.Errorf("stored status = %q, want it unchanged", stored.Status)
+				}
+				return
+			}
+			if closure.PayoutAmount != 0 || closure.PayoutTransactionID != "" {
+				t.Errorf("closure = %+v, want no payout", closure)
+			}
+			if history, _ := f.wallets.ListStatusChanges("wallet-1"); len(history) != 1 || history[0].ToStatus != models.WalletClosed {
+				t.Errorf("status history = %+v, want the closure", history)
+			}
+		})
+	}
+}
+
+func TestCloseWalletRacingDeposit(t *testing.T) {
+	f, closures, repo := newClosureFixture(enabledWallet(100))
+	f.transactions.CreateTransaction(&models.Transaction{ID: "deposit-1", WalletID: "wallet-1", Type: "deposit", Amount: 100})
+	// A deposit lands after the payout was worked out
+	repo.beforeClose = func() {
+		f.transactions.CreateTransaction(&models.Transaction{ID: "deposit-2", WalletID: "wallet-1", Type: "deposit", Amount: 50})
+	}
+
+	if _, _, err := closures.Close(customer, "BCA 1234567890", Authorization{}); !errors.Is(err, ErrBalanceChanged) {
+		t.Fatalf("Close error = %v, want ErrBalanceChanged", err)
+	}
+	if wallet := f.wallets.wallet(customer); wallet.Status != models.WalletEnabled {
+		t.Errorf("wallet = %+v, want it left open", wallet)
+	}
+	if _, ok := repo.closures["wallet-1"]; ok {
+		t.Error("closure was stored")
+	}
+
+	repo.beforeClose = nil
+	_, closure, err := closures.Close(customer, "BCA 1234567890", Authorization{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if closure.PayoutAmount != 150 {
+		t.Errorf("payout = %d, want the deposit paid out too", closure.PayoutAmount)
+	}
+}
@@ -0,0 +1,36 @@
+package service
+
+import (
+	"time"
+
+	"mini-wallet/admin"
+	"mini-wallet/models"
+)
+
+// ComplianceReports exports the compliance reports matching filter, most
+// recent transactions first. Without a limit it returns up to 50 reports;
+// a limit may ask for up to 10000.
+func (s *AdminService) ComplianceReports(actor *models.AdminKey, filter models.ComplianceReportFilter, format string) ([]models.ComplianceReport, error) {
+	if err := authorize(actor, admin.PermComplianceRead); err != nil {
+		return nil, err
+	}
+	if filter.Limit <= 0 {
+		filter.Limit = defaultAdminQueryLimit
+	}
+	filter.Limit = min(filter.Limit, maxComplianceExport)
+	reports, err := s.complianceRepo.ListReports(filter)
+	if err != nil {
+		return nil, err
+	}
+	details := map[string]any{"kind": filter.Kind, "format": format, "results": len(reports)}
+	if !filter.From.IsZero() {
+		details["from"] = filter.From.Format(time.RFC3339)
+	}
+	if !filter.To.IsZero() {
+		details["to"] = filter.To.Format(time.RFC3339)
+	}
+	if err := s.record(actor, AuditComplianceExport, auditTargetWallet, filter.WalletID, "", details); err != nil {
+		return nil, err
+	}
+	return reports, nil
+}
@@ -0,0 +1,96 @@
+package service
+
+// Kind classifies a domain error so transports can map whole classes of
+// errors onto their own status codes.
+type Kind int
+
+const (
+	// KindInvalid means the request itself is unacceptable.
+	KindInvalid Kind = iota + 1
+	// KindUnauthenticated means the caller could not be identified.
+	KindUnauthenticated
+	// KindNotFound means the wallet or customer does not exist.
+	KindNotFound
+	// KindConflict means the request collides with another one, such as a
+	// reused reference_id.
+	KindConflict
+	// KindFailedPrecondition means the wallet's state does not allow the request.
+	KindFailedPrecondition
+	// KindPermissionDenied means the caller is identified but not allowed
+	// to make the request.
+	KindPermissionDenied
+)
+
+// Error is a business rule violation. Any other error returned by the
+// services is an internal failure.
+type Error struct {
+	Kind    Kind
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Domain errors returned by the services, compared with errors.Is.
+var (
+	ErrInvalidToken             = &Error{KindUnauthenticated, "invalid token"}
+	ErrTokenRevoked             = &Error{KindUnauthenticated, "token revoked"}
+	ErrWalletNotFound           = &Error{KindNotFound, "wallet not found"}
+	ErrWalletDisabled           = &Error{KindFailedPrecondition, "wallet disabled"}
+	ErrWalletAlreadyEnabled     = &Error{KindFailedPrecondition, "wallet already enabled"}
+	ErrWalletAlreadyDisabled    = &Error{KindFailedPrecondition, "wallet already disabled"}
+	ErrInvalidAmount            = &Error{KindInvalid, "amount must be greater than 0"}
+	ErrDuplicateReference       = &Error{KindConflict, "duplicate reference_id"}
+	ErrInsufficientBalance      = &Error{KindFailedPrecondition, "insufficient balance"}
+	ErrWalletLocked             = &Error{KindConflict, "wallet balance is being updated"}
+	ErrBalanceChanged           = &Error{KindConflict, "balance changed while the request was processed"}
+	ErrWalletFrozen             = &Error{KindFailedPrecondition, "wallet frozen"}
+	ErrWalletNotFrozen          = &Error{KindFailedPrecondition, "wallet not frozen"}
+	ErrWalletSuspended          = &Error{KindFailedPrecondition, "wallet suspended"}
+	ErrWalletNotSuspended       = &Error{KindFailedPrecondition, "wallet not suspended"}
+	ErrWalletClosed             = &Error{KindFailedPrecondition, "wallet closed"}
+	ErrInvalidTransition        = &Error{KindFailedPrecondition, "wallet status cannot change that way"}
+	ErrInvalidAdminKey          = &Error{KindUnauthenticated, "invalid admin key"}
+	ErrForbidden                = &Error{KindPermissionDenied, "role does not allow this action"}
+	ErrReasonRequired           = &Error{KindInvalid, "reason is required"}
+	ErrAdjustmentNotFound       = &Error{KindNotFound, "adjustment not found"}
+	ErrAdjustmentNotPending     = &Error{KindFailedPrecondition, "adjustment already reviewed"}
+	ErrSelfReview               = &Error{KindPermissionDenied, "adjustments must be reviewed by another admin"}
+	ErrPayoutRequired           = &Error{KindInvalid, "payout_destination is required to pay out the balance"}
+	ErrTransactionBlocked       = &Error{KindFailedPrecondition, "transaction blocked by risk rules"}
+	ErrRiskDecisionNotFound     = &Error{KindNotFound, "risk decision not found"}
+	ErrReconciliationNotFound   = &Error{KindNotFound, "reconciliation run not found"}
+	ErrRiskDecisionNotHeld      = &Error{KindFailedPrecondition, "risk decision is not held for review"}
+	ErrKYCTierRequired          = &Error{KindFailedPrecondition, "KYC tier does not allow this operation"}
+	ErrBalanceLimit             = &Error{KindFailedPrecondition, "balance would exceed the KYC tier limit"}
+	ErrInvalidKYCTier           = &Error{KindInvalid, "tier must be between 0 and 2"}
+	ErrCustomerNotFound         = &Error{KindNotFound, "customer not found"}
+	ErrPINRequired              = &Error{KindPermissionDenied, "transaction PIN or step-up token is required"}
+	ErrInvalidPIN               = &Error{KindPermissionDenied, "invalid transaction PIN"}
+	ErrInvalidStepUpToken       = &Error{KindPermissionDenied, "invalid or expired step-up token"}
+	ErrPINLocked                = &Error{KindFailedPrecondition, "transaction PIN locked after too many wrong attempts"}
+	ErrPINNotSet                = &Error{KindFailedPrecondition, "no transaction PIN set"}
+	ErrInvalidPINFormat         = &Error{KindInvalid, "PIN must be 6 digits, not repeated or sequential"}
+	ErrTOTPRequired             = &Error{KindPermissionDenied, "one-time code is required"}
+	ErrInvalidTOTP              = &Error{KindPermissionDenied, "invalid one-time code"}
+	ErrTOTPLocked               = &Error{KindFailedPrecondition, "one-time codes locked after too many wrong attempts"}
+	ErrTOTPAlreadyEnrolled      = &Error{KindFailedPrecondition, "authenticator app already enrolled"}
+	ErrTOTPNotEnrolled          = &Error{KindFailedPrecondition, "no authenticator app enrolled"}
+	ErrTOTPUnavailable          = &Error{KindNotFound, "two-factor authentication is not available"}
+	ErrSelfTransfer             = &Error{KindInvalid, "cannot transfer to your own wallet"}
+	ErrPayeeNotFound            = &Error{KindNotFound, "payee not found"}
+	ErrPayeeUnavailable         = &Error{KindFailedPrecondition, "payee cannot receive transfers"}
+	ErrInvalidFrequency         = &Error{KindInvalid, "frequency must be once, daily, weekly or monthly"}
+	ErrStartInPast              = &Error{KindInvalid, "start_at must not be in the past"}
+	ErrEndBeforeStart           = &Error{KindInvalid, "ends_at must not be before start_at"}
+	ErrStandingOrderNotFound    = &Error{KindNotFound, "standing order not found"}
+	ErrStandingOrderInactive    = &Error{KindFailedPrecondition, "standing order is no longer active"}
+	ErrSelfRequest              = &Error{KindInvalid, "cannot request money from yourself"}
+	ErrPayerNotFound            = &Error{KindNotFound, "payer not found"}
+	ErrInvalidExpiry            = &Error{KindInvalid, "expires_at is out of range"}
+	ErrOwnPaymentRequest        = &Error{KindPermissionDenied, "cannot pay or decline your own payment request"}
+	ErrDeclineNotAllowed        = &Error{KindPermissionDenied, "only the payer a payment request is addressed to can decline it"}
+	ErrPaymentRequestNotFound   = &Error{KindNotFound, "payment request not found"}
+	ErrPaymentRequestNotPending = &Error{KindFailedPrecondition, "payment request is no longer pending"}
+)
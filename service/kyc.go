@@ -0,0 +1,155 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"mini-wallet/admin"
+	"mini-wallet/models"
+)
+
+// basicProfile is the KYC profile of a customer nobody verified yet.
+func basicProfile(customerXID string) *models.KYCProfile {
+	return &models.KYCProfile{
+		CustomerXID: customerXID,
+		Tier:        models.KYCTierBasic,
+		Status:      models.KYCUnverified,
+		Documents:   []models.KYCDocument{},
+	}
+}
+
+// kycProfile returns the customer's KYC profile. Customers from before KYC
+// profiles have none stored and get the basic one.
+func (s *WalletService) kycProfile(customerXID string) (*models.KYCProfile, error) {
+	profile, err := s.kycRepo.GetProfile(customerXID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return basicProfile(customerXID), nil
+	}
+	return profile, err
+}
+
+// checkTier passes the customer's KYC tier to allow, unless KYC tiers are
+// not enforced.
+func (s *WalletService) checkTier(customerXID string, allow func(tier int) error) error {
+	if s.kycRepo == nil {
+		return nil
+	}
+	profile, err := s.kycProfile(customerXID)
+	if err != nil {
+		return err
+	}
+	return allow(profile.Tier)
+}
+
+// checkEnableTier refuses to enable the wallet of a customer below the
+// configured enable tier.
+func (s *WalletService) checkEnableTier(customerXID string) error {
+	return s.checkTier(customerXID, func(tier int) error {
+		if tier < s.cfg.KYC.EnableTier {
+			return ErrKYCTierRequired
+		}
+		return nil
+	})
+}
+
+// checkTransactionTier refuses withdrawals below the configured withdraw
+// tier, and deposits that would bring the stored balance over the cap of
+// the customer's tier.
+func (s *WalletService) checkTransactionTier(wallet *models.Wallet, op Operation, amount int64) error {
+	return s.checkTier(wallet.OwnedBy, func(tier int) error {
+		if op == OpWithdraw {
+			if tier < s.cfg.KYC.WithdrawTier {
+				return ErrKYCTierRequired
+			}
+			return nil
+		}
+		if limit := s.maxBalance(tier); limit > 0 && wallet.Balance+amount > limit {
+			return ErrBalanceLimit
+		}
+		return nil
+	})
+}
+
+// maxBalance is the balance cap of tier, 0 when it has none.
+func (s *WalletService) maxBalance(tier int) int64 {
+	switch tier {
+	case models.KYCTierBasic:
+		return s.cfg.KYC.BasicMaxBalance
+	case models.KYCTierVerified:
+		return s.cfg.KYC.VerifiedMaxBalance
+	}
+	return 0
+}
+
+// KYCProfile returns a customer's KYC profile.
+func (s *AdminService) KYCProfile(actor *models.AdminKey, customerXID string) (*models.KYCProfile, error) {
+	if err := authorize(actor, admin.PermCustomersRead); err != nil {
+		return nil, err
+	}
+	profile, err := s.customerProfile(customerXID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.record(actor, AuditKYCView, auditTargetCustomer, customerXID, "", nil); err != nil {
+		return nil, err
+	}
+	return profile, nil
+}
+
+// ChangeKYCTier moves a customer to tier, adding the documents checked to
+// reach it. Moving up verifies the customer; moving down to the basic tier
+// revokes an earlier verification.
+func (s *AdminService) ChangeKYCTier(actor *models.AdminKey, customerXID string, tier int, reason string, documents []models.KYCDocument) (*models.KYCProfile, error) {
+	if err := authorize(actor, admin.PermKYCManage); err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(reason) == "" {
+		return nil, ErrReasonRequired
+	}
+	if tier < models.KYCTierBasic || tier > models.KYCTierFull {
+		return nil, ErrInvalidKYCTier
+	}
+	profile, err := s.customerProfile(customerXID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	from := profile.Tier
+	switch {
+	case tier > from:
+		profile.Status = models.KYCVerified
+		profile.VerifiedAt = &now
+	case tier == models.KYCTierBasic && profile.Status == models.KYCVerified:
+		profile.Status = models.KYCRevoked
+	}
+	profile.Tier = tier
+	for _, document := range documents {
+		document.AddedAt = now
+		profile.Documents = append(profile.Documents, document)
+	}
+	profile.UpdatedAt = now
+	if err := s.wallets.kycRepo.SaveProfile(profile); err != nil {
+		return nil, err
+	}
+
+	details := map[string]any{"from_tier": from, "to_tier": tier, "status": profile.Status, "documents": len(documents)}
+	if err := s.record(actor, AuditKYCTierChange, auditTargetCustomer, customerXID, reason, details); err != nil {
+		return nil, err
+	}
+	return profile, nil
+}
+
+// customerProfile returns the KYC profile of an existing customer.
+func (s *AdminService) customerProfile(customerXID string) (*models.KYCProfile, error) {
+	exists, err := s.customerTokenRepo.CustomerExists(customerXID)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrCustomerNotFound
+	}
+	return s.wallets.kycProfile(customerXID)
+}
@@ -0,0 +1,113 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"mini-wallet/admin"
+	"mini-wallet/models"
+)
+
+func newKYCFixture(wallets ...models.Wallet) (*fixture, *AdminService, *mockKYCRepo, *mockAuditRepo) {
+	f := newFixture(wallets...)
+	f.tokens.tokens[customer] = "token-1"
+	cfg := f.service.cfg
+	cfg.KYC.EnableTier = 0
+	cfg.KYC.WithdrawTier = 1
+	cfg.KYC.BasicMaxBalance = 500
+	cfg.KYC.VerifiedMaxBalance = 800
+	profiles := &mockKYCRepo{profiles: make(map[string]models.KYCProfile)}
+	f.service = NewWalletService(f.wallets, f.transactions, f.snapshots, f.tokens, nil, f.events, nil, nil, profiles, nil, nil, cfg)
+	audit := &mockAuditRepo{}
+	return f, NewAdminService(f.service, f.wallets, f.transactions, f.tokens, nil, audit, nil, nil, nil), profiles, audit
+}
+
+func TestKYCTierGatesWallet(t *testing.T) {
+	f, _, profiles, _ := newKYCFixture(enabledWallet(400))
+
+	if _, err := f.service.Withdraw(customer, 10, reference, Authorization{}); !errors.Is(err, ErrKYCTierRequired) {
+		t.Errorf("tier 0 withdrawal error = %v, want ErrKYCTierRequired", err)
+	}
+	if _, err := f.service.Deposit(customer, 101, reference); !errors.Is(err, ErrBalanceLimit) {
+		t.Errorf("deposit over the tier 0 cap error = %v, want ErrBalanceLimit", err)
+	}
+	if _, err := f.service.Deposit(customer, 100, reference); err != nil {
+		t.Errorf("deposit up to the tier 0 cap error = %v, want it allowed", err)
+	}
+
+	profiles.profiles[customer] = models.KYCProfile{CustomerXID: customer, Tier: models.KYCTierVerified, Status: models.KYCVerified}
+	if _, err := f.service.Withdraw(customer, 10, "withdrawal-1", Authorization{}); err != nil {
+		t.Errorf("tier 1 withdrawal error = %v, want it allowed", err)
+	}
+	profiles.profiles[customer] = models.KYCProfile{CustomerXID: customer, Tier: models.KYCTierFull, Status: models.KYCVerified}
+	if _, err := f.service.Deposit(customer, 1000, "deposit-2"); err != nil {
+		t.Errorf("tier 2 deposit error = %v, tier 2 has no cap", err)
+	}
+}
+
+func TestKYCEnableTier(t *testing.T) {
+	f, _, _, _ := newKYCFixture(disabledWallet())
+	f.service.cfg.KYC.EnableTier = 1
+
+	if _, err := f.service.Enable(customer); !errors.Is(err, ErrKYCTierRequired) {
+		t.Errorf("tier 0 enable error = %v, want ErrKYCTierRequired", err)
+	}
+	if got := f.wallets.wallet(customer).Status; got != models.WalletDisabled {
+		t.Errorf("wallet status = %q, want it still disabled", got)
+	}
+}
+
+func TestInitCreatesBasicProfile(t *testing.T) {
+	f, _, profiles, _ := newKYCFixture()
+	if _, err := f.service.Init("new-customer"); err != nil {
+		t.Fatal(err)
+	}
+	profile, ok := profiles.profiles["new-customer"]
+	if !ok || profile.Tier != models.KYCTierBasic || profile.Status != models.KYCUnverified {
+		t.Errorf("profile = %+v, %v, want an unverified tier 0 profile", profile, ok)
+	}
+}
+
+func TestChangeKYCTier(t *testing.T) {
+	f, admins, _, audit := newKYCFixture(enabledWallet(100))
+
+	if _, err := admins.ChangeKYCTier(actor(admin.RoleViewer), customer, 1, "checked", nil); !errors.Is(err, ErrForbidden) {
+		t.Errorf("viewer change error = %v, want ErrForbidden", err)
+	}
+	if _, err := admins.ChangeKYCTier(actor(admin.RoleSupport), customer, 1, " ", nil); !errors.Is(err, ErrReasonRequired) {
+		t.Errorf("change without reason error = %v, want ErrReasonRequired", err)
+	}
+	if _, err := admins.ChangeKYCTier(actor(admin.RoleSupport), customer, 3, "checked", nil); !errors.Is(err, ErrInvalidKYCTier) {
+		t.Errorf("tier 3 error = %v, want ErrInvalidKYCTier", err)
+	}
+	if _, err := admins.ChangeKYCTier(actor(admin.RoleSupport), "unknown", 1, "checked", nil); !errors.Is(err, ErrCustomerNotFound) {
+		t.Errorf("unknown customer error = %v, want ErrCustomerNotFound", err)
+	}
+
+	documents := []models.KYCDocument{{Type: "national_id", Reference: "kyc/national-id.jpg"}}
+	upgraded, err := admins.ChangeKYCTier(actor(admin.RoleSupport), customer, 1, "national ID checked", documents)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if upgraded.Status != models.KYCVerified || upgraded.VerifiedAt == nil || len(upgraded.Documents) != 1 || upgraded.Documents[0].AddedAt.IsZero() {
+		t.Errorf("upgraded profile = %+v, want verified with the document", upgraded)
+	}
+	if _, err := f.service.Withdraw(customer, 10, reference, Authorization{}); err != nil {
+		t.Errorf("withdrawal after the upgrade error = %v, want it allowed", err)
+	}
+
+	downgraded, err := admins.ChangeKYCTier(actor(admin.RoleOperator), customer, 0, "document forged", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if downgraded.Status != models.KYCRevoked || len(downgraded.Documents) != 1 {
+		t.Errorf("downgraded profile = %+v, want revoked keeping the document", downgraded)
+	}
+
+	if got := audit.actions(); len(got) != 2 || got[0] != AuditKYCTierChange || got[1] != AuditKYCTierChange {
+		t.Fatalf("audited %v, want both tier changes", got)
+	}
+	if details := audit.entries[1].Details; details["from_tier"] != 1 || details["to_tier"] != 0 {
+		t.Errorf("audit details = %v, want from_tier 1 and to_tier 0", details)
+	}
+}
@@ -0,0 +1,59 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+
+	"mini-wallet/admin"
+	"mini-wallet/ledger"
+	"mini-wallet/models"
+)
+
+// VerifyLedger walks the hash chain of any wallet's transactions and
+// reports the first broken link, if any.
+func (s *AdminService) VerifyLedger(actor *models.AdminKey, walletID string) (*ledger.Report, error) {
+	if err := authorize(actor, admin.PermLedgerRead); err != nil {
+		return nil, err
+	}
+	if _, err := s.wallets.walletByID(walletID); err != nil {
+		return nil, err
+	}
+	report, err := ledger.Verify(s.transactionRepo, walletID)
+	if err != nil {
+		return nil, err
+	}
+	details := map[string]any{"valid": report.Valid, "head_sequence": report.HeadSequence, "head_hash": report.HeadHash}
+	if err := s.record(actor, AuditLedgerVerify, auditTargetWallet, walletID, "", details); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// ReconciliationReport returns a balance reconciliation run and its
+// discrepancies, the latest run unless runID is given.
+func (s *AdminService) ReconciliationReport(actor *models.AdminKey, runID string) (*models.ReconciliationRun, []models.BalanceDiscrepancy, error) {
+	if err := authorize(actor, admin.PermLedgerRead); err != nil {
+		return nil, nil, err
+	}
+	var run *models.ReconciliationRun
+	var err error
+	if runID != "" {
+		run, err = s.reconciliationRepo.GetRun(runID)
+	} else {
+		run, err = s.reconciliationRepo.GetLatestRun()
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil, ErrReconciliationNotFound
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	discrepancies, err := s.reconciliationRepo.GetDiscrepanciesByRunID(run.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := s.record(actor, AuditReconciliationView, auditTargetReconciliation, run.ID, "", map[string]any{"discrepancies": len(discrepancies)}); err != nil {
+		return nil, nil, err
+	}
+	return run, discrepancies, nil
+}
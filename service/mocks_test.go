@@ -0,0 +1,1034 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"time"
+
+	"mini-wallet/events"
+	"mini-wallet/models"
+	"mini-wallet/repositories"
+
+	"github.com/google/uuid"
+)
+
+// mockWalletRepo keeps wallets by owner. Methods the service does not use
+// panic through the nil embedded interface.
+type mockWalletRepo struct {
+	repositories.WalletRepository
+	mu      sync.Mutex
+	wallets map[string]*models.Wallet
+	changes []models.WalletStatusChange
+}
+
+func newMockWalletRepo(wallets ...models.Wallet) *mockWalletRepo {
+	r := &mockWalletRepo{wallets: make(map[string]*models.Wallet)}
+	for i := range wallets {
+		r.wallets[wallets[i].OwnedBy] = &wallets[i]
+	}
+	return r
+}
+
+func (r *mockWalletRepo) GetWalletByCustomerXID(customerXID string) (*models.Wallet, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	wallet, ok := r.wallets[customerXID]
+	if !ok {
+		return nil, nil
+	}
+	copied := *wallet
+	return &copied, nil
+}
+
+func (r *mockWalletRepo) GetWalletByID(walletID string) (*models.Wallet, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, wallet := range r.wallets {
+		if wallet.ID == walletID {
+			copied := *wallet
+			return &copied, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (r *mockWalletRepo) CreateWallet(wallet *models.Wallet) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	copied := *wallet
+	r.wallets[wallet.OwnedBy] = &copied
+	return nil
+}
+
+func (r *mockWalletRepo) UpdateWalletStatus(wallet *models.Wallet, change *models.WalletStatusChange) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for owner, stored := range r.wallets {
+		if stored.ID == wallet.ID && stored.Status == change.FromStatus {
+			updated := *stored
+			updated.Status, updated.EnabledAt, updated.DisabledAt = wallet.Status, wallet.EnabledAt, wallet.DisabledAt
+			r.wallets[owner] = &updated
+			r.changes = append(r.changes, *change)
+			return nil
+		}
+	}
+	return sql.ErrNoRows
+}
+
+func (r *mockWalletRepo) ListStatusChanges(walletID string) ([]models.WalletStatusChange, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var changes []models.WalletStatusChange
+	for _, change := range r.changes {
+		if change.WalletID == walletID {
+			changes = append(changes, change)
+		}
+	}
+	return changes, nil
+}
+
+func (r *mockWalletRepo) UpdateWalletBalance(walletID string, balance int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, wallet := range r.wallets {
+		if wallet.ID == walletID {
+			wallet.Balance = balance
+			return nil
+		}
+	}
+	return sql.ErrNoRows
+}
+
+func (r *mockWalletRepo) wallet(customerXID string) models.Wallet {
+	wallet, _ := r.GetWalletByCustomerXID(customerXID)
+	return *wallet
+}
+
+type mockTransactionRepo struct {
+	repositories.TransactionRepository
+	mu           sync.Mutex
+	transactions []models.Transaction
+}
+
+func (r *mockTransactionRepo) CreateTransaction(transaction *models.Transaction) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.transactions = append(r.transactions, *transaction)
+	return nil
+}
+
+func (r *mockTransactionRepo) GetTransactionByReferenceID(referenceID string) (*models.Transaction, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, t := range r.transactions {
+		if t.ReferenceID == referenceID {
+			return &t, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (r *mockTransactionRepo) GetTransactionsByWalletID(walletID string) ([]models.Transaction, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var transactions []models.Transaction
+	for _, t := range r.transactions {
+		if t.WalletID == walletID {
+			transactions = append(transactions, t)
+		}
+	}
+	return transactions, nil
+}
+
+func (r *mockTransactionRepo) GetTransactionsSince(walletID string, since time.Time) ([]models.Transaction, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var transactions []models.Transaction
+	for _, t := range r.transactions {
+		if t.WalletID == walletID && t.TransactedAt.After(since) {
+			transactions = append(transactions, t)
+		}
+	}
+	return transactions, nil
+}
+
+func (r *mockTransactionRepo) BalanceChange(walletID string, from, to time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var change int64
+	for _, t := range r.transactions {
+		if t.WalletID == walletID && !t.TransactedAt.Before(from) && t.TransactedAt.Before(to) {
+			change += t.SignedAmount()
+		}
+	}
+	return change, nil
+}
+
+func (r *mockTransactionRepo) StreamChain(walletID string, fn func(models.Transaction) error) error {
+	transactions, _ := r.GetTransactionsByWalletID(walletID)
+	for _, t := range transactions {
+		if err := fn(t); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mockSnapshotRepo keeps snapshots in the order they were saved.
+type mockSnapshotRepo struct {
+	mu        sync.Mutex
+	snapshots []models.BalanceSnapshot
+}
+
+func (r *mockSnapshotRepo) SaveSnapshot(snapshot *models.BalanceSnapshot) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.snapshots = append(r.snapshots, *snapshot)
+	return nil
+}
+
+func (r *mockSnapshotRepo) GetLatestSnapshot(walletID string, at time.Time) (*models.BalanceSnapshot, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var latest *models.BalanceSnapshot
+	for i, s := range r.snapshots {
+		if s.WalletID == walletID && !s.AsOf.After(at) && (latest == nil || s.AsOf.After(latest.AsOf)) {
+			latest = &r.snapshots[i]
+		}
+	}
+	if latest == nil {
+		return nil, sql.ErrNoRows
+	}
+	copied := *latest
+	return &copied, nil
+}
+
+type mockCustomerTokenRepo struct {
+	repositories.CustomerTokenRepository
+	tokens   map[string]string
+	revoked  map[string]bool
+	issuedAt map[string]time.Time
+}
+
+func (r *mockCustomerTokenRepo) revoke(customerXID string) {
+	if r.revoked == nil {
+		r.revoked = make(map[string]bool)
+	}
+	r.revoked[customerXID] = true
+}
+
+func (r *mockCustomerTokenRepo) CustomerExists(customerXID string) (bool, error) {
+	_, ok := r.tokens[customerXID]
+	return ok, nil
+}
+
+func (r *mockCustomerTokenRepo) CreateToken(customerXID, token string) error {
+	r.tokens[customerXID] = token
+	return nil
+}
+
+func (r *mockCustomerTokenRepo) GetToken(customerXID string) (string, error) {
+	token, ok := r.tokens[customerXID]
+	if !ok || r.revoked[customerXID] {
+		return "", sql.ErrNoRows
+	}
+	return token, nil
+}
+
+func (r *mockCustomerTokenRepo) GetCustomerToken(customerXID string) (*models.CustomerToken, error) {
+	token, err := r.GetToken(customerXID)
+	if err != nil {
+		return nil, err
+	}
+	return &models.CustomerToken{CustomerXID: customerXID, Token: token, CreatedAt: r.issuedAt[customerXID]}, nil
+}
+
+func (r *mockCustomerTokenRepo) GetCustomerXIDByToken(token string) (string, error) {
+	for customerXID, t := range r.tokens {
+		if t == token && !r.revoked[customerXID] {
+			return customerXID, nil
+		}
+	}
+	return "", sql.ErrNoRows
+}
+
+func (r *mockCustomerTokenRepo) RotateToken(customerXID, token string) error {
+	if _, ok := r.tokens[customerXID]; !ok {
+		return sql.ErrNoRows
+	}
+	r.tokens[customerXID] = token
+	delete(r.revoked, customerXID)
+	return nil
+}
+
+// mockAuditRepo keeps audit entries in the order they were written.
+type mockAuditRepo struct {
+	repositories.AuditRepository
+	mu      sync.Mutex
+	entries []models.AuditEntry
+}
+
+func (r *mockAuditRepo) CreateEntry(entry *models.AuditEntry) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries = append(r.entries, *entry)
+	return nil
+}
+
+func (r *mockAuditRepo) ListEntries(filter models.AuditFilter) ([]models.AuditEntry, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]models.AuditEntry(nil), r.entries...), nil
+}
+
+func (r *mockAuditRepo) actions() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	actions := make([]string, len(r.entries))
+	for i, entry := range r.entries {
+		actions[i] = entry.Action
+	}
+	return actions
+}
+
+// mockAdjustmentRepo posts approved adjustments into the mock transaction
+// and wallet repositories, as the real one does in a database transaction.
+type mockAdjustmentRepo struct {
+	mu           sync.Mutex
+	adjustments  map[string]models.Adjustment
+	wallets      *mockWalletRepo
+	transactions *mockTransactionRepo
+	audit        *mockAuditRepo
+}
+
+func (r *mockAdjustmentRepo) CreateAdjustment(adjustment *models.Adjustment) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.adjustments[adjustment.ID] = *adjustment
+	return nil
+}
+
+func (r *mockAdjustmentRepo) GetAdjustment(id string) (*models.Adjustment, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	adjustment, ok := r.adjustments[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return &adjustment, nil
+}
+
+func (r *mockAdjustmentRepo) ListAdjustments(filter models.AdjustmentFilter) ([]models.Adjustment, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var adjustments []models.Adjustment
+	for _, adjustment := range r.adjustments {
+		adjustments = append(adjustments, adjustment)
+	}
+	return adjustments, nil
+}
+
+func (r *mockAdjustmentRepo) review(adjustment *models.Adjustment) error {
+	if r.adjustments[adjustment.ID].Status != models.AdjustmentPending {
+		return sql.ErrNoRows
+	}
+	r.adjustments[adjustment.ID] = *adjustment
+	return nil
+}
+
+func (r *mockAdjustmentRepo) ApproveAdjustment(adjustment *models.Adjustment, transaction *models.Transaction, entry *models.AuditEntry) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	wallet, _ := r.wallets.GetWalletByID(transaction.WalletID)
+	if wallet.Balance+transaction.Amount < 0 {
+		return repositories.ErrInsufficientBalance
+	}
+	if err := r.review(adjustment); err != nil {
+		return err
+	}
+	r.transactions.CreateTransaction(transaction)
+	r.audit.CreateEntry(entry)
+	return r.wallets.UpdateWalletBalance(wallet.ID, wallet.Balance+transaction.Amount)
+}
+
+func (r *mockAdjustmentRepo) RejectAdjustment(adjustment *models.Adjustment) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.review(adjustment)
+}
+
+// mockClosureRepo closes wallets in the mock wallet, transaction and token
+// repositories, as the real one does in a database transaction.
+type mockClosureRepo struct {
+	repositories.ClosureRepository
+	mu           sync.Mutex
+	closures     map[string]models.WalletClosure
+	wallets      *mockWalletRepo
+	transactions *mockTransactionRepo
+	tokens       *mockCustomerTokenRepo
+	// beforeClose, if set, runs as a closure is recorded, standing in for
+	// a concurrent request.
+	beforeClose func()
+}
+
+func (r *mockClosureRepo) CloseWallet(wallet *models.Wallet, change *models.WalletStatusChange, payout *models.Transaction, closure *models.WalletClosure) error {
+	if r.beforeClose != nil {
+		r.beforeClose()
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if transactions, _ := r.transactions.GetTransactionsByWalletID(wallet.ID); models.Balance(transactions) != closure.PayoutAmount {
+		return repositories.ErrBalanceChanged
+	}
+	if err := r.wallets.UpdateWalletStatus(wallet, change); err != nil {
+		return err
+	}
+	if payout != nil {
+		r.transactions.CreateTransaction(payout)
+	}
+	r.tokens.revoke(closure.CustomerXID)
+	r.closures[closure.WalletID] = *closure
+	return r.wallets.UpdateWalletBalance(wallet.ID, 0)
+}
+
+// recorder collects published events.
+type recorder struct {
+	mu     sync.Mutex
+	events []events.Event
+}
+
+func (r *recorder) Publish(_ context.Context, event events.Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+}
+
+func (r *recorder) types() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	types := make([]string, len(r.events))
+	for i, event := range r.events {
+		types[i] = event.Type
+	}
+	return types
+}
+
+// mockRiskRepo posts released transactions into the mock transaction
+// repository and audits them, as the real one does in a database
+// transaction.
+type mockRiskRepo struct {
+	mu           sync.Mutex
+	decisions    map[string]models.RiskDecision
+	transactions *mockTransactionRepo
+	audit        *mockAuditRepo
+}
+
+func (r *mockRiskRepo) CreateDecision(decision *models.RiskDecision) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.decisions[decision.ID] = *decision
+	return nil
+}
+
+func (r *mockRiskRepo) GetDecision(id string) (*models.RiskDecision, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	decision, ok := r.decisions[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return &decision, nil
+}
+
+func (r *mockRiskRepo) GetHeldDecisionByReference(referenceID string) (*models.RiskDecision, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, decision := range r.decisions {
+		if decision.ReferenceID == referenceID && decision.Status == models.RiskHeld {
+			return &decision, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (r *mockRiskRepo) ListDecisions(filter models.RiskDecisionFilter) ([]models.RiskDecision, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var decisions []models.RiskDecision
+	for _, decision := range r.decisions {
+		if filter.Outcome == "" || decision.Outcome == filter.Outcome {
+			decisions = append(decisions, decision)
+		}
+	}
+	return decisions, nil
+}
+
+func (r *mockRiskRepo) review(decision *models.RiskDecision) error {
+	if r.decisions[decision.ID].Status != models.RiskHeld {
+		return sql.ErrNoRows
+	}
+	r.decisions[decision.ID] = *decision
+	return nil
+}
+
+func (r *mockRiskRepo) ReleaseDecision(decision *models.RiskDecision, transaction *models.Transaction, entry *models.AuditEntry) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	history, _ := r.transactions.GetTransactionsByWalletID(transaction.WalletID)
+	if models.Balance(history)+transaction.SignedAmount() < 0 {
+		return repositories.ErrInsufficientBalance
+	}
+	if err := r.review(decision); err != nil {
+		return err
+	}
+	r.transactions.CreateTransaction(transaction)
+	return r.audit.CreateEntry(entry)
+}
+
+func (r *mockRiskRepo) RejectDecision(decision *models.RiskDecision) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.review(decision)
+}
+
+// mockComplianceRepo records the filter of the last report listing.
+type mockComplianceRepo struct {
+	repositories.ComplianceRepository
+	filter models.ComplianceReportFilter
+}
+
+func (r *mockComplianceRepo) ListReports(filter models.ComplianceReportFilter) ([]models.ComplianceReport, error) {
+	r.filter = filter
+	return nil, nil
+}
+
+// mockReconciliationRepo serves a fixed set of runs, the last one latest.
+type mockReconciliationRepo struct {
+	repositories.ReconciliationRepository
+	runs          []models.ReconciliationRun
+	discrepancies []models.BalanceDiscrepancy
+}
+
+func (r *mockReconciliationRepo) GetLatestRun() (*models.ReconciliationRun, error) {
+	if len(r.runs) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return &r.runs[len(r.runs)-1], nil
+}
+
+func (r *mockReconciliationRepo) GetRun(id string) (*models.ReconciliationRun, error) {
+	for _, run := range r.runs {
+		if run.ID == id {
+			return &run, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (r *mockReconciliationRepo) GetDiscrepanciesByRunID(runID string) ([]models.BalanceDiscrepancy, error) {
+	var discrepancies []models.BalanceDiscrepancy
+	for _, d := range r.discrepancies {
+		if d.RunID == runID {
+			discrepancies = append(discrepancies, d)
+		}
+	}
+	return discrepancies, nil
+}
+
+type mockKYCRepo struct {
+	mu       sync.Mutex
+	profiles map[string]models.KYCProfile
+}
+
+func (r *mockKYCRepo) CreateProfile(profile *models.KYCProfile) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.profiles[profile.CustomerXID]; !ok {
+		r.profiles[profile.CustomerXID] = *profile
+	}
+	return nil
+}
+
+func (r *mockKYCRepo) GetProfile(customerXID string) (*models.KYCProfile, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	profile, ok := r.profiles[customerXID]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return &profile, nil
+}
+
+func (r *mockKYCRepo) SaveProfile(profile *models.KYCProfile) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.profiles[profile.CustomerXID] = *profile
+	return nil
+}
+
+type mockPINRepo struct {
+	mu     sync.Mutex
+	pins   map[string]models.TransactionPIN
+	tokens map[string]models.StepUpToken
+}
+
+func newMockPINRepo() *mockPINRepo {
+	return &mockPINRepo{pins: make(map[string]models.TransactionPIN), tokens: make(map[string]models.StepUpToken)}
+}
+
+func (r *mockPINRepo) GetPIN(customerXID string) (*models.TransactionPIN, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	pin, ok := r.pins[customerXID]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return &pin, nil
+}
+
+func (r *mockPINRepo) SetPIN(pin *models.TransactionPIN) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.pins[pin.CustomerXID] = models.TransactionPIN{CustomerXID: pin.CustomerXID, Hash: pin.Hash, UpdatedAt: pin.UpdatedAt}
+	r.deleteTokens(pin.CustomerXID)
+	return nil
+}
+
+func (r *mockPINRepo) DeletePIN(customerXID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.pins[customerXID]; !ok {
+		return sql.ErrNoRows
+	}
+	delete(r.pins, customerXID)
+	r.deleteTokens(customerXID)
+	return nil
+}
+
+func (r *mockPINRepo) deleteTokens(customerXID string) {
+	for hash, token := range r.tokens {
+		if token.CustomerXID == customerXID {
+			delete(r.tokens, hash)
+		}
+	}
+}
+
+func (r *mockPINRepo) ClaimAttempt(customerXID string, at time.Time) (*models.TransactionPIN, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	pin, ok := r.pins[customerXID]
+	if !ok || (pin.LockedUntil != nil && pin.LockedUntil.After(at)) {
+		return nil, sql.ErrNoRows
+	}
+	pin.FailedAttempts++
+	r.pins[customerXID] = pin
+	return &pin, nil
+}
+
+func (r *mockPINRepo) LockPIN(customerXID string, until time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	pin := r.pins[customerXID]
+	pin.LockedUntil = &until
+	r.pins[customerXID] = pin
+	return nil
+}
+
+func (r *mockPINRepo) ResetAttempts(customerXID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	pin := r.pins[customerXID]
+	pin.FailedAttempts, pin.LockedUntil = 0, nil
+	r.pins[customerXID] = pin
+	return nil
+}
+
+func (r *mockPINRepo) CreateStepUpToken(token *models.StepUpToken) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.tokens[token.TokenHash] = *token
+	return nil
+}
+
+func (r *mockPINRepo) CheckStepUpToken(tokenHash, customerXID string, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	token, ok := r.tokens[tokenHash]
+	if !ok || token.CustomerXID != customerXID || token.UsedAt != nil || !token.ExpiresAt.After(at) {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (r *mockPINRepo) ConsumeStepUpToken(tokenHash, customerXID string, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	token, ok := r.tokens[tokenHash]
+	if !ok || token.CustomerXID != customerXID || token.UsedAt != nil || !token.ExpiresAt.After(at) {
+		return sql.ErrNoRows
+	}
+	token.UsedAt = &at
+	r.tokens[tokenHash] = token
+	return nil
+}
+
+type mockTOTPRepo struct {
+	mu          sync.Mutex
+	enrollments map[string]models.TOTPEnrollment
+}
+
+func newMockTOTPRepo() *mockTOTPRepo {
+	return &mockTOTPRepo{enrollments: make(map[string]models.TOTPEnrollment)}
+}
+
+func (r *mockTOTPRepo) GetEnrollment(customerXID string) (*models.TOTPEnrollment, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	enrollment, ok := r.enrollments[customerXID]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	enrollment.RecoveryCodes = append([]string(nil), enrollment.RecoveryCodes...)
+	return &enrollment, nil
+}
+
+func (r *mockTOTPRepo) SaveEnrollment(enrollment *models.TOTPEnrollment) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	existing, ok := r.enrollments[enrollment.CustomerXID]
+	if ok && existing.ConfirmedAt != nil {
+		return sql.ErrNoRows
+	}
+	// Enrolling again keeps the attempts and lock
+	saved := *enrollment
+	saved.FailedAttempts, saved.LockedUntil = existing.FailedAttempts, existing.LockedUntil
+	r.enrollments[enrollment.CustomerXID] = saved
+	return nil
+}
+
+func (r *mockTOTPRepo) ConfirmEnrollment(customerXID string, counter int64, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	enrollment, ok := r.enrollments[customerXID]
+	if !ok || enrollment.ConfirmedAt != nil {
+		return sql.ErrNoRows
+	}
+	enrollment.LastCounter, enrollment.ConfirmedAt = counter, &at
+	r.enrollments[customerXID] = enrollment
+	return nil
+}
+
+func (r *mockTOTPRepo) UseCounter(customerXID string, counter int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	enrollment, ok := r.enrollments[customerXID]
+	if !ok || enrollment.LastCounter >= counter {
+		return sql.ErrNoRows
+	}
+	enrollment.LastCounter = counter
+	r.enrollments[customerXID] = enrollment
+	return nil
+}
+
+func (r *mockTOTPRepo) UseRecoveryCode(customerXID, codeHash string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	enrollment := r.enrollments[customerXID]
+	for i, hash := range enrollment.RecoveryCodes {
+		if hash == codeHash {
+			enrollment.RecoveryCodes = append(enrollment.RecoveryCodes[:i:i], enrollment.RecoveryCodes[i+1:]...)
+			r.enrollments[customerXID] = enrollment
+			return nil
+		}
+	}
+	return sql.ErrNoRows
+}
+
+func (r *mockTOTPRepo) ReplaceRecoveryCodes(customerXID string, codeHashes []string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	enrollment := r.enrollments[customerXID]
+	enrollment.RecoveryCodes = codeHashes
+	r.enrollments[customerXID] = enrollment
+	return nil
+}
+
+func (r *mockTOTPRepo) ClaimAttempt(customerXID string, at time.Time) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	enrollment, ok := r.enrollments[customerXID]
+	if !ok || (enrollment.LockedUntil != nil && enrollment.LockedUntil.After(at)) {
+		return 0, sql.ErrNoRows
+	}
+	enrollment.FailedAttempts++
+	r.enrollments[customerXID] = enrollment
+	return enrollment.FailedAttempts, nil
+}
+
+func (r *mockTOTPRepo) LockCodes(customerXID string, until time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	enrollment := r.enrollments[customerXID]
+	enrollment.LockedUntil = &until
+	r.enrollments[customerXID] = enrollment
+	return nil
+}
+
+func (r *mockTOTPRepo) ResetAttempts(customerXID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	enrollment := r.enrollments[customerXID]
+	enrollment.FailedAttempts, enrollment.LockedUntil = 0, nil
+	r.enrollments[customerXID] = enrollment
+	return nil
+}
+
+func (r *mockTOTPRepo) DeleteEnrollment(customerXID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.enrollments[customerXID]; !ok {
+		return sql.ErrNoRows
+	}
+	delete(r.enrollments, customerXID)
+	return nil
+}
+
+// mockStandingOrderRepo posts the transfers of runs into the mock wallet
+// and transaction repositories, as the real one does in a database
+// transaction.
+type mockStandingOrderRepo struct {
+	mu           sync.Mutex
+	orders       map[string]models.StandingOrder
+	wallets      *mockWalletRepo
+	transactions *mockTransactionRepo
+	// beforeRun, if set, runs as a run is posted, standing in for a
+	// concurrent request.
+	beforeRun func()
+}
+
+func (r *mockStandingOrderRepo) CreateStandingOrder(order *models.StandingOrder) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.orders[order.ID] = *order
+	return nil
+}
+
+func (r *mockStandingOrderRepo) GetStandingOrder(id, customerXID string) (*models.StandingOrder, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	order, ok := r.orders[id]
+	if !ok || order.CustomerXID != customerXID {
+		return nil, sql.ErrNoRows
+	}
+	return &order, nil
+}
+
+func (r *mockStandingOrderRepo) ListStandingOrders(customerXID string) ([]models.StandingOrder, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var orders []models.StandingOrder
+	for _, order := range r.orders {
+		if order.CustomerXID == customerXID {
+			orders = append(orders, order)
+		}
+	}
+	return orders, nil
+}
+
+func (r *mockStandingOrderRepo) UpdateStandingOrder(order *models.StandingOrder) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stored, ok := r.orders[order.ID]
+	if !ok || stored.Status != models.StandingOrderActive {
+		return sql.ErrNoRows
+	}
+	stored.Amount, stored.Description, stored.EndsAt, stored.UpdatedAt = order.Amount, order.Description, order.EndsAt, order.UpdatedAt
+	stored.LeaseID = ""
+	r.orders[order.ID] = stored
+	return nil
+}
+
+func (r *mockStandingOrderRepo) CancelStandingOrder(id, customerXID string, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stored, ok := r.orders[id]
+	if !ok || stored.CustomerXID != customerXID || stored.Status != models.StandingOrderActive {
+		return sql.ErrNoRows
+	}
+	stored.Status, stored.NextRunAt, stored.NextAttemptAt, stored.LeaseID, stored.UpdatedAt = models.StandingOrderCancelled, nil, nil, "", at
+	r.orders[id] = stored
+	return nil
+}
+
+// ClaimDueStandingOrders ignores the lease's duration: an order stays
+// claimed until it is saved or changed.
+func (r *mockStandingOrderRepo) ClaimDueStandingOrders(now time.Time, limit int, lease time.Duration) ([]models.StandingOrder, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var orders []models.StandingOrder
+	for id, order := range r.orders {
+		if len(orders) == limit {
+			break
+		}
+		if order.Status != models.StandingOrderActive || order.LeaseID != "" || order.NextAttemptAt == nil || order.NextAttemptAt.After(now) {
+			continue
+		}
+		order.LeaseID = uuid.New().String()
+		r.orders[id] = order
+		orders = append(orders, order)
+	}
+	return orders, nil
+}
+
+func (r *mockStandingOrderRepo) saveRun(order *models.StandingOrder) error {
+	if order.LeaseID == "" || r.orders[order.ID].LeaseID != order.LeaseID {
+		return sql.ErrNoRows
+	}
+	saved := *order
+	saved.LeaseID = ""
+	r.orders[order.ID] = saved
+	return nil
+}
+
+func (r *mockStandingOrderRepo) CompleteRun(order *models.StandingOrder, transfer *models.Transfer) error {
+	if r.beforeRun != nil {
+		r.beforeRun()
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if payer, _ := r.wallets.GetWalletByID(transfer.Out.WalletID); payer.Balance+transfer.Out.SignedAmount() < 0 {
+		return repositories.ErrInsufficientBalance
+	}
+	if payee, _ := r.wallets.GetWalletByID(transfer.In.WalletID); payee.Status == models.WalletClosed {
+		return repositories.ErrWalletClosed
+	}
+	if err := r.saveRun(order); err != nil {
+		return err
+	}
+	for _, leg := range []*models.Transaction{transfer.Out, transfer.In} {
+		r.transactions.CreateTransaction(leg)
+		wallet, _ := r.wallets.GetWalletByID(leg.WalletID)
+		r.wallets.UpdateWalletBalance(wallet.ID, wallet.Balance+leg.SignedAmount())
+	}
+	return nil
+}
+
+func (r *mockStandingOrderRepo) SaveAttempt(order *models.StandingOrder) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.saveRun(order)
+}
+
+func (r *mockStandingOrderRepo) order(id string) models.StandingOrder {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.orders[id]
+}
+
+// mockPaymentRequestRepo posts the transfers of paid requests into the mock
+// wallet and transaction repositories, as the real one does in a database
+// transaction.
+type mockPaymentRequestRepo struct {
+	mu           sync.Mutex
+	requests     map[string]models.PaymentRequest
+	wallets      *mockWalletRepo
+	transactions *mockTransactionRepo
+	// beforePay, if set, runs as a payment is posted, standing in for a
+	// concurrent request.
+	beforePay func()
+}
+
+func (r *mockPaymentRequestRepo) CreatePaymentRequest(request *models.PaymentRequest) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.requests[request.ID] = *request
+	return nil
+}
+
+func (r *mockPaymentRequestRepo) GetPaymentRequest(id string) (*models.PaymentRequest, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	request, ok := r.requests[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return &request, nil
+}
+
+func (r *mockPaymentRequestRepo) GetPaymentRequestByCode(code string) (*models.PaymentRequest, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, request := range r.requests {
+		if request.Code != "" && request.Code == code {
+			return &request, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (r *mockPaymentRequestRepo) ListPaymentRequests(filter models.PaymentRequestFilter) ([]models.PaymentRequest, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var requests []models.PaymentRequest
+	for _, request := range r.requests {
+		incoming, outgoing := request.PayerCustomerXID == filter.CustomerXID, request.CustomerXID == filter.CustomerXID
+		if filter.Direction == models.DirectionIncoming && !incoming || filter.Direction == models.DirectionOutgoing && !outgoing || !incoming && !outgoing {
+			continue
+		}
+		status := request
+		status.Expire(filter.Now)
+		if filter.Status != "" && status.Status != filter.Status {
+			continue
+		}
+		requests = append(requests, request)
+	}
+	return requests, nil
+}
+
+func (r *mockPaymentRequestRepo) respond(request *models.PaymentRequest) error {
+	stored, ok := r.requests[request.ID]
+	if !ok || stored.Status != models.PaymentRequestPending || !request.RespondedAt.Before(stored.ExpiresAt) {
+		return sql.ErrNoRows
+	}
+	r.requests[request.ID] = *request
+	return nil
+}
+
+func (r *mockPaymentRequestRepo) PayPaymentRequest(request *models.PaymentRequest, transfer *models.Transfer) error {
+	if r.beforePay != nil {
+		r.beforePay()
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if payer, _ := r.wallets.GetWalletByID(transfer.Out.WalletID); payer.Balance+transfer.Out.SignedAmount() < 0 {
+		return repositories.ErrInsufficientBalance
+	}
+	if payee, _ := r.wallets.GetWalletByID(transfer.In.WalletID); payee.Status == models.WalletClosed {
+		return repositories.ErrWalletClosed
+	}
+	if err := r.respond(request); err != nil {
+		return err
+	}
+	for _, leg := range []*models.Transaction{transfer.Out, transfer.In} {
+		r.transactions.CreateTransaction(leg)
+		wallet, _ := r.wallets.GetWalletByID(leg.WalletID)
+		r.wallets.UpdateWalletBalance(wallet.ID, wallet.Balance+leg.SignedAmount())
+	}
+	return nil
+}
+
+func (r *mockPaymentRequestRepo) RespondPaymentRequest(request *models.PaymentRequest) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.respond(request)
+}
+
+// expire moves a request's expiry into the past.
+func (r *mockPaymentRequestRepo) expire(id string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	request := r.requests[id]
+	request.ExpiresAt = time.Now().UTC().Add(-time.Second)
+	r.requests[id] = request
+}
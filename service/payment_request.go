@@ -0,0 +1,253 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"mini-wallet/config"
+	"mini-wallet/events"
+	"mini-wallet/models"
+	"mini-wallet/repositories"
+
+	"github.com/google/uuid"
+)
+
+// paymentRequestListLimit caps a customer's payment request listing.
+const paymentRequestListLimit = 100
+
+// requestCodeAlphabet leaves out characters easily confused with others.
+const requestCodeAlphabet = "ABCDEFGHJKMNPQRSTUVWXYZ23456789"
+
+// PaymentRequestService lets customers ask each other for money.
+type PaymentRequestService struct {
+	wallets     *WalletService
+	requestRepo repositories.PaymentRequestRepository
+	cfg         config.PaymentRequestsConfig
+}
+
+func NewPaymentRequestService(wallets *WalletService, requestRepo repositories.PaymentRequestRepository, cfg config.PaymentRequestsConfig) *PaymentRequestService {
+	return &PaymentRequestService{
+		wallets:     wallets,
+		requestRepo: requestRepo,
+		cfg:         cfg,
+	}
+}
+
+// Create asks for request.Amount to be paid into the customer's wallet by
+// request.PayerCustomerXID, who is told, or, when it is empty, by whoever
+// is given the request's shareable code. A zero expiry defaults to
+// DefaultExpiry from now.
+func (s *PaymentRequestService) Create(customerXID string, request *models.PaymentRequest) (*models.PaymentRequest, error) {
+	now := time.Now().UTC()
+	if request.Amount <= 0 {
+		return nil, ErrInvalidAmount
+	}
+	if request.ExpiresAt.IsZero() {
+		request.ExpiresAt = now.Add(s.cfg.DefaultExpiry)
+	}
+	request.ExpiresAt = request.ExpiresAt.UTC()
+	if !request.ExpiresAt.After(now) || request.ExpiresAt.After(now.Add(s.cfg.MaxExpiry)) {
+		return nil, ErrInvalidExpiry
+	}
+
+	wallet, err := s.wallets.Wallet(customerXID)
+	if err != nil {
+		return nil, err
+	}
+	if err := Allow(wallet.Status, OpDeposit); err != nil {
+		return nil, err
+	}
+	var payer *models.Wallet
+	if request.PayerCustomerXID != "" {
+		if request.PayerCustomerXID == customerXID {
+			return nil, ErrSelfRequest
+		}
+		payer, err = s.wallets.walletRepo.GetWalletByCustomerXID(request.PayerCustomerXID)
+		if err != nil || payer == nil {
+			return nil, ErrPayerNotFound
+		}
+		request.Code = ""
+	} else if request.Code, err = newRequestCode(); err != nil {
+		return nil, err
+	}
+
+	request.ID = uuid.New().String()
+	request.CustomerXID = customerXID
+	request.WalletID = wallet.ID
+	request.Status = models.PaymentRequestPending
+	request.TransactionID = ""
+	request.RespondedAt = nil
+	request.CreatedAt = now
+	if err := s.requestRepo.CreatePaymentRequest(request); err != nil {
+		return nil, err
+	}
+	if payer != nil {
+		s.wallets.publisher.Publish(context.Background(), events.New(events.PaymentRequestReceived, payer.OwnedBy, payer.ID, request))
+	}
+	return request, nil
+}
+
+// newRequestCode returns a random code such as "K7DM2XQF9P" to share a
+// payment request with.
+func newRequestCode() (string, error) {
+	b := make([]byte, 10)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	for i, c := range b {
+		// The bias of the modulo is negligible for this alphabet
+		b[i] = requestCodeAlphabet[int(c)%len(requestCodeAlphabet)]
+	}
+	return string(b), nil
+}
+
+// PaymentRequests returns the customer's latest requests matching filter,
+// newest first: incoming, outgoing or both.
+func (s *PaymentRequestService) PaymentRequests(customerXID string, filter models.PaymentRequestFilter) ([]models.PaymentRequest, error) {
+	filter.CustomerXID = customerXID
+	filter.Now = time.Now().UTC()
+	filter.Limit = paymentRequestListLimit
+	requests, err := s.requestRepo.ListPaymentRequests(filter)
+	if err != nil {
+		return nil, err
+	}
+	for i := range requests {
+		requests[i].Expire(filter.Now)
+	}
+	return requests, nil
+}
+
+// PaymentRequest returns a request by ID to its requester and payer, or by
+// its shareable code to anyone.
+func (s *PaymentRequestService) PaymentRequest(customerXID, idOrCode string) (*models.PaymentRequest, error) {
+	var request *models.PaymentRequest
+	var err error
+	if _, parseErr := uuid.Parse(idOrCode); parseErr == nil {
+		request, err = s.requestRepo.GetPaymentRequest(idOrCode)
+		if err == nil && request.CustomerXID != customerXID && request.PayerCustomerXID != customerXID {
+			err = sql.ErrNoRows
+		}
+	} else {
+		request, err = s.requestRepo.GetPaymentRequestByCode(strings.ToUpper(idOrCode))
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrPaymentRequestNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	request.Expire(time.Now().UTC())
+	return request, nil
+}
+
+// payable finds a pending request the customer may answer as its payer.
+func (s *PaymentRequestService) payable(customerXID, idOrCode string) (*models.PaymentRequest, error) {
+	request, err := s.PaymentRequest(customerXID, idOrCode)
+	if err != nil {
+		return nil, err
+	}
+	if request.CustomerXID == customerXID {
+		return nil, ErrOwnPaymentRequest
+	}
+	if request.Status != models.PaymentRequestPending {
+		return nil, ErrPaymentRequestNotPending
+	}
+	return request, nil
+}
+
+// Accept pays the request from the customer's wallet into the requester's.
+// auth authorizes the payment as it would a withdrawal of its amount. It
+// is checked first, but its step-up token is spent only once the transfer
+// checks pass, so a payment that cannot go through leaves it for another.
+func (s *PaymentRequestService) Accept(customerXID, idOrCode string, auth Authorization) (*models.PaymentRequest, error) {
+	request, err := s.payable(customerXID, idOrCode)
+	if err != nil {
+		return nil, err
+	}
+
+	// Nothing about the balance or the requester's wallet is told before
+	// the customer is authorized
+	if err := s.wallets.checkWithdrawal(customerXID, request.Amount, auth); err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	// The request's ID is the transfer's reference, so it is paid once at most
+	transfer, err := s.wallets.prepareTransfer(customerXID, request.CustomerXID, request.Amount, uuid.MustParse(request.ID), now)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.wallets.spendStepUpToken(customerXID, auth); err != nil {
+		return nil, err
+	}
+	request.Status = models.PaymentRequestPaid
+	request.PayerCustomerXID = customerXID
+	request.TransactionID = transfer.Out.ID
+	request.RespondedAt = &now
+	if err := s.requestRepo.PayPaymentRequest(request, transfer); err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrPaymentRequestNotPending
+		case errors.Is(err, repositories.ErrInsufficientBalance):
+			return nil, ErrInsufficientBalance
+		case errors.Is(err, repositories.ErrWalletClosed):
+			return nil, ErrPayeeUnavailable
+		}
+		return nil, err
+	}
+	s.wallets.transferred(transfer, customerXID, request.CustomerXID)
+	s.wallets.publisher.Publish(context.Background(), events.New(events.PaymentRequestPaid, request.CustomerXID, request.WalletID, request))
+	return request, nil
+}
+
+// Decline refuses a request addressed to the customer. Requests shared by
+// code cannot be declined, only left to expire.
+func (s *PaymentRequestService) Decline(customerXID, idOrCode string) (*models.PaymentRequest, error) {
+	request, err := s.payable(customerXID, idOrCode)
+	if err != nil {
+		return nil, err
+	}
+	if request.PayerCustomerXID != customerXID {
+		return nil, ErrDeclineNotAllowed
+	}
+	if err := s.respond(request, models.PaymentRequestDeclined); err != nil {
+		return nil, err
+	}
+	s.wallets.publisher.Publish(context.Background(), events.New(events.PaymentRequestDeclined, request.CustomerXID, request.WalletID, request))
+	return request, nil
+}
+
+// Cancel withdraws one of the customer's pending requests.
+func (s *PaymentRequestService) Cancel(customerXID, id string) (*models.PaymentRequest, error) {
+	request, err := s.PaymentRequest(customerXID, id)
+	if err != nil {
+		return nil, err
+	}
+	if request.CustomerXID != customerXID {
+		return nil, ErrPaymentRequestNotFound
+	}
+	if request.Status != models.PaymentRequestPending {
+		return nil, ErrPaymentRequestNotPending
+	}
+	if err := s.respond(request, models.PaymentRequestCancelled); err != nil {
+		return nil, err
+	}
+	return request, nil
+}
+
+// respond gives a pending request its final status.
+func (s *PaymentRequestService) respond(request *models.PaymentRequest, status string) error {
+	now := time.Now().UTC()
+	request.Status = status
+	request.RespondedAt = &now
+	if err := s.requestRepo.RespondPaymentRequest(request); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrPaymentRequestNotPending
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,252 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"mini-wallet/config"
+	"mini-wallet/events"
+	"mini-wallet/models"
+)
+
+const stranger = "0f6a1d8e-3c2b-4f7a-9e5d-7b8c9a0d1e2f"
+
+// newPaymentRequestFixture gives the payee a wallet holding balance to pay
+// the customer's requests with, and a stranger an empty one.
+func newPaymentRequestFixture(balance int64) (*fixture, *PaymentRequestService, *mockPaymentRequestRepo) {
+	f := newFixture(
+		enabledWallet(0),
+		models.Wallet{ID: "wallet-2", OwnedBy: payee, Status: "enabled", Balance: balance},
+		models.Wallet{ID: "wallet-3", OwnedBy: stranger, Status: "enabled", Balance: balance},
+	)
+	f.transactions.CreateTransaction(&models.Transaction{ID: "deposit-2", WalletID: "wallet-2", Type: "deposit", Amount: balance})
+	f.transactions.CreateTransaction(&models.Transaction{ID: "deposit-3", WalletID: "wallet-3", Type: "deposit", Amount: balance})
+	repo := &mockPaymentRequestRepo{requests: make(map[string]models.PaymentRequest), wallets: f.wallets, transactions: f.transactions}
+	return f, NewPaymentRequestService(f.service, repo, config.Default().PaymentRequests), repo
+}
+
+func TestAcceptPaymentRequestTransfers(t *testing.T) {
+	f, requests, _ := newPaymentRequestFixture(500)
+	request, err := requests.Create(customer, &models.PaymentRequest{PayerCustomerXID: payee, Amount: 200, Description: "dinner"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if request.Code != "" || request.Status != models.PaymentRequestPending {
+		t.Fatalf("request = %+v, want a pending request without a code", request)
+	}
+
+	paid, err := requests.Accept(payee, request.ID, Authorization{})
+	if err != nil || paid.Status != models.PaymentRequestPaid || paid.TransactionID == "" {
+		t.Fatalf("Accept = %+v, %v, want the request paid", paid, err)
+	}
+	eventually(t, func() bool {
+		return f.wallets.wallet(customer).Balance == 200 && f.wallets.wallet(payee).Balance == 300
+	})
+	want := []string{events.PaymentRequestReceived, events.TransferSent, events.TransferReceived, events.PaymentRequestPaid}
+	if got := f.events.types(); len(got) != len(want) || got[0] != want[0] || got[3] != want[3] {
+		t.Errorf("published %v, want %v", got, want)
+	}
+
+	if _, err := requests.Accept(payee, request.ID, Authorization{}); !errors.Is(err, ErrPaymentRequestNotPending) {
+		t.Errorf("second Accept error = %v, want ErrPaymentRequestNotPending", err)
+	}
+	if got := f.wallets.wallet(payee).Balance; got != 300 {
+		t.Errorf("payer balance = %d, want it paid once", got)
+	}
+}
+
+func TestAcceptPaymentRequestKeepsStepUpToken(t *testing.T) {
+	f, _, repo := newPaymentRequestFixture(100)
+	pins := newMockPINRepo()
+	f.service = NewWalletService(f.wallets, f.transactions, f.snapshots, f.tokens, nil, f.events, nil, nil, nil, pins, nil, f.service.cfg)
+	requests := NewPaymentRequestService(f.service, repo, config.Default().PaymentRequests)
+	if err := f.service.SetPIN(payee, testPIN, ""); err != nil {
+		t.Fatal(err)
+	}
+	token, _, err := f.service.StepUp(payee, testPIN)
+	if err != nil {
+		t.Fatal(err)
+	}
+	large, err := requests.Create(customer, &models.PaymentRequest{PayerCustomerXID: payee, Amount: 200})
+	if err != nil {
+		t.Fatal(err)
+	}
+	small, err := requests.Create(customer, &models.PaymentRequest{PayerCustomerXID: payee, Amount: 50})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The customer is authorized before the balance is told
+	if _, err := requests.Accept(payee, large.ID, Authorization{}); !errors.Is(err, ErrPINRequired) {
+		t.Fatalf("unauthorized Accept error = %v, want ErrPINRequired", err)
+	}
+	// The balance is checked before the token is used up
+	if _, err := requests.Accept(payee, large.ID, Authorization{StepUpToken: token}); !errors.Is(err, ErrInsufficientBalance) {
+		t.Fatalf("Accept over the balance error = %v, want ErrInsufficientBalance", err)
+	}
+	if _, err := requests.Accept(payee, small.ID, Authorization{StepUpToken: token}); err != nil {
+		t.Errorf("Accept with the token error = %v, want it paid", err)
+	}
+}
+
+func TestAcceptPaymentRequestRacingWithdrawal(t *testing.T) {
+	f, requests, repo := newPaymentRequestFixture(500)
+	request, err := requests.Create(customer, &models.PaymentRequest{PayerCustomerXID: payee, Amount: 200})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A withdrawal spends the balance after the payment checked it
+	repo.beforePay = func() { f.wallets.UpdateWalletBalance("wallet-2", 100) }
+
+	if _, err := requests.Accept(payee, request.ID, Authorization{}); !errors.Is(err, ErrInsufficientBalance) {
+		t.Fatalf("Accept error = %v, want ErrInsufficientBalance", err)
+	}
+	if got := f.wallets.wallet(payee).Balance; got != 100 {
+		t.Errorf("payer balance = %d, want 100", got)
+	}
+	if got := f.wallets.wallet(customer).Balance; got != 0 {
+		t.Errorf("requester balance = %d, want nothing transferred", got)
+	}
+	if stored, _ := requests.PaymentRequest(customer, request.ID); stored.Status != models.PaymentRequestPending {
+		t.Errorf("request = %+v, want it still pending", stored)
+	}
+}
+
+func TestAcceptPaymentRequestToClosedWallet(t *testing.T) {
+	f, requests, repo := newPaymentRequestFixture(500)
+	request, err := requests.Create(customer, &models.PaymentRequest{PayerCustomerXID: payee, Amount: 200})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The requester closes their wallet after the payment checked it
+	repo.beforePay = func() {
+		wallet := f.wallets.wallet(customer)
+		wallet.Status = models.WalletClosed
+		f.wallets.UpdateWalletStatus(&wallet, &models.WalletStatusChange{FromStatus: models.WalletEnabled})
+	}
+
+	if _, err := requests.Accept(payee, request.ID, Authorization{}); !errors.Is(err, ErrPayeeUnavailable) {
+		t.Fatalf("Accept error = %v, want ErrPayeeUnavailable", err)
+	}
+	if got := f.wallets.wallet(payee).Balance; got != 500 {
+		t.Errorf("payer balance = %d, want nothing transferred", got)
+	}
+}
+
+func TestPaymentRequestByCode(t *testing.T) {
+	f, requests, _ := newPaymentRequestFixture(500)
+	request, err := requests.Create(customer, &models.PaymentRequest{Amount: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(request.Code) != 10 {
+		t.Fatalf("code = %q, want a shareable code", request.Code)
+	}
+	if got := f.events.types(); len(got) != 0 {
+		t.Errorf("published %v, want nothing for a request nobody is addressed", got)
+	}
+
+	if _, err := requests.Decline(stranger, request.Code); !errors.Is(err, ErrDeclineNotAllowed) {
+		t.Errorf("Decline by code error = %v, want ErrDeclineNotAllowed", err)
+	}
+	if _, err := requests.Accept(customer, request.Code, Authorization{}); !errors.Is(err, ErrOwnPaymentRequest) {
+		t.Errorf("Accept by the requester error = %v, want ErrOwnPaymentRequest", err)
+	}
+	paid, err := requests.Accept(stranger, request.Code, Authorization{})
+	if err != nil || paid.PayerCustomerXID != stranger {
+		t.Fatalf("Accept = %+v, %v, want the request paid by the code holder", paid, err)
+	}
+	eventually(t, func() bool {
+		return f.wallets.wallet(customer).Balance == 100 && f.wallets.wallet(stranger).Balance == 400
+	})
+
+	incoming, err := requests.PaymentRequests(stranger, models.PaymentRequestFilter{Direction: models.DirectionIncoming})
+	if err != nil || len(incoming) != 1 || incoming[0].Status != models.PaymentRequestPaid {
+		t.Errorf("incoming = %+v, %v, want the paid request", incoming, err)
+	}
+}
+
+func TestDeclinePaymentRequest(t *testing.T) {
+	f, requests, _ := newPaymentRequestFixture(500)
+	request, err := requests.Create(customer, &models.PaymentRequest{PayerCustomerXID: payee, Amount: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := requests.PaymentRequest(stranger, request.ID); !errors.Is(err, ErrPaymentRequestNotFound) {
+		t.Errorf("PaymentRequest of a stranger error = %v, want ErrPaymentRequestNotFound", err)
+	}
+	declined, err := requests.Decline(payee, request.ID)
+	if err != nil || declined.Status != models.PaymentRequestDeclined {
+		t.Fatalf("Decline = %+v, %v, want the request declined", declined, err)
+	}
+	if got := f.events.types(); len(got) != 2 || got[1] != events.PaymentRequestDeclined {
+		t.Errorf("published %v, want the request received then declined", got)
+	}
+	if _, err := requests.Accept(payee, request.ID, Authorization{}); !errors.Is(err, ErrPaymentRequestNotPending) {
+		t.Errorf("Accept after Decline error = %v, want ErrPaymentRequestNotPending", err)
+	}
+}
+
+func TestExpiredPaymentRequestCannotBePaid(t *testing.T) {
+	f, requests, repo := newPaymentRequestFixture(500)
+	request, err := requests.Create(customer, &models.PaymentRequest{PayerCustomerXID: payee, Amount: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo.expire(request.ID)
+
+	if _, err := requests.Accept(payee, request.ID, Authorization{}); !errors.Is(err, ErrPaymentRequestNotPending) {
+		t.Errorf("Accept error = %v, want ErrPaymentRequestNotPending", err)
+	}
+	outgoing, err := requests.PaymentRequests(customer, models.PaymentRequestFilter{Status: models.PaymentRequestExpired})
+	if err != nil || len(outgoing) != 1 || outgoing[0].Status != models.PaymentRequestExpired {
+		t.Errorf("expired = %+v, %v, want the request", outgoing, err)
+	}
+	if got := f.wallets.wallet(payee).Balance; got != 500 {
+		t.Errorf("payer balance = %d, want nothing paid", got)
+	}
+}
+
+func TestCancelPaymentRequest(t *testing.T) {
+	_, requests, _ := newPaymentRequestFixture(500)
+	request, err := requests.Create(customer, &models.PaymentRequest{PayerCustomerXID: payee, Amount: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := requests.Cancel(payee, request.ID); !errors.Is(err, ErrPaymentRequestNotFound) {
+		t.Errorf("Cancel by the payer error = %v, want ErrPaymentRequestNotFound", err)
+	}
+	cancelled, err := requests.Cancel(customer, request.ID)
+	if err != nil || cancelled.Status != models.PaymentRequestCancelled {
+		t.Fatalf("Cancel = %+v, %v, want the request cancelled", cancelled, err)
+	}
+	if _, err := requests.Accept(payee, request.ID, Authorization{}); !errors.Is(err, ErrPaymentRequestNotPending) {
+		t.Errorf("Accept after Cancel error = %v, want ErrPaymentRequestNotPending", err)
+	}
+}
+
+func TestCreatePaymentRequestRules(t *testing.T) {
+	now := time.Now().UTC()
+	tests := []struct {
+		name    string
+		request models.PaymentRequest
+		wantErr error
+	}{
+		{"asks a customer", models.PaymentRequest{PayerCustomerXID: payee, Amount: 100}, nil},
+		{"refuses the customer as payer", models.PaymentRequest{PayerCustomerXID: customer, Amount: 100}, ErrSelfRequest},
+		{"refuses a payer without a wallet", models.PaymentRequest{PayerCustomerXID: "nobody", Amount: 100}, ErrPayerNotFound},
+		{"refuses an expiry in the past", models.PaymentRequest{Amount: 100, ExpiresAt: now.Add(-time.Hour)}, ErrInvalidExpiry},
+		{"refuses an expiry past the maximum", models.PaymentRequest{Amount: 100, ExpiresAt: now.AddDate(1, 0, 0)}, ErrInvalidExpiry},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, requests, _ := newPaymentRequestFixture(0)
+			if _, err := requests.Create(customer, &tt.request); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Create error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
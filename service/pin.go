@@ -0,0 +1,185 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"mini-wallet/admin"
+	"mini-wallet/models"
+	"mini-wallet/pin"
+)
+
+// Authorization is what the customer presented to authorize a sensitive
+// operation: their transaction PIN or a step-up token obtained with it,
+// and a one-time code or recovery code when they enrolled for TOTP.
+type Authorization struct {
+	PIN         string
+	StepUpToken string
+	TOTPCode    string
+}
+
+// authorize checks the customer's authorization of a withdrawal, unless
+// PINs are not enforced. A step-up token is checked but left for
+// spendStepUpToken to use up. Customers without a PIN need none unless the
+// configuration requires one.
+func (s *WalletService) authorize(customerXID string, auth Authorization) error {
+	if s.pinRepo == nil {
+		return nil
+	}
+	now := time.Now().UTC()
+	if auth.StepUpToken != "" {
+		err := s.pinRepo.CheckStepUpToken(pin.HashToken(auth.StepUpToken), customerXID, now)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrInvalidStepUpToken
+		}
+		return err
+	}
+	if _, err := s.pinRepo.GetPIN(customerXID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			if s.cfg.PIN.Required {
+				return ErrPINNotSet
+			}
+			return nil
+		}
+		return err
+	}
+	if auth.PIN == "" {
+		return ErrPINRequired
+	}
+	return s.checkPIN(customerXID, auth.PIN, now)
+}
+
+// spendStepUpToken uses up the step-up token auth carries, if any, once
+// the operation it authorized goes ahead. It fails when the token was used
+// meanwhile.
+func (s *WalletService) spendStepUpToken(customerXID string, auth Authorization) error {
+	if s.pinRepo == nil || auth.StepUpToken == "" {
+		return nil
+	}
+	err := s.pinRepo.ConsumeStepUpToken(pin.HashToken(auth.StepUpToken), customerXID, time.Now().UTC())
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrInvalidStepUpToken
+	}
+	return err
+}
+
+// checkPIN checks p against the customer's PIN. The attempt is counted and
+// any lockout it earns applied before the PIN is checked, so concurrent
+// guesses cannot slip past the lock; a right PIN then clears both.
+func (s *WalletService) checkPIN(customerXID, p string, now time.Time) error {
+	current, err := s.pinRepo.ClaimAttempt(customerXID, now)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrPINLocked
+	}
+	if err != nil {
+		return err
+	}
+	lockout := pin.Lockout(current.FailedAttempts, s.cfg.PIN.MaxAttempts, s.cfg.PIN.LockoutBase, s.cfg.PIN.LockoutMax)
+	if lockout > 0 {
+		if err := s.pinRepo.LockPIN(customerXID, now.Add(lockout)); err != nil {
+			return err
+		}
+	}
+	if !pin.Verify(current.Hash, p) {
+		if lockout > 0 {
+			return ErrPINLocked
+		}
+		return ErrInvalidPIN
+	}
+	return s.pinRepo.ResetAttempts(customerXID)
+}
+
+// PIN returns the customer's transaction PIN, or nil when they have not
+// set one.
+func (s *WalletService) PIN(customerXID string) (*models.TransactionPIN, error) {
+	current, err := s.pinRepo.GetPIN(customerXID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return current, err
+}
+
+// SetPIN sets the customer's transaction PIN to newPIN. Changing a PIN
+// takes the current one, which counts as an attempt at it.
+func (s *WalletService) SetPIN(customerXID, newPIN, currentPIN string) error {
+	if !pin.Valid(newPIN) {
+		return ErrInvalidPINFormat
+	}
+	now := time.Now().UTC()
+	_, err := s.pinRepo.GetPIN(customerXID)
+	switch {
+	case err == nil:
+		if currentPIN == "" {
+			return ErrPINRequired
+		}
+		if err := s.checkPIN(customerXID, currentPIN, now); err != nil {
+			return err
+		}
+	case !errors.Is(err, sql.ErrNoRows):
+		return err
+	}
+
+	hash, err := pin.Hash(newPIN)
+	if err != nil {
+		return err
+	}
+	return s.pinRepo.SetPIN(&models.TransactionPIN{CustomerXID: customerXID, Hash: hash, UpdatedAt: now})
+}
+
+// StepUp checks the customer's PIN and returns a single-use token that
+// authorizes one withdrawal in its place until it expires.
+func (s *WalletService) StepUp(customerXID, p string) (string, time.Time, error) {
+	if _, err := s.pinRepo.GetPIN(customerXID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", time.Time{}, ErrPINNotSet
+		}
+		return "", time.Time{}, err
+	}
+	now := time.Now().UTC()
+	if err := s.checkPIN(customerXID, p, now); err != nil {
+		return "", time.Time{}, err
+	}
+
+	token, err := pin.NewToken()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	stepUp := &models.StepUpToken{
+		TokenHash:   pin.HashToken(token),
+		CustomerXID: customerXID,
+		ExpiresAt:   now.Add(s.cfg.PIN.StepUpTTL),
+		CreatedAt:   now,
+	}
+	if err := s.pinRepo.CreateStepUpToken(stepUp); err != nil {
+		return "", time.Time{}, err
+	}
+	return token, stepUp.ExpiresAt, nil
+}
+
+// ResetPIN removes a customer's transaction PIN and step-up tokens, for
+// customers who forgot their PIN or got it locked out. The customer sets a
+// new one without presenting the old.
+func (s *AdminService) ResetPIN(actor *models.AdminKey, customerXID, reason string) error {
+	if err := authorize(actor, admin.PermPINReset); err != nil {
+		return err
+	}
+	if strings.TrimSpace(reason) == "" {
+		return ErrReasonRequired
+	}
+	exists, err := s.customerTokenRepo.CustomerExists(customerXID)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return ErrCustomerNotFound
+	}
+	if err := s.wallets.pinRepo.DeletePIN(customerXID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrPINNotSet
+		}
+		return err
+	}
+	return s.record(actor, AuditPINReset, auditTargetCustomer, customerXID, reason, nil)
+}
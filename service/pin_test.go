@@ -0,0 +1,280 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"mini-wallet/admin"
+	"mini-wallet/models"
+	"mini-wallet/pin"
+	"mini-wallet/risk"
+)
+
+const testPIN = "294751"
+
+func newPINFixture(wallets ...models.Wallet) (*fixture, *AdminService, *mockPINRepo, *mockAuditRepo) {
+	f := newFixture(wallets...)
+	f.tokens.tokens[customer] = "token-1"
+	// Settlement recomputes the balance from the transactions
+	f.transactions.CreateTransaction(&models.Transaction{ID: "deposit-0", WalletID: "wallet-1", Type: "deposit", Amount: 100})
+	cfg := f.service.cfg
+	cfg.PIN.MaxAttempts = 3
+	cfg.PIN.LockoutBase = time.Minute
+	cfg.PIN.LockoutMax = time.Hour
+	pins := newMockPINRepo()
+	f.service = NewWalletService(f.wallets, f.transactions, f.snapshots, f.tokens, nil, f.events, nil, nil, nil, pins, nil, cfg)
+	audit := &mockAuditRepo{}
+	return f, NewAdminService(f.service, f.wallets, f.transactions, f.tokens, nil, audit, nil, nil, nil), pins, audit
+}
+
+func TestWithdrawalNeedsPIN(t *testing.T) {
+	f, _, _, _ := newPINFixture(enabledWallet(100))
+	f.service.cfg.PIN.Required = false
+
+	if _, err := f.service.Withdraw(customer, 10, "withdrawal-1", Authorization{}); err != nil {
+		t.Errorf("withdrawal without a PIN set error = %v, want it allowed", err)
+	}
+	if err := f.service.SetPIN(customer, testPIN, ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.service.Withdraw(customer, 10, "withdrawal-2", Authorization{}); !errors.Is(err, ErrPINRequired) {
+		t.Errorf("withdrawal without the PIN error = %v, want ErrPINRequired", err)
+	}
+	if _, err := f.service.Withdraw(customer, 10, "withdrawal-2", Authorization{PIN: "294752"}); !errors.Is(err, ErrInvalidPIN) {
+		t.Errorf("withdrawal with a wrong PIN error = %v, want ErrInvalidPIN", err)
+	}
+	if _, err := f.service.Withdraw(customer, 10, "withdrawal-2", Authorization{PIN: testPIN}); err != nil {
+		t.Errorf("withdrawal with the PIN error = %v, want it allowed", err)
+	}
+	if _, err := f.service.Deposit(customer, 10, "deposit-1"); err != nil {
+		t.Errorf("deposit error = %v, deposits need no PIN", err)
+	}
+}
+
+func TestPINRequiredByDefault(t *testing.T) {
+	f, _, _, _ := newPINFixture(enabledWallet(100))
+
+	if _, err := f.service.Withdraw(customer, 10, reference, Authorization{}); !errors.Is(err, ErrPINNotSet) {
+		t.Errorf("withdrawal without a PIN set error = %v, want ErrPINNotSet", err)
+	}
+	if err := f.service.SetPIN(customer, testPIN, ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.service.Withdraw(customer, 10, reference, Authorization{PIN: testPIN}); err != nil {
+		t.Errorf("withdrawal with the PIN error = %v, want it allowed", err)
+	}
+}
+
+func TestPINLockout(t *testing.T) {
+	f, _, pins, _ := newPINFixture(enabledWallet(100))
+	if err := f.service.SetPIN(customer, testPIN, ""); err != nil {
+		t.Fatal(err)
+	}
+	wrong := Authorization{PIN: "294752"}
+
+	for i := 1; i < 3; i++ {
+		if _, err := f.service.Withdraw(customer, 10, reference, wrong); !errors.Is(err, ErrInvalidPIN) {
+			t.Fatalf("wrong PIN %d error = %v, want ErrInvalidPIN", i, err)
+		}
+	}
+	if _, err := f.service.Withdraw(customer, 10, reference, wrong); !errors.Is(err, ErrPINLocked) {
+		t.Fatalf("last wrong PIN error = %v, want ErrPINLocked", err)
+	}
+	locked := pins.pins[customer].LockedUntil
+	if locked == nil || time.Until(*locked) <= 0 || time.Until(*locked) > time.Minute {
+		t.Fatalf("locked until %v, want a minute from now", locked)
+	}
+	if _, err := f.service.Withdraw(customer, 10, reference, Authorization{PIN: testPIN}); !errors.Is(err, ErrPINLocked) {
+		t.Errorf("right PIN while locked error = %v, want ErrPINLocked", err)
+	}
+
+	// Each wrong PIN after the lock ends doubles it
+	past := time.Now().Add(-time.Second)
+	pins.LockPIN(customer, past)
+	if _, err := f.service.Withdraw(customer, 10, reference, wrong); !errors.Is(err, ErrPINLocked) {
+		t.Fatalf("wrong PIN after the lock error = %v, want ErrPINLocked", err)
+	}
+	if locked := pins.pins[customer].LockedUntil; time.Until(*locked) <= time.Minute {
+		t.Errorf("locked until %v, want two minutes from now", locked)
+	}
+
+	pins.LockPIN(customer, past)
+	if _, err := f.service.Withdraw(customer, 10, reference, Authorization{PIN: testPIN}); err != nil {
+		t.Fatalf("right PIN after the lock error = %v, want it allowed", err)
+	}
+	if pin := pins.pins[customer]; pin.FailedAttempts != 0 || pin.LockedUntil != nil {
+		t.Errorf("PIN = %+v, want the attempts and lock cleared", pin)
+	}
+}
+
+func TestStepUpToken(t *testing.T) {
+	f, _, pins, _ := newPINFixture(enabledWallet(100))
+
+	if _, _, err := f.service.StepUp(customer, testPIN); !errors.Is(err, ErrPINNotSet) {
+		t.Errorf("StepUp without a PIN error = %v, want ErrPINNotSet", err)
+	}
+	if err := f.service.SetPIN(customer, testPIN, ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := f.service.StepUp(customer, "294752"); !errors.Is(err, ErrInvalidPIN) {
+		t.Errorf("StepUp with a wrong PIN error = %v, want ErrInvalidPIN", err)
+	}
+	token, expiresAt, err := f.service.StepUp(customer, testPIN)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ttl := time.Until(expiresAt); ttl <= 0 || ttl > f.service.cfg.PIN.StepUpTTL {
+		t.Errorf("token expires in %v, want within %v", ttl, f.service.cfg.PIN.StepUpTTL)
+	}
+	for hash := range pins.tokens {
+		if hash == token {
+			t.Error("the token is stored as is, want only its hash")
+		}
+	}
+
+	if _, err := f.service.Withdraw(customer, 10, "withdrawal-1", Authorization{StepUpToken: token}); err != nil {
+		t.Fatalf("withdrawal with the token error = %v, want it allowed", err)
+	}
+	if _, err := f.service.Withdraw(customer, 10, "withdrawal-2", Authorization{StepUpToken: token}); !errors.Is(err, ErrInvalidStepUpToken) {
+		t.Errorf("reused token error = %v, want ErrInvalidStepUpToken", err)
+	}
+
+	expired, _, err := f.service.StepUp(customer, testPIN)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for hash, stored := range pins.tokens {
+		stored.ExpiresAt = time.Now().Add(-time.Second)
+		pins.tokens[hash] = stored
+	}
+	if _, err := f.service.Withdraw(customer, 10, "withdrawal-2", Authorization{StepUpToken: expired}); !errors.Is(err, ErrInvalidStepUpToken) {
+		t.Errorf("expired token error = %v, want ErrInvalidStepUpToken", err)
+	}
+}
+
+func TestWithdrawalAuthorizedBeforeOtherChecks(t *testing.T) {
+	f, _, _, _ := newPINFixture(enabledWallet(100))
+	if err := f.service.SetPIN(customer, testPIN, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	// The balance is not revealed to callers without the PIN
+	if _, err := f.service.Withdraw(customer, 500, "withdrawal-1", Authorization{}); !errors.Is(err, ErrPINRequired) {
+		t.Errorf("withdrawal over the balance without the PIN error = %v, want ErrPINRequired", err)
+	}
+
+	token, _, err := f.service.StepUp(customer, testPIN)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.service.Withdraw(customer, 500, "withdrawal-1", Authorization{StepUpToken: token}); !errors.Is(err, ErrInsufficientBalance) {
+		t.Errorf("withdrawal over the balance error = %v, want ErrInsufficientBalance", err)
+	}
+	if _, err := f.service.Withdraw(customer, 10, "withdrawal-1", Authorization{StepUpToken: token}); err != nil {
+		t.Errorf("withdrawal with the token kept by a failed one error = %v, want it allowed", err)
+	}
+}
+
+func TestBlockedWithdrawalKeepsStepUpToken(t *testing.T) {
+	engine, err := risk.Parse([]byte(testRules))
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, _, pins, _ := newPINFixture(enabledWallet(100))
+	f.tokens.issuedAt = map[string]time.Time{customer: time.Now().UTC()}
+	decisions := &mockRiskRepo{decisions: make(map[string]models.RiskDecision), transactions: f.transactions}
+	f.service = NewWalletService(f.wallets, f.transactions, f.snapshots, f.tokens, nil, f.events, engine, decisions, nil, pins, nil, f.service.cfg)
+	if err := f.service.SetPIN(customer, testPIN, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := f.service.Withdraw(customer, 10, "withdrawal-1", Authorization{PIN: testPIN}); err != nil {
+		t.Fatal(err)
+	}
+	token, _, err := f.service.StepUp(customer, testPIN)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.service.Withdraw(customer, 10, "withdrawal-2", Authorization{StepUpToken: token}); !errors.Is(err, ErrTransactionBlocked) {
+		t.Fatalf("second withdrawal within the hour error = %v, want ErrTransactionBlocked", err)
+	}
+	if err := pins.CheckStepUpToken(pin.HashToken(token), customer, time.Now()); err != nil {
+		t.Errorf("step-up token after a blocked withdrawal: %v, want it unused", err)
+	}
+}
+
+func TestSetPIN(t *testing.T) {
+	f, _, pins, _ := newPINFixture(enabledWallet(100))
+
+	for _, weak := range []string{"12345", "abcdef", "111111", "123456"} {
+		if err := f.service.SetPIN(customer, weak, ""); !errors.Is(err, ErrInvalidPINFormat) {
+			t.Errorf("SetPIN(%q) error = %v, want ErrInvalidPINFormat", weak, err)
+		}
+	}
+	if err := f.service.SetPIN(customer, testPIN, ""); err != nil {
+		t.Fatal(err)
+	}
+	if pins.pins[customer].Hash == testPIN {
+		t.Error("the PIN is stored as is, want only its hash")
+	}
+	if err := f.service.SetPIN(customer, "803316", ""); !errors.Is(err, ErrPINRequired) {
+		t.Errorf("change without the current PIN error = %v, want ErrPINRequired", err)
+	}
+	if err := f.service.SetPIN(customer, "803316", "294752"); !errors.Is(err, ErrInvalidPIN) {
+		t.Errorf("change with a wrong current PIN error = %v, want ErrInvalidPIN", err)
+	}
+	if err := f.service.SetPIN(customer, "803316", testPIN); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.service.Withdraw(customer, 10, reference, Authorization{PIN: testPIN}); !errors.Is(err, ErrInvalidPIN) {
+		t.Errorf("withdrawal with the old PIN error = %v, want ErrInvalidPIN", err)
+	}
+	if _, err := f.service.Withdraw(customer, 10, reference, Authorization{PIN: "803316"}); err != nil {
+		t.Errorf("withdrawal with the new PIN error = %v, want it allowed", err)
+	}
+}
+
+func TestResetPIN(t *testing.T) {
+	f, admins, _, audit := newPINFixture(enabledWallet(100))
+	if err := f.service.SetPIN(customer, testPIN, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := admins.ResetPIN(actor(admin.RoleViewer), customer, "forgotten"); !errors.Is(err, ErrForbidden) {
+		t.Errorf("viewer ResetPIN error = %v, want ErrForbidden", err)
+	}
+	if err := admins.ResetPIN(actor(admin.RoleSupport), customer, " "); !errors.Is(err, ErrReasonRequired) {
+		t.Errorf("ResetPIN without a reason error = %v, want ErrReasonRequired", err)
+	}
+	if err := admins.ResetPIN(actor(admin.RoleSupport), "unknown", "forgotten"); !errors.Is(err, ErrCustomerNotFound) {
+		t.Errorf("ResetPIN of an unknown customer error = %v, want ErrCustomerNotFound", err)
+	}
+	if err := admins.ResetPIN(actor(admin.RoleSupport), customer, "forgotten"); err != nil {
+		t.Fatal(err)
+	}
+	if got := audit.actions(); len(got) != 1 || got[0] != AuditPINReset {
+		t.Errorf("audited %v, want the reset", got)
+	}
+	if err := admins.ResetPIN(actor(admin.RoleSupport), customer, "forgotten"); !errors.Is(err, ErrPINNotSet) {
+		t.Errorf("second ResetPIN error = %v, want ErrPINNotSet", err)
+	}
+	if err := f.service.SetPIN(customer, "803316", ""); err != nil {
+		t.Errorf("SetPIN after the reset error = %v, want no current PIN needed", err)
+	}
+}
+
+func TestClosurePayoutNeedsPIN(t *testing.T) {
+	f, _, _, _ := newPINFixture(enabledWallet(100))
+	closures := NewClosureService(f.service, &mockClosureRepo{closures: make(map[string]models.WalletClosure), wallets: f.wallets, transactions: f.transactions, tokens: f.tokens}, retention)
+	if err := f.service.SetPIN(customer, testPIN, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err := closures.Close(customer, "BCA 1234567890", Authorization{}); !errors.Is(err, ErrPINRequired) {
+		t.Errorf("Close without the PIN error = %v, want ErrPINRequired", err)
+	}
+	if _, _, err := closures.Close(customer, "BCA 1234567890", Authorization{PIN: testPIN}); err != nil {
+		t.Errorf("Close with the PIN error = %v, want it closed", err)
+	}
+}
@@ -0,0 +1,227 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"mini-wallet/admin"
+	"mini-wallet/models"
+	"mini-wallet/repositories"
+	"mini-wallet/risk"
+
+	"github.com/google/uuid"
+)
+
+// screen runs the risk rules on a transaction about to be posted and
+// stores a decision whenever one matched. It fails with
+// ErrTransactionBlocked when the rules block the transaction, and reports
+// held when they hold it for review; the transaction is then pending and
+// must not be posted.
+func (s *WalletService) screen(wallet *models.Wallet, transaction *models.Transaction) (held bool, err error) {
+	if s.riskRepo != nil {
+		// A held transaction keeps its reference_id until it is reviewed
+		_, err := s.riskRepo.GetHeldDecisionByReference(transaction.ReferenceID)
+		if err == nil {
+			return false, ErrDuplicateReference
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return false, err
+		}
+	}
+	if s.riskEngine.Empty() {
+		return false, nil
+	}
+
+	// Only the history the rules look at is loaded
+	var history []models.Transaction
+	if lookback, bounded := s.riskEngine.Lookback(); !bounded {
+		history, err = s.transactionRepo.GetTransactionsByWalletID(wallet.ID)
+	} else if lookback > 0 {
+		history, err = s.transactionRepo.GetTransactionsSince(wallet.ID, transaction.TransactedAt.Add(-lookback))
+	}
+	if err != nil {
+		return false, err
+	}
+	token, err := s.customerTokenRepo.GetCustomerToken(wallet.OwnedBy)
+	if err != nil {
+		return false, err
+	}
+	verdict := s.riskEngine.Evaluate(&risk.Input{
+		Type:           transaction.Type,
+		Amount:         transaction.Amount,
+		Now:            transaction.TransactedAt,
+		TokenCreatedAt: token.CreatedAt,
+		History:        history,
+	})
+	if verdict.Outcome == risk.Allow {
+		return false, nil
+	}
+
+	decision := &models.RiskDecision{
+		ID:              uuid.New().String(),
+		WalletID:        wallet.ID,
+		CustomerXID:     wallet.OwnedBy,
+		TransactionID:   transaction.ID,
+		TransactionType: transaction.Type,
+		Amount:          transaction.Amount,
+		ReferenceID:     transaction.ReferenceID,
+		Outcome:         string(verdict.Outcome),
+		Matches:         make([]models.RiskMatch, len(verdict.Matches)),
+		CreatedAt:       transaction.TransactedAt,
+	}
+	for i, match := range verdict.Matches {
+		decision.Matches[i] = models.RiskMatch{Rule: match.Rule, Outcome: string(match.Outcome), Reason: match.Reason}
+	}
+	if verdict.Outcome == risk.Review {
+		decision.Status = models.RiskHeld
+	}
+	if err := s.riskRepo.CreateDecision(decision); err != nil {
+		return false, err
+	}
+
+	if verdict.Outcome == risk.Block {
+		return false, ErrTransactionBlocked
+	}
+	transaction.Status = "pending"
+	return true, nil
+}
+
+func (s *AdminService) riskDecision(id string) (*models.RiskDecision, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, ErrRiskDecisionNotFound
+	}
+	decision, err := s.wallets.riskRepo.GetDecision(id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrRiskDecisionNotFound
+	}
+	return decision, err
+}
+
+// RiskDecision returns a risk decision in whatever state it is.
+func (s *AdminService) RiskDecision(actor *models.AdminKey, id string) (*models.RiskDecision, error) {
+	if err := authorize(actor, admin.PermWalletsRead); err != nil {
+		return nil, err
+	}
+	decision, err := s.riskDecision(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.record(actor, AuditRiskDecisionView, auditTargetRiskDecision, id, "", nil); err != nil {
+		return nil, err
+	}
+	return decision, nil
+}
+
+// RiskDecisions lists risk decisions matching filter, newest first.
+func (s *AdminService) RiskDecisions(actor *models.AdminKey, filter models.RiskDecisionFilter) ([]models.RiskDecision, error) {
+	if err := authorize(actor, admin.PermWalletsRead); err != nil {
+		return nil, err
+	}
+	filter.Limit = clampLimit(filter.Limit)
+	decisions, err := s.wallets.riskRepo.ListDecisions(filter)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.record(actor, AuditRiskDecisionView, auditTargetWallet, filter.WalletID, "", map[string]any{"outcome": filter.Outcome, "status": filter.Status, "results": len(decisions)}); err != nil {
+		return nil, err
+	}
+	return decisions, nil
+}
+
+// ReleaseRiskDecision posts a transaction held by the risk rules, under the
+// transaction ID reserved when it was held. The wallet must still allow
+// the transaction, and a withdrawal must still be covered by the balance
+// as the transaction log has it once the withdrawal is posted.
+func (s *AdminService) ReleaseRiskDecision(actor *models.AdminKey, id, note string) (*models.RiskDecision, error) {
+	decision, err := s.heldDecision(actor, id)
+	if err != nil {
+		return nil, err
+	}
+	wallet, err := s.wallets.walletByID(decision.WalletID)
+	if err != nil {
+		return nil, err
+	}
+	op := OpDeposit
+	if decision.TransactionType == "withdrawal" {
+		op = OpWithdraw
+	}
+	if err := Allow(wallet.Status, op); err != nil {
+		return nil, err
+	}
+	if err := s.wallets.checkTransactionTier(wallet, op, decision.Amount); err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	transaction := &models.Transaction{
+		ID:           decision.TransactionID,
+		WalletID:     wallet.ID,
+		Type:         decision.TransactionType,
+		Status:       "success",
+		Amount:       decision.Amount,
+		ReferenceID:  decision.ReferenceID,
+		TransactedAt: now,
+	}
+	decision.Status = models.RiskReleased
+	decision.ReviewedBy, decision.ReviewedByName, decision.ReviewNote = actor.ID, actor.Name, note
+	decision.ReviewedAt = &now
+	entry := auditEntry(actor, AuditRiskRelease, auditTargetRiskDecision, decision.ID, note, map[string]any{"wallet_id": wallet.ID, "amount": decision.Amount, "transaction_id": transaction.ID})
+	if err := s.wallets.riskRepo.ReleaseDecision(decision, transaction, entry); err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRiskDecisionNotHeld
+		case errors.Is(err, repositories.ErrInsufficientBalance):
+			return nil, ErrInsufficientBalance
+		}
+		return nil, err
+	}
+
+	go s.wallets.settleBalance(wallet.ID, wallet.OwnedBy)
+	s.wallets.publisher.Publish(context.Background(), TransactionEvent(wallet.OwnedBy, transaction))
+	return decision, nil
+}
+
+// RejectRiskDecision rejects a transaction held by the risk rules; it is
+// never posted.
+func (s *AdminService) RejectRiskDecision(actor *models.AdminKey, id, reason string) (*models.RiskDecision, error) {
+	decision, err := s.heldDecision(actor, id)
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(reason) == "" {
+		return nil, ErrReasonRequired
+	}
+
+	now := time.Now().UTC()
+	decision.Status = models.RiskRejected
+	decision.ReviewedBy, decision.ReviewedByName, decision.ReviewNote = actor.ID, actor.Name, reason
+	decision.ReviewedAt = &now
+	if err := s.wallets.riskRepo.RejectDecision(decision); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrRiskDecisionNotHeld
+		}
+		return nil, err
+	}
+	if err := s.record(actor, AuditRiskReject, auditTargetRiskDecision, decision.ID, reason, map[string]any{"wallet_id": decision.WalletID, "amount": decision.Amount}); err != nil {
+		return nil, err
+	}
+	return decision, nil
+}
+
+// heldDecision returns a held risk decision actor may review.
+func (s *AdminService) heldDecision(actor *models.AdminKey, id string) (*models.RiskDecision, error) {
+	if err := authorize(actor, admin.PermRiskReview); err != nil {
+		return nil, err
+	}
+	decision, err := s.riskDecision(id)
+	if err != nil {
+		return nil, err
+	}
+	if decision.Status != models.RiskHeld {
+		return nil, ErrRiskDecisionNotHeld
+	}
+	return decision, nil
+}
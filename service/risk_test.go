@@ -0,0 +1,175 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"mini-wallet/admin"
+	"mini-wallet/events"
+	"mini-wallet/models"
+	"mini-wallet/risk"
+)
+
+// Deposits of 500 or more from a fresh token are held, and a second
+// withdrawal within the hour is blocked.
+const testRules = `
+rules:
+  - name: fresh-token
+    kind: new_token
+    type: deposit
+    token_age: 1h
+    min_amount: 500
+    outcome: review
+  - name: withdrawal-velocity
+    kind: velocity
+    type: withdrawal
+    max_count: 1
+    window: 1h
+    outcome: block
+`
+
+func newRiskFixture(t *testing.T, wallets ...models.Wallet) (*fixture, *AdminService, *mockRiskRepo, *mockAuditRepo) {
+	t.Helper()
+	engine, err := risk.Parse([]byte(testRules))
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := newFixture(wallets...)
+	f.tokens.tokens[customer] = "token-1"
+	f.tokens.issuedAt = map[string]time.Time{customer: time.Now().UTC()}
+	audit := &mockAuditRepo{}
+	decisions := &mockRiskRepo{decisions: make(map[string]models.RiskDecision), transactions: f.transactions, audit: audit}
+	f.service = NewWalletService(f.wallets, f.transactions, f.snapshots, f.tokens, nil, f.events, engine, decisions, nil, nil, nil, f.service.cfg)
+	return f, NewAdminService(f.service, f.wallets, f.transactions, f.tokens, nil, audit, nil, nil, nil), decisions, audit
+}
+
+func TestRiskRulesHoldAndBlock(t *testing.T) {
+	f, _, decisions, _ := newRiskFixture(t, enabledWallet(100))
+
+	if _, err := f.service.Deposit(customer, 100, "deposit-small"); err != nil {
+		t.Fatalf("small deposit error = %v, want it allowed", err)
+	}
+	held, err := f.service.Deposit(customer, 600, reference)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if held.Status != "pending" {
+		t.Errorf("held deposit status = %q, want pending", held.Status)
+	}
+	if _, err := f.transactions.GetTransactionByReferenceID(reference); err == nil {
+		t.Error("held deposit was posted")
+	}
+	if _, err := f.service.Deposit(customer, 600, reference); !errors.Is(err, ErrDuplicateReference) {
+		t.Errorf("resubmitting a held deposit error = %v, want ErrDuplicateReference", err)
+	}
+
+	if _, err := f.service.Withdraw(customer, 10, "withdrawal-1", Authorization{}); err != nil {
+		t.Fatalf("first withdrawal error = %v, want it allowed", err)
+	}
+	if _, err := f.service.Withdraw(customer, 10, "withdrawal-2", Authorization{}); !errors.Is(err, ErrTransactionBlocked) {
+		t.Errorf("second withdrawal error = %v, want ErrTransactionBlocked", err)
+	}
+
+	stored, _ := decisions.ListDecisions(models.RiskDecisionFilter{})
+	if len(stored) != 2 {
+		t.Fatalf("stored %d decisions, want the hold and the block", len(stored))
+	}
+	for _, decision := range stored {
+		switch decision.Outcome {
+		case string(risk.Review):
+			if decision.Status != models.RiskHeld || decision.TransactionID != held.ID || decision.Matches[0].Rule != "fresh-token" {
+				t.Errorf("review decision = %+v, want the held deposit", decision)
+			}
+		case string(risk.Block):
+			if decision.Status != "" || decision.ReferenceID != "withdrawal-2" {
+				t.Errorf("block decision = %+v, want the second withdrawal without a status", decision)
+			}
+		}
+	}
+	if got := f.events.types(); len(got) != 2 {
+		t.Errorf("published %v, want only the posted deposit and withdrawal", got)
+	}
+}
+
+func TestReleaseHeldTransaction(t *testing.T) {
+	f, admins, decisions, audit := newRiskFixture(t, enabledWallet(0))
+	held, err := f.service.Deposit(customer, 600, reference)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stored, _ := decisions.ListDecisions(models.RiskDecisionFilter{})
+	id := stored[0].ID
+
+	if _, err := admins.ReleaseRiskDecision(actor(admin.RoleViewer), id, ""); !errors.Is(err, ErrForbidden) {
+		t.Errorf("viewer release error = %v, want ErrForbidden", err)
+	}
+	released, err := admins.ReleaseRiskDecision(actor(admin.RoleSupport), id, "customer verified")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if released.Status != models.RiskReleased || released.ReviewedBy != "key-1" {
+		t.Errorf("decision = %+v, want released by key-1", released)
+	}
+	posted, err := f.transactions.GetTransactionByReferenceID(reference)
+	if err != nil || posted.ID != held.ID || posted.Status != "success" {
+		t.Errorf("posted = %+v, %v, want the held deposit under its ID", posted, err)
+	}
+	eventually(t, func() bool { return f.wallets.wallet(customer).Balance == 600 })
+	if got := f.events.types(); len(got) == 0 || got[0] != events.DepositSucceeded {
+		t.Errorf("published %v, want deposit.succeeded", got)
+	}
+
+	if _, err := admins.RejectRiskDecision(actor(admin.RoleSupport), id, "too late"); !errors.Is(err, ErrRiskDecisionNotHeld) {
+		t.Errorf("reviewing twice error = %v, want ErrRiskDecisionNotHeld", err)
+	}
+	if got := audit.actions(); len(got) != 1 || got[0] != AuditRiskRelease {
+		t.Errorf("audited %v, want the release", got)
+	}
+}
+
+func TestRejectHeldTransaction(t *testing.T) {
+	f, admins, decisions, _ := newRiskFixture(t, enabledWallet(0))
+	if _, err := f.service.Deposit(customer, 600, reference); err != nil {
+		t.Fatal(err)
+	}
+	stored, _ := decisions.ListDecisions(models.RiskDecisionFilter{})
+	id := stored[0].ID
+
+	if _, err := admins.RejectRiskDecision(actor(admin.RoleOperator), id, " "); !errors.Is(err, ErrReasonRequired) {
+		t.Errorf("reject without reason error = %v, want ErrReasonRequired", err)
+	}
+	rejected, err := admins.RejectRiskDecision(actor(admin.RoleOperator), id, "stolen card")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rejected.Status != models.RiskRejected || rejected.ReviewNote != "stolen card" {
+		t.Errorf("decision = %+v, want rejected with the reason", rejected)
+	}
+	if _, err := f.transactions.GetTransactionByReferenceID(reference); err == nil {
+		t.Error("rejected deposit was posted")
+	}
+	// The reference is free again once the hold is reviewed
+	if _, err := f.service.Deposit(customer, 100, reference); err != nil {
+		t.Errorf("reusing the reference error = %v, want it allowed", err)
+	}
+}
+
+func TestReleaseHeldWithdrawalChecksTheLog(t *testing.T) {
+	f, admins, decisions, audit := newRiskFixture(t, enabledWallet(100))
+	// The stored balance has not settled a withdrawal the log already has
+	f.transactions.CreateTransaction(&models.Transaction{ID: "deposit-1", WalletID: "wallet-1", Type: "deposit", Amount: 100})
+	f.transactions.CreateTransaction(&models.Transaction{ID: "withdrawal-1", WalletID: "wallet-1", Type: "withdrawal", Amount: 60})
+	decisions.CreateDecision(&models.RiskDecision{ID: "9b2f4c1e-7d3a-4e5b-8c6d-0a1b2c3d4e5f", WalletID: "wallet-1", CustomerXID: customer,
+		TransactionID: "withdrawal-2", TransactionType: "withdrawal", Amount: 50, ReferenceID: reference, Outcome: "review", Status: models.RiskHeld})
+
+	if _, err := admins.ReleaseRiskDecision(actor(admin.RoleSupport), "9b2f4c1e-7d3a-4e5b-8c6d-0a1b2c3d4e5f", "ok"); !errors.Is(err, ErrInsufficientBalance) {
+		t.Fatalf("Release error = %v, want ErrInsufficientBalance", err)
+	}
+	if decision, _ := decisions.GetDecision("9b2f4c1e-7d3a-4e5b-8c6d-0a1b2c3d4e5f"); decision.Status != models.RiskHeld {
+		t.Errorf("decision = %+v, want it still held", decision)
+	}
+	if got := audit.actions(); len(got) != 0 {
+		t.Errorf("audited %v, want nothing for a release that failed", got)
+	}
+}
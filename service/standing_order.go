@@ -0,0 +1,285 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"strconv"
+	"time"
+
+	"mini-wallet/config"
+	"mini-wallet/events"
+	"mini-wallet/models"
+	"mini-wallet/repositories"
+
+	"github.com/google/uuid"
+)
+
+// startGrace lets an order start slightly in the past, so a client whose
+// clock runs behind can still schedule one for now.
+const startGrace = time.Minute
+
+// StandingOrderService manages the customers' standing orders and runs
+// them when due.
+type StandingOrderService struct {
+	wallets   *WalletService
+	orderRepo repositories.StandingOrderRepository
+	cfg       config.StandingOrdersConfig
+}
+
+func NewStandingOrderService(wallets *WalletService, orderRepo repositories.StandingOrderRepository, cfg config.StandingOrdersConfig) *StandingOrderService {
+	return &StandingOrderService{
+		wallets:   wallets,
+		orderRepo: orderRepo,
+		cfg:       cfg,
+	}
+}
+
+// StandingOrderChange lists what an update changes; nil fields are kept.
+type StandingOrderChange struct {
+	Amount      *int64
+	Description *string
+	EndsAt      *time.Time
+}
+
+// Create schedules transfers from the customer's wallet as order describes
+// them: payee, amount, description, frequency, start, now when zero, and
+// end. auth authorizes the order once, as it would a withdrawal of its
+// amount; its runs need nothing further.
+func (s *StandingOrderService) Create(customerXID string, order *models.StandingOrder, auth Authorization) (*models.StandingOrder, error) {
+	now := time.Now().UTC()
+	if order.StartAt.IsZero() {
+		order.StartAt = now
+	}
+	order.StartAt = order.StartAt.UTC()
+	if err := s.check(order); err != nil {
+		return nil, err
+	}
+	if order.StartAt.Before(now.Add(-startGrace)) {
+		return nil, ErrStartInPast
+	}
+
+	wallet, err := s.wallets.Wallet(customerXID)
+	if err != nil {
+		return nil, err
+	}
+	if err := Allow(wallet.Status, OpWithdraw); err != nil {
+		return nil, err
+	}
+	if order.PayeeCustomerXID == customerXID {
+		return nil, ErrSelfTransfer
+	}
+	if payee, err := s.wallets.walletRepo.GetWalletByCustomerXID(order.PayeeCustomerXID); err != nil || payee == nil {
+		return nil, ErrPayeeNotFound
+	}
+	if err := s.wallets.authorizeWithdrawal(customerXID, order.Amount, auth); err != nil {
+		return nil, err
+	}
+
+	order.ID = uuid.New().String()
+	order.CustomerXID = customerXID
+	order.WalletID = wallet.ID
+	order.Status = models.StandingOrderActive
+	order.Runs, order.Attempts = 0, 0
+	order.NextRunAt = order.Next()
+	order.NextAttemptAt = order.NextRunAt
+	order.CreatedAt, order.UpdatedAt = now, now
+	if err := s.orderRepo.CreateStandingOrder(order); err != nil {
+		return nil, err
+	}
+	return order, nil
+}
+
+// check validates the terms of an order.
+func (s *StandingOrderService) check(order *models.StandingOrder) error {
+	switch order.Frequency {
+	case models.FrequencyOnce, models.FrequencyDaily, models.FrequencyWeekly, models.FrequencyMonthly:
+	default:
+		return ErrInvalidFrequency
+	}
+	if order.Amount <= 0 {
+		return ErrInvalidAmount
+	}
+	if order.EndsAt != nil && order.EndsAt.Before(order.StartAt) {
+		return ErrEndBeforeStart
+	}
+	return nil
+}
+
+// StandingOrders returns the customer's orders, newest first.
+func (s *StandingOrderService) StandingOrders(customerXID string) ([]models.StandingOrder, error) {
+	return s.orderRepo.ListStandingOrders(customerXID)
+}
+
+// StandingOrder returns one of the customer's orders.
+func (s *StandingOrderService) StandingOrder(customerXID, id string) (*models.StandingOrder, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, ErrStandingOrderNotFound
+	}
+	order, err := s.orderRepo.GetStandingOrder(id, customerXID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrStandingOrderNotFound
+	}
+	return order, err
+}
+
+// Update changes the amount, description or end of an active order. A
+// new amount takes auth, as creating the order did. Bringing the end
+// before the next run completes the order once that run is due.
+func (s *StandingOrderService) Update(customerXID, id string, change StandingOrderChange, auth Authorization) (*models.StandingOrder, error) {
+	order, err := s.StandingOrder(customerXID, id)
+	if err != nil {
+		return nil, err
+	}
+	if order.Status != models.StandingOrderActive {
+		return nil, ErrStandingOrderInactive
+	}
+	if change.Amount != nil {
+		order.Amount = *change.Amount
+	}
+	if change.Description != nil {
+		order.Description = *change.Description
+	}
+	if change.EndsAt != nil {
+		ends := change.EndsAt.UTC()
+		order.EndsAt = &ends
+	}
+	if err := s.check(order); err != nil {
+		return nil, err
+	}
+	if change.Amount != nil {
+		if err := s.wallets.authorizeWithdrawal(customerXID, order.Amount, auth); err != nil {
+			return nil, err
+		}
+	}
+
+	order.UpdatedAt = time.Now().UTC()
+	if err := s.orderRepo.UpdateStandingOrder(order); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrStandingOrderInactive
+		}
+		return nil, err
+	}
+	return order, nil
+}
+
+// Cancel cancels an active order; its runs so far stay posted.
+func (s *StandingOrderService) Cancel(customerXID, id string) (*models.StandingOrder, error) {
+	order, err := s.StandingOrder(customerXID, id)
+	if err != nil {
+		return nil, err
+	}
+	if order.Status != models.StandingOrderActive {
+		return nil, ErrStandingOrderInactive
+	}
+	now := time.Now().UTC()
+	if err := s.orderRepo.CancelStandingOrder(order.ID, customerXID, now); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrStandingOrderInactive
+		}
+		return nil, err
+	}
+	order.Status = models.StandingOrderCancelled
+	order.NextRunAt, order.NextAttemptAt = nil, nil
+	order.UpdatedAt = now
+	return order, nil
+}
+
+// RunDue claims up to limit orders due at now, runs each and reports how
+// many were claimed. An order whose run failed for an internal reason is
+// attempted again once its claim runs out.
+func (s *StandingOrderService) RunDue(now time.Time, limit int) (int, error) {
+	orders, err := s.orderRepo.ClaimDueStandingOrders(now, limit, s.cfg.Lease)
+	if err != nil {
+		return 0, err
+	}
+	for i := range orders {
+		if err := s.run(&orders[i], now); err != nil {
+			log.Printf("Failed to run standing order %s: %v", orders[i].ID, err)
+		}
+	}
+	return len(orders), nil
+}
+
+// run executes the order's next run. A run finding too little balance is
+// attempted again RetryDelay later, up to RetryAttempts times; a run
+// failing for any other business rule is given up at once. Either way the
+// customer is told.
+func (s *StandingOrderService) run(order *models.StandingOrder, now time.Time) error {
+	order.UpdatedAt = now
+	if order.EndsAt != nil && order.NextRunAt.After(*order.EndsAt) {
+		// The end was brought before the run since it was scheduled
+		order.Status = models.StandingOrderCompleted
+		order.NextRunAt, order.NextAttemptAt = nil, nil
+		return claimed(s.orderRepo.SaveAttempt(order))
+	}
+
+	// Each run has its own reference, so a run is posted once at most
+	reference := uuid.NewSHA1(uuid.MustParse(order.ID), []byte(strconv.Itoa(order.Runs)))
+	transfer, err := s.wallets.prepareTransfer(order.CustomerXID, order.PayeeCustomerXID, order.Amount, reference, now)
+	if err == nil {
+		ran := *order
+		ran.LastError = ""
+		ran.LastRunAt = &now
+		advance(&ran, models.StandingOrderCompleted)
+		err = s.orderRepo.CompleteRun(&ran, transfer)
+		if err == nil {
+			*order = ran
+			s.wallets.transferred(transfer, order.CustomerXID, order.PayeeCustomerXID)
+			return nil
+		}
+		switch {
+		case errors.Is(err, repositories.ErrInsufficientBalance):
+			// The balance was spent between the check and the posting
+			err = ErrInsufficientBalance
+		case errors.Is(err, repositories.ErrWalletClosed):
+			// So was the payee's wallet closed
+			err = ErrPayeeUnavailable
+		default:
+			return claimed(err)
+		}
+	}
+
+	var rule *Error
+	eventType := events.StandingOrderFailed
+	switch {
+	case errors.Is(err, ErrInsufficientBalance) && order.Attempts+1 < s.cfg.RetryAttempts:
+		order.Attempts++
+		order.LastError = err.Error()
+		retry := now.Add(s.cfg.RetryDelay)
+		order.NextAttemptAt = &retry
+		eventType = events.StandingOrderRetrying
+	case errors.As(err, &rule):
+		order.LastError = err.Error()
+		advance(order, models.StandingOrderFailed)
+	default:
+		return err
+	}
+	if err := s.orderRepo.SaveAttempt(order); err != nil {
+		return claimed(err)
+	}
+	s.wallets.publisher.Publish(context.Background(), events.New(eventType, order.CustomerXID, order.WalletID, order))
+	return nil
+}
+
+// advance moves the order past its next run, ending it with status when
+// the schedule has no run left.
+func advance(order *models.StandingOrder, status string) {
+	order.Runs++
+	order.Attempts = 0
+	order.NextRunAt = order.Next()
+	order.NextAttemptAt = order.NextRunAt
+	if order.NextRunAt == nil {
+		order.Status = status
+	}
+}
+
+// claimed reports a lost claim as no error: the order changed or another
+// scheduler claimed it meanwhile, and nothing was posted.
+func claimed(err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,181 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"mini-wallet/config"
+	"mini-wallet/events"
+	"mini-wallet/models"
+)
+
+const payee = "526ea8b2-428e-403b-b9fd-f10972e0d6fe"
+
+func newStandingOrderFixture(balance int64) (*fixture, *StandingOrderService, *mockStandingOrderRepo) {
+	f := newFixture(enabledWallet(balance), models.Wallet{ID: "wallet-2", OwnedBy: payee, Status: "enabled"})
+	f.transactions.CreateTransaction(&models.Transaction{ID: "deposit-0", WalletID: "wallet-1", Type: "deposit", Amount: balance})
+	repo := &mockStandingOrderRepo{orders: make(map[string]models.StandingOrder), wallets: f.wallets, transactions: f.transactions}
+	cfg := config.Default().StandingOrders
+	cfg.RetryAttempts = 2
+	return f, NewStandingOrderService(f.service, repo, cfg), repo
+}
+
+func TestMonthlyStandingOrderRunsOnLastDayOfShortMonths(t *testing.T) {
+	order := models.StandingOrder{Frequency: models.FrequencyMonthly, StartAt: time.Date(2024, 1, 31, 9, 0, 0, 0, time.UTC)}
+	want := []time.Time{
+		time.Date(2024, 1, 31, 9, 0, 0, 0, time.UTC),
+		time.Date(2024, 2, 29, 9, 0, 0, 0, time.UTC),
+		time.Date(2024, 3, 31, 9, 0, 0, 0, time.UTC),
+		time.Date(2024, 4, 30, 9, 0, 0, 0, time.UTC),
+	}
+	for n, at := range want {
+		if got := order.Occurrence(n); !got.Equal(at) {
+			t.Errorf("Occurrence(%d) = %v, want %v", n, got, at)
+		}
+	}
+}
+
+func TestStandingOrderRunTransfers(t *testing.T) {
+	f, orders, repo := newStandingOrderFixture(500)
+	order, err := orders.Create(customer, &models.StandingOrder{PayeeCustomerXID: payee, Amount: 200, Frequency: models.FrequencyDaily}, Authorization{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := order.StartAt.Add(time.Second)
+	if claimed, err := orders.RunDue(now, 10); err != nil || claimed != 1 {
+		t.Fatalf("RunDue = %d, %v, want the order claimed", claimed, err)
+	}
+	if got := f.wallets.wallet(customer).Balance; got != 300 {
+		t.Errorf("payer balance = %d, want 300", got)
+	}
+	if got := f.wallets.wallet(payee).Balance; got != 200 {
+		t.Errorf("payee balance = %d, want 200", got)
+	}
+	if got := f.events.types(); len(got) != 2 || got[0] != events.TransferSent || got[1] != events.TransferReceived {
+		t.Errorf("published %v, want the transfer sent then received", got)
+	}
+	if history, _ := f.transactions.GetTransactionsByWalletID("wallet-2"); len(history) != 1 || history[0].Type != "transfer_in" {
+		t.Errorf("payee history = %+v, want one transfer_in", history)
+	}
+	stored := repo.order(order.ID)
+	if stored.Runs != 1 || stored.Status != models.StandingOrderActive || !stored.NextRunAt.Equal(order.StartAt.AddDate(0, 0, 1)) {
+		t.Errorf("order = %+v, want one run and the next a day later", stored)
+	}
+
+	// Nothing is due until the next day
+	if claimed, _ := orders.RunDue(now, 10); claimed != 0 {
+		t.Errorf("second RunDue claimed %d orders, want none", claimed)
+	}
+	eventually(t, func() bool {
+		return f.wallets.wallet(payee).Balance == 200 && f.wallets.wallet(customer).Balance == 300
+	})
+}
+
+func TestStandingOrderRetriesThenFails(t *testing.T) {
+	f, orders, repo := newStandingOrderFixture(100)
+	order, err := orders.Create(customer, &models.StandingOrder{PayeeCustomerXID: payee, Amount: 200, Frequency: models.FrequencyOnce}, Authorization{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := order.StartAt.Add(time.Second)
+	orders.RunDue(now, 10)
+	stored := repo.order(order.ID)
+	if stored.Status != models.StandingOrderActive || stored.Attempts != 1 || !stored.NextAttemptAt.Equal(now.Add(orders.cfg.RetryDelay)) {
+		t.Fatalf("order = %+v, want a retry after the delay", stored)
+	}
+	if claimed, _ := orders.RunDue(now, 10); claimed != 0 {
+		t.Errorf("RunDue before the retry claimed %d orders, want none", claimed)
+	}
+
+	orders.RunDue(now.Add(orders.cfg.RetryDelay), 10)
+	stored = repo.order(order.ID)
+	if stored.Status != models.StandingOrderFailed || stored.NextRunAt != nil || stored.LastError != ErrInsufficientBalance.Error() {
+		t.Errorf("order = %+v, want it failed for the balance", stored)
+	}
+	if got := f.events.types(); len(got) != 2 || got[0] != events.StandingOrderRetrying || got[1] != events.StandingOrderFailed {
+		t.Errorf("published %v, want a retry then the failure", got)
+	}
+	if got := f.wallets.wallet(payee).Balance; got != 0 {
+		t.Errorf("payee balance = %d, want nothing transferred", got)
+	}
+}
+
+func TestStandingOrderRunRacingWithdrawal(t *testing.T) {
+	f, orders, repo := newStandingOrderFixture(500)
+	order, err := orders.Create(customer, &models.StandingOrder{PayeeCustomerXID: payee, Amount: 200, Frequency: models.FrequencyOnce}, Authorization{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A withdrawal spends the balance after the run checked it
+	repo.beforeRun = func() { f.wallets.UpdateWalletBalance("wallet-1", 100) }
+
+	now := order.StartAt.Add(time.Second)
+	orders.RunDue(now, 10)
+	stored := repo.order(order.ID)
+	if stored.Status != models.StandingOrderActive || stored.Runs != 0 || stored.Attempts != 1 || !stored.NextAttemptAt.Equal(now.Add(orders.cfg.RetryDelay)) {
+		t.Errorf("order = %+v, want the run retried after the delay", stored)
+	}
+	if got := f.wallets.wallet(customer).Balance; got != 100 {
+		t.Errorf("payer balance = %d, want 100", got)
+	}
+	if got := f.wallets.wallet(payee).Balance; got != 0 {
+		t.Errorf("payee balance = %d, want nothing transferred", got)
+	}
+	if got := f.events.types(); len(got) != 1 || got[0] != events.StandingOrderRetrying {
+		t.Errorf("published %v, want a retry", got)
+	}
+}
+
+func TestCreateStandingOrderRules(t *testing.T) {
+	now := time.Now().UTC()
+	past := now.Add(-time.Hour)
+
+	tests := []struct {
+		name    string
+		order   models.StandingOrder
+		wantErr error
+	}{
+		{"schedules a transfer", models.StandingOrder{PayeeCustomerXID: payee, Amount: 100, Frequency: models.FrequencyWeekly}, nil},
+		{"refuses the customer as payee", models.StandingOrder{PayeeCustomerXID: customer, Amount: 100, Frequency: models.FrequencyWeekly}, ErrSelfTransfer},
+		{"refuses a payee without a wallet", models.StandingOrder{PayeeCustomerXID: "nobody", Amount: 100, Frequency: models.FrequencyWeekly}, ErrPayeeNotFound},
+		{"refuses an unknown frequency", models.StandingOrder{PayeeCustomerXID: payee, Amount: 100, Frequency: "hourly"}, ErrInvalidFrequency},
+		{"refuses a start in the past", models.StandingOrder{PayeeCustomerXID: payee, Amount: 100, Frequency: models.FrequencyOnce, StartAt: past}, ErrStartInPast},
+		{"refuses an end before the start", models.StandingOrder{PayeeCustomerXID: payee, Amount: 100, Frequency: models.FrequencyDaily, StartAt: now.Add(time.Hour), EndsAt: &now}, ErrEndBeforeStart},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, orders, _ := newStandingOrderFixture(0)
+			if _, err := orders.Create(customer, &tt.order, Authorization{}); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Create error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCancelStandingOrder(t *testing.T) {
+	_, orders, _ := newStandingOrderFixture(500)
+	order, err := orders.Create(customer, &models.StandingOrder{PayeeCustomerXID: payee, Amount: 100, Frequency: models.FrequencyMonthly}, Authorization{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cancelled, err := orders.Cancel(customer, order.ID)
+	if err != nil || cancelled.Status != models.StandingOrderCancelled {
+		t.Fatalf("Cancel = %+v, %v, want the order cancelled", cancelled, err)
+	}
+	if claimed, _ := orders.RunDue(order.StartAt.Add(time.Hour), 10); claimed != 0 {
+		t.Errorf("RunDue claimed %d orders, want none once cancelled", claimed)
+	}
+	if _, err := orders.Cancel(customer, order.ID); !errors.Is(err, ErrStandingOrderInactive) {
+		t.Errorf("second Cancel error = %v, want ErrStandingOrderInactive", err)
+	}
+	if _, err := orders.Update(customer, order.ID, StandingOrderChange{}, Authorization{}); !errors.Is(err, ErrStandingOrderInactive) {
+		t.Errorf("Update error = %v, want ErrStandingOrderInactive", err)
+	}
+	if _, err := orders.StandingOrder("someone-else", order.ID); !errors.Is(err, ErrStandingOrderNotFound) {
+		t.Errorf("StandingOrder of another customer error = %v, want ErrStandingOrderNotFound", err)
+	}
+}
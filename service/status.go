@@ -0,0 +1,168 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"mini-wallet/events"
+	"mini-wallet/models"
+
+	"github.com/google/uuid"
+)
+
+// Operation is something done with a wallet that its status may forbid.
+type Operation int
+
+const (
+	// OpView covers the balance, the transaction history and live events.
+	OpView Operation = iota
+	OpDeposit
+	OpWithdraw
+	// OpAdjust is a staff balance adjustment.
+	OpAdjust
+)
+
+// statusRule is what a wallet in one status allows.
+type statusRule struct {
+	// next maps each status the wallet may move to onto whether the
+	// customer may make that move; staff may make every listed move.
+	next map[string]bool
+	// allows lists the operations the status permits.
+	allows map[Operation]bool
+	// denied is returned for operations and moves the status forbids.
+	denied error
+}
+
+// statusRules is the wallet state machine. Every status check of the
+// service goes through it.
+var statusRules = map[string]statusRule{
+	models.WalletPending: {
+		next: map[string]bool{models.WalletEnabled: true, models.WalletClosed: true},
+		// Wallets that were never enabled have always answered as disabled
+		denied: ErrWalletDisabled,
+	},
+	models.WalletEnabled: {
+		next:   map[string]bool{models.WalletDisabled: true, models.WalletClosed: true, models.WalletFrozen: false, models.WalletSuspended: false},
+		allows: map[Operation]bool{OpView: true, OpDeposit: true, OpWithdraw: true, OpAdjust: true},
+	},
+	models.WalletFrozen: {
+		next:   map[string]bool{models.WalletEnabled: false, models.WalletSuspended: false, models.WalletDisabled: false},
+		allows: map[Operation]bool{OpView: true, OpDeposit: true, OpAdjust: true},
+		denied: ErrWalletFrozen,
+	},
+	models.WalletSuspended: {
+		next:   map[string]bool{models.WalletEnabled: false, models.WalletFrozen: false, models.WalletDisabled: false, models.WalletClosed: false},
+		allows: map[Operation]bool{OpView: true, OpAdjust: true},
+		denied: ErrWalletSuspended,
+	},
+	models.WalletDisabled: {
+		next:   map[string]bool{models.WalletEnabled: true, models.WalletClosed: true},
+		denied: ErrWalletDisabled,
+	},
+	models.WalletClosed: {
+		denied: ErrWalletClosed,
+	},
+}
+
+// alreadyErrors report a move to the status the wallet already has.
+var alreadyErrors = map[string]error{
+	models.WalletEnabled:  ErrWalletAlreadyEnabled,
+	models.WalletDisabled: ErrWalletAlreadyDisabled,
+	models.WalletFrozen:   ErrWalletFrozen,
+	models.WalletClosed:   ErrWalletClosed,
+}
+
+// Allow reports whether a wallet in status permits op, returning the
+// status's error when it does not.
+func Allow(status string, op Operation) error {
+	rule, ok := statusRules[status]
+	if !ok {
+		return ErrInvalidTransition
+	}
+	if rule.allows[op] {
+		return nil
+	}
+	if rule.denied != nil {
+		return rule.denied
+	}
+	return ErrInvalidTransition
+}
+
+// CanTransition reports whether a wallet may move from one status to
+// another, by the customer or by staff.
+func CanTransition(from, to string, byCustomer bool) error {
+	customerAllowed, ok := statusRules[from].next[to]
+	if ok && (customerAllowed || !byCustomer) {
+		return nil
+	}
+	// A wallet that was never enabled is already disabled for its customer
+	if from == to || from == models.WalletPending && to == models.WalletDisabled {
+		if err, ok := alreadyErrors[to]; ok {
+			return err
+		}
+	}
+	if denied := statusRules[from].denied; denied != nil {
+		return denied
+	}
+	return ErrInvalidTransition
+}
+
+// Actor identifies who changes a wallet's status, for its history.
+type Actor struct {
+	Type string
+	ID   string
+}
+
+// transition moves the wallet to status after checking the state machine,
+// records the change with reason in the status history, and publishes
+// eventType. Enabling a pending or disabled wallet stamps enabled_at and
+// disabling stamps disabled_at; other moves, such as unfreezing, keep both.
+func (s *WalletService) transition(wallet *models.Wallet, to string, by Actor, reason, eventType string) (*models.Wallet, error) {
+	if err := CanTransition(wallet.Status, to, by.Type == models.ActorCustomer); err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	change := &models.WalletStatusChange{
+		ID:         uuid.New().String(),
+		WalletID:   wallet.ID,
+		FromStatus: wallet.Status,
+		ToStatus:   to,
+		Reason:     reason,
+		ActorType:  by.Type,
+		ActorID:    by.ID,
+		ChangedAt:  now,
+	}
+	updated := *wallet
+	updated.Status = to
+	switch {
+	case to == models.WalletEnabled && (wallet.Status == models.WalletPending || wallet.Status == models.WalletDisabled):
+		updated.EnabledAt = now
+	case to == models.WalletDisabled:
+		updated.DisabledAt = now
+	}
+	if err := s.walletRepo.UpdateWalletStatus(&updated, change); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			// Another request changed the status since it was read
+			return nil, ErrInvalidTransition
+		}
+		return nil, err
+	}
+
+	view := EnabledWalletView(&updated)
+	if to == models.WalletDisabled {
+		view = DisabledWalletView(&updated)
+	}
+	s.publisher.Publish(context.Background(), events.New(eventType, updated.OwnedBy, updated.ID, view))
+	return &updated, nil
+}
+
+// StatusHistory returns the status changes of a wallet, oldest first.
+func (s *WalletService) StatusHistory(walletID string) ([]models.WalletStatusChange, error) {
+	if _, err := s.walletByID(walletID); err != nil {
+		return nil, err
+	}
+	return s.walletRepo.ListStatusChanges(walletID)
+}
@@ -0,0 +1,126 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"mini-wallet/admin"
+	"mini-wallet/models"
+)
+
+func TestCanTransition(t *testing.T) {
+	tests := []struct {
+		from, to   string
+		byCustomer bool
+		want       error
+	}{
+		{models.WalletPending, models.WalletEnabled, true, nil},
+		{models.WalletPending, models.WalletDisabled, true, ErrWalletAlreadyDisabled},
+		{models.WalletPending, models.WalletFrozen, false, ErrWalletDisabled},
+		{models.WalletEnabled, models.WalletEnabled, true, ErrWalletAlreadyEnabled},
+		{models.WalletEnabled, models.WalletFrozen, true, ErrInvalidTransition},
+		{models.WalletEnabled, models.WalletFrozen, false, nil},
+		{models.WalletEnabled, models.WalletSuspended, false, nil},
+		{models.WalletFrozen, models.WalletDisabled, true, ErrWalletFrozen},
+		{models.WalletFrozen, models.WalletDisabled, false, nil},
+		{models.WalletFrozen, models.WalletFrozen, false, ErrWalletFrozen},
+		{models.WalletSuspended, models.WalletEnabled, true, ErrWalletSuspended},
+		{models.WalletSuspended, models.WalletEnabled, false, nil},
+		{models.WalletDisabled, models.WalletEnabled, true, nil},
+		{models.WalletDisabled, models.WalletFrozen, false, ErrWalletDisabled},
+		{models.WalletClosed, models.WalletEnabled, false, ErrWalletClosed},
+		{"unknown", models.WalletEnabled, false, ErrInvalidTransition},
+	}
+	for _, tt := range tests {
+		who := "staff"
+		if tt.byCustomer {
+			who = "customer"
+		}
+		t.Run(tt.from+" to "+tt.to+" by "+who, func(t *testing.T) {
+			if err := CanTransition(tt.from, tt.to, tt.byCustomer); !errors.Is(err, tt.want) {
+				t.Errorf("CanTransition error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestAllow(t *testing.T) {
+	tests := []struct {
+		status string
+		op     Operation
+		want   error
+	}{
+		{models.WalletPending, OpView, ErrWalletDisabled},
+		{models.WalletEnabled, OpWithdraw, nil},
+		{models.WalletFrozen, OpDeposit, nil},
+		{models.WalletFrozen, OpWithdraw, ErrWalletFrozen},
+		{models.WalletSuspended, OpView, nil},
+		{models.WalletSuspended, OpDeposit, ErrWalletSuspended},
+		{models.WalletSuspended, OpAdjust, nil},
+		{models.WalletDisabled, OpAdjust, ErrWalletDisabled},
+		{models.WalletClosed, OpView, ErrWalletClosed},
+	}
+	for _, tt := range tests {
+		if err := Allow(tt.status, tt.op); !errors.Is(err, tt.want) {
+			t.Errorf("Allow(%s, %d) error = %v, want %v", tt.status, tt.op, err, tt.want)
+		}
+	}
+}
+
+func TestDisableKeepsEnabledAt(t *testing.T) {
+	enabledAt := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	wallet := enabledWallet(0)
+	wallet.EnabledAt = enabledAt
+	f := newFixture(wallet)
+
+	disabled, err := f.service.Disable(customer, Authorization{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !disabled.EnabledAt.Equal(enabledAt) || disabled.DisabledAt.IsZero() {
+		t.Errorf("wallet = %+v, want enabled_at kept and disabled_at set", disabled)
+	}
+	if stored := f.wallets.wallet(customer); !stored.EnabledAt.Equal(enabledAt) {
+		t.Errorf("stored enabled_at = %v, want %v", stored.EnabledAt, enabledAt)
+	}
+}
+
+func TestSuspensionIsRecorded(t *testing.T) {
+	f, admins, audit := newAdminFixture(enabledWallet(100))
+
+	if _, err := admins.Suspend(actor(admin.RoleSupport), "wallet-1", "fraud review"); !errors.Is(err, ErrForbidden) {
+		t.Errorf("Suspend by support error = %v, want ErrForbidden", err)
+	}
+	if _, err := admins.Suspend(actor(admin.RoleOperator), "wallet-1", "fraud review"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.service.Deposit(customer, 10, reference); !errors.Is(err, ErrWalletSuspended) {
+		t.Errorf("Deposit error = %v, want ErrWalletSuspended", err)
+	}
+	if _, err := f.service.Enable(customer); !errors.Is(err, ErrWalletSuspended) {
+		t.Errorf("Enable error = %v, want ErrWalletSuspended", err)
+	}
+	if _, err := admins.Reinstate(actor(admin.RoleOperator), "wallet-1", "cleared"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := admins.Reinstate(actor(admin.RoleOperator), "wallet-1", "cleared"); !errors.Is(err, ErrWalletNotSuspended) {
+		t.Errorf("second Reinstate error = %v, want ErrWalletNotSuspended", err)
+	}
+
+	history, err := admins.StatusHistory(actor(admin.RoleViewer), "wallet-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 2 {
+		t.Fatalf("history = %+v, want suspend and reinstate", history)
+	}
+	first := history[0]
+	if first.FromStatus != models.WalletEnabled || first.ToStatus != models.WalletSuspended ||
+		first.Reason != "fraud review" || first.ActorType != models.ActorAdmin || first.ActorID != "key-1" {
+		t.Errorf("first change = %+v, want enabled to suspended by key-1", first)
+	}
+	if got := audit.actions(); len(got) != 3 || got[0] != AuditWalletSuspend || got[1] != AuditWalletReinstate || got[2] != AuditStatusHistoryView {
+		t.Errorf("audited %v, want suspend, reinstate and the history view", got)
+	}
+}
@@ -0,0 +1,266 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"mini-wallet/auth"
+	"mini-wallet/models"
+	"mini-wallet/pin"
+	"mini-wallet/repositories"
+)
+
+// TOTPEnabled reports whether customers can enroll an authenticator app.
+func (s *WalletService) TOTPEnabled() bool {
+	return s.totpRepo != nil
+}
+
+// confirmedEnrollment returns the customer's enrollment once confirmed, or
+// nil when they have none in effect.
+func (s *WalletService) confirmedEnrollment(customerXID string) (*models.TOTPEnrollment, error) {
+	if s.totpRepo == nil {
+		return nil, nil
+	}
+	enrollment, err := s.totpRepo.GetEnrollment(customerXID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if enrollment.ConfirmedAt == nil {
+		return nil, nil
+	}
+	return enrollment, nil
+}
+
+// checkSecondFactor requires a one-time code or recovery code from
+// customers with an enrollment in effect. Others pass.
+func (s *WalletService) checkSecondFactor(customerXID, code string) error {
+	enrollment, err := s.confirmedEnrollment(customerXID)
+	if err != nil || enrollment == nil {
+		return err
+	}
+	if code == "" {
+		return ErrTOTPRequired
+	}
+	return s.useCode(enrollment, code)
+}
+
+// useCode accepts code once: a one-time code of a later time step than the
+// last accepted, or one of the remaining recovery codes.
+func (s *WalletService) useCode(enrollment *models.TOTPEnrollment, code string) error {
+	return s.attemptCode(enrollment.CustomerXID, func(now time.Time) error {
+		if len(code) != auth.Digits {
+			return s.totpRepo.UseRecoveryCode(enrollment.CustomerXID, auth.HashRecoveryCode(code))
+		}
+		counter, ok := auth.Validate(enrollment.Secret, code, now)
+		if !ok {
+			return sql.ErrNoRows
+		}
+		return s.totpRepo.UseCounter(enrollment.CustomerXID, counter)
+	})
+}
+
+// attemptCode runs check on a code of the customer's enrollment, which
+// reports a wrong code as sql.ErrNoRows. As with the PIN, the attempt is
+// counted and any lockout it earns applied before the code is checked; a
+// right code clears both.
+func (s *WalletService) attemptCode(customerXID string, check func(now time.Time) error) error {
+	now := time.Now().UTC()
+	failures, err := s.totpRepo.ClaimAttempt(customerXID, now)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrTOTPLocked
+	}
+	if err != nil {
+		return err
+	}
+	lockout := pin.Lockout(failures, s.cfg.TOTP.MaxAttempts, s.cfg.TOTP.LockoutBase, s.cfg.TOTP.LockoutMax)
+	if lockout > 0 {
+		if err := s.totpRepo.LockCodes(customerXID, now.Add(lockout)); err != nil {
+			return err
+		}
+	}
+
+	err = check(now)
+	if errors.Is(err, sql.ErrNoRows) {
+		if lockout > 0 {
+			return ErrTOTPLocked
+		}
+		return ErrInvalidTOTP
+	}
+	if err != nil {
+		return err
+	}
+	return s.totpRepo.ResetAttempts(customerXID)
+}
+
+// authorizeWithdrawal checks the customer's authorization of money leaving
+// their wallet, as checkWithdrawal does, and uses up its step-up token.
+func (s *WalletService) authorizeWithdrawal(customerXID string, amount int64, authorization Authorization) error {
+	if err := s.checkWithdrawal(customerXID, amount, authorization); err != nil {
+		return err
+	}
+	return s.spendStepUpToken(customerXID, authorization)
+}
+
+// checkWithdrawal checks the customer's authorization of money leaving
+// their wallet: the transaction PIN or a step-up token, and from the
+// configured large withdrawal amount, the one-time code too. The step-up
+// token is left for spendStepUpToken.
+func (s *WalletService) checkWithdrawal(customerXID string, amount int64, authorization Authorization) error {
+	if err := s.authorize(customerXID, authorization); err != nil {
+		return err
+	}
+	if s.cfg.TOTP.LargeWithdrawal > 0 && amount >= s.cfg.TOTP.LargeWithdrawal {
+		return s.checkSecondFactor(customerXID, authorization.TOTPCode)
+	}
+	return nil
+}
+
+// TOTPStatus returns the customer's enrollment, or nil when they have none.
+func (s *WalletService) TOTPStatus(customerXID string) (*models.TOTPEnrollment, error) {
+	if s.totpRepo == nil {
+		return nil, ErrTOTPUnavailable
+	}
+	enrollment, err := s.totpRepo.GetEnrollment(customerXID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return enrollment, err
+}
+
+// EnrollTOTP starts an enrollment with a new secret and recovery codes,
+// replacing an unconfirmed one. It takes effect once ConfirmTOTP gets a
+// code from the authenticator app.
+func (s *WalletService) EnrollTOTP(customerXID string) (*models.TOTPSetup, error) {
+	if s.totpRepo == nil {
+		return nil, ErrTOTPUnavailable
+	}
+	secret, err := auth.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+	codes, err := auth.NewRecoveryCodes(auth.RecoveryCodeCount)
+	if err != nil {
+		return nil, err
+	}
+	enrollment := &models.TOTPEnrollment{
+		CustomerXID:   customerXID,
+		Secret:        secret,
+		RecoveryCodes: hashRecoveryCodes(codes),
+		CreatedAt:     time.Now().UTC(),
+	}
+	if err := s.totpRepo.SaveEnrollment(enrollment); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrTOTPAlreadyEnrolled
+		}
+		return nil, err
+	}
+	return &models.TOTPSetup{
+		Secret:          auth.EncodeSecret(secret),
+		ProvisioningURI: auth.ProvisioningURI(s.cfg.TOTP.Issuer, customerXID, secret),
+		RecoveryCodes:   codes,
+	}, nil
+}
+
+// ConfirmTOTP puts the customer's enrollment into effect given a first
+// one-time code, proving their app has the secret. Wrong codes count
+// towards the lockout like any other.
+func (s *WalletService) ConfirmTOTP(customerXID, code string) error {
+	if s.totpRepo == nil {
+		return ErrTOTPUnavailable
+	}
+	enrollment, err := s.totpRepo.GetEnrollment(customerXID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrTOTPNotEnrolled
+	}
+	if err != nil {
+		return err
+	}
+	if enrollment.ConfirmedAt != nil {
+		return ErrTOTPAlreadyEnrolled
+	}
+	return s.attemptCode(customerXID, func(now time.Time) error {
+		counter, ok := auth.Validate(enrollment.Secret, code, now)
+		if !ok {
+			return sql.ErrNoRows
+		}
+		err := s.totpRepo.ConfirmEnrollment(customerXID, counter, now)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrTOTPAlreadyEnrolled
+		}
+		return err
+	})
+}
+
+// RemoveTOTP removes the customer's enrollment. One in effect takes a
+// one-time code or recovery code.
+func (s *WalletService) RemoveTOTP(customerXID, code string) error {
+	if s.totpRepo == nil {
+		return ErrTOTPUnavailable
+	}
+	if err := s.checkSecondFactor(customerXID, code); err != nil {
+		return err
+	}
+	if err := s.totpRepo.DeleteEnrollment(customerXID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrTOTPNotEnrolled
+		}
+		return err
+	}
+	return nil
+}
+
+// RegenerateRecoveryCodes replaces the customer's recovery codes, for
+// customers who used or lost them. It takes a one-time code or one of the
+// old recovery codes.
+func (s *WalletService) RegenerateRecoveryCodes(customerXID, code string) ([]string, error) {
+	if s.totpRepo == nil {
+		return nil, ErrTOTPUnavailable
+	}
+	enrollment, err := s.confirmedEnrollment(customerXID)
+	if err != nil {
+		return nil, err
+	}
+	if enrollment == nil {
+		return nil, ErrTOTPNotEnrolled
+	}
+	if code == "" {
+		return nil, ErrTOTPRequired
+	}
+	if err := s.useCode(enrollment, code); err != nil {
+		return nil, err
+	}
+	codes, err := auth.NewRecoveryCodes(auth.RecoveryCodeCount)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.totpRepo.ReplaceRecoveryCodes(customerXID, hashRecoveryCodes(codes)); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// RotateToken replaces the customer's token with a new one, which
+// enrolled customers authorize with a one-time code. The old token stops
+// working.
+func (s *WalletService) RotateToken(customerXID string, authorization Authorization) (string, error) {
+	if err := s.checkSecondFactor(customerXID, authorization.TOTPCode); err != nil {
+		return "", err
+	}
+	token := repositories.GenerateToken(customerXID)
+	if err := s.customerTokenRepo.RotateToken(customerXID, token); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+func hashRecoveryCodes(codes []string) []string {
+	hashes := make([]string, len(codes))
+	for i, code := range codes {
+		hashes[i] = auth.HashRecoveryCode(code)
+	}
+	return hashes
+}
@@ -0,0 +1,260 @@
+package service
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"mini-wallet/auth"
+	"mini-wallet/models"
+)
+
+func newTOTPFixture(wallets ...models.Wallet) (*fixture, *mockTOTPRepo) {
+	f := newFixture(wallets...)
+	f.tokens.tokens[customer] = "token-1"
+	// Settlement recomputes the balance from the transactions
+	f.transactions.CreateTransaction(&models.Transaction{ID: "deposit-0", WalletID: "wallet-1", Type: "deposit", Amount: 100})
+	cfg := f.service.cfg
+	cfg.TOTP.Issuer = "Mini Wallet"
+	cfg.TOTP.LargeWithdrawal = 50
+	cfg.TOTP.MaxAttempts = 3
+	cfg.TOTP.LockoutBase = time.Minute
+	cfg.TOTP.LockoutMax = time.Hour
+	totps := newMockTOTPRepo()
+	f.service = NewWalletService(f.wallets, f.transactions, f.snapshots, f.tokens, nil, f.events, nil, nil, nil, nil, totps, cfg)
+	return f, totps
+}
+
+// enroll enrolls the customer and confirms it with the current code,
+// returning the setup and a function giving each further time step's code.
+func enroll(t *testing.T, f *fixture, totps *mockTOTPRepo) (*models.TOTPSetup, func() string) {
+	t.Helper()
+	setup, err := f.service.EnrollTOTP(customer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	secret := totps.enrollments[customer].Secret
+	counter := auth.Counter(time.Now())
+	if err := f.service.ConfirmTOTP(customer, auth.Code(secret, counter)); err != nil {
+		t.Fatal(err)
+	}
+	// Codes of the next time step are accepted too, once each
+	next := func() string {
+		counter++
+		return auth.Code(secret, counter)
+	}
+	return setup, next
+}
+
+func TestEnrollTOTP(t *testing.T) {
+	f, totps := newTOTPFixture(enabledWallet(100))
+
+	setup, err := f.service.EnrollTOTP(customer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(setup.ProvisioningURI, "otpauth://totp/Mini%20Wallet:") || !strings.Contains(setup.ProvisioningURI, "secret="+setup.Secret) {
+		t.Errorf("provisioning URI = %q, want an otpauth URI with the secret", setup.ProvisioningURI)
+	}
+	if len(setup.RecoveryCodes) != auth.RecoveryCodeCount {
+		t.Errorf("got %d recovery codes, want %d", len(setup.RecoveryCodes), auth.RecoveryCodeCount)
+	}
+	for _, hash := range totps.enrollments[customer].RecoveryCodes {
+		if hash == setup.RecoveryCodes[0] {
+			t.Error("a recovery code is stored as is, want only its hash")
+		}
+	}
+
+	// An unconfirmed enrollment asks for nothing and can be replaced
+	if _, err := f.service.RotateToken(customer, Authorization{}); err != nil {
+		t.Errorf("RotateToken before confirming error = %v, want it allowed", err)
+	}
+	if _, err := f.service.EnrollTOTP(customer); err != nil {
+		t.Fatalf("second EnrollTOTP error = %v, want the first replaced", err)
+	}
+	secret := totps.enrollments[customer].Secret
+	if err := f.service.ConfirmTOTP(customer, auth.Code(secret, auth.Counter(time.Now())+5)); !errors.Is(err, ErrInvalidTOTP) {
+		t.Errorf("ConfirmTOTP with a future code error = %v, want ErrInvalidTOTP", err)
+	}
+	if err := f.service.ConfirmTOTP(customer, auth.Code(secret, auth.Counter(time.Now()))); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.service.ConfirmTOTP(customer, auth.Code(secret, auth.Counter(time.Now()))); !errors.Is(err, ErrTOTPAlreadyEnrolled) {
+		t.Errorf("second ConfirmTOTP error = %v, want ErrTOTPAlreadyEnrolled", err)
+	}
+	if _, err := f.service.EnrollTOTP(customer); !errors.Is(err, ErrTOTPAlreadyEnrolled) {
+		t.Errorf("EnrollTOTP once confirmed error = %v, want ErrTOTPAlreadyEnrolled", err)
+	}
+}
+
+func TestTOTPUnavailable(t *testing.T) {
+	f := newFixture(enabledWallet(100))
+
+	if _, err := f.service.EnrollTOTP(customer); !errors.Is(err, ErrTOTPUnavailable) {
+		t.Errorf("EnrollTOTP error = %v, want ErrTOTPUnavailable", err)
+	}
+	if _, err := f.service.Disable(customer, Authorization{}); err != nil {
+		t.Errorf("Disable error = %v, want it allowed without TOTP", err)
+	}
+}
+
+func TestDisableNeedsTOTP(t *testing.T) {
+	f, totps := newTOTPFixture(enabledWallet(100))
+	_, next := enroll(t, f, totps)
+
+	if _, err := f.service.Disable(customer, Authorization{}); !errors.Is(err, ErrTOTPRequired) {
+		t.Errorf("Disable without a code error = %v, want ErrTOTPRequired", err)
+	}
+	if _, err := f.service.Disable(customer, Authorization{TOTPCode: "000000"}); !errors.Is(err, ErrInvalidTOTP) {
+		t.Errorf("Disable with a wrong code error = %v, want ErrInvalidTOTP", err)
+	}
+	if _, err := f.service.Disable(customer, Authorization{TOTPCode: next()}); err != nil {
+		t.Errorf("Disable with the code error = %v, want it disabled", err)
+	}
+}
+
+func TestLargeWithdrawalNeedsTOTP(t *testing.T) {
+	f, totps := newTOTPFixture(enabledWallet(100))
+	_, next := enroll(t, f, totps)
+
+	if _, err := f.service.Withdraw(customer, 10, "withdrawal-1", Authorization{}); err != nil {
+		t.Errorf("small withdrawal error = %v, want it allowed without a code", err)
+	}
+	if _, err := f.service.Withdraw(customer, 50, "withdrawal-2", Authorization{}); !errors.Is(err, ErrTOTPRequired) {
+		t.Errorf("large withdrawal without a code error = %v, want ErrTOTPRequired", err)
+	}
+	code := next()
+	if _, err := f.service.Withdraw(customer, 50, "withdrawal-2", Authorization{TOTPCode: code}); err != nil {
+		t.Fatalf("large withdrawal with the code error = %v, want it allowed", err)
+	}
+	if _, err := f.service.Withdraw(customer, 50, "withdrawal-3", Authorization{TOTPCode: code}); !errors.Is(err, ErrInvalidTOTP) {
+		t.Errorf("reused code error = %v, want ErrInvalidTOTP", err)
+	}
+}
+
+func TestTOTPLockout(t *testing.T) {
+	f, totps := newTOTPFixture(enabledWallet(100))
+	_, next := enroll(t, f, totps)
+	wrong := Authorization{TOTPCode: "000000"}
+
+	for i := 1; i < 3; i++ {
+		if _, err := f.service.Disable(customer, wrong); !errors.Is(err, ErrInvalidTOTP) {
+			t.Fatalf("wrong code %d error = %v, want ErrInvalidTOTP", i, err)
+		}
+	}
+	// Recovery codes count against the same attempts
+	if _, err := f.service.RegenerateRecoveryCodes(customer, "not-a-code"); !errors.Is(err, ErrTOTPLocked) {
+		t.Fatalf("last wrong code error = %v, want ErrTOTPLocked", err)
+	}
+	locked := totps.enrollments[customer].LockedUntil
+	if locked == nil || time.Until(*locked) <= 0 || time.Until(*locked) > time.Minute {
+		t.Fatalf("locked until %v, want a minute from now", locked)
+	}
+	right := Authorization{TOTPCode: next()}
+	if _, err := f.service.Disable(customer, right); !errors.Is(err, ErrTOTPLocked) {
+		t.Errorf("right code while locked error = %v, want ErrTOTPLocked", err)
+	}
+
+	// Each wrong code after the lock ends doubles it
+	past := time.Now().Add(-time.Second)
+	totps.LockCodes(customer, past)
+	if _, err := f.service.Disable(customer, wrong); !errors.Is(err, ErrTOTPLocked) {
+		t.Fatalf("wrong code after the lock error = %v, want ErrTOTPLocked", err)
+	}
+	if locked := totps.enrollments[customer].LockedUntil; time.Until(*locked) <= time.Minute {
+		t.Errorf("locked until %v, want two minutes from now", locked)
+	}
+
+	totps.LockCodes(customer, past)
+	if _, err := f.service.Disable(customer, right); err != nil {
+		t.Fatalf("right code after the lock error = %v, want it allowed", err)
+	}
+	if enrollment := totps.enrollments[customer]; enrollment.FailedAttempts != 0 || enrollment.LockedUntil != nil {
+		t.Errorf("enrollment = %+v, want the attempts and lock cleared", enrollment)
+	}
+}
+
+func TestConfirmTOTPLockout(t *testing.T) {
+	f, totps := newTOTPFixture(enabledWallet(100))
+	if _, err := f.service.EnrollTOTP(customer); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 1; i < 3; i++ {
+		if err := f.service.ConfirmTOTP(customer, "000000"); !errors.Is(err, ErrInvalidTOTP) {
+			t.Fatalf("wrong code %d error = %v, want ErrInvalidTOTP", i, err)
+		}
+	}
+	if err := f.service.ConfirmTOTP(customer, "000000"); !errors.Is(err, ErrTOTPLocked) {
+		t.Fatalf("last wrong code error = %v, want ErrTOTPLocked", err)
+	}
+	// Starting over does not lift the lock
+	if _, err := f.service.EnrollTOTP(customer); err != nil {
+		t.Fatal(err)
+	}
+	right := auth.Code(totps.enrollments[customer].Secret, auth.Counter(time.Now()))
+	if err := f.service.ConfirmTOTP(customer, right); !errors.Is(err, ErrTOTPLocked) {
+		t.Errorf("right code while locked error = %v, want ErrTOTPLocked", err)
+	}
+
+	totps.LockCodes(customer, time.Now().Add(-time.Second))
+	if err := f.service.ConfirmTOTP(customer, right); err != nil {
+		t.Fatalf("right code after the lock error = %v, want it confirmed", err)
+	}
+	if enrollment := totps.enrollments[customer]; enrollment.ConfirmedAt == nil || enrollment.FailedAttempts != 0 || enrollment.LockedUntil != nil {
+		t.Errorf("enrollment = %+v, want it confirmed with the attempts and lock cleared", enrollment)
+	}
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	f, totps := newTOTPFixture(enabledWallet(100))
+	setup, next := enroll(t, f, totps)
+	recovery := setup.RecoveryCodes[0]
+
+	if _, err := f.service.RotateToken(customer, Authorization{TOTPCode: strings.ToUpper(recovery)}); err != nil {
+		t.Fatalf("RotateToken with a recovery code error = %v, want it rotated", err)
+	}
+	if _, err := f.service.RotateToken(customer, Authorization{TOTPCode: recovery}); !errors.Is(err, ErrInvalidTOTP) {
+		t.Errorf("reused recovery code error = %v, want ErrInvalidTOTP", err)
+	}
+	if got := len(totps.enrollments[customer].RecoveryCodes); got != auth.RecoveryCodeCount-1 {
+		t.Errorf("%d recovery codes left, want %d", got, auth.RecoveryCodeCount-1)
+	}
+
+	codes, err := f.service.RegenerateRecoveryCodes(customer, next())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(codes) != auth.RecoveryCodeCount {
+		t.Errorf("got %d recovery codes, want %d", len(codes), auth.RecoveryCodeCount)
+	}
+	if err := f.service.RemoveTOTP(customer, setup.RecoveryCodes[1]); !errors.Is(err, ErrInvalidTOTP) {
+		t.Errorf("RemoveTOTP with an old recovery code error = %v, want ErrInvalidTOTP", err)
+	}
+	if err := f.service.RemoveTOTP(customer, codes[0]); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.service.RotateToken(customer, Authorization{}); err != nil {
+		t.Errorf("RotateToken once removed error = %v, want it allowed without a code", err)
+	}
+}
+
+func TestRotateToken(t *testing.T) {
+	f, totps := newTOTPFixture(enabledWallet(100))
+	_, next := enroll(t, f, totps)
+
+	if _, err := f.service.RotateToken(customer, Authorization{}); !errors.Is(err, ErrTOTPRequired) {
+		t.Errorf("RotateToken without a code error = %v, want ErrTOTPRequired", err)
+	}
+	token, err := f.service.RotateToken(customer, Authorization{TOTPCode: next()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.service.Authenticate("token-1"); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("old token error = %v, want ErrInvalidToken", err)
+	}
+	if got, err := f.service.Authenticate(token); err != nil || got != customer {
+		t.Errorf("new token authenticates %q, %v, want %q", got, err, customer)
+	}
+}
@@ -0,0 +1,75 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"mini-wallet/models"
+
+	"github.com/google/uuid"
+)
+
+// prepareTransfer checks that amount may move from the payer's wallet to
+// the payee's and returns the transfer to post. Its legs take reference
+// IDs derived from reference, so one transfer cannot be posted twice.
+// Authorizing the payer is left to the caller.
+//
+// Transfers stay within the wallets, so they are not screened by the risk
+// rules; money leaving the payee's wallet afterwards is.
+func (s *WalletService) prepareTransfer(payerXID, payeeXID string, amount int64, reference uuid.UUID, at time.Time) (*models.Transfer, error) {
+	payer, err := s.Wallet(payerXID)
+	if err != nil {
+		return nil, err
+	}
+	if err := Allow(payer.Status, OpWithdraw); err != nil {
+		return nil, err
+	}
+	if payeeXID == payerXID {
+		return nil, ErrSelfTransfer
+	}
+	payee, err := s.walletRepo.GetWalletByCustomerXID(payeeXID)
+	if err != nil || payee == nil {
+		return nil, ErrPayeeNotFound
+	}
+	// The payee's own state is none of the payer's business
+	if Allow(payee.Status, OpDeposit) != nil || s.checkTransactionTier(payee, OpDeposit, amount) != nil {
+		return nil, ErrPayeeUnavailable
+	}
+	if amount <= 0 {
+		return nil, ErrInvalidAmount
+	}
+	if payer.Balance < amount {
+		return nil, ErrInsufficientBalance
+	}
+	if err := s.checkTransactionTier(payer, OpWithdraw, amount); err != nil {
+		return nil, err
+	}
+
+	// The references are derived as they were when transfer legs were
+	// stored as withdrawals and deposits, so those posted before still count
+	leg := func(wallet *models.Wallet, transactionType, derivation string) *models.Transaction {
+		return &models.Transaction{
+			ID:           uuid.New().String(),
+			WalletID:     wallet.ID,
+			Type:         transactionType,
+			Status:       "success",
+			Amount:       amount,
+			ReferenceID:  uuid.NewSHA1(reference, []byte(derivation)).String(),
+			TransactedAt: at,
+		}
+	}
+	transfer := &models.Transfer{Out: leg(payer, "transfer_out", "withdrawal"), In: leg(payee, "transfer_in", "deposit")}
+	if _, err := s.transactionRepo.GetTransactionByReferenceID(transfer.Out.ReferenceID); err == nil {
+		return nil, ErrDuplicateReference
+	}
+	return transfer, nil
+}
+
+// transferred tells both customers about a posted transfer. The stored
+// balances already include it; settling refreshes the caches.
+func (s *WalletService) transferred(transfer *models.Transfer, payerXID, payeeXID string) {
+	s.publisher.Publish(context.Background(), TransactionEvent(payerXID, transfer.Out))
+	s.publisher.Publish(context.Background(), TransactionEvent(payeeXID, transfer.In))
+	go s.settleBalance(transfer.Out.WalletID, payerXID)
+	go s.settleBalance(transfer.In.WalletID, payeeXID)
+}
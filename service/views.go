@@ -0,0 +1,95 @@
+package service
+
+import (
+	"time"
+
+	"mini-wallet/events"
+	"mini-wallet/models"
+)
+
+// EnabledWalletView renders an enabled wallet the way the API and its
+// events show it.
+func EnabledWalletView(wallet *models.Wallet) map[string]any {
+	return map[string]any{
+		"id":         wallet.ID,
+		"owned_by":   wallet.OwnedBy,
+		"status":     wallet.Status,
+		"enabled_at": wallet.EnabledAt,
+		"balance":    wallet.Balance,
+	}
+}
+
+// DisabledWalletView renders a disabled wallet the way the API and its
+// events show it.
+func DisabledWalletView(wallet *models.Wallet) map[string]any {
+	return map[string]any{
+		"id":          wallet.ID,
+		"owned_by":    wallet.OwnedBy,
+		"status":      wallet.Status,
+		"disabled_at": wallet.DisabledAt.Format(time.RFC3339),
+		"balance":     wallet.Balance,
+	}
+}
+
+// ClosedWalletView renders a closed wallet with its payout the way the API
+// and its events show it.
+func ClosedWalletView(wallet *models.Wallet, closure *models.WalletClosure) map[string]any {
+	view := map[string]any{
+		"id":              wallet.ID,
+		"owned_by":        wallet.OwnedBy,
+		"status":          wallet.Status,
+		"closed_at":       closure.ClosedAt,
+		"balance":         wallet.Balance,
+		"payout_amount":   closure.PayoutAmount,
+		"anonymize_after": closure.AnonymizeAfter,
+	}
+	if closure.PayoutTransactionID != "" {
+		view["payout_destination"] = closure.PayoutDestination
+		view["payout_transaction_id"] = closure.PayoutTransactionID
+	}
+	return view
+}
+
+// TransactionView renders a deposit, withdrawal, adjustment or transfer leg
+// the way the API and its events show it.
+func TransactionView(customerXID string, transaction *models.Transaction) map[string]any {
+	by, at := "deposited_by", "deposited_at"
+	switch transaction.Type {
+	case "withdrawal":
+		by, at = "withdrawn_by", "withdrawn_at"
+	case "adjustment":
+		by, at = "adjusted_for", "adjusted_at"
+	case "transfer_out":
+		by, at = "sent_by", "sent_at"
+	case "transfer_in":
+		by, at = "received_by", "received_at"
+	}
+	return map[string]any{
+		"id":           transaction.ID,
+		by:             customerXID,
+		"status":       transaction.Status,
+		at:             transaction.TransactedAt,
+		"amount":       transaction.Amount,
+		"reference_id": transaction.ReferenceID,
+	}
+}
+
+// TransactionEvent describes a recorded transaction. The event reuses the
+// transaction ID so event streams can resume from it; its type must be one
+// events.FromTransaction accepts.
+func TransactionEvent(customerXID string, transaction *models.Transaction) events.Event {
+	eventType := events.DepositSucceeded
+	switch transaction.Type {
+	case "withdrawal":
+		eventType = events.WithdrawalSucceeded
+	case "adjustment":
+		eventType = events.AdjustmentPosted
+	case "transfer_out":
+		eventType = events.TransferSent
+	case "transfer_in":
+		eventType = events.TransferReceived
+	}
+	event := events.New(eventType, customerXID, transaction.WalletID, TransactionView(customerXID, transaction))
+	event.ID = transaction.ID
+	return event
+}
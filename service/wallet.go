@@ -0,0 +1,461 @@
+// Package service holds the wallet business rules shared by the REST and
+// gRPC APIs, the admin CLI and the background jobs.
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"math/rand"
+	"time"
+
+	"mini-wallet/config"
+	"mini-wallet/events"
+	"mini-wallet/models"
+	"mini-wallet/repositories"
+	"mini-wallet/risk"
+	"mini-wallet/statements"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+)
+
+type WalletService struct {
+	walletRepo        repositories.WalletRepository
+	transactionRepo   repositories.TransactionRepository
+	snapshotRepo      repositories.SnapshotRepository
+	customerTokenRepo repositories.CustomerTokenRepository
+	redisClient       *redis.Client
+	publisher         events.Publisher
+	riskEngine        *risk.Engine
+	riskRepo          repositories.RiskRepository
+	kycRepo           repositories.KYCRepository
+	pinRepo           repositories.PINRepository
+	totpRepo          repositories.TOTPRepository
+	cfg               config.WalletConfig
+	settleSlots       chan struct{}
+}
+
+// NewWalletService creates a WalletService. redisClient may be nil for
+// callers without Redis, such as walletctl; balances are then neither
+// cached nor recomputed under the wallet lock. A nil riskEngine allows every
+// transaction; riskRepo may then be nil too. A nil kycRepo lets every
+// customer do everything, whatever their KYC tier, and a nil pinRepo lets
+// them withdraw without a transaction PIN. A nil totpRepo turns TOTP off:
+// nobody can enroll and no one-time codes are asked for.
+func NewWalletService(walletRepo repositories.WalletRepository, transactionRepo repositories.TransactionRepository, snapshotRepo repositories.SnapshotRepository, customerTokenRepo repositories.CustomerTokenRepository, redisClient *redis.Client, publisher events.Publisher, riskEngine *risk.Engine, riskRepo repositories.RiskRepository, kycRepo repositories.KYCRepository, pinRepo repositories.PINRepository, totpRepo repositories.TOTPRepository, cfg config.WalletConfig) *WalletService {
+	return &WalletService{
+		walletRepo:        walletRepo,
+		transactionRepo:   transactionRepo,
+		snapshotRepo:      snapshotRepo,
+		customerTokenRepo: customerTokenRepo,
+		redisClient:       redisClient,
+		publisher:         publisher,
+		riskEngine:        riskEngine,
+		riskRepo:          riskRepo,
+		kycRepo:           kycRepo,
+		pinRepo:           pinRepo,
+		totpRepo:          totpRepo,
+		cfg:               cfg,
+		settleSlots:       make(chan struct{}, cfg.SettleWorkers),
+	}
+}
+
+// Init creates the customer's token, a pending wallet and a basic KYC
+// profile, or returns the existing token. Customers who closed their wallet cannot come back until
+// their data is anonymized, and a revoked token is only reinstated by
+// staff issuing a new one.
+func (s *WalletService) Init(customerXID string) (string, error) {
+	exists, err := s.customerTokenRepo.CustomerExists(customerXID)
+	if err != nil {
+		return "", err
+	}
+	if exists {
+		token, err := s.customerTokenRepo.GetToken(customerXID)
+		if errors.Is(err, sql.ErrNoRows) {
+			if wallet, _ := s.walletRepo.GetWalletByCustomerXID(customerXID); wallet != nil && wallet.Status == models.WalletClosed {
+				return "", ErrWalletClosed
+			}
+			return "", ErrTokenRevoked
+		}
+		return token, err
+	}
+
+	token := repositories.GenerateToken(customerXID)
+	if err := s.customerTokenRepo.CreateToken(customerXID, token); err != nil {
+		return "", err
+	}
+	wallet := models.Wallet{
+		ID:      uuid.New().String(),
+		OwnedBy: customerXID,
+		Status:  models.WalletPending,
+	}
+	if err := s.walletRepo.CreateWallet(&wallet); err != nil {
+		return "", err
+	}
+	if s.kycRepo != nil {
+		profile := basicProfile(customerXID)
+		profile.UpdatedAt = time.Now().UTC()
+		if err := s.kycRepo.CreateProfile(profile); err != nil {
+			return "", err
+		}
+	}
+	return token, nil
+}
+
+// Authenticate returns the customer owning an active token.
+func (s *WalletService) Authenticate(token string) (string, error) {
+	customerXID, err := s.customerTokenRepo.GetCustomerXIDByToken(token)
+	if err != nil {
+		return "", ErrInvalidToken
+	}
+	return customerXID, nil
+}
+
+// Wallet returns the customer's wallet, failing when it is missing or its
+// status does not allow viewing it. Callers check what else the status
+// allows.
+func (s *WalletService) Wallet(customerXID string) (*models.Wallet, error) {
+	wallet, err := s.walletRepo.GetWalletByCustomerXID(customerXID)
+	if err != nil || wallet == nil {
+		return nil, ErrWalletNotFound
+	}
+	if err := Allow(wallet.Status, OpView); err != nil {
+		return nil, err
+	}
+	return wallet, nil
+}
+
+// Enable enables the customer's wallet, creating it when it does not exist.
+// The customer's KYC tier must be at least the configured enable tier.
+func (s *WalletService) Enable(customerXID string) (*models.Wallet, error) {
+	if err := s.checkEnableTier(customerXID); err != nil {
+		return nil, err
+	}
+	wallet, err := s.walletRepo.GetWalletByCustomerXID(customerXID)
+	if err != nil || wallet == nil {
+		wallet = &models.Wallet{
+			ID:        uuid.New().String(),
+			OwnedBy:   customerXID,
+			Status:    models.WalletEnabled,
+			EnabledAt: time.Now().UTC(),
+		}
+		if err := s.walletRepo.CreateWallet(wallet); err != nil {
+			return nil, err
+		}
+		s.publisher.Publish(context.Background(), events.New(events.WalletEnabled, customerXID, wallet.ID, EnabledWalletView(wallet)))
+		return wallet, nil
+	}
+	return s.transition(wallet, models.WalletEnabled, Actor{models.ActorCustomer, customerXID}, "", events.WalletEnabled)
+}
+
+// Disable disables the customer's wallet, which auth must authorize with
+// a one-time code when the customer enrolled for TOTP.
+func (s *WalletService) Disable(customerXID string, auth Authorization) (*models.Wallet, error) {
+	wallet, err := s.walletRepo.GetWalletByCustomerXID(customerXID)
+	if err != nil || wallet == nil {
+		return nil, ErrWalletNotFound
+	}
+	if err := CanTransition(wallet.Status, models.WalletDisabled, true); err != nil {
+		return nil, err
+	}
+	if err := s.checkSecondFactor(customerXID, auth.TOTPCode); err != nil {
+		return nil, err
+	}
+	return s.transition(wallet, models.WalletDisabled, Actor{models.ActorCustomer, customerXID}, "", events.WalletDisabled)
+}
+
+// Freeze stops withdrawals from a wallet until it is unfrozen. Customers
+// can still deposit and view the wallet, but can neither enable nor
+// disable it.
+func (s *WalletService) Freeze(walletID string, by Actor, reason string) (*models.Wallet, error) {
+	wallet, err := s.walletByID(walletID)
+	if err != nil {
+		return nil, err
+	}
+	return s.transition(wallet, models.WalletFrozen, by, reason, events.WalletFrozen)
+}
+
+// Unfreeze returns a frozen wallet to enabled.
+func (s *WalletService) Unfreeze(walletID string, by Actor, reason string) (*models.Wallet, error) {
+	wallet, err := s.walletByID(walletID)
+	if err != nil {
+		return nil, err
+	}
+	if wallet.Status != models.WalletFrozen {
+		return nil, ErrWalletNotFrozen
+	}
+	return s.transition(wallet, models.WalletEnabled, by, reason, events.WalletUnfrozen)
+}
+
+// Suspend stops all money movement of a wallet pending an investigation.
+// The customer can still view it.
+func (s *WalletService) Suspend(walletID string, by Actor, reason string) (*models.Wallet, error) {
+	wallet, err := s.walletByID(walletID)
+	if err != nil {
+		return nil, err
+	}
+	if wallet.Status == models.WalletSuspended {
+		return nil, ErrWalletSuspended
+	}
+	return s.transition(wallet, models.WalletSuspended, by, reason, events.WalletSuspended)
+}
+
+// Reinstate returns a suspended wallet to enabled.
+func (s *WalletService) Reinstate(walletID string, by Actor, reason string) (*models.Wallet, error) {
+	wallet, err := s.walletByID(walletID)
+	if err != nil {
+		return nil, err
+	}
+	if wallet.Status != models.WalletSuspended {
+		return nil, ErrWalletNotSuspended
+	}
+	return s.transition(wallet, models.WalletEnabled, by, reason, events.WalletReinstated)
+}
+
+// ForceDisable disables a wallet on behalf of staff, even when it is
+// frozen or suspended.
+func (s *WalletService) ForceDisable(walletID string, by Actor, reason string) (*models.Wallet, error) {
+	wallet, err := s.walletByID(walletID)
+	if err != nil {
+		return nil, err
+	}
+	return s.transition(wallet, models.WalletDisabled, by, reason, events.WalletDisabled)
+}
+
+func (s *WalletService) walletByID(walletID string) (*models.Wallet, error) {
+	wallet, err := s.walletRepo.GetWalletByID(walletID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrWalletNotFound
+	}
+	return wallet, err
+}
+
+// Balance returns the enabled wallet with its stored balance and refreshes
+// the cached balance.
+func (s *WalletService) Balance(customerXID string) (*models.Wallet, error) {
+	wallet, err := s.Wallet(customerXID)
+	if err != nil {
+		return nil, err
+	}
+	s.cacheBalance(context.Background(), customerXID, wallet.Balance)
+	return wallet, nil
+}
+
+// History returns the transactions of the customer's enabled wallet.
+func (s *WalletService) History(customerXID string) ([]models.Transaction, error) {
+	wallet, err := s.Wallet(customerXID)
+	if err != nil {
+		return nil, err
+	}
+	return s.transactionRepo.GetTransactionsByWalletID(wallet.ID)
+}
+
+// Statement streams the statement of the customer's wallet over period
+// into enc. Disabled wallets keep their history, so they get statements too.
+func (s *WalletService) Statement(customerXID string, period statements.Period, enc statements.Encoder) error {
+	wallet, err := s.walletRepo.GetWalletByCustomerXID(customerXID)
+	if err != nil || wallet == nil {
+		return ErrWalletNotFound
+	}
+	opening, err := s.balanceAt(wallet.ID, period.From)
+	if err != nil {
+		return err
+	}
+	return statements.Generate(s.transactionRepo, wallet, period, opening, enc)
+}
+
+// BalanceAt returns the balance the customer's wallet had at the given
+// time, from the transactions recorded before it. Disabled wallets keep
+// their history, so they can be queried too.
+func (s *WalletService) BalanceAt(customerXID string, at time.Time) (*models.Wallet, int64, error) {
+	wallet, err := s.walletRepo.GetWalletByCustomerXID(customerXID)
+	if err != nil || wallet == nil {
+		return nil, 0, ErrWalletNotFound
+	}
+	balance, err := s.balanceAt(wallet.ID, at)
+	if err != nil {
+		return nil, 0, err
+	}
+	return wallet, balance, nil
+}
+
+// Snapshot records the wallet's balance as of the given time.
+func (s *WalletService) Snapshot(walletID string, asOf time.Time) (*models.BalanceSnapshot, error) {
+	balance, err := s.balanceAt(walletID, asOf)
+	if err != nil {
+		return nil, err
+	}
+	snapshot := &models.BalanceSnapshot{
+		WalletID:  walletID,
+		AsOf:      asOf,
+		Balance:   balance,
+		CreatedAt: time.Now().UTC(),
+	}
+	if err := s.snapshotRepo.SaveSnapshot(snapshot); err != nil {
+		return nil, err
+	}
+	return snapshot, nil
+}
+
+// balanceAt starts from the nearest snapshot taken as of at or earlier and
+// adds the transactions recorded after it, or replays the whole history
+// when there is no snapshot yet.
+func (s *WalletService) balanceAt(walletID string, at time.Time) (int64, error) {
+	var balance int64
+	var from time.Time
+	snapshot, err := s.snapshotRepo.GetLatestSnapshot(walletID, at)
+	switch {
+	case err == nil:
+		balance, from = snapshot.Balance, snapshot.AsOf
+	case !errors.Is(err, sql.ErrNoRows):
+		return 0, err
+	}
+
+	change, err := s.transactionRepo.BalanceChange(walletID, from, at)
+	if err != nil {
+		return 0, err
+	}
+	return balance + change, nil
+}
+
+// Deposit records a deposit into the customer's enabled wallet, up to the
+// balance cap of the customer's KYC tier. The stored balance settles
+// asynchronously. A deposit the risk rules hold for review is returned
+// pending and not posted.
+func (s *WalletService) Deposit(customerXID string, amount int64, referenceID string) (*models.Transaction, error) {
+	return s.record(customerXID, "deposit", amount, referenceID, Authorization{})
+}
+
+// Withdraw records a withdrawal from the customer's enabled wallet, checked
+// against the stored balance and the customer's KYC tier and authorized by
+// auth, with a one-time code from the large withdrawal amount. The stored
+// balance settles asynchronously. A withdrawal the risk
+// rules hold for review is returned pending and not posted.
+func (s *WalletService) Withdraw(customerXID string, amount int64, referenceID string, auth Authorization) (*models.Transaction, error) {
+	return s.record(customerXID, "withdrawal", amount, referenceID, auth)
+}
+
+func (s *WalletService) record(customerXID, transactionType string, amount int64, referenceID string, auth Authorization) (*models.Transaction, error) {
+	wallet, err := s.Wallet(customerXID)
+	if err != nil {
+		return nil, err
+	}
+	op := OpDeposit
+	if transactionType == "withdrawal" {
+		op = OpWithdraw
+	}
+	if err := Allow(wallet.Status, op); err != nil {
+		return nil, err
+	}
+	if amount <= 0 {
+		return nil, ErrInvalidAmount
+	}
+	// Nothing about the balance or limits is told before the customer is
+	// authorized
+	if op == OpWithdraw {
+		if err := s.checkWithdrawal(customerXID, amount, auth); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := s.transactionRepo.GetTransactionByReferenceID(referenceID); err == nil {
+		return nil, ErrDuplicateReference
+	}
+	if transactionType == "withdrawal" && wallet.Balance < amount {
+		return nil, ErrInsufficientBalance
+	}
+	if err := s.checkTransactionTier(wallet, op, amount); err != nil {
+		return nil, err
+	}
+
+	transaction := models.Transaction{
+		ID:           uuid.New().String(),
+		WalletID:     wallet.ID,
+		Type:         transactionType,
+		Status:       "success",
+		Amount:       amount,
+		ReferenceID:  referenceID,
+		TransactedAt: time.Now().UTC(),
+	}
+	held, err := s.screen(wallet, &transaction)
+	if err != nil {
+		return nil, err
+	}
+	// A step-up token is spent on a withdrawal the risk rules let through,
+	// even if only to review
+	if err := s.spendStepUpToken(customerXID, auth); err != nil {
+		return nil, err
+	}
+	if held {
+		return &transaction, nil
+	}
+	if err := s.transactionRepo.CreateTransaction(&transaction); err != nil {
+		return nil, err
+	}
+
+	// Defer balance update with a random delay
+	go s.settleBalance(wallet.ID, customerXID)
+
+	s.publisher.Publish(context.Background(), TransactionEvent(customerXID, &transaction))
+	return &transaction, nil
+}
+
+// settleBalance recomputes the stored balance of a wallet from its
+// transactions after a random delay, bounded by the configured worker count.
+func (s *WalletService) settleBalance(walletID string, customerXID string) {
+	if s.cfg.MaxSettleDelay > 0 {
+		time.Sleep(time.Duration(rand.Int63n(int64(s.cfg.MaxSettleDelay))))
+	}
+
+	s.settleSlots <- struct{}{}
+	defer func() { <-s.settleSlots }()
+
+	if _, err := s.RecomputeBalance(context.Background(), walletID, customerXID); err != nil {
+		log.Printf("Failed to settle balance of wallet %s: %v", walletID, err)
+	}
+}
+
+// RecomputeBalance derives the wallet's balance from its transactions and
+// stores it. It holds the wallet lock meanwhile so concurrent settlements
+// never interleave, and fails with ErrWalletLocked when another holds it.
+func (s *WalletService) RecomputeBalance(ctx context.Context, walletID, customerXID string) (int64, error) {
+	if s.redisClient != nil {
+		lockKey := "lock:wallet:" + customerXID
+		lock := s.redisClient.SetNX(ctx, lockKey, "1", s.cfg.LockTTL)
+		if err := lock.Err(); err != nil {
+			return 0, err
+		}
+		if !lock.Val() {
+			return 0, ErrWalletLocked
+		}
+		defer s.redisClient.Del(ctx, lockKey)
+	}
+
+	transactions, err := s.transactionRepo.GetTransactionsByWalletID(walletID)
+	if err != nil {
+		return 0, err
+	}
+	balance := models.Balance(transactions)
+	if err := s.walletRepo.UpdateWalletBalance(walletID, balance); err != nil {
+		return 0, err
+	}
+
+	s.publisher.Publish(ctx, events.New(events.BalanceUpdated, customerXID, walletID, map[string]any{
+		"balance": balance,
+	}))
+	s.cacheBalance(ctx, customerXID, balance)
+	return balance, nil
+}
+
+// cacheBalance refreshes the cached balance, dropping it when the write fails.
+func (s *WalletService) cacheBalance(ctx context.Context, customerXID string, balance int64) {
+	if s.redisClient == nil {
+		return
+	}
+	cacheKey := "wallet_balance:" + customerXID
+	if err := s.redisClient.Set(ctx, cacheKey, balance, s.cfg.BalanceCacheTTL).Err(); err != nil {
+		log.Printf("Failed to update Redis balance for %s: %v", cacheKey, err)
+		s.redisClient.Del(ctx, cacheKey)
+	}
+}
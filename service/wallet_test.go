@@ -0,0 +1,312 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"mini-wallet/config"
+	"mini-wallet/events"
+	"mini-wallet/models"
+)
+
+const (
+	customer  = "ea0212d3-abd6-406f-8c67-868e814a2436"
+	reference = "50535246-dcb2-4929-8cc9-004ea06f5241"
+)
+
+type fixture struct {
+	service      *WalletService
+	wallets      *mockWalletRepo
+	transactions *mockTransactionRepo
+	snapshots    *mockSnapshotRepo
+	tokens       *mockCustomerTokenRepo
+	events       *recorder
+}
+
+func newFixture(wallets ...models.Wallet) *fixture {
+	cfg := config.Default().Wallet
+	cfg.MaxSettleDelay = 0
+
+	f := &fixture{
+		wallets:      newMockWalletRepo(wallets...),
+		transactions: &mockTransactionRepo{},
+		snapshots:    &mockSnapshotRepo{},
+		tokens:       &mockCustomerTokenRepo{tokens: make(map[string]string)},
+		events:       &recorder{},
+	}
+	f.service = NewWalletService(f.wallets, f.transactions, f.snapshots, f.tokens, nil, f.events, nil, nil, nil, nil, nil, cfg)
+	return f
+}
+
+func enabledWallet(balance int64) models.Wallet {
+	return models.Wallet{ID: "wallet-1", OwnedBy: customer, Status: "enabled", Balance: balance}
+}
+
+func disabledWallet() models.Wallet {
+	return models.Wallet{ID: "wallet-1", OwnedBy: customer, Status: "disabled"}
+}
+
+// eventually waits for settlement, which runs in the background.
+func eventually(t *testing.T, condition func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met within a second")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestInit(t *testing.T) {
+	f := newFixture()
+
+	token, err := f.service.Init(customer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wallet := f.wallets.wallet(customer); wallet.Status != models.WalletPending {
+		t.Errorf("new wallet status = %q, want pending", wallet.Status)
+	}
+
+	again, err := f.service.Init(customer)
+	if err != nil || again != token {
+		t.Errorf("second Init = %q, %v, want the existing token %q", again, err, token)
+	}
+}
+
+func TestInitAfterRevoke(t *testing.T) {
+	f := newFixture()
+	token, err := f.service.Init(customer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.tokens.revoke(customer)
+
+	if _, err := f.service.Init(customer); !errors.Is(err, ErrTokenRevoked) {
+		t.Errorf("Init after revoke error = %v, want ErrTokenRevoked", err)
+	}
+	if _, err := f.service.Authenticate(token); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Authenticate(revoked) error = %v, want ErrInvalidToken", err)
+	}
+}
+
+func TestAuthenticate(t *testing.T) {
+	f := newFixture()
+	token, _ := f.service.Init(customer)
+
+	if got, err := f.service.Authenticate(token); err != nil || got != customer {
+		t.Errorf("Authenticate(valid) = %q, %v", got, err)
+	}
+	if _, err := f.service.Authenticate("unknown"); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Authenticate(unknown) error = %v, want ErrInvalidToken", err)
+	}
+}
+
+func TestEnable(t *testing.T) {
+	tests := []struct {
+		name    string
+		wallets []models.Wallet
+		wantErr error
+	}{
+		{"creates a missing wallet", nil, nil},
+		{"enables a disabled wallet", []models.Wallet{disabledWallet()}, nil},
+		{"rejects an enabled wallet", []models.Wallet{enabledWallet(0)}, ErrWalletAlreadyEnabled},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFixture(tt.wallets...)
+
+			wallet, err := f.service.Enable(customer)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Enable error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				if len(f.events.types()) != 0 {
+					t.Errorf("published %v for a failed enable", f.events.types())
+				}
+				return
+			}
+			if wallet.Status != "enabled" || wallet.EnabledAt.IsZero() {
+				t.Errorf("wallet = %+v, want enabled with enabled_at", wallet)
+			}
+			if stored := f.wallets.wallet(customer); stored.Status != "enabled" {
+				t.Errorf("stored status = %q, want enabled", stored.Status)
+			}
+			if got := f.events.types(); len(got) != 1 || got[0] != events.WalletEnabled {
+				t.Errorf("published %v, want [%s]", got, events.WalletEnabled)
+			}
+		})
+	}
+}
+
+func TestDisable(t *testing.T) {
+	tests := []struct {
+		name    string
+		wallets []models.Wallet
+		wantErr error
+	}{
+		{"disables an enabled wallet", []models.Wallet{enabledWallet(50)}, nil},
+		{"rejects a disabled wallet", []models.Wallet{disabledWallet()}, ErrWalletAlreadyDisabled},
+		{"rejects a missing wallet", nil, ErrWalletNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFixture(tt.wallets...)
+
+			wallet, err := f.service.Disable(customer, Authorization{})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Disable error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			if wallet.Status != "disabled" || wallet.DisabledAt.IsZero() || wallet.Balance != 50 {
+				t.Errorf("wallet = %+v, want disabled with disabled_at and its balance", wallet)
+			}
+			if got := f.events.types(); len(got) != 1 || got[0] != events.WalletDisabled {
+				t.Errorf("published %v, want [%s]", got, events.WalletDisabled)
+			}
+		})
+	}
+}
+
+func TestBalanceAndHistoryNeedAnEnabledWallet(t *testing.T) {
+	f := newFixture(disabledWallet())
+
+	if _, err := f.service.Balance(customer); !errors.Is(err, ErrWalletDisabled) {
+		t.Errorf("Balance error = %v, want ErrWalletDisabled", err)
+	}
+	if _, err := f.service.History(customer); !errors.Is(err, ErrWalletDisabled) {
+		t.Errorf("History error = %v, want ErrWalletDisabled", err)
+	}
+	if _, err := newFixture().service.History(customer); !errors.Is(err, ErrWalletNotFound) {
+		t.Errorf("History without wallet error = %v, want ErrWalletNotFound", err)
+	}
+}
+
+func TestDepositSettlesBalance(t *testing.T) {
+	f := newFixture(enabledWallet(100))
+
+	transaction, err := f.service.Deposit(customer, 250, reference)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if transaction.Type != "deposit" || transaction.Amount != 250 || transaction.WalletID != "wallet-1" {
+		t.Errorf("transaction = %+v", transaction)
+	}
+
+	// The balance is derived from the transaction log, not added to the stored one
+	eventually(t, func() bool { return f.wallets.wallet(customer).Balance == 250 })
+	eventually(t, func() bool { return len(f.events.types()) == 2 })
+	if got := f.events.types(); got[0] != events.DepositSucceeded || got[1] != events.BalanceUpdated {
+		t.Errorf("published %v, want deposit then balance", got)
+	}
+	if history, _ := f.service.History(customer); len(history) != 1 {
+		t.Errorf("history has %d transactions, want 1", len(history))
+	}
+}
+
+func TestTransactionRules(t *testing.T) {
+	tests := []struct {
+		name     string
+		wallet   models.Wallet
+		withdraw bool
+		amount   int64
+		wantErr  error
+	}{
+		{"deposit into a disabled wallet", disabledWallet(), false, 10, ErrWalletDisabled},
+		{"zero deposit", enabledWallet(0), false, 0, ErrInvalidAmount},
+		{"withdrawal above the balance", enabledWallet(99), true, 100, ErrInsufficientBalance},
+		{"withdrawal of the whole balance", enabledWallet(100), true, 100, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFixture(tt.wallet)
+
+			record := f.service.Deposit
+			if tt.withdraw {
+				record = func(customerXID string, amount int64, referenceID string) (*models.Transaction, error) {
+					return f.service.Withdraw(customerXID, amount, referenceID, Authorization{})
+				}
+			}
+			_, err := record(customer, tt.amount, reference)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+			recorded, _ := f.transactions.GetTransactionsByWalletID("wallet-1")
+			if want := tt.wantErr == nil; (len(recorded) == 1) != want {
+				t.Errorf("recorded %d transactions, want one: %v", len(recorded), want)
+			}
+		})
+	}
+}
+
+func TestDuplicateReference(t *testing.T) {
+	f := newFixture(enabledWallet(100))
+
+	if _, err := f.service.Deposit(customer, 10, reference); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.service.Withdraw(customer, 10, reference, Authorization{}); !errors.Is(err, ErrDuplicateReference) {
+		t.Errorf("reused reference error = %v, want ErrDuplicateReference", err)
+	}
+}
+
+func TestRecomputeBalance(t *testing.T) {
+	f := newFixture(enabledWallet(999))
+	f.transactions.transactions = []models.Transaction{
+		{WalletID: "wallet-1", Type: "deposit", Amount: 300},
+		{WalletID: "wallet-1", Type: "withdrawal", Amount: 120},
+		{WalletID: "wallet-2", Type: "deposit", Amount: 5000},
+	}
+
+	balance, err := f.service.RecomputeBalance(context.Background(), "wallet-1", customer)
+	if err != nil || balance != 180 {
+		t.Fatalf("RecomputeBalance = %d, %v, want 180", balance, err)
+	}
+	if stored := f.wallets.wallet(customer).Balance; stored != 180 {
+		t.Errorf("stored balance = %d, want 180", stored)
+	}
+}
+
+func TestBalanceAtStartsFromTheNearestSnapshot(t *testing.T) {
+	f := newFixture(disabledWallet())
+	day := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	f.transactions.transactions = []models.Transaction{
+		{WalletID: "wallet-1", Type: "deposit", Amount: 500, TransactedAt: day.Add(-time.Hour)},
+		{WalletID: "wallet-1", Type: "withdrawal", Amount: 200, TransactedAt: day.Add(time.Hour)},
+		{WalletID: "wallet-1", Type: "deposit", Amount: 50, TransactedAt: day.Add(3 * time.Hour)},
+	}
+
+	// Without snapshots the whole history is replayed
+	if _, balance, err := f.service.BalanceAt(customer, day.Add(2*time.Hour)); err != nil || balance != 300 {
+		t.Fatalf("BalanceAt without snapshots = %d, %v, want 300", balance, err)
+	}
+
+	snapshot, err := f.service.Snapshot("wallet-1", day)
+	if err != nil || snapshot.Balance != 500 {
+		t.Fatalf("Snapshot = %+v, %v, want balance 500", snapshot, err)
+	}
+	// A snapshot that disagrees with the log shows it is the starting point
+	f.snapshots.snapshots[0].Balance = 1000
+	if _, balance, err := f.service.BalanceAt(customer, day.Add(2*time.Hour)); err != nil || balance != 800 {
+		t.Errorf("BalanceAt after the snapshot = %d, %v, want 800", balance, err)
+	}
+	if _, balance, err := f.service.BalanceAt(customer, day.Add(-30*time.Minute)); err != nil || balance != 500 {
+		t.Errorf("BalanceAt before the snapshot = %d, %v, want 500", balance, err)
+	}
+
+	if _, _, err := f.service.BalanceAt("unknown", day); !errors.Is(err, ErrWalletNotFound) {
+		t.Errorf("BalanceAt of a missing wallet error = %v, want ErrWalletNotFound", err)
+	}
+}
+
+func TestDomainErrorsHaveKinds(t *testing.T) {
+	var domainErr *Error
+	if !errors.As(error(ErrDuplicateReference), &domainErr) || domainErr.Kind != KindConflict {
+		t.Errorf("ErrDuplicateReference kind = %v, want KindConflict", domainErr.Kind)
+	}
+}
@@ -0,0 +1,43 @@
+package statements
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"time"
+)
+
+// csvEncoder writes one row per transaction, framed by opening and closing
+// balance rows so the file stands on its own in a spreadsheet.
+type csvEncoder struct {
+	w *csv.Writer
+}
+
+func newCSVEncoder(w io.Writer) *csvEncoder {
+	return &csvEncoder{w: csv.NewWriter(w)}
+}
+
+func (e *csvEncoder) Begin(s *Statement) error {
+	e.w.Write([]string{"transacted_at", "id", "type", "status", "reference_id", "amount", "balance"})
+	e.w.Write([]string{s.Period.From.Format(time.RFC3339), "", "opening_balance", "", "", "", strconv.FormatInt(s.OpeningBalance, 10)})
+	return e.w.Error()
+}
+
+func (e *csvEncoder) Entry(entry Entry) error {
+	e.w.Write([]string{
+		entry.TransactedAt.UTC().Format(time.RFC3339),
+		entry.ID,
+		entry.Type,
+		entry.Status,
+		entry.ReferenceID,
+		strconv.FormatInt(entry.SignedAmount(), 10),
+		strconv.FormatInt(entry.Balance, 10),
+	})
+	return e.w.Error()
+}
+
+func (e *csvEncoder) End(s *Statement) error {
+	e.w.Write([]string{s.Period.To.Format(time.RFC3339), "", "closing_balance", "", "", "", strconv.FormatInt(s.ClosingBalance, 10)})
+	e.w.Flush()
+	return e.w.Error()
+}
@@ -0,0 +1,77 @@
+package statements
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+const (
+	// Currency is the currency wallet amounts are held in.
+	Currency = "IDR"
+	// ofxBankID identifies the wallet provider to the importing software.
+	ofxBankID = "MINIWALLET"
+)
+
+// ofxEncoder writes an OFX 2.2 bank statement response, which accounting
+// software imports as a checking account.
+type ofxEncoder struct {
+	w *bufio.Writer
+}
+
+func newOFXEncoder(w io.Writer) *ofxEncoder {
+	return &ofxEncoder{w: bufio.NewWriter(w)}
+}
+
+func ofxTime(t time.Time) string {
+	return t.UTC().Format("20060102150405") + "[0:GMT]"
+}
+
+func ofxText(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+func (e *ofxEncoder) Begin(s *Statement) error {
+	_, err := fmt.Fprintf(e.w, `<?xml version="1.0" encoding="UTF-8" standalone="no"?>
+<?OFX OFXHEADER="200" VERSION="220" SECURITY="NONE" OLDFILEUID="NONE" NEWFILEUID="NONE"?>
+<OFX>
+<SIGNONMSGSRSV1><SONRS>
+<STATUS><CODE>0</CODE><SEVERITY>INFO</SEVERITY></STATUS>
+<DTSERVER>%s</DTSERVER><LANGUAGE>ENG</LANGUAGE>
+</SONRS></SIGNONMSGSRSV1>
+<BANKMSGSRSV1><STMTTRNRS>
+<TRNUID>%s</TRNUID>
+<STATUS><CODE>0</CODE><SEVERITY>INFO</SEVERITY></STATUS>
+<STMTRS>
+<CURDEF>%s</CURDEF>
+<BANKACCTFROM><BANKID>%s</BANKID><ACCTID>%s</ACCTID><ACCTTYPE>CHECKING</ACCTTYPE></BANKACCTFROM>
+<BANKTRANLIST>
+<DTSTART>%s</DTSTART><DTEND>%s</DTEND>
+`, ofxTime(s.GeneratedAt), ofxText(s.WalletID), Currency, ofxBankID, ofxText(s.WalletID), ofxTime(s.Period.From), ofxTime(s.Period.To))
+	return err
+}
+
+func (e *ofxEncoder) Entry(entry Entry) error {
+	trnType := "CREDIT"
+	if entry.SignedAmount() < 0 {
+		trnType = "DEBIT"
+	}
+	_, err := fmt.Fprintf(e.w, "<STMTTRN><TRNTYPE>%s</TRNTYPE><DTPOSTED>%s</DTPOSTED><TRNAMT>%d</TRNAMT><FITID>%s</FITID><REFNUM>%s</REFNUM><NAME>%s</NAME></STMTTRN>\n",
+		trnType, ofxTime(entry.TransactedAt), entry.SignedAmount(), ofxText(entry.ID), ofxText(entry.ReferenceID), ofxText(entry.Type))
+	return err
+}
+
+func (e *ofxEncoder) End(s *Statement) error {
+	fmt.Fprintf(e.w, `</BANKTRANLIST>
+<LEDGERBAL><BALAMT>%d</BALAMT><DTASOF>%s</DTASOF></LEDGERBAL>
+</STMTRS>
+</STMTTRNRS></BANKMSGSRSV1>
+</OFX>
+`, s.ClosingBalance, ofxTime(s.Period.To))
+	return e.w.Flush()
+}
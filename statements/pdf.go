@@ -0,0 +1,105 @@
+package statements
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/jung-kurt/gofpdf"
+)
+
+// pdfColumns are the widths in millimetres of the transaction table, which
+// spans the 190 mm between the A4 margins.
+var pdfColumns = []struct {
+	title string
+	width float64
+	align string
+}{
+	{"Date (UTC)", 36, "L"},
+	{"Type", 24, "L"},
+	{"Reference", 70, "L"},
+	{"Amount", 30, "R"},
+	{"Balance", 30, "R"},
+}
+
+// pdfEncoder lays out a simple A4 statement. The document is assembled in
+// memory by gofpdf and written out when the statement ends.
+type pdfEncoder struct {
+	w   io.Writer
+	pdf *gofpdf.Fpdf
+}
+
+func newPDFEncoder(w io.Writer) *pdfEncoder {
+	return &pdfEncoder{w: w}
+}
+
+func (e *pdfEncoder) Begin(s *Statement) error {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetTitle("Wallet statement", false)
+	pdf.SetCreator("mini-wallet", false)
+	pdf.SetCreationDate(s.GeneratedAt)
+	pdf.AliasNbPages("")
+	pdf.SetHeaderFunc(func() {
+		if pdf.PageNo() > 1 {
+			e.tableHeader()
+		}
+	})
+	pdf.SetFooterFunc(func() {
+		pdf.SetY(-15)
+		pdf.SetFont("Helvetica", "", 8)
+		pdf.CellFormat(0, 10, fmt.Sprintf("Page %d/{nb}", pdf.PageNo()), "", 0, "C", false, 0, "")
+	})
+	e.pdf = pdf
+
+	pdf.AddPage()
+	pdf.SetFont("Helvetica", "B", 16)
+	pdf.CellFormat(0, 10, "Wallet statement", "", 1, "L", false, 0, "")
+	pdf.SetFont("Helvetica", "", 10)
+	last := s.Period.To.Add(-time.Second)
+	for _, line := range []string{
+		"Customer: " + s.CustomerXID,
+		"Wallet: " + s.WalletID,
+		fmt.Sprintf("Period: %s to %s", s.Period.From.Format(time.RFC3339), last.Format(time.RFC3339)),
+		fmt.Sprintf("Opening balance: %s %d", Currency, s.OpeningBalance),
+	} {
+		pdf.CellFormat(0, 6, line, "", 1, "L", false, 0, "")
+	}
+	pdf.Ln(4)
+	e.tableHeader()
+	return pdf.Error()
+}
+
+func (e *pdfEncoder) tableHeader() {
+	e.pdf.SetFont("Helvetica", "B", 9)
+	e.pdf.SetFillColor(230, 230, 230)
+	for _, col := range pdfColumns {
+		e.pdf.CellFormat(col.width, 7, col.title, "1", 0, col.align, true, 0, "")
+	}
+	e.pdf.Ln(-1)
+	e.pdf.SetFont("Helvetica", "", 9)
+}
+
+func (e *pdfEncoder) Entry(entry Entry) error {
+	values := []string{
+		entry.TransactedAt.UTC().Format("2006-01-02 15:04:05"),
+		entry.Type,
+		entry.ReferenceID,
+		strconv.FormatInt(entry.SignedAmount(), 10),
+		strconv.FormatInt(entry.Balance, 10),
+	}
+	for i, col := range pdfColumns {
+		e.pdf.CellFormat(col.width, 6, values[i], "1", 0, col.align, false, 0, "")
+	}
+	e.pdf.Ln(-1)
+	return e.pdf.Error()
+}
+
+func (e *pdfEncoder) End(s *Statement) error {
+	e.pdf.Ln(4)
+	e.pdf.SetFont("Helvetica", "B", 10)
+	e.pdf.CellFormat(0, 6, fmt.Sprintf("Closing balance: %s %d", Currency, s.ClosingBalance), "", 1, "L", false, 0, "")
+	e.pdf.SetFont("Helvetica", "", 8)
+	e.pdf.CellFormat(0, 6, "Generated at "+s.GeneratedAt.Format(time.RFC3339), "", 1, "L", false, 0, "")
+	return e.pdf.Output(e.w)
+}
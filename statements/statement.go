@@ -0,0 +1,163 @@
+// Package statements renders period statements of a wallet: the opening
+// balance, every transaction of the period with the running balance, and
+// the closing balance.
+package statements
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"mini-wallet/models"
+	"mini-wallet/repositories"
+)
+
+// Formats lists the supported statement formats.
+var Formats = []string{"csv", "ofx", "pdf"}
+
+// ErrUnsupportedFormat is returned for a format outside Formats.
+var ErrUnsupportedFormat = errors.New("unsupported statement format")
+
+// Period is the half-open interval [From, To) a statement covers.
+type Period struct {
+	From time.Time
+	To   time.Time
+}
+
+const dateLayout = "2006-01-02"
+
+// PeriodError reports an invalid bound of a statement period.
+type PeriodError struct {
+	Field  string
+	Reason string
+}
+
+func (e *PeriodError) Error() string {
+	return fmt.Sprintf("invalid %s: %s", e.Field, e.Reason)
+}
+
+// ParsePeriod reads the bounds of a statement period. Each bound is a date
+// or an RFC 3339 timestamp; a date as the upper bound includes that whole
+// day. An empty lower bound defaults to the start of the current month and
+// an empty upper bound to now. Errors are *PeriodError.
+func ParsePeriod(from, to string, now time.Time) (Period, error) {
+	now = now.UTC()
+	period := Period{
+		From: time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC),
+		To:   now,
+	}
+	if from != "" {
+		t, err := parseBound(from, false)
+		if err != nil {
+			return Period{}, &PeriodError{Field: "from", Reason: err.Error()}
+		}
+		period.From = t
+	}
+	if to != "" {
+		t, err := parseBound(to, true)
+		if err != nil {
+			return Period{}, &PeriodError{Field: "to", Reason: err.Error()}
+		}
+		period.To = t
+	}
+	if !period.From.Before(period.To) {
+		return Period{}, &PeriodError{Field: "to", Reason: "Must be after from."}
+	}
+	return period, nil
+}
+
+func parseBound(value string, upper bool) (time.Time, error) {
+	if day, err := time.Parse(dateLayout, value); err == nil {
+		if upper {
+			day = day.AddDate(0, 0, 1)
+		}
+		return day, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, errors.New("Not a valid date or RFC 3339 timestamp.")
+	}
+	return t.UTC(), nil
+}
+
+// Statement describes the statement being rendered. ClosingBalance is only
+// known once every entry has been written.
+type Statement struct {
+	WalletID       string
+	CustomerXID    string
+	Period         Period
+	OpeningBalance int64
+	ClosingBalance int64
+	GeneratedAt    time.Time
+}
+
+// Entry is a transaction with the wallet balance after it.
+type Entry struct {
+	models.Transaction
+	Balance int64
+}
+
+// Encoder renders a statement as it is generated, one entry at a time.
+type Encoder interface {
+	Begin(s *Statement) error
+	Entry(e Entry) error
+	End(s *Statement) error
+}
+
+// NewEncoder returns the encoder of format writing to w.
+func NewEncoder(format string, w io.Writer) (Encoder, error) {
+	switch format {
+	case "csv":
+		return newCSVEncoder(w), nil
+	case "ofx":
+		return newOFXEncoder(w), nil
+	case "pdf":
+		return newPDFEncoder(w), nil
+	}
+	return nil, ErrUnsupportedFormat
+}
+
+// ContentType is the media type of a statement in format.
+func ContentType(format string) string {
+	switch format {
+	case "csv":
+		return "text/csv"
+	case "ofx":
+		return "application/x-ofx"
+	case "pdf":
+		return "application/pdf"
+	}
+	return "application/octet-stream"
+}
+
+// Filename names the statement of a period in format.
+func Filename(period Period, format string) string {
+	last := period.To.Add(-time.Nanosecond)
+	return fmt.Sprintf("statement-%s-%s.%s", period.From.Format(dateLayout), last.Format(dateLayout), format)
+}
+
+// Generate streams the statement of wallet over period into enc, starting
+// from the balance at the start of the period. The transactions are read
+// one at a time rather than loaded together.
+func Generate(transactionRepo repositories.TransactionRepository, wallet *models.Wallet, period Period, opening int64, enc Encoder) error {
+	s := &Statement{
+		WalletID:       wallet.ID,
+		CustomerXID:    wallet.OwnedBy,
+		Period:         period,
+		OpeningBalance: opening,
+		ClosingBalance: opening,
+		GeneratedAt:    time.Now().UTC(),
+	}
+	if err := enc.Begin(s); err != nil {
+		return err
+	}
+	err := transactionRepo.StreamTransactions(wallet.ID, period.From, period.To, func(t models.Transaction) error {
+		s.ClosingBalance += t.SignedAmount()
+		return enc.Entry(Entry{Transaction: t, Balance: s.ClosingBalance})
+	})
+	if err != nil {
+		return err
+	}
+	return enc.End(s)
+}
@@ -0,0 +1,32 @@
+// Package webhooks delivers wallet events to the URLs customers subscribe,
+// through a persistent queue retried with exponential backoff.
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+
+	"mini-wallet/events"
+	"mini-wallet/repositories"
+)
+
+// Dispatcher queues a delivery for every subscription interested in an event.
+type Dispatcher struct {
+	webhookRepo repositories.WebhookRepository
+}
+
+func NewDispatcher(webhookRepo repositories.WebhookRepository) *Dispatcher {
+	return &Dispatcher{webhookRepo: webhookRepo}
+}
+
+func (d *Dispatcher) Publish(ctx context.Context, event events.Event) {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("Failed to encode %s event %s: %v", event.Type, event.ID, err)
+		return
+	}
+	if _, err := d.webhookRepo.EnqueueForEvent(event.CustomerXID, event.ID, event.Type, payload); err != nil {
+		log.Printf("Failed to queue webhooks for %s event %s: %v", event.Type, event.ID, err)
+	}
+}
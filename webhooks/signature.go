@@ -0,0 +1,42 @@
+package webhooks
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+)
+
+// Headers set on every delivery.
+const (
+	HeaderEvent     = "X-Wallet-Event"
+	HeaderDelivery  = "X-Wallet-Delivery"
+	HeaderTimestamp = "X-Wallet-Timestamp"
+	HeaderSignature = "X-Wallet-Signature"
+)
+
+// Sign returns the signature header value of a payload: the hex
+// HMAC-SHA256, keyed by the subscription secret, of "<timestamp>.<body>".
+// Receivers recompute it and compare with hmac.Equal.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify reports whether signature matches the payload.
+func Verify(secret string, timestamp int64, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, timestamp, body)), []byte(signature))
+}
+
+// NewSecret generates a random signing secret for a subscription.
+func NewSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(b), nil
+}
@@ -0,0 +1,157 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"mini-wallet/models"
+	"mini-wallet/repositories"
+)
+
+type WorkerOptions struct {
+	Workers      int
+	PollInterval time.Duration
+	BatchSize    int
+	Timeout      time.Duration
+	MaxAttempts  int
+	BackoffBase  time.Duration
+	BackoffMax   time.Duration
+}
+
+// Worker sends queued deliveries. Several workers, in one or more processes,
+// may run against the same queue since deliveries are claimed with a lease.
+type Worker struct {
+	webhookRepo repositories.WebhookRepository
+	client      *http.Client
+	opts        WorkerOptions
+	now         func() time.Time
+}
+
+func NewWorker(webhookRepo repositories.WebhookRepository, opts WorkerOptions) *Worker {
+	return &Worker{
+		webhookRepo: webhookRepo,
+		client:      &http.Client{Timeout: opts.Timeout},
+		opts:        opts,
+		now:         time.Now,
+	}
+}
+
+// Run polls the queue with the configured number of workers until ctx is done.
+func (w *Worker) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for i := 0; i < w.opts.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w.poll(ctx)
+		}()
+	}
+	wg.Wait()
+}
+
+func (w *Worker) poll(ctx context.Context) {
+	ticker := time.NewTicker(w.opts.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		// Keep draining while full batches come back
+		for {
+			n, err := w.ProcessDue(ctx)
+			if err != nil {
+				log.Println("Failed to process webhook deliveries:", err)
+			}
+			if err != nil || n < w.opts.BatchSize {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ProcessDue claims one batch of due deliveries, attempts each and reports
+// how many were claimed.
+func (w *Worker) ProcessDue(ctx context.Context) (int, error) {
+	// The lease outlives one attempt, so a crashed worker's claim expires and
+	// the delivery is retried elsewhere.
+	deliveries, err := w.webhookRepo.ClaimDueDeliveries(w.opts.BatchSize, 2*w.opts.Timeout)
+	if err != nil {
+		return 0, err
+	}
+	for _, delivery := range deliveries {
+		w.attempt(ctx, delivery)
+	}
+	return len(deliveries), nil
+}
+
+func (w *Worker) attempt(ctx context.Context, delivery models.WebhookDelivery) {
+	statusCode, err := w.send(ctx, delivery)
+	if err == nil {
+		if err := w.webhookRepo.MarkDelivered(delivery.ID, statusCode); err != nil {
+			log.Printf("Failed to mark webhook delivery %s delivered: %v", delivery.ID, err)
+		}
+		return
+	}
+
+	attempts := delivery.Attempts + 1
+	dead := attempts >= w.opts.MaxAttempts
+	next := w.now().UTC().Add(w.backoff(attempts))
+	if dead {
+		log.Printf("Webhook delivery %s dead-lettered after %d attempts: %v", delivery.ID, attempts, err)
+	}
+	if err := w.webhookRepo.MarkFailed(delivery.ID, attempts, statusCode, err.Error(), next, dead); err != nil {
+		log.Printf("Failed to record webhook delivery %s failure: %v", delivery.ID, err)
+	}
+}
+
+// send posts the signed payload and returns the receiver's status code. Any
+// non-2xx answer is an error.
+func (w *Worker) send(ctx context.Context, delivery models.WebhookDelivery) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := w.now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "mini-wallet-webhooks/1")
+	req.Header.Set(HeaderEvent, delivery.EventType)
+	req.Header.Set(HeaderDelivery, delivery.ID)
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderSignature, Sign(delivery.Secret, timestamp, delivery.Payload))
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("receiver answered %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+// backoff returns the wait before the attempt following the given number of
+// failed attempts: BackoffBase doubled per attempt, capped at BackoffMax.
+func (w *Worker) backoff(attempts int) time.Duration {
+	delay := w.opts.BackoffBase
+	for i := 1; i < attempts && delay < w.opts.BackoffMax; i++ {
+		delay *= 2
+	}
+	if delay > w.opts.BackoffMax {
+		delay = w.opts.BackoffMax
+	}
+	return delay
+}
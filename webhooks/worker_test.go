@@ -0,0 +1,135 @@
+package webhooks
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"mini-wallet/models"
+	"mini-wallet/repositories"
+)
+
+// queueRepo is an in-memory delivery queue. Only the methods the worker
+// uses are implemented; the rest panic through the nil embedded interface.
+type queueRepo struct {
+	repositories.WebhookRepository
+	due       []models.WebhookDelivery
+	delivered map[string]int
+	failed    map[string]failure
+}
+
+type failure struct {
+	attempts int
+	next     time.Time
+	dead     bool
+}
+
+func (r *queueRepo) ClaimDueDeliveries(limit int, lease time.Duration) ([]models.WebhookDelivery, error) {
+	claimed := r.due
+	r.due = nil
+	return claimed, nil
+}
+
+func (r *queueRepo) MarkDelivered(id string, statusCode int) error {
+	r.delivered[id] = statusCode
+	return nil
+}
+
+func (r *queueRepo) MarkFailed(id string, attempts int, statusCode int, lastError string, next time.Time, dead bool) error {
+	r.failed[id] = failure{attempts: attempts, next: next, dead: dead}
+	return nil
+}
+
+func newTestWorker(repo *queueRepo, now time.Time) *Worker {
+	w := NewWorker(repo, WorkerOptions{
+		Workers:      1,
+		PollInterval: time.Second,
+		BatchSize:    10,
+		Timeout:      time.Second,
+		MaxAttempts:  3,
+		BackoffBase:  10 * time.Second,
+		BackoffMax:   time.Minute,
+	})
+	w.now = func() time.Time { return now }
+	return w
+}
+
+func TestDeliverySignedAndMarkedDelivered(t *testing.T) {
+	payload := []byte(`{"id":"evt","type":"deposit.succeeded"}`)
+	received := make(chan *http.Request, 1)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		timestamp, _ := strconv.ParseInt(r.Header.Get(HeaderTimestamp), 10, 64)
+		if string(body) != string(payload) || !Verify("secret", timestamp, body, r.Header.Get(HeaderSignature)) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		received <- r
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer receiver.Close()
+
+	repo := &queueRepo{
+		due: []models.WebhookDelivery{{
+			ID: "d1", EventType: "deposit.succeeded", Payload: payload, URL: receiver.URL, Secret: "secret",
+		}},
+		delivered: map[string]int{},
+		failed:    map[string]failure{},
+	}
+	n, err := newTestWorker(repo, time.Now()).ProcessDue(context.Background())
+	if err != nil || n != 1 {
+		t.Fatalf("ProcessDue = %d, %v, want 1 delivery", n, err)
+	}
+
+	if repo.delivered["d1"] != http.StatusNoContent {
+		t.Fatalf("delivery not marked delivered, failures: %+v", repo.failed)
+	}
+	r := <-received
+	if r.Header.Get(HeaderEvent) != "deposit.succeeded" || r.Header.Get(HeaderDelivery) != "d1" {
+		t.Errorf("unexpected headers %v", r.Header)
+	}
+}
+
+func TestFailedDeliveryBacksOffThenDeadLetters(t *testing.T) {
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer receiver.Close()
+
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		previousAttempts int
+		wantNext         time.Duration
+		wantDead         bool
+	}{
+		{0, 10 * time.Second, false},
+		{1, 20 * time.Second, false},
+		{2, 40 * time.Second, true},
+	}
+	for _, tt := range tests {
+		repo := &queueRepo{
+			due:       []models.WebhookDelivery{{ID: "d1", Attempts: tt.previousAttempts, URL: receiver.URL, Secret: "secret"}},
+			delivered: map[string]int{},
+			failed:    map[string]failure{},
+		}
+		if _, err := newTestWorker(repo, now).ProcessDue(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+
+		got := repo.failed["d1"]
+		if got.attempts != tt.previousAttempts+1 || got.next != now.Add(tt.wantNext) || got.dead != tt.wantDead {
+			t.Errorf("after %d attempts: got %+v, want next in %s, dead %v", tt.previousAttempts, got, tt.wantNext, tt.wantDead)
+		}
+	}
+}
+
+func TestBackoffIsCapped(t *testing.T) {
+	w := newTestWorker(&queueRepo{}, time.Now())
+	if got := w.backoff(10); got != time.Minute {
+		t.Errorf("backoff(10) = %s, want the one minute cap", got)
+	}
+}